@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/server"
+)
+
+var output string
+
+func init() {
+	flag.StringVar(&output, "output", "-", "Output path. \"-\" means writing to stdout")
+}
+
+func main() {
+	flag.Parse()
+
+	doc, err := server.RESTOpenAPI()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error while generating the OpenAPI document: %+v\n", err)
+		os.Exit(1)
+	}
+
+	if output == "-" {
+		os.Stdout.Write(doc)
+		return
+	}
+	if err := os.WriteFile(output, doc, 0640); err != nil {
+		fmt.Fprintf(os.Stderr, "Error while writing %s: %+v\n", output, err)
+		os.Exit(1)
+	}
+}
@@ -62,15 +62,18 @@ type Diagnostics struct {
 	diagProvider diagnosticsProvider
 	limiter      *rate.Limiter
 	uploader     Uploader
+	redaction    *diagnostics.RedactionPolicy
 }
 
 // NewDiagnostics returns a new Diagnostics handler.
-func NewDiagnostics(log abstractLogger, coord diagnosticsProvider, cfg config.Limit, uploader Uploader) *Diagnostics {
+// The bundles are redacted following the redaction policy, the default profile when nil.
+func NewDiagnostics(log abstractLogger, coord diagnosticsProvider, cfg config.Limit, uploader Uploader, redaction *diagnostics.RedactionPolicy) *Diagnostics {
 	return &Diagnostics{
 		log:          log,
 		diagProvider: coord,
 		limiter:      rate.NewLimiter(rate.Every(cfg.Interval), cfg.Burst),
 		uploader:     uploader,
+		redaction:    redaction,
 	}
 }
 
@@ -150,7 +153,7 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 				h.log.Warn(str)
 			}
 		}()
-		err := diagnostics.ZipArchive(&wBuf, &b, aDiag, uDiag, cDiag)
+		err := h.zipArchive(&wBuf, &b, aDiag, uDiag, cDiag)
 		if err != nil {
 			h.log.Errorw(
 				"diagnostics action handler failed generate zip archive",
@@ -184,6 +187,33 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 	h.log.Debugw(fmt.Sprintf("Diagnostics action complete. Took %s", elapsed), "action", action, "elapsed", elapsed)
 }
 
+// WriteBundle collects the same diagnostics as the Diagnostics actions and writes them to w as a zip archive.
+func (h *Diagnostics) WriteBundle(ctx context.Context, w io.Writer) error {
+	aDiag, err := h.runHooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run diagnostics hooks: %w", err)
+	}
+	uDiag := h.diagUnits(ctx)
+	cDiag := h.diagComponents(ctx)
+
+	var wBuf bytes.Buffer
+	defer func() {
+		if str := wBuf.String(); str != "" {
+			h.log.Warn(str)
+		}
+	}()
+	return h.zipArchive(&wBuf, w, aDiag, uDiag, cDiag)
+}
+
+// zipArchive writes the diagnostics to a zip archive redacted following the redaction policy.
+func (h *Diagnostics) zipArchive(errOut, w io.Writer, aDiag []client.DiagnosticFileResult, uDiag []client.DiagnosticUnitResult, cDiag []client.DiagnosticComponentResult) error {
+	redactor, err := diagnostics.NewRedactor(h.redaction)
+	if err != nil {
+		return err
+	}
+	return diagnostics.ZipArchive(errOut, w, redactor, aDiag, uDiag, cDiag)
+}
+
 // runHooks runs the agent diagnostics hooks.
 func (h *Diagnostics) runHooks(ctx context.Context) ([]client.DiagnosticFileResult, error) {
 	hooks := append(h.diagProvider.DiagnosticHooks(), diagnostics.GlobalHooks()...)
@@ -297,7 +327,7 @@ func (h *Diagnostics) diagFile(aDiag []client.DiagnosticFileResult, uDiag []clie
 			h.log.Warn(str)
 		}
 	}()
-	if err := diagnostics.ZipArchive(&wBuf, f, aDiag, uDiag, cDiag); err != nil {
+	if err := h.zipArchive(&wBuf, f, aDiag, uDiag, cDiag); err != nil {
 		os.Remove(name)
 		return nil, 0, err
 	}
@@ -5,6 +5,8 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"os"
 	"path"
@@ -86,7 +88,7 @@ func TestDiagnosticHandlerHappyPathWithLogs(t *testing.T) {
 	mockDiagProvider := mocks.NewDiagnosticsProvider(t)
 	mockUploader := mocks.NewUploader(t)
 	testLogger, observedLogs := logger.NewTesting("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader, nil)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{hook1})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{mockUnitDiagnostic})
@@ -167,7 +169,7 @@ func TestDiagnosticHandlerUploaderErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mocks.NewDiagnosticsProvider(t)
 	mockUploader := mocks.NewUploader(t)
 	testLogger, observedLogs := logger.NewTesting("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader, nil)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -208,7 +210,7 @@ func TestDiagnosticHandlerZipArchiveErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mocks.NewDiagnosticsProvider(t)
 	mockUploader := mocks.NewUploader(t)
 	testLogger, observedLogs := logger.NewTesting("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader, nil)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -244,7 +246,7 @@ func TestDiagnosticHandlerAckErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mocks.NewDiagnosticsProvider(t)
 	mockUploader := mocks.NewUploader(t)
 	testLogger, observedLogs := logger.NewTesting("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader, nil)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -283,7 +285,7 @@ func TestDiagnosticHandlerCommitErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mocks.NewDiagnosticsProvider(t)
 	mockUploader := mocks.NewUploader(t)
 	testLogger, observedLogs := logger.NewTesting("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader, nil)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -323,7 +325,7 @@ func TestDiagnosticHandlerContexteExpiredErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mocks.NewDiagnosticsProvider(t)
 	mockUploader := mocks.NewUploader(t)
 	testLogger, observedLogs := logger.NewTesting("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, mockUploader, nil)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 
@@ -350,3 +352,30 @@ func TestDiagnosticHandlerContexteExpiredErrorWithLogs(t *testing.T) {
 		1)
 	// we could assert the logs for the hooks, but those will be the same as the happy path, so for brevity we won't
 }
+
+func TestDiagnosticHandlerWriteBundle(t *testing.T) {
+	tempAgentRoot := t.TempDir()
+	paths.SetTop(tempAgentRoot)
+	err := os.MkdirAll(path.Join(tempAgentRoot, "data"), 0755)
+	require.NoError(t, err)
+
+	mockDiagProvider := mocks.NewDiagnosticsProvider(t)
+	testLogger, _ := logger.NewTesting("diagnostic-handler-test")
+	handler := NewDiagnostics(testLogger, mockDiagProvider, defaultRateLimit, nil, nil)
+
+	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{hook1})
+	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{mockUnitDiagnostic})
+	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
+
+	var b bytes.Buffer
+	require.NoError(t, handler.WriteBundle(context.Background(), &b))
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, hook1.Filename)
+	assert.Contains(t, names, "components/ComponentID/UnitID/mock_unit_diag_file.yaml")
+}
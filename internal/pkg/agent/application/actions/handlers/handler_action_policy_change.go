@@ -149,6 +149,7 @@ func (h *PolicyChangeHandler) handleFleetServerHosts(ctx context.Context, c *con
 	prevHost := h.config.Fleet.Client.Host
 	prevHosts := h.config.Fleet.Client.Hosts
 	prevProxy := h.config.Fleet.Client.Transport.Proxy
+	prevCompression := h.config.Fleet.Client.Compression
 	h.config.Fleet.Client.Protocol = cfg.Fleet.Client.Protocol
 	h.config.Fleet.Client.Path = cfg.Fleet.Client.Path
 	h.config.Fleet.Client.Host = cfg.Fleet.Client.Host
@@ -166,6 +167,11 @@ func (h *PolicyChangeHandler) handleFleetServerHosts(ctx context.Context, c *con
 		h.log.Debug("received proxy from fleet, applying it")
 	}
 
+	// Like proxies, an absent compression from fleet keeps the current one.
+	if cfg.Fleet.Client.Compression != "" {
+		h.config.Fleet.Client.Compression = cfg.Fleet.Client.Compression
+	}
+
 	// rollback on failure
 	defer func() {
 		if err != nil {
@@ -174,6 +180,7 @@ func (h *PolicyChangeHandler) handleFleetServerHosts(ctx context.Context, c *con
 			h.config.Fleet.Client.Host = prevHost
 			h.config.Fleet.Client.Hosts = prevHosts
 			h.config.Fleet.Client.Transport.Proxy = prevProxy
+			h.config.Fleet.Client.Compression = prevCompression
 		}
 	}()
 
@@ -228,6 +235,9 @@ func clientEqual(k1 remote.Config, k2 remote.Config) bool {
 	if k1.Path != k2.Path {
 		return false
 	}
+	if k2.Compression != "" && k1.Compression != k2.Compression {
+		return false
+	}
 
 	sort.Strings(k1.Hosts)
 	sort.Strings(k2.Hosts)
@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// rotateAPIKeyTimeout bounds the checkin verifying a new API key, the dispatcher waits for it.
+const rotateAPIKeyTimeout = 30 * time.Second
+
+// CheckinVerifier checks in with fleet-server through a client, reporting the
+// current state of the agent.
+type CheckinVerifier interface {
+	VerifyCheckin(ctx context.Context, sender client.Sender) error
+}
+
+// RotateAPIKey handles ROTATE_API_KEY actions, replacing the API key used to
+// authenticate with fleet-server without re-enrolling.
+type RotateAPIKey struct {
+	log       *logger.Logger
+	agentInfo *info.AgentInfo
+	config    *configuration.Configuration
+	store     storage.Store
+	setters   []actions.ClientSetter
+
+	// verifier performs the checkin verifying the new key.
+	verifier CheckinVerifier
+
+	// newClient creates the fleet-server client for an API key, allows to inject
+	// a client for tests.
+	newClient func(apiKey string) (client.Sender, error)
+}
+
+// NewRotateAPIKey creates a new RotateAPIKey handler. The store persists the fleet
+// configuration holding the new key, it's the encrypted store of the fleet configuration
+// wrapped like at enrollment, see storage.ReplaceOnSuccessStore.
+func NewRotateAPIKey(
+	log *logger.Logger,
+	agentInfo *info.AgentInfo,
+	config *configuration.Configuration,
+	store storage.Store,
+	setters ...actions.ClientSetter,
+) *RotateAPIKey {
+	h := &RotateAPIKey{
+		log:       log,
+		agentInfo: agentInfo,
+		config:    config,
+		store:     store,
+		setters:   setters,
+	}
+	h.newClient = func(apiKey string) (client.Sender, error) {
+		return client.NewAuthWithConfig(h.log, apiKey, h.config.Fleet.Client)
+	}
+	return h
+}
+
+// SetCheckinVerifier sets the verifier of the new keys, the actions fail until it's set.
+func (h *RotateAPIKey) SetCheckinVerifier(v CheckinVerifier) {
+	h.verifier = v
+}
+
+// AddSetter adds a setter into a collection of client setters.
+func (h *RotateAPIKey) AddSetter(cs actions.ClientSetter) {
+	h.setters = append(h.setters, cs)
+}
+
+// Handle handles ROTATE_API_KEY action. The new key is verified with a checkin before
+// being persisted and used, on any error the current key is kept and the error is
+// reported with the action acknowledgement.
+func (h *RotateAPIKey) Handle(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
+	h.log.Debugf("handlerRotateAPIKey: action '%+v' received", a)
+	action, ok := a.(*fleetapi.ActionRotateAPIKey)
+	if !ok {
+		return fmt.Errorf("invalid type, expected ActionRotateAPIKey and received %T", a)
+	}
+
+	sender, err := h.rotate(ctx, action)
+	if err != nil {
+		h.log.Errorw("Failed to rotate the Fleet API key, keeping the current key",
+			"error.message", err, "api_key_id", action.APIKeyID)
+		action.Err = err
+	} else {
+		for _, setter := range h.setters {
+			setter.SetClient(sender)
+		}
+		h.log.Infow("Fleet API key rotated", "api_key_id", action.APIKeyID)
+	}
+
+	if err := acker.Ack(ctx, action); err != nil {
+		h.log.Errorf("failed to acknowledge ROTATE_API_KEY action with id '%s'", action.ActionID)
+	} else if err := acker.Commit(ctx); err != nil {
+		h.log.Errorf("failed to commit acker after acknowledging action with id '%s'", action.ActionID)
+	}
+
+	return action.Err
+}
+
+func (h *RotateAPIKey) rotate(ctx context.Context, action *fleetapi.ActionRotateAPIKey) (client.Sender, error) {
+	if h.config.Fleet.Server != nil {
+		// the agent and its Fleet Server share the key of the bootstrap, re-enroll instead
+		return nil, errors.New("ROTATE_API_KEY action is not supported when running Fleet Server", errors.TypeConfig)
+	}
+	if action.APIKey == "" {
+		return nil, errors.New("ROTATE_API_KEY action has no API key", errors.TypeConfig)
+	}
+	if h.verifier == nil {
+		return nil, errors.New("ROTATE_API_KEY action received before the Fleet gateway started", errors.TypeApplication)
+	}
+	if action.APIKey == h.config.Fleet.AccessAPIKey {
+		return nil, errors.New("ROTATE_API_KEY action API key is the key currently in use", errors.TypeConfig)
+	}
+
+	sender, err := h.newClient(action.APIKey)
+	if err != nil {
+		return nil, errors.New(err, "fail to create API client with the new API key", errors.TypeConfig)
+	}
+
+	if err := h.verify(ctx, sender); err != nil {
+		return nil, err
+	}
+
+	prevAPIKey := h.config.Fleet.AccessAPIKey
+	h.config.Fleet.AccessAPIKey = action.APIKey
+	if err := h.persist(); err != nil {
+		h.config.Fleet.AccessAPIKey = prevAPIKey
+		return nil, err
+	}
+
+	return sender, nil
+}
+
+// verify checks in with fleet-server using the new key, reporting the current state. The
+// checkin has no ack token, fleet-server answers it right away with the actions not
+// acknowledged yet, at least this one. The response is discarded: the regular checkin
+// will receive the actions.
+func (h *RotateAPIKey) verify(ctx context.Context, sender client.Sender) error {
+	ctx, cancel := context.WithTimeout(ctx, rotateAPIKeyTimeout)
+	defer cancel()
+
+	err := h.verifier.VerifyCheckin(ctx, sender)
+	if errors.Is(err, client.ErrInvalidAPIKey) {
+		return errors.New(err, "fleet-server rejected the new API key", errors.TypeSecurity)
+	}
+	if err != nil {
+		return errors.New(err, "fail to checkin with the new API key", errors.TypeNetwork)
+	}
+	return nil
+}
+
+func (h *RotateAPIKey) persist() error {
+	reader, err := fleetToReader(h.agentInfo, h.config)
+	if err != nil {
+		return errors.New(err, "fail to serialize the fleet configuration", errors.TypeUnexpected)
+	}
+
+	if err := h.store.Save(reader); err != nil {
+		return errors.New(err, "fail to persist the new API key", errors.TypeFilesystem)
+	}
+	return nil
+}
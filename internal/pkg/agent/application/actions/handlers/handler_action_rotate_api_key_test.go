@@ -0,0 +1,147 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestRotateAPIKey(t *testing.T) {
+	const (
+		oldAPIKey = "old-api-key"
+		newAPIKey = "new-api-key"
+	)
+
+	fleetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey "+newAPIKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, err := w.Write([]byte(`{"action": "checkin", "actions": []}`))
+		require.NoError(t, err)
+	}))
+	defer fleetServer.Close()
+
+	newConfig := func() *configuration.Configuration {
+		return &configuration.Configuration{
+			Fleet: &configuration.FleetAgentConfig{
+				AccessAPIKey: oldAPIKey,
+				Client:       remote.Config{Host: fleetServer.URL},
+			},
+			Settings: configuration.DefaultSettingsConfig(),
+		}
+	}
+
+	verifier := &testVerifier{}
+
+	tests := []struct {
+		name        string
+		apiKey      string
+		fleetServer bool
+		noVerifier  bool
+		saveErr     error
+		wantErr     bool
+		wantSaved   bool
+	}{
+		{name: "valid key is persisted and used", apiKey: newAPIKey, wantSaved: true},
+		{name: "rejected key keeps the current key", apiKey: "invalid-api-key", wantErr: true},
+		{name: "empty key keeps the current key", apiKey: "", wantErr: true},
+		{name: "current key is not rotated", apiKey: oldAPIKey, wantErr: true},
+		{name: "failed save keeps the current key", apiKey: newAPIKey, saveErr: errors.New("disk full"), wantErr: true},
+		{name: "not supported with Fleet Server", apiKey: newAPIKey, fleetServer: true, wantErr: true},
+		{name: "not supported before the gateway started", apiKey: newAPIKey, noVerifier: true, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			log, _ := logger.NewTesting("TestRotateAPIKey")
+			cfg := newConfig()
+			if tc.fleetServer {
+				cfg.Fleet.Server = &configuration.FleetServerConfig{}
+			}
+			store := &testStore{err: tc.saveErr}
+
+			var setterCalledCount int
+			setter := &testSetter{SetClientFn: func(c client.Sender) {
+				setterCalledCount++
+			}}
+
+			h := NewRotateAPIKey(log, &info.AgentInfo{}, cfg, store, setter)
+			if !tc.noVerifier {
+				h.SetCheckinVerifier(verifier)
+			}
+
+			action := &fleetapi.ActionRotateAPIKey{
+				ActionID:   "rotate-1",
+				ActionType: fleetapi.ActionTypeRotateAPIKey,
+				APIKeyID:   "key-id",
+				APIKey:     tc.apiKey,
+			}
+			tacker := &testAcker{}
+
+			err := h.Handle(context.Background(), action, tacker)
+
+			assert.Equal(t, []string{"rotate-1"}, tacker.Items(), "the action must always be acked")
+			if tc.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, err, action.Err)
+				assert.Equal(t, oldAPIKey, cfg.Fleet.AccessAPIKey)
+				assert.Zero(t, setterCalledCount)
+				assert.Equal(t, err.Error(), action.AckEvent().Error)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, newAPIKey, cfg.Fleet.AccessAPIKey)
+				assert.Equal(t, 1, setterCalledCount)
+				assert.Empty(t, action.AckEvent().Error)
+			}
+
+			if tc.wantSaved {
+				assert.Contains(t, string(store.saved), newAPIKey)
+			} else if tc.saveErr == nil {
+				assert.Empty(t, store.saved)
+			}
+		})
+	}
+}
+
+// testVerifier checks in with an empty request through the client.
+type testVerifier struct{}
+
+func (v *testVerifier) VerifyCheckin(ctx context.Context, sender client.Sender) error {
+	_, _, err := fleetapi.NewCheckinCmd(&info.AgentInfo{}, sender).Execute(ctx, &fleetapi.CheckinRequest{})
+	return err
+}
+
+type testStore struct {
+	saved []byte
+	err   error
+}
+
+func (s *testStore) Save(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.saved = data
+	return nil
+}
@@ -17,6 +17,8 @@ import (
 
 	"github.com/elastic/elastic-agent-libs/logp"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions/handlers"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/capture"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
@@ -28,6 +30,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/composable"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
@@ -47,7 +50,7 @@ func New(
 	fleetInitTimeout time.Duration,
 	disableMonitoring bool,
 	modifiers ...component.PlatformModifier,
-) (*coordinator.Coordinator, coordinator.ConfigManager, composable.Controller, error) {
+) (*coordinator.Coordinator, coordinator.ConfigManager, composable.Controller, *capture.Capturer, error) {
 
 	err := version.InitVersionInformation()
 	if err != nil {
@@ -57,21 +60,24 @@ func New(
 
 	platform, err := component.LoadPlatformDetail(modifiers...)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to gather system information: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to gather system information: %w", err)
 	}
 	log.Info("Gathered system information")
 
 	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to detect inputs and outputs: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to detect inputs and outputs: %w", err)
 	}
 	log.With("inputs", specs.Inputs()).Info("Detected available inputs and outputs")
 
 	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), log)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to determine capabilities: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to determine capabilities: %w", err)
 	}
 	log.Info("Determined allowed capabilities")
+	if max, ok := caps.MaxIsolationGroups(); ok {
+		specs.LimitIsolationGroups(max)
+	}
 
 	pathConfigFile := paths.ConfigFile()
 
@@ -80,7 +86,7 @@ func New(
 		// testing mode doesn't read any configuration from the disk
 		rawConfig, err = config.NewConfigFrom("")
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+			return nil, nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
 		}
 
 		// monitoring is always disabled in testing mode
@@ -89,22 +95,22 @@ func New(
 		log.Infof("Loading baseline config from %v", pathConfigFile)
 		rawConfig, err = config.LoadFile(pathConfigFile)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+			return nil, nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
 		}
 	}
 	if err := info.InjectAgentConfig(rawConfig); err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 	cfg, err := configuration.NewFromConfig(rawConfig)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	// monitoring is not supported in bootstrap mode https://github.com/elastic/elastic-agent/issues/1761
 	isMonitoringSupported := !disableMonitoring && cfg.Settings.V1MonitoringEnabled
 	upgrader, err := upgrade.NewUpgrader(log, cfg.Settings.DownloadConfig, agentInfo)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to create upgrader: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to create upgrader: %w", err)
 	}
 	monitor := monitoring.New(isMonitoringSupported, cfg.Settings.DownloadConfig.OS(), cfg.Settings.MonitoringConfig, agentInfo)
 
@@ -116,13 +122,15 @@ func New(
 		tracer,
 		monitor,
 		cfg.Settings.GRPC,
+		cfg.Settings.ProcessConfig,
 	)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to initialize runtime manager: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to initialize runtime manager: %w", err)
 	}
 
 	var configMgr coordinator.ConfigManager
 	var managed *managedConfigManager
+	var overlay *overlayConfigManager
 	var compModifiers = []coordinator.ComponentsModifier{InjectAPMConfig}
 	var composableManaged bool
 	var isManaged bool
@@ -149,7 +157,7 @@ func New(
 		var store storage.Store
 		store, cfg, err = mergeFleetConfig(ctx, rawConfig)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, err
 		}
 		if configuration.IsFleetServerBootstrap(cfg.Fleet) {
 			log.Info("Parsed configuration and determined agent is in Fleet Server bootstrap mode")
@@ -167,15 +175,21 @@ func New(
 
 			managed, err = newManagedConfigManager(ctx, log, agentInfo, cfg, store, runtime, fleetInitTimeout, upgrader)
 			if err != nil {
-				return nil, nil, nil, err
+				return nil, nil, nil, nil, err
 			}
 			configMgr = coordinator.NewConfigPatchManager(managed, PatchAPMConfig(log, rawConfig))
+			if cfg.Settings.Overlay.Enabled {
+				log.Infof("Local overlay is enabled, merging the inputs of %s into the Fleet policy", paths.ExternalInputs())
+				overlay = newOverlayConfigManager(log, configMgr, cfg.Settings.Overlay.Period,
+					config.Discoverer(paths.ExternalInputs()), config.NewLoader(log, paths.ExternalInputs()), caps)
+				configMgr = overlay
+			}
 		}
 	}
 
 	composable, err := composable.New(log, rawConfig, composableManaged)
 	if err != nil {
-		return nil, nil, nil, errors.New(err, "failed to initialize composable controller")
+		return nil, nil, nil, nil, errors.New(err, "failed to initialize composable controller")
 	}
 
 	coord := coordinator.New(log, cfg, logLevel, agentInfo, specs, reexec, upgrader, runtime, configMgr, composable, caps, monitor, isManaged, compModifiers...)
@@ -184,6 +198,23 @@ func New(
 		// coordinator, so it must be set here once the coordinator is created
 		managed.coord = coord
 	}
+	if overlay != nil {
+		overlay.setOverlayError = coord.SetOverlayError
+	}
+
+	var capturer *capture.Capturer
+	if captureCfg := cfg.Settings.Diagnostics.Capture; captureCfg != nil && captureCfg.Enabled {
+		var uploads capture.Uploader
+		if captureCfg.Upload {
+			if managed != nil {
+				uploads = uploader.New(agentInfo.AgentID(), managed.client, cfg.Settings.MonitoringConfig.Diagnostics.Uploader)
+			} else {
+				log.Warn("Captured diagnostics are only uploaded by Fleet-managed agents, ignoring agent.diagnostics.capture.upload")
+			}
+		}
+		collector := handlers.NewDiagnostics(log, coord, cfg.Settings.MonitoringConfig.Diagnostics.Limit, nil, cfg.Settings.Diagnostics.RedactionPolicy())
+		capturer = capture.New(log.Named("capture"), captureCfg, coord, collector, uploads)
+	}
 
 	// every time we change the limits we'll see the log message
 	limits.AddLimitsOnChangeCallback(func(new, old limits.LimitsConfig) {
@@ -191,16 +222,16 @@ func New(
 	}, "application.go")
 	// applying the initial limits for the agent process
 	if err := limits.Apply(rawConfig); err != nil {
-		return nil, nil, nil, fmt.Errorf("could not parse and apply limits config: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("could not parse and apply limits config: %w", err)
 	}
 
 	// It is important that feature flags from configuration are applied as late as possible.  This will ensure that
 	// any feature flag change callbacks are registered before they get called by `features.Apply`.
 	if err := features.Apply(rawConfig); err != nil {
-		return nil, nil, nil, fmt.Errorf("could not parse and apply feature flags config: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("could not parse and apply feature flags config: %w", err)
 	}
 
-	return coord, configMgr, composable, nil
+	return coord, configMgr, composable, capturer, nil
 }
 
 func mergeFleetConfig(ctx context.Context, rawConfig *config.Config) (storage.Store, *configuration.Configuration, error) {
@@ -0,0 +1,364 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package capture captures diagnostics bundles when the agent or its components misbehave,
+// while the failure can still be observed.
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/go-sysinfo"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// TriggerComponentFailed fires when a component enters the FAILED state.
+	TriggerComponentFailed = "component_failed"
+	// TriggerRestarts fires when a component restarts too often.
+	TriggerRestarts = "restarts"
+	// TriggerMemory fires when the agent or a component uses too much memory.
+	TriggerMemory = "memory"
+	// TriggerSchedule fires at the times of the schedule.
+	TriggerSchedule = "schedule"
+
+	bundlePrefix = "elastic-agent-diagnostics-"
+	bundleExt    = ".zip"
+	// timestampFormat is RFC3339 in UTC with - instead of : so it works on Windows
+	timestampFormat = "2006-01-02T15-04-05Z"
+
+	memoryCheckInterval = 30 * time.Second
+)
+
+// StateProvider provides the state of the agent and its components.
+type StateProvider interface {
+	StateSubscribe(ctx context.Context, bufferLen int) chan coordinator.State
+}
+
+// Collector collects a diagnostics bundle.
+type Collector interface {
+	WriteBundle(ctx context.Context, w io.Writer) error
+}
+
+// Uploader uploads a diagnostics bundle to fleet-server.
+type Uploader interface {
+	UploadDiagnostics(context.Context, string, string, int64, io.Reader) (string, error)
+}
+
+// Capturer captures diagnostics bundles to a local directory when the triggers fire, and
+// uploads them when an uploader is set.
+type Capturer struct {
+	log       *logger.Logger
+	cfg       *configuration.DiagnosticsCaptureConfig
+	dir       string
+	states    StateProvider
+	collector Collector
+	uploader  Uploader
+
+	agentRSS func() (uint64, error)
+	now      func() time.Time
+
+	lastCapture     time.Time
+	components      map[string]*componentState
+	agentOverMemory bool
+}
+
+// componentState tracks a component across the state changes.
+type componentState struct {
+	state      client.UnitState
+	starts     []time.Time
+	overMemory bool
+}
+
+// New creates a Capturer, the bundles are only uploaded with a non-nil uploader.
+func New(log *logger.Logger, cfg *configuration.DiagnosticsCaptureConfig, states StateProvider, collector Collector, uploader Uploader) *Capturer {
+	dir := cfg.Directory
+	if dir == "" {
+		dir = filepath.Join(paths.Data(), "diagnostics")
+	}
+	return &Capturer{
+		log:        log,
+		cfg:        cfg,
+		dir:        dir,
+		states:     states,
+		collector:  collector,
+		uploader:   uploader,
+		agentRSS:   agentRSS,
+		now:        time.Now,
+		components: make(map[string]*componentState),
+	}
+}
+
+// Run watches the triggers until the context is cancelled.
+func (c *Capturer) Run(ctx context.Context) {
+	states := c.states.StateSubscribe(ctx, 32)
+
+	var memoryC <-chan time.Time
+	if c.cfg.Triggers.Memory.RSS > 0 {
+		t := time.NewTicker(memoryCheckInterval)
+		defer t.Stop()
+		memoryC = t.C
+	}
+
+	scheduleTimer := c.scheduleTimer()
+	defer func() {
+		if scheduleTimer != nil {
+			scheduleTimer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case state := <-states:
+			if trigger, reason := c.observe(state, c.now()); trigger != "" {
+				c.capture(ctx, trigger, reason)
+			}
+		case <-memoryC:
+			if reason := c.checkAgentMemory(); reason != "" {
+				c.capture(ctx, TriggerMemory, reason)
+			}
+		case <-timerC(scheduleTimer):
+			c.capture(ctx, TriggerSchedule, fmt.Sprintf("scheduled at %q", c.cfg.Triggers.Schedule))
+			scheduleTimer = c.scheduleTimer()
+		}
+	}
+}
+
+// scheduleTimer returns a timer firing at the next time of the schedule, nil without schedule.
+func (c *Capturer) scheduleTimer() *time.Timer {
+	now := c.now()
+	next := c.cfg.Triggers.NextScheduled(now)
+	if next.IsZero() {
+		return nil
+	}
+	return time.NewTimer(next.Sub(now))
+}
+
+// timerC returns the channel of the timer, nil without timer.
+func timerC(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
+
+// observe updates the tracked components with the state and returns the first trigger it
+// fires with its reason, an empty trigger when none fires.
+func (c *Capturer) observe(state coordinator.State, now time.Time) (string, string) {
+	var trigger, reason string
+	fire := func(t, r string) {
+		if trigger == "" {
+			trigger, reason = t, r
+		}
+	}
+
+	triggers := c.cfg.Triggers
+	seen := make(map[string]bool, len(state.Components))
+	for _, comp := range state.Components {
+		id := comp.Component.ID
+		seen[id] = true
+		current := comp.State.State
+
+		tracked, ok := c.components[id]
+		if !ok {
+			tracked = &componentState{state: client.UnitStateStarting}
+			c.components[id] = tracked
+			if current == client.UnitStateStarting {
+				// the first start isn't a restart
+				tracked.state = current
+			}
+		}
+
+		if current != tracked.state {
+			if current == client.UnitStateFailed && triggers.ComponentFailed {
+				fire(TriggerComponentFailed, fmt.Sprintf("component %s failed: %s", id, comp.State.Message))
+			}
+			if current == client.UnitStateStarting && triggers.Restarts.Count > 0 {
+				tracked.starts = append(tracked.starts, now)
+				since := now.Add(-triggers.Restarts.Period)
+				for len(tracked.starts) > 0 && tracked.starts[0].Before(since) {
+					tracked.starts = tracked.starts[1:]
+				}
+				if len(tracked.starts) >= triggers.Restarts.Count {
+					fire(TriggerRestarts, fmt.Sprintf("component %s restarted %d times in %s", id, len(tracked.starts), triggers.Restarts.Period))
+					tracked.starts = nil
+				}
+			}
+			tracked.state = current
+		}
+
+		if triggers.Memory.RSS > 0 {
+			over := comp.State.Resources != nil && comp.State.Resources.RSS > triggers.Memory.RSS
+			if over && !tracked.overMemory {
+				fire(TriggerMemory, fmt.Sprintf("component %s uses %d bytes of memory, over %d", id, comp.State.Resources.RSS, triggers.Memory.RSS))
+			}
+			tracked.overMemory = over
+		}
+	}
+	for id := range c.components {
+		if !seen[id] {
+			delete(c.components, id)
+		}
+	}
+	return trigger, reason
+}
+
+// checkAgentMemory returns the reason of the memory trigger when the agent goes over the threshold.
+func (c *Capturer) checkAgentMemory() string {
+	rss, err := c.agentRSS()
+	if err != nil {
+		c.log.Debugw("Failed to read the memory of the agent", "error.message", err)
+		return ""
+	}
+	over := rss > c.cfg.Triggers.Memory.RSS
+	fired := over && !c.agentOverMemory
+	c.agentOverMemory = over
+	if !fired {
+		return ""
+	}
+	return fmt.Sprintf("the agent uses %d bytes of memory, over %d", rss, c.cfg.Triggers.Memory.RSS)
+}
+
+// capture writes a bundle to the directory, removes the expired ones and uploads it.
+func (c *Capturer) capture(ctx context.Context, trigger, reason string) {
+	now := c.now()
+	if !c.lastCapture.IsZero() && now.Sub(c.lastCapture) < c.cfg.Cooldown {
+		c.log.Debugw("Skipping the diagnostics capture, a bundle was captured recently",
+			"trigger", trigger, "reason", reason, "last_capture", c.lastCapture)
+		return
+	}
+	c.lastCapture = now
+
+	ts := now.UTC().Format(timestampFormat)
+	path, err := c.writeBundle(ctx, bundlePrefix+ts+"-"+trigger+bundleExt)
+	if err != nil {
+		c.log.Errorw("Failed to capture diagnostics", "trigger", trigger, "reason", reason, "error.message", err)
+		return
+	}
+	c.log.Infow("Captured diagnostics", "trigger", trigger, "reason", reason, "file.path", path)
+	c.cleanup(now)
+
+	if c.uploader == nil {
+		return
+	}
+	uploadID, err := c.upload(ctx, path, "capture-"+trigger+"-"+ts, ts)
+	if err != nil {
+		c.log.Errorw("Failed to upload captured diagnostics", "file.path", path, "error.message", err)
+		return
+	}
+	c.log.Infow("Uploaded captured diagnostics", "file.path", path, "upload_id", uploadID)
+}
+
+// writeBundle writes the bundle to a temporary file renamed once complete, so incomplete
+// bundles are never kept.
+func (c *Capturer) writeBundle(ctx context.Context, name string) (string, error) {
+	if err := os.MkdirAll(c.dir, 0750); err != nil {
+		return "", err
+	}
+	path := filepath.Join(c.dir, name)
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	err = c.collector.WriteBundle(ctx, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return "", err
+	}
+	return path, nil
+}
+
+// cleanup removes the bundles beyond the maximum count and age, the oldest first.
+func (c *Capturer) cleanup(now time.Time) {
+	bundles, err := c.bundles()
+	if err != nil {
+		c.log.Warnw("Failed to list captured diagnostics", "error.message", err)
+		return
+	}
+	for i, b := range bundles {
+		expired := c.cfg.MaxAge > 0 && now.Sub(b.ModTime) > c.cfg.MaxAge
+		if i < len(bundles)-c.cfg.MaxBundles || expired {
+			if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+				c.log.Warnw("Failed to remove captured diagnostics", "file.path", b.Path, "error.message", err)
+			}
+		}
+	}
+}
+
+func (c *Capturer) upload(ctx context.Context, path, id, ts string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	return c.uploader.UploadDiagnostics(ctx, id, ts, fi.Size(), f)
+}
+
+// bundle is a captured diagnostics bundle.
+type bundle struct {
+	Path    string
+	ModTime time.Time
+}
+
+// bundles returns the captured bundles, the oldest first.
+func (c *Capturer) bundles() ([]bundle, error) {
+	entries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var bundles []bundle
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, bundlePrefix) || !strings.HasSuffix(name, bundleExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		bundles = append(bundles, bundle{Path: filepath.Join(c.dir, name), ModTime: info.ModTime()})
+	}
+	// the names start with the UTC timestamp of the capture
+	sort.Slice(bundles, func(i, j int) bool { return bundles[i].Path < bundles[j].Path })
+	return bundles, nil
+}
+
+func agentRSS() (uint64, error) {
+	self, err := sysinfo.Self()
+	if err != nil {
+		return 0, err
+	}
+	mem, err := self.Memory()
+	if err != nil {
+		return 0, err
+	}
+	return mem.Resident, nil
+}
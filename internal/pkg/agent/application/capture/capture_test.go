@@ -0,0 +1,197 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package capture
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+type fakeStates struct {
+	ch chan coordinator.State
+}
+
+func (f *fakeStates) StateSubscribe(context.Context, int) chan coordinator.State {
+	return f.ch
+}
+
+type fakeCollector struct {
+	bundles int
+}
+
+func (f *fakeCollector) WriteBundle(_ context.Context, w io.Writer) error {
+	f.bundles++
+	_, err := w.Write([]byte("bundle"))
+	return err
+}
+
+type fakeUploader struct {
+	ids   []string
+	sizes []int64
+}
+
+func (f *fakeUploader) UploadDiagnostics(_ context.Context, id string, _ string, size int64, r io.Reader) (string, error) {
+	if _, err := io.ReadAll(r); err != nil {
+		return "", err
+	}
+	f.ids = append(f.ids, id)
+	f.sizes = append(f.sizes, size)
+	return "upload-id", nil
+}
+
+func agentState(components ...runtime.ComponentComponentState) coordinator.State {
+	return coordinator.State{Components: components}
+}
+
+func compState(id string, state client.UnitState, rss uint64) runtime.ComponentComponentState {
+	s := runtime.ComponentComponentState{
+		Component: component.Component{ID: id},
+		State:     runtime.ComponentState{State: state, Message: state.String()},
+	}
+	if rss > 0 {
+		s.State.Resources = &runtime.ComponentResources{RSS: rss}
+	}
+	return s
+}
+
+func newTestCapturer(t *testing.T, cfg *configuration.DiagnosticsCaptureConfig) (*Capturer, *fakeCollector, *fakeUploader) {
+	t.Helper()
+	if cfg.Directory == "" {
+		cfg.Directory = t.TempDir()
+	}
+	require.NoError(t, cfg.Validate())
+	log, _ := logger.NewTesting("capture")
+	collector := &fakeCollector{}
+	uploads := &fakeUploader{}
+	return New(log, cfg, &fakeStates{}, collector, uploads), collector, uploads
+}
+
+func TestCapturerTriggers(t *testing.T) {
+	cfg := configuration.DefaultDiagnosticsCaptureConfig()
+	cfg.Triggers.Restarts = configuration.RestartsTrigger{Count: 3, Period: time.Minute}
+	cfg.Triggers.Memory.RSS = 1000
+	c, _, _ := newTestCapturer(t, cfg)
+	now := time.Now()
+
+	trigger, _ := c.observe(agentState(compState("filestream-default", client.UnitStateStarting, 0)), now)
+	assert.Empty(t, trigger, "the first start isn't a restart")
+	trigger, _ = c.observe(agentState(compState("filestream-default", client.UnitStateHealthy, 0)), now)
+	assert.Empty(t, trigger)
+
+	trigger, reason := c.observe(agentState(compState("filestream-default", client.UnitStateFailed, 0)), now)
+	assert.Equal(t, TriggerComponentFailed, trigger)
+	assert.Contains(t, reason, "filestream-default")
+	trigger, _ = c.observe(agentState(compState("filestream-default", client.UnitStateFailed, 0)), now)
+	assert.Empty(t, trigger, "only entering FAILED fires")
+
+	// restarts outside of the period don't count
+	for i, restart := range []time.Duration{0, 2 * time.Minute, 2*time.Minute + time.Second, 2*time.Minute + 2*time.Second} {
+		at := now.Add(restart)
+		trigger, reason = c.observe(agentState(compState("filestream-default", client.UnitStateStarting, 0)), at)
+		if i < 3 {
+			assert.Empty(t, trigger)
+		} else {
+			assert.Equal(t, TriggerRestarts, trigger)
+			assert.Contains(t, reason, "restarted 3 times")
+		}
+		c.observe(agentState(compState("filestream-default", client.UnitStateHealthy, 0)), at)
+	}
+
+	trigger, _ = c.observe(agentState(compState("filestream-default", client.UnitStateHealthy, 999)), now)
+	assert.Empty(t, trigger)
+	trigger, reason = c.observe(agentState(compState("filestream-default", client.UnitStateHealthy, 1001)), now)
+	assert.Equal(t, TriggerMemory, trigger)
+	assert.Contains(t, reason, "1001 bytes")
+	trigger, _ = c.observe(agentState(compState("filestream-default", client.UnitStateHealthy, 1002)), now)
+	assert.Empty(t, trigger, "only going over the threshold fires")
+
+	// a new component entering FAILED fires
+	trigger, _ = c.observe(agentState(compState("system-metrics", client.UnitStateFailed, 0)), now)
+	assert.Equal(t, TriggerComponentFailed, trigger)
+	assert.NotContains(t, c.components, "filestream-default", "removed components aren't tracked")
+
+	rss := uint64(500)
+	c.agentRSS = func() (uint64, error) { return rss, nil }
+	assert.Empty(t, c.checkAgentMemory())
+	rss = 2000
+	assert.Contains(t, c.checkAgentMemory(), "the agent uses 2000 bytes")
+	assert.Empty(t, c.checkAgentMemory())
+}
+
+func TestCapturerRetention(t *testing.T) {
+	cfg := configuration.DefaultDiagnosticsCaptureConfig()
+	cfg.MaxBundles = 2
+	cfg.Cooldown = time.Minute
+	cfg.Upload = true
+	c, collector, uploads := newTestCapturer(t, cfg)
+
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	for i := 0; i < 4; i++ {
+		c.capture(context.Background(), TriggerComponentFailed, "test")
+		now = now.Add(30 * time.Second)
+	}
+	assert.Equal(t, 2, collector.bundles, "the captures in the cooldown are skipped")
+	assert.Equal(t, []string{"capture-component_failed-2024-03-01T12-00-00Z", "capture-component_failed-2024-03-01T12-01-00Z"}, uploads.ids)
+	assert.Equal(t, []int64{6, 6}, uploads.sizes)
+
+	c.capture(context.Background(), TriggerSchedule, "test")
+	entries, err := os.ReadDir(cfg.Directory)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{
+		"elastic-agent-diagnostics-2024-03-01T12-01-00Z-component_failed.zip",
+		"elastic-agent-diagnostics-2024-03-01T12-02-00Z-schedule.zip",
+	}, names)
+
+	// the bundles older than max_age are removed
+	cfg.MaxAge = time.Hour
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Directory, "unrelated.zip"), nil, 0600))
+	old := filepath.Join(cfg.Directory, "elastic-agent-diagnostics-2024-03-01T12-01-00Z-component_failed.zip")
+	require.NoError(t, os.Chtimes(old, now.Add(-2*time.Hour), now.Add(-2*time.Hour)))
+	c.cleanup(now)
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, filepath.Join(cfg.Directory, "elastic-agent-diagnostics-2024-03-01T12-02-00Z-schedule.zip"))
+	assert.FileExists(t, filepath.Join(cfg.Directory, "unrelated.zip"))
+}
+
+func TestCapturerRun(t *testing.T) {
+	cfg := configuration.DefaultDiagnosticsCaptureConfig()
+	c, collector, _ := newTestCapturer(t, cfg)
+	states := make(chan coordinator.State)
+	c.states = &fakeStates{ch: states}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	states <- agentState(compState("filestream-default", client.UnitStateHealthy, 0))
+	states <- agentState(compState("filestream-default", client.UnitStateFailed, 0))
+	// the failure is captured before the next state is received
+	states <- agentState(compState("filestream-default", client.UnitStateHealthy, 0))
+	cancel()
+	<-done
+	assert.Equal(t, 1, collector.bundles)
+}
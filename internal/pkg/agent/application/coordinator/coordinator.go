@@ -8,7 +8,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -20,16 +22,19 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -115,6 +120,9 @@ type RuntimeManager interface {
 	// PerformComponentDiagnostics executes the diagnostic action for the provided components. If no components are provided,
 	// then it performs the diagnostics for all current units.
 	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+
+	// Certificates returns the expiry of the certificates of the component connections.
+	Certificates() []runtime.CertificateInfo
 }
 
 // ConfigChange provides an interface for receiving a new configuration.
@@ -184,6 +192,12 @@ type Coordinator struct {
 
 	monitoringServerReloader configReloader
 
+	// fleetHostsMx protects fleetHosts.
+	fleetHostsMx sync.Mutex
+	// fleetHosts returns the health of the Fleet Server hosts, nil when not
+	// managed by Fleet.
+	fleetHosts func() []remote.HostHealth
+
 	runtimeMgr RuntimeManager
 	configMgr  ConfigManager
 	varsMgr    VarsManager
@@ -223,6 +237,35 @@ type Coordinator struct {
 	// SetUpgradeDetails helper to the Coordinator goroutine.
 	upgradeDetailsChan chan *details.Details
 
+	// integrityErrChan forwards the results of the integrity checks from the
+	// publicly accessible SetIntegrityError helper to the Coordinator goroutine.
+	integrityErrChan chan error
+
+	// overlayErrChan forwards the conflicts of the local overlay from the
+	// publicly accessible SetOverlayError helper to the Coordinator goroutine.
+	overlayErrChan chan error
+
+	// heartbeatChan receives the liveness probes, the Coordinator goroutine
+	// closes the received channel to show its run loop isn't stuck.
+	heartbeatChan chan chan struct{}
+
+	// probeMx protects startedAt and stateChangedAt, they are written on the
+	// Coordinator goroutine and read by the health probes.
+	probeMx sync.RWMutex
+	// startedAt is when the agent first applied a policy and, when managed,
+	// checked in with Fleet successfully. It's zero while the agent is starting.
+	startedAt time.Time
+	// stateChangedAt is when the reported state or message last changed.
+	stateChangedAt time.Time
+	// reportedState is the state last forwarded to the broadcaster, it's only
+	// accessed on the Coordinator goroutine.
+	reportedState State
+	// policyApplied and checkedIn are set once the first policy was applied
+	// and the first checkin with Fleet succeeded, they are only accessed on
+	// the Coordinator goroutine.
+	policyApplied bool
+	checkedIn     bool
+
 	// loglevelCh forwards log level changes from the public API (SetLogLevel)
 	// to the run loop in Coordinator's main goroutine.
 	logLevelCh chan logp.Level
@@ -260,6 +303,23 @@ type Coordinator struct {
 	componentGenErr  error
 	runtimeUpdateErr error
 
+	// integrityErr is set when the installed files don't match the integrity
+	// manifest, it reports agentclient.Degraded.
+	integrityErr error
+
+	// overlayErr is set when inputs of the local overlay were not merged into
+	// the policy, it reports agentclient.Degraded.
+	overlayErr error
+
+	// maintenanceWindows are the windows of the disruptive operations, outside
+	// of them the policy changes stopping components are deferred to the
+	// start of the next window, maintenanceAt, when the maintenanceTimer
+	// fires. The deferred change isn't acknowledged until it's applied.
+	maintenanceWindows configuration.MaintenanceWindows
+	maintenanceTimer   *time.Timer
+	maintenanceAt      time.Time
+	deferredConfig     ConfigChange
+
 	// The raw policy before spec lookup or variable substitution
 	ast *transpiler.AST
 
@@ -273,11 +333,22 @@ type Coordinator struct {
 	// value that is sent to the runtime manager).
 	componentModel []component.Component
 
-	// Disabled for 8.8.0 release in order to limit the surface
-	// https://github.com/elastic/security-team/issues/6501
-
-	// mx         sync.RWMutex
-	// protection protection.Config
+	// protectionMx protects protection.
+	protectionMx sync.RWMutex
+	// protection is the agent protection configuration of the current policy.
+	protection protection.Config
+
+	// tamperLog records the protected operations that were blocked.
+	tamperLog *protection.TamperLog
+	// protectionState persists the protection configuration for the commands
+	// run while the configuration can't be loaded, nil disables it.
+	protectionState *protection.State
+	// protectionSaved is set once protectionState holds the current protection.
+	// Only accessed on the Coordinator goroutine.
+	protectionSaved bool
+	// allowUnsignedProtectionDisable is the local override accepting the unsigned
+	// policies disabling the protection when no signing key is known.
+	allowUnsignedProtectionDisable bool
 }
 
 // The channels Coordinator reads to receive updates from the various managers.
@@ -349,6 +420,21 @@ func New(logger *logger.Logger, cfg *configuration.Configuration, logLevel logp.
 		logLevelCh:         make(chan logp.Level),
 		overrideStateChan:  make(chan *coordinatorOverrideState),
 		upgradeDetailsChan: make(chan *details.Details),
+		integrityErrChan:   make(chan error),
+		overlayErrChan:     make(chan error),
+		heartbeatChan:      make(chan chan struct{}),
+
+		stateChangedAt: time.Now().UTC(),
+		reportedState:  state,
+
+		tamperLog:       protection.NewTamperLog(paths.TamperEventsFile()),
+		protectionState: protection.NewState(paths.ProtectionStateFile()),
+	}
+	if cfg != nil && cfg.Settings != nil {
+		c.maintenanceWindows = cfg.Settings.MaintenanceWindows
+		if cfg.Settings.TamperProtection != nil {
+			c.allowUnsignedProtectionDisable = cfg.Settings.TamperProtection.AllowUnsignedDisable
+		}
 	}
 	// Setup communication channels for any non-nil components. This pattern
 	// lets us transparently accept nil managers / simulated events during
@@ -386,7 +472,23 @@ func New(logger *logger.Logger, cfg *configuration.Configuration, logLevel logp.
 // State returns the current state for the coordinator.
 // Called by external goroutines.
 func (c *Coordinator) State() State {
-	return c.stateBroadcaster.Get()
+	s := c.stateBroadcaster.Get()
+	c.fleetHostsMx.Lock()
+	defer c.fleetHostsMx.Unlock()
+	if c.fleetHosts != nil {
+		s.FleetHosts = c.fleetHosts()
+	}
+	return s
+}
+
+// SetFleetHostsProvider sets the function returning the health of the Fleet
+// Server hosts reported by State. The health changes with every request, so
+// it's read when the state is, instead of being broadcast.
+// Called by external goroutines.
+func (c *Coordinator) SetFleetHostsProvider(fleetHosts func() []remote.HostHealth) {
+	c.fleetHostsMx.Lock()
+	defer c.fleetHostsMx.Unlock()
+	c.fleetHosts = fleetHosts
 }
 
 func (c *Coordinator) RegisterMonitoringServer(s configReloader) {
@@ -414,23 +516,94 @@ func (c *Coordinator) StateSubscribe(ctx context.Context, bufferLen int) chan St
 	return c.stateBroadcaster.Subscribe(ctx, bufferLen)
 }
 
-// Disabled for 8.8.0 release in order to limit the surface
-// https://github.com/elastic/security-team/issues/6501
+// Protection returns the current agent protection configuration.
+// Called by external goroutines.
+func (c *Coordinator) Protection() protection.Config {
+	c.protectionMx.RLock()
+	defer c.protectionMx.RUnlock()
+	return c.protection
+}
+
+// setProtection sets protection configuration and persists it when it changed.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setProtection(protectionConfig protection.Config) {
+	c.protectionMx.Lock()
+	changed := !reflect.DeepEqual(c.protection, protectionConfig)
+	c.protection = protectionConfig
+	c.protectionMx.Unlock()
+
+	if c.protectionState == nil || (c.protectionSaved && !changed) {
+		return
+	}
+	if err := c.protectionState.Save(protectionConfig); err != nil {
+		c.logger.Errorf("Failed to persist the agent protection configuration: %v", err)
+		return
+	}
+	c.protectionSaved = true
+}
+
+// VerifyProtectedOperation verifies the uninstall token of a protected
+// operation when the agent is protected, whether Endpoint is running or not.
+// A failed verification is recorded and reported to Fleet.
+// Called by external goroutines.
+func (c *Coordinator) VerifyProtectedOperation(op protection.Operation, uninstallToken string) error {
+	if !features.TamperProtection() {
+		return nil
+	}
+	err := protection.VerifyOperation(c.Protection(), op, uninstallToken, c.tamperLog)
+	if err != nil {
+		c.logger.Warnw("Blocked a protected operation", "operation", op, "error.message", err)
+	}
+	return err
+}
 
-// // Protection returns the current agent protection configuration
-// // This is needed to be able to access the protection configuration for actions validation
-// func (c *Coordinator) Protection() protection.Config {
-// 	c.mx.RLock()
-// 	defer c.mx.RUnlock()
-// 	return c.protection
-// }
+// verifyProtectionChange returns an error when next disables the protection of
+// the current policy without being signed by Fleet: the signed data of the
+// policy, validated with the current signing key, must disable it. Without a
+// known signing key the change is only accepted with the local override
+// agent.tamper_protection.allow_unsigned_disable.
+func (c *Coordinator) verifyProtectionChange(policy map[string]interface{}, next protection.Config) error {
+	current := c.Protection()
+	if !current.Protected() || next.Protected() || !features.TamperProtection() {
+		return nil
+	}
+	if len(current.SignatureValidationKey) == 0 && c.allowUnsignedProtectionDisable {
+		c.logger.Warn("Agent protection disabled by a policy that can't be verified, no signing key is known and unsigned changes are allowed")
+		return nil
+	}
 
-// // setProtection sets protection configuration
-// func (c *Coordinator) setProtection(protectionConfig protection.Config) {
-// 	c.mx.Lock()
-// 	c.protection = protectionConfig
-// 	c.mx.Unlock()
-// }
+	err := verifySignedProtectionDisable(c.logger, policy, current.SignatureValidationKey)
+	if err == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("refusing to disable the agent protection: %w", err)
+	if c.tamperLog != nil {
+		_ = c.tamperLog.Record(protection.OperationPolicyChange, err)
+	}
+	c.logger.Warnw("Blocked a policy change disabling the agent protection", "error.message", err)
+	return err
+}
+
+// verifySignedProtectionDisable returns an error unless the signed data of policy,
+// validated with key, disables the protection.
+func verifySignedProtectionDisable(log *logger.Logger, policy map[string]interface{}, key []byte) error {
+	if len(key) == 0 {
+		return errors.New("no signing key is known to verify the policy")
+	}
+	signed, _, err := protection.ValidatePolicySignature(log, policy, key)
+	if err != nil {
+		return err
+	}
+	signedCfg, err := protection.GetAgentProtectionConfig(signed)
+	if err != nil && !errors.Is(err, protection.ErrNotFound) {
+		return err
+	}
+	if signedCfg.Protected() {
+		return errors.New("the signed policy keeps the protection enabled")
+	}
+	return nil
+}
 
 // ReExec performs the re-execution.
 // Called from external goroutines.
@@ -804,6 +977,7 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 					Message        string                 `yaml:"message"`
 					FleetState     agentclient.State      `yaml:"fleet_state"`
 					FleetMessage   string                 `yaml:"fleet_message"`
+					FleetHosts     []remote.HostHealth    `yaml:"fleet_hosts,omitempty"`
 					LogLevel       logp.Level             `yaml:"log_level"`
 					Components     []StateComponentOutput `yaml:"components"`
 					UpgradeDetails *details.Details       `yaml:"upgrade_details,omitempty"`
@@ -823,6 +997,7 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 					Message:        s.Message,
 					FleetState:     s.FleetState,
 					FleetMessage:   s.FleetMessage,
+					FleetHosts:     s.FleetHosts,
 					LogLevel:       s.LogLevel,
 					Components:     compStates,
 					UpgradeDetails: s.UpgradeDetails,
@@ -834,6 +1009,27 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 				return o
 			},
 		},
+		{
+			Name:        "certificates",
+			Filename:    "certificates.yaml",
+			Description: "expiry of the certificates of the connections of the components",
+			ContentType: "application/yaml",
+			Hook: func(_ context.Context) []byte {
+				var certs []runtime.CertificateInfo
+				if c.runtimeMgr != nil {
+					certs = c.runtimeMgr.Certificates()
+				}
+				o, err := yaml.Marshal(struct {
+					Certificates []runtime.CertificateInfo `yaml:"certificates"`
+				}{
+					Certificates: certs,
+				})
+				if err != nil {
+					return []byte(fmt.Sprintf("error: %q", err))
+				}
+				return o
+			},
+		},
 	}
 }
 
@@ -924,16 +1120,28 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 		// is successful.
 		c.setRuntimeUpdateError(runtimeErr)
 		if runtimeErr == nil {
+			c.policyApplied = true
 			c.setCoordinatorState(agentclient.Healthy, "Running")
 		}
 
 	case configErr := <-c.managerChans.configManagerError:
 		if c.isManaged {
 			var wErr *WarningError
+			var pErr *PacingError
+			var rErr *RetryAfterError
 			if configErr == nil {
+				c.checkedIn = true
 				c.setFleetState(agentclient.Healthy, "Connected")
+			} else if errors.As(configErr, &pErr) {
+				// the checkin succeeded, fleet-server only delays the next one
+				c.checkedIn = true
+				c.setFleetState(agentclient.Healthy, "Connected, "+pErr.Error())
+			} else if errors.As(configErr, &rErr) {
+				// the checkin failed, fleet-server is overloaded and delays the retry
+				c.setFleetState(agentclient.Degraded, rErr.Error())
 			} else if errors.As(configErr, &wErr) {
 				// we received a warning from Fleet, set state to degraded and the warning as state string
+				c.checkedIn = true
 				c.setFleetState(agentclient.Degraded, wErr.Error())
 			} else {
 				c.setFleetState(agentclient.Failed, configErr.Error())
@@ -955,6 +1163,18 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	case upgradeDetails := <-c.upgradeDetailsChan:
 		c.setUpgradeDetails(upgradeDetails)
 
+	case integrityErr := <-c.integrityErrChan:
+		c.setIntegrityError(integrityErr)
+
+	case overlayErr := <-c.overlayErrChan:
+		c.setOverlayError(overlayErr)
+
+	case <-c.maintenanceC():
+		c.runDeferredOperations(ctx)
+
+	case heartbeat := <-c.heartbeatChan:
+		close(heartbeat)
+
 	case componentState := <-c.managerChans.runtimeManagerUpdate:
 		// New component change reported by the runtime manager via
 		// Coordinator.watchRuntimeComponents(), merge it with the
@@ -962,27 +1182,10 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 		c.applyComponentState(componentState)
 
 	case change := <-c.managerChans.configManagerUpdate:
-		if err := c.processConfig(ctx, change.Config()); err != nil {
-			c.logger.Errorf("applying new policy: %s", err.Error())
-			change.Fail(err)
-		} else {
-			if err := change.Ack(); err != nil {
-				err = fmt.Errorf("failed to ack configuration change: %w", err)
-				// Workaround: setConfigManagerError is usually used by the config
-				// manager to report failed ACKs / etc when communicating with Fleet.
-				// We need to report a failed ACK here, but the policy change has
-				// already been successfully applied so we don't want to report it as
-				// a general Coordinator or policy failure.
-				// This arises uniquely here because this is the only case where an
-				// action is responsible for reporting the failure of its own ACK
-				// call. The "correct" fix is to make this Ack() call unfailable
-				// and handle ACK retries and reporting in the config manager like
-				// with other action types -- this error would then end up invoking
-				// setConfigManagerError "organically" via the config manager's
-				// reporting channel. In the meantime, we do it manually.
-				c.setConfigManagerError(err)
-				c.logger.Errorf("%s", err.Error())
-			}
+		// a deferred policy change is acknowledged once it's applied in the
+		// maintenance window
+		if !c.deferConfig(change) {
+			c.applyConfigChange(ctx, change)
 		}
 
 	case vars := <-c.managerChans.varsManagerUpdate:
@@ -1008,6 +1211,34 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	}
 }
 
+// applyConfigChange applies the policy change and acknowledges it, or fails
+// it when it can't be applied.
+// Always called on the main Coordinator goroutine.
+func (c *Coordinator) applyConfigChange(ctx context.Context, change ConfigChange) {
+	if err := c.processConfig(ctx, change.Config()); err != nil {
+		c.logger.Errorf("applying new policy: %s", err.Error())
+		change.Fail(err)
+	} else {
+		if err := change.Ack(); err != nil {
+			err = fmt.Errorf("failed to ack configuration change: %w", err)
+			// Workaround: setConfigManagerError is usually used by the config
+			// manager to report failed ACKs / etc when communicating with Fleet.
+			// We need to report a failed ACK here, but the policy change has
+			// already been successfully applied so we don't want to report it as
+			// a general Coordinator or policy failure.
+			// This arises uniquely here because this is the only case where an
+			// action is responsible for reporting the failure of its own ACK
+			// call. The "correct" fix is to make this Ack() call unfailable
+			// and handle ACK retries and reporting in the config manager like
+			// with other action types -- this error would then end up invoking
+			// setConfigManagerError "organically" via the config manager's
+			// reporting channel. In the meantime, we do it manually.
+			c.setConfigManagerError(err)
+			c.logger.Errorf("%s", err.Error())
+		}
+	}
+}
+
 // Always called on the main Coordinator goroutine.
 func (c *Coordinator) processConfig(ctx context.Context, cfg *config.Config) (err error) {
 	span, ctx := apm.StartSpan(ctx, "config", "app.internal")
@@ -1022,11 +1253,6 @@ func (c *Coordinator) processConfig(ctx context.Context, cfg *config.Config) (er
 		return err
 	}
 
-	// Disabled for 8.8.0 release in order to limit the surface
-	// https://github.com/elastic/security-team/issues/6501
-
-	// c.setProtection(protectionConfig)
-
 	if c.vars != nil {
 		return c.refreshComponentModel(ctx)
 	}
@@ -1053,12 +1279,14 @@ func (c *Coordinator) generateAST(cfg *config.Config) (err error) {
 		return fmt.Errorf("could not create the map from the configuration: %w", err)
 	}
 
-	// Disabled for 8.8.0 release in order to limit the surface
-	// https://github.com/elastic/security-team/issues/6501
-	// protectionConfig, err := protection.GetAgentProtectionConfig(m)
-	// if err != nil && !errors.Is(err, protection.ErrNotFound) {
-	// 	return fmt.Errorf("could not read the agent protection configuration: %w", err)
-	// }
+	protectionConfig, err := protection.GetAgentProtectionConfig(m)
+	if err != nil && !errors.Is(err, protection.ErrNotFound) {
+		return fmt.Errorf("could not read the agent protection configuration: %w", err)
+	}
+	if err := c.verifyProtectionChange(m, protectionConfig); err != nil {
+		return err
+	}
+	c.setProtection(protectionConfig)
 
 	rawAst, err := transpiler.NewAST(m)
 	if err != nil {
@@ -1172,22 +1400,37 @@ func (c *Coordinator) generateComponentModel() (err error) {
 		c.setComponentGenError(err)
 	}()
 
-	ast := c.ast.Clone()
+	cfg, comps, err := c.renderComponents(c.ast)
+	if err != nil {
+		return err
+	}
+
+	// If we made it this far, update our internal derived values and
+	// return with no error
+	c.derivedConfig = cfg
+	c.componentModel = comps
+	return nil
+}
+
+// renderComponents renders the configuration tree and the components of
+// an AST with the current vars.
+func (c *Coordinator) renderComponents(rawAst *transpiler.AST) (map[string]interface{}, []component.Component, error) {
+	ast := rawAst.Clone()
 	inputs, ok := transpiler.Lookup(ast, "inputs")
 	if ok {
 		renderedInputs, err := transpiler.RenderInputs(inputs, c.vars)
 		if err != nil {
-			return fmt.Errorf("rendering inputs failed: %w", err)
+			return nil, nil, fmt.Errorf("rendering inputs failed: %w", err)
 		}
 		err = transpiler.Insert(ast, renderedInputs, "inputs")
 		if err != nil {
-			return fmt.Errorf("inserting rendered inputs failed: %w", err)
+			return nil, nil, fmt.Errorf("inserting rendered inputs failed: %w", err)
 		}
 	}
 
 	cfg, err := ast.Map()
 	if err != nil {
-		return fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
+		return nil, nil, fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
 	}
 	var configInjector component.GenerateMonitoringCfgFn
 	if c.monitorMgr != nil && c.monitorMgr.Enabled() {
@@ -1201,7 +1444,7 @@ func (c *Coordinator) generateComponentModel() (err error) {
 		c.agentInfo,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to render components: %w", err)
+		return nil, nil, fmt.Errorf("failed to render components: %w", err)
 	}
 
 	// Filter any disallowed inputs/outputs from the components
@@ -1210,15 +1453,10 @@ func (c *Coordinator) generateComponentModel() (err error) {
 	for _, modifier := range c.modifiers {
 		comps, err = modifier(comps, cfg)
 		if err != nil {
-			return fmt.Errorf("failed to modify components: %w", err)
+			return nil, nil, fmt.Errorf("failed to modify components: %w", err)
 		}
 	}
-
-	// If we made it this far, update our internal derived values and
-	// return with no error
-	c.derivedConfig = cfg
-	c.componentModel = comps
-	return nil
+	return cfg, comps, nil
 }
 
 // Filter any inputs and outputs in the generated component model
@@ -1235,8 +1473,9 @@ func (c *Coordinator) filterByCapabilities(comps []component.Component) []compon
 			c.logger.Infof("Component '%v' with input type '%v' filtered by capabilities.yml", component.ID, component.InputType)
 			continue
 		}
-		if !c.caps.AllowOutput(component.OutputType) {
-			c.logger.Infof("Component '%v' with output type '%v' filtered by capabilities.yml", component.ID, component.OutputType)
+		// A component writing to several outputs is filtered when one of them isn't allowed
+		if blockedOutput := capabilities.BlockedOutputType(c.caps, component.OutputTypes()); blockedOutput != "" {
+			c.logger.Infof("Component '%v' with output type '%v' filtered by capabilities.yml", component.ID, blockedOutput)
 			continue
 		}
 		result = append(result, component)
@@ -6,12 +6,14 @@ package coordinator
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 
 	"github.com/elastic/elastic-agent-libs/logp"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 )
@@ -29,6 +31,8 @@ type State struct {
 	// The state of the
 	FleetState   agentclient.State `yaml:"fleet_state"`
 	FleetMessage string            `yaml:"fleet_message"`
+	// The health of the Fleet Server hosts, see Coordinator.SetFleetHostsProvider.
+	FleetHosts []remote.HostHealth `yaml:"fleet_hosts,omitempty"`
 
 	Components []runtime.ComponentComponentState `yaml:"components"`
 	LogLevel   logp.Level                        `yaml:"log_level"`
@@ -63,6 +67,18 @@ func (c *Coordinator) SetUpgradeDetails(upgradeDetails *details.Details) {
 	c.upgradeDetailsChan <- upgradeDetails
 }
 
+// SetIntegrityError sets the result of the last integrity check of the
+// installed files, a non-nil error reports the agent as degraded.
+func (c *Coordinator) SetIntegrityError(err error) {
+	c.integrityErrChan <- err
+}
+
+// SetOverlayError sets the conflicts found merging the local overlay into the
+// Fleet policy, a non-nil error reports the agent as degraded.
+func (c *Coordinator) SetOverlayError(err error) {
+	c.overlayErrChan <- err
+}
+
 // setRuntimeUpdateError reports a failed policy update in the runtime manager.
 // Called on the main Coordinator goroutine.
 func (c *Coordinator) setRuntimeUpdateError(err error) {
@@ -99,6 +115,20 @@ func (c *Coordinator) setConfigError(err error) {
 	c.stateNeedsRefresh = true
 }
 
+// setIntegrityError updates the error state for the integrity check.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setIntegrityError(err error) {
+	c.integrityErr = err
+	c.stateNeedsRefresh = true
+}
+
+// setOverlayError updates the error state for the local overlay.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setOverlayError(err error) {
+	c.overlayErr = err
+	c.stateNeedsRefresh = true
+}
+
 // setComponentGenError updates the error state for generating a component
 // model from an AST and variables.
 // Called on the main Coordinator goroutine.
@@ -125,10 +155,32 @@ func (c *Coordinator) setUpgradeDetails(upgradeDetails *details.Details) {
 // Forward the current state to the broadcaster and clear the stateNeedsRefresh
 // flag. Must be called on the main Coordinator goroutine.
 func (c *Coordinator) refreshState() {
-	c.stateBroadcaster.InputChan <- c.generateReportableState()
+	s := c.generateReportableState()
+	c.updateProbeState(s)
+	c.stateBroadcaster.InputChan <- s
 	c.stateNeedsRefresh = false
 }
 
+// updateProbeState records when the reported state changed and when the
+// agent completed its startup, for the health probes.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) updateProbeState(s State) {
+	now := time.Now().UTC()
+	// the fleet state is reported for failed checkins as well, only a
+	// successful one ends the startup
+	started := c.policyApplied && (!c.isManaged || c.checkedIn)
+
+	c.probeMx.Lock()
+	defer c.probeMx.Unlock()
+	if s.State != c.reportedState.State || s.Message != c.reportedState.Message {
+		c.stateChangedAt = now
+	}
+	if started && c.startedAt.IsZero() {
+		c.startedAt = now
+	}
+	c.reportedState = s
+}
+
 // applyComponentState merges a changed component state into the overall
 // Coordinator state and sets stateNeedsRefresh.
 // Must be called on the main Coordinator goroutine.
@@ -179,6 +231,8 @@ func (c *Coordinator) generateReportableState() (s State) {
 	// - Override state, if present
 	// - Errors applying the configured policy (report Failed)
 	// - Errors reported by managers (report Failed)
+	// - Installed files not matching the integrity manifest (report Degraded)
+	// - Conflicts of the local overlay (report Degraded)
 	// - Errors in component/unit state (report Degraded)
 	if c.overrideState != nil {
 		// state has been overridden by an upgrade in progress
@@ -202,6 +256,12 @@ func (c *Coordinator) generateReportableState() (s State) {
 	} else if c.varsMgrErr != nil {
 		s.State = agentclient.Failed
 		s.Message = fmt.Sprintf("Vars manager: %s", c.varsMgrErr.Error())
+	} else if c.integrityErr != nil {
+		s.State = agentclient.Degraded
+		s.Message = fmt.Sprintf("Integrity check: %s", c.integrityErr.Error())
+	} else if c.overlayErr != nil {
+		s.State = agentclient.Degraded
+		s.Message = fmt.Sprintf("Local overlay: %s", c.overlayErr.Error())
 	} else if hasState(s.Components, client.UnitStateFailed) {
 		s.State = agentclient.Degraded
 		s.Message = "1 or more components/units in a failed state"
@@ -213,6 +273,10 @@ func (c *Coordinator) generateReportableState() (s State) {
 		// Coordinator state.
 		s.State = s.CoordinatorState
 		s.Message = s.CoordinatorMessage
+		if deferred := c.deferredOperations(); deferred != "" {
+			s.Message = fmt.Sprintf("%s, %s deferred to the maintenance window at %s",
+				s.Message, deferred, c.maintenanceAt.Format(time.RFC3339))
+		}
 	}
 	return s
 }
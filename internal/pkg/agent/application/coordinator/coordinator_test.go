@@ -40,6 +40,7 @@ import (
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/core/process"
 )
 
 const (
@@ -231,6 +232,20 @@ func TestCoordinator_State_ConfigError_Managed(t *testing.T) {
 		return state.State == agentclient.Healthy && state.Message == "Running" && state.FleetState == agentclient.Degraded && state.FleetMessage == "some msg from Fleet"
 	}, 3*time.Second, 10*time.Millisecond)
 
+	// report checkins paced by fleet-server
+	cfgMgr.ReportError(ctx, NewPacingError(time.Minute, "checkin_interval"))
+	assert.Eventually(t, func() bool {
+		state := coord.State()
+		return state.FleetState == agentclient.Healthy && state.FleetMessage == "Connected, next checkin in 1m0s as requested by fleet-server (checkin_interval)"
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// report a failed checkin delayed by fleet-server
+	cfgMgr.ReportError(ctx, NewRetryAfterError(time.Minute, errors.New("status code: 503")))
+	assert.Eventually(t, func() bool {
+		state := coord.State()
+		return state.FleetState == agentclient.Degraded && state.FleetMessage == "checkin failed, retrying in 1m0s as requested by fleet-server: status code: 503"
+	}, 3*time.Second, 10*time.Millisecond)
+
 	// recover from warning error
 	cfgMgr.ReportError(ctx, nil)
 	assert.Eventually(t, func() bool {
@@ -581,7 +596,7 @@ func createCoordinator(t *testing.T, ctx context.Context, opts ...CoordinatorOpt
 	require.NoError(t, err)
 
 	monitoringMgr := newTestMonitoringMgr()
-	rm, err := runtime.NewManager(l, l, "localhost:0", ai, apmtest.DiscardTracer, monitoringMgr, configuration.DefaultGRPCConfig())
+	rm, err := runtime.NewManager(l, l, "localhost:0", ai, apmtest.DiscardTracer, monitoringMgr, configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 
 	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
@@ -789,6 +804,7 @@ type fakeRuntimeManager struct {
 	updateCallback func([]component.Component) error
 	result         error
 	errChan        chan error
+	certificates   []runtime.CertificateInfo
 }
 
 func (r *fakeRuntimeManager) Run(ctx context.Context) error {
@@ -835,6 +851,11 @@ func (r *fakeRuntimeManager) PerformComponentDiagnostics(_ context.Context, _ []
 	return nil, nil
 }
 
+// Certificates returns the expiry of the certificates of the component connections.
+func (r *fakeRuntimeManager) Certificates() []runtime.CertificateInfo {
+	return r.certificates
+}
+
 func testBinary(t *testing.T, name string) string {
 	t.Helper()
 
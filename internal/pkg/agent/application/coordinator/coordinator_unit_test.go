@@ -12,8 +12,16 @@ package coordinator
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -28,6 +36,8 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
@@ -945,6 +955,318 @@ func TestCoordinatorReportsOverrideState(t *testing.T) {
 	}
 }
 
+func TestCoordinatorReportsIntegrityError(t *testing.T) {
+	// Set a one-second timeout -- nothing here should block, but if it
+	// does let's report a failure instead of timing out the test runner.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Channels have buffer length 1 so we don't have to run on multiple
+	// goroutines.
+	stateChan := make(chan State, 1)
+	integrityErrChan := make(chan error, 1)
+	coord := &Coordinator{
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		integrityErrChan: integrityErrChan,
+	}
+
+	integrityErrChan <- errors.New("1 files don't match the integrity manifest of version 8.12.0: components/filebeat (modified)")
+	coord.runLoopIteration(ctx)
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Degraded, state.State, "expected Degraded State")
+		assert.Equal(t, "Integrity check: 1 files don't match the integrity manifest of version 8.12.0: components/filebeat (modified)", state.Message)
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+
+	// A successful check clears the error
+	integrityErrChan <- nil
+	coord.runLoopIteration(ctx)
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State, "state should return to its original value")
+		assert.Equal(t, "Running", state.Message, "state message should return to its original value")
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+}
+
+func TestCoordinatorReportsOverlayError(t *testing.T) {
+	// Set a one-second timeout -- nothing here should block, but if it
+	// does let's report a failure instead of timing out the test runner.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Channels have buffer length 1 so we don't have to run on multiple
+	// goroutines.
+	stateChan := make(chan State, 1)
+	overlayErrChan := make(chan error, 1)
+	coord := &Coordinator{
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		overlayErrChan: overlayErrChan,
+	}
+
+	overlayErrChan <- errors.New("local input \"nginx-logs\": input type \"filestream\" is not allowed")
+	coord.runLoopIteration(ctx)
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Degraded, state.State, "expected Degraded State")
+		assert.Equal(t, `Local overlay: local input "nginx-logs": input type "filestream" is not allowed`, state.Message)
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+
+	// Merging the overlay without conflicts clears the error
+	overlayErrChan <- nil
+	coord.runLoopIteration(ctx)
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State, "state should return to its original value")
+		assert.Equal(t, "Running", state.Message, "state message should return to its original value")
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+}
+
+func TestCoordinatorDefersToMaintenanceWindow(t *testing.T) {
+	// Set a one-second timeout -- nothing here should block, but if it
+	// does let's report a failure instead of timing out the test runner.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// a daily window of an hour starting in 2 hours
+	start := time.Now().Add(2 * time.Hour).UTC()
+	windows := configuration.MaintenanceWindows{{
+		Schedule: fmt.Sprintf("%d %d * * *", start.Minute(), start.Hour()),
+		Duration: time.Hour,
+		Timezone: "UTC",
+	}}
+	require.NoError(t, windows[0].Validate())
+
+	var updated bool
+	configChan := make(chan ConfigChange, 1)
+	stateChan := make(chan State, 1)
+	coord := &Coordinator{
+		logger:    logp.NewLogger("testing"),
+		agentInfo: &info.AgentInfo{},
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				updated = true
+				return nil
+			},
+		},
+		reexecMgr:          &fakeReExecManager{},
+		overrideStateChan:  make(chan *coordinatorOverrideState),
+		vars:               emptyVars(t),
+		maintenanceWindows: windows,
+	}
+
+	policy := `
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: test-input
+    type: filestream
+    use_output: default
+`
+	running, err := coord.policyComponents(config.MustNewConfigFrom(policy))
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	coord.state.Components = []runtime.ComponentComponentState{{Component: running[0]}}
+
+	// a policy change keeping the running component is applied
+	cfgChange := &configChange{cfg: config.MustNewConfigFrom(policy + `
+    paths: [/var/log/syslog]
+`)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	assert.True(t, cfgChange.acked)
+	assert.True(t, updated, "a policy change not stopping components should be applied")
+	assert.Nil(t, coord.maintenanceTimer)
+	<-stateChan
+
+	// a policy change restarting the running component with another output type is deferred
+	updated = false
+	cfgChange = &configChange{cfg: config.MustNewConfigFrom(`
+outputs:
+  default:
+    type: logstash
+inputs:
+  - id: test-input
+    type: filestream
+    use_output: default
+`)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	assert.False(t, cfgChange.acked, "a deferred policy change shouldn't be acknowledged")
+	assert.False(t, updated, "a policy change restarting components should be deferred")
+	require.NotNil(t, coord.maintenanceTimer)
+	<-stateChan
+
+	// a policy change stopping the running component is deferred and replaces the previous one
+	cfgChange = &configChange{cfg: config.MustNewConfigFrom(nil)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	assert.False(t, cfgChange.acked, "a deferred policy change shouldn't be acknowledged")
+	assert.False(t, updated, "a policy change stopping components should be deferred")
+	require.NotNil(t, coord.maintenanceTimer)
+	assert.Equal(t, windows.Next(time.Now()), coord.maintenanceAt)
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State)
+		assert.Equal(t, "Running, policy change deferred to the maintenance window at "+coord.maintenanceAt.Format(time.RFC3339), state.Message)
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+
+	// a restart isn't deferred
+	restarted := make(chan struct{})
+	go coord.ReExec(func() error {
+		close(restarted)
+		return nil
+	})
+	coord.runLoopIteration(ctx)
+	select {
+	case <-restarted:
+	case <-ctx.Done():
+		assert.Fail(t, "a restart shouldn't be deferred")
+	}
+	<-stateChan
+
+	// the policy change is applied and acknowledged in the maintenance window
+	coord.maintenanceWindows = nil
+	coord.runDeferredOperations(ctx)
+	assert.True(t, updated, "the deferred policy change should be applied")
+	assert.True(t, cfgChange.acked, "the deferred policy change should be acknowledged once applied")
+	assert.Nil(t, coord.maintenanceTimer)
+	assert.Empty(t, coord.deferredOperations())
+}
+
+func TestComponentDisruption(t *testing.T) {
+	running := component.Component{
+		ID:         "filestream-default",
+		InputType:  "filestream",
+		OutputType: "elasticsearch",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType:  "filestream",
+			BinaryName: "filebeat",
+			BinaryPath: "/opt/elastic-agent/components/filebeat",
+			Spec: component.InputSpec{
+				Name:    "filestream",
+				Command: &component.CommandSpec{Args: []string{"-E", "setup.ilm.enabled=false"}},
+			},
+		},
+		Units: []component.Unit{{ID: "filestream-default-test-input", Type: client.UnitTypeInput}},
+	}
+	withUpdate := func(update func(comp *component.Component)) component.Component {
+		comp := running
+		spec := *running.InputSpec
+		comp.InputSpec = &spec
+		comp.Units = nil
+		update(&comp)
+		return comp
+	}
+
+	testcases := []struct {
+		name    string
+		updated component.Component
+		reason  string
+	}{
+		{"units", withUpdate(func(comp *component.Component) {
+			comp.Units = []component.Unit{{ID: "filestream-default-other-input", Type: client.UnitTypeInput}}
+		}), ""},
+		{"binary", withUpdate(func(comp *component.Component) {
+			comp.InputSpec.BinaryPath = "/opt/elastic-agent/components/filebeat-new"
+		}), "input specification changed"},
+		{"command", withUpdate(func(comp *component.Component) {
+			comp.InputSpec.Spec.Command = &component.CommandSpec{Args: []string{"-E", "setup.ilm.enabled=true"}}
+		}), "input specification changed"},
+		{"output type", withUpdate(func(comp *component.Component) {
+			comp.OutputType = "logstash"
+		}), "output type changed"},
+		{"shipper", withUpdate(func(comp *component.Component) {
+			comp.ShipperRef = &component.ShipperReference{ShipperType: "shipper", ComponentID: "shipper-default", UnitID: "filestream-default"}
+		}), "shipper changed"},
+		{"isolation group", withUpdate(func(comp *component.Component) {
+			comp.IsolationGroup = "critical"
+		}), "isolation group changed"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reason, componentDisruption(running, tc.updated))
+		})
+	}
+}
+
+func TestCoordinatorAppliesCriticalPolicyChange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now().Add(2 * time.Hour).UTC()
+	windows := configuration.MaintenanceWindows{{
+		Schedule: fmt.Sprintf("%d %d * * *", start.Minute(), start.Hour()),
+		Duration: time.Hour,
+		Timezone: "UTC",
+	}}
+
+	var updated bool
+	configChan := make(chan ConfigChange, 1)
+	coord := &Coordinator{
+		logger:    logp.NewLogger("testing"),
+		agentInfo: &info.AgentInfo{},
+		state: State{
+			Components: []runtime.ComponentComponentState{
+				{Component: component.Component{ID: "filestream-default"}},
+			},
+		},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				updated = true
+				return nil
+			},
+		},
+		vars:               emptyVars(t),
+		maintenanceWindows: windows,
+	}
+
+	cfgChange := &configChange{cfg: config.MustNewConfigFrom(`
+agent.critical_change: true
+`)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	assert.True(t, cfgChange.acked)
+	assert.True(t, updated, "a critical policy change should be applied outside of the maintenance windows")
+	assert.Nil(t, coord.maintenanceTimer)
+}
+
 func TestCoordinatorInitiatesUpgrade(t *testing.T) {
 	// Set a one-second timeout -- nothing here should block, but if it
 	// does let's report a failure instead of timing out the test runner.
@@ -1032,3 +1354,170 @@ func (fs *fakeMonitoringServer) Reset() {
 	fs.stopTriggered = false
 	fs.startTriggered = false
 }
+
+func TestCoordinatorVerifyProtectionChange(t *testing.T) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pubKey, err := x509.MarshalPKIXPublicKey(&pk.PublicKey)
+	require.NoError(t, err)
+	signingKey := base64.StdEncoding.EncodeToString(pubKey)
+
+	protectionPolicy := func(enabled bool) map[string]interface{} {
+		return map[string]interface{}{
+			"enabled":              enabled,
+			"uninstall_token_hash": protection.HashUninstallToken("secret"),
+			"signing_key":          signingKey,
+		}
+	}
+	// policy returns a policy disabling the protection, its signed layer sets
+	// the protection to signedProtection and is signed with key.
+	policy := func(signedProtection map[string]interface{}, key *ecdsa.PrivateKey) map[string]interface{} {
+		data, err := json.Marshal(map[string]interface{}{
+			"id":    "policy-1",
+			"agent": map[string]interface{}{"protection": signedProtection},
+		})
+		require.NoError(t, err)
+		hash := sha256.Sum256(data)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		require.NoError(t, err)
+
+		return map[string]interface{}{
+			"id":    "policy-1",
+			"agent": map[string]interface{}{"protection": protectionPolicy(false)},
+			"signed": map[string]interface{}{
+				"data":      base64.StdEncoding.EncodeToString(data),
+				"signature": base64.StdEncoding.EncodeToString(signature),
+			},
+		}
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	protected := protection.Config{
+		Enabled:                true,
+		UninstallTokenHash:     protection.HashUninstallToken("secret"),
+		SignatureValidationKey: pubKey,
+	}
+	disabled := protection.Config{
+		UninstallTokenHash:     protected.UninstallTokenHash,
+		SignatureValidationKey: pubKey,
+	}
+
+	withoutKey := protected
+	withoutKey.SignatureValidationKey = nil
+	unsigned := map[string]interface{}{"agent": map[string]interface{}{"protection": protectionPolicy(false)}}
+
+	testCases := []struct {
+		name          string
+		current       protection.Config
+		policy        map[string]interface{}
+		next          protection.Config
+		allowUnsigned bool
+		wantErr       string
+	}{
+		{
+			name:    "not protected",
+			current: protection.Config{},
+			policy:  map[string]interface{}{},
+			next:    protection.Config{},
+		},
+		{
+			name:    "still protected",
+			current: protected,
+			policy:  map[string]interface{}{},
+			next:    protected,
+		},
+		{
+			name:    "disabled by fleet",
+			current: protected,
+			policy:  policy(protectionPolicy(false), pk),
+			next:    disabled,
+		},
+		{
+			name:    "unsigned",
+			current: protected,
+			policy:  map[string]interface{}{"agent": map[string]interface{}{"protection": protectionPolicy(false)}},
+			next:    disabled,
+			wantErr: "refusing to disable the agent protection",
+		},
+		{
+			name:    "unsigned without signing key",
+			current: withoutKey,
+			policy:  unsigned,
+			next:    disabled,
+			wantErr: "refusing to disable the agent protection: no signing key is known to verify the policy",
+		},
+		{
+			name:          "unsigned without signing key allowed locally",
+			current:       withoutKey,
+			policy:        unsigned,
+			next:          disabled,
+			allowUnsigned: true,
+		},
+		{
+			name:          "unsigned with signing key allowed locally",
+			current:       protected,
+			policy:        unsigned,
+			next:          disabled,
+			allowUnsigned: true,
+			wantErr:       "refusing to disable the agent protection",
+		},
+		{
+			name:    "signed with another key",
+			current: protected,
+			policy:  policy(protectionPolicy(false), otherKey),
+			next:    disabled,
+			wantErr: "refusing to disable the agent protection: invalid signature",
+		},
+		{
+			name:    "signed layer still protected",
+			current: protected,
+			policy:  policy(protectionPolicy(true), pk),
+			next:    disabled,
+			wantErr: "the signed policy keeps the protection enabled",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tamperLog := protection.NewTamperLog(filepath.Join(t.TempDir(), "tamper_events.json"))
+			coord := &Coordinator{
+				logger:                         logp.NewLogger("testing"),
+				protection:                     tc.current,
+				tamperLog:                      tamperLog,
+				allowUnsignedProtectionDisable: tc.allowUnsigned,
+			}
+
+			err := coord.verifyProtectionChange(tc.policy, tc.next)
+			events, eventsErr := tamperLog.Events()
+			require.NoError(t, eventsErr)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Empty(t, events)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+			require.Len(t, events, 1)
+			assert.Equal(t, protection.OperationPolicyChange, events[0].Operation)
+		})
+	}
+}
+
+func TestCoordinatorSetProtectionPersists(t *testing.T) {
+	state := protection.NewState(filepath.Join(t.TempDir(), "protection.json"))
+	coord := &Coordinator{
+		logger:          logp.NewLogger("testing"),
+		protectionState: state,
+	}
+
+	// the first configuration is persisted even when it doesn't change
+	coord.setProtection(protection.Config{})
+	saved, err := state.Load()
+	require.NoError(t, err)
+	assert.False(t, saved.Protected())
+
+	protected := protection.Config{Enabled: true, UninstallTokenHash: protection.HashUninstallToken("secret")}
+	coord.setProtection(protected)
+	saved, err = state.Load()
+	require.NoError(t, err)
+	assert.Equal(t, protected, saved)
+}
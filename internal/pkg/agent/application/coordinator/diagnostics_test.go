@@ -40,6 +40,7 @@ func TestCoordinatorExpectedDiagnosticHooks(t *testing.T) {
 		"components-expected",
 		"components-actual",
 		"state",
+		"certificates",
 	}
 
 	coord := &Coordinator{}
@@ -91,6 +92,13 @@ agent:
   reload: null
   upgrade: null
   v1_monitoring_enabled: false
+  vault: null
+  integrity: null
+  overlay: null
+  rest: null
+  maintenance_windows: []
+  diagnostics: null
+  tamper_protection: null
   monitoring:
     enabled: false
     http: null
@@ -487,6 +495,68 @@ upgrade_details:
 	assert.YAMLEq(t, expected, string(result), "state diagnostic returned unexpected value")
 }
 
+func TestDiagnosticStateFleetHosts(t *testing.T) {
+	// The health of the Fleet Server hosts isn't broadcast, the state
+	// diagnostic reads it from the provider.
+
+	now := time.Now().UTC()
+	coord := &Coordinator{
+		stateBroadcaster: broadcaster.New(State{FleetState: agentclient.Healthy, FleetMessage: "Connected"}, 0, 0),
+	}
+	coord.SetFleetHostsProvider(func() []remote.HostHealth {
+		return []remote.HostHealth{
+			{
+				Host:                "https://fleet-1:8220/",
+				Circuit:             remote.CircuitOpen,
+				ConsecutiveFailures: 3,
+				LastUsed:            now,
+				LastError:           "connection refused",
+				LastErrorAt:         now,
+			},
+			{
+				Host:     "https://fleet-2:8220/",
+				Priority: 1,
+				Selected: true,
+				Circuit:  remote.CircuitClosed,
+				Latency:  25 * time.Millisecond,
+				LastUsed: now,
+			},
+		}
+	})
+
+	expected := fmt.Sprintf(`
+state: 0
+message: ""
+fleet_state: 2
+fleet_message: "Connected"
+fleet_hosts:
+  - host: https://fleet-1:8220/
+    priority: 0
+    selected: false
+    circuit: open
+    consecutive_failures: 3
+    latency: 0s
+    last_used: %[1]s
+    last_error: connection refused
+    last_error_at: %[1]s
+  - host: https://fleet-2:8220/
+    priority: 1
+    selected: true
+    circuit: closed
+    consecutive_failures: 0
+    latency: 25ms
+    last_used: %[1]s
+log_level: "info"
+components: []
+`, now.Format(time.RFC3339Nano))
+
+	hook, ok := diagnosticHooksMap(coord)["state"]
+	require.True(t, ok, "diagnostic hooks should have an entry for state")
+
+	result := hook.Hook(context.Background())
+	assert.YAMLEq(t, expected, string(result), "state diagnostic returned unexpected value")
+}
+
 func TestDiagnosticStateForAPM(t *testing.T) {
 	// Create a coordinator with a test state and verify that the state
 	// diagnostic reports it
@@ -573,6 +643,38 @@ components:
 	assert.YAMLEq(t, expected, string(result), "state diagnostic returned unexpected value")
 }
 
+func TestDiagnosticCertificates(t *testing.T) {
+	notAfter := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	coord := &Coordinator{
+		runtimeMgr: &fakeRuntimeManager{
+			certificates: []runtime.CertificateInfo{
+				{Usage: runtime.CertificateUsageCA, NotAfter: notAfter.AddDate(10, 0, 0)},
+				{ComponentID: "filestream-default", Usage: runtime.CertificateUsageClient, NotAfter: notAfter.AddDate(10, 0, 0)},
+				{ComponentID: "shipper-default", UnitID: "filestream-default", Usage: runtime.CertificateUsageShipper, NotAfter: notAfter},
+			},
+		},
+	}
+
+	expected := `
+certificates:
+  - usage: ca
+    not_after: 2034-03-01T12:00:00Z
+  - component_id: filestream-default
+    usage: client
+    not_after: 2034-03-01T12:00:00Z
+  - component_id: shipper-default
+    unit_id: filestream-default
+    usage: shipper
+    not_after: 2024-03-01T12:00:00Z
+`
+
+	hook, ok := diagnosticHooksMap(coord)["certificates"]
+	require.True(t, ok, "diagnostic hooks should have an entry for certificates")
+
+	result := hook.Hook(context.Background())
+	assert.YAMLEq(t, expected, string(result), "certificates diagnostic returned unexpected value")
+}
+
 // Fetch the diagnostic hooks and add them to a lookup table for
 // easier verification
 func diagnosticHooksMap(coord *Coordinator) map[string]diagnostics.Hook {
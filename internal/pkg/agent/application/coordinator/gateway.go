@@ -6,6 +6,8 @@ package coordinator
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
@@ -41,3 +43,41 @@ func (w WarningError) Error() string {
 func NewWarningError(warningMsg string) *WarningError {
 	return &WarningError{msg: warningMsg}
 }
+
+// PacingError is emitted when the checkin succeeded but fleet-server asked to delay
+// the next one.
+type PacingError struct {
+	// Delay is the time before the next checkin.
+	Delay time.Duration
+	// Source is what requested the delay.
+	Source string
+}
+
+func (p PacingError) Error() string {
+	return fmt.Sprintf("next checkin in %s as requested by fleet-server (%s)", p.Delay, p.Source)
+}
+
+func NewPacingError(delay time.Duration, source string) *PacingError {
+	return &PacingError{Delay: delay, Source: source}
+}
+
+// RetryAfterError is emitted when the checkin failed and fleet-server asked to
+// wait before retrying it.
+type RetryAfterError struct {
+	// Delay is the time before the next attempt.
+	Delay time.Duration
+	// Err is the error of the failed checkin.
+	Err error
+}
+
+func (r RetryAfterError) Error() string {
+	return fmt.Sprintf("checkin failed, retrying in %s as requested by fleet-server: %v", r.Delay, r.Err)
+}
+
+func (r RetryAfterError) Unwrap() error {
+	return r.Err
+}
+
+func NewRetryAfterError(delay time.Duration, err error) *RetryAfterError {
+	return &RetryAfterError{Delay: delay, Err: err}
+}
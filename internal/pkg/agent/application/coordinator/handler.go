@@ -5,13 +5,31 @@
 package coordinator
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
+	eaclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 )
 
+// Probe is a health probe served by Coordinator.ProbeHandler.
+type Probe string
+
+const (
+	// ProbeLiveness succeeds while the coordinator loop isn't stuck.
+	ProbeLiveness Probe = "liveness"
+	// ProbeReadiness succeeds once the output units are healthy.
+	ProbeReadiness Probe = "readiness"
+	// ProbeStartup succeeds once the agent applied its first policy, after
+	// connecting to Fleet when managed.
+	ProbeStartup Probe = "startup"
+)
+
 // LivenessResponse is the response body for the liveness endpoint.
 type LivenessResponse struct {
 	ID         string    `json:"id"`
@@ -20,28 +38,137 @@ type LivenessResponse struct {
 	UpdateTime time.Time `json:"update_timestamp"`
 }
 
+// ProbeResponse is the response body for the health probes.
+type ProbeResponse struct {
+	LivenessResponse
+	Probe Probe `json:"probe"`
+	// Reason explains why the probe failed.
+	Reason string `json:"reason,omitempty"`
+}
+
 // ServeHTTP is an HTTP Handler for the coordinatorr.
 // Response code is 200 for a healthy agent, and 503 otherwise.
 // Response body is a JSON object that contains the agent ID, status, message, and the last status update time.
 func (c *Coordinator) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
 	s := c.State()
-	lr := LivenessResponse{
-		ID:      c.agentInfo.AgentID(),
-		Status:  s.State.String(),
-		Message: s.Message,
-
-		// TODO(blakerouse): Coordinator should be changed to store the last timestamp that the state has changed.
-		UpdateTime: time.Now().UTC(),
-	}
+	lr := c.livenessResponse(s)
 	status := http.StatusOK
 	if s.State != client.Healthy {
 		status = http.StatusServiceUnavailable
 	}
+	c.writeProbeResponse(wr, status, lr)
+}
+
+// ProbeHandler returns the HTTP handler of a health probe. Response code is
+// 200 when the probe succeeds, and 503 otherwise with the reason in the body.
+func (c *Coordinator) ProbeHandler(probe Probe, cfg monitoringCfg.HealthConfig) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		var err error
+		switch probe {
+		case ProbeLiveness:
+			err = c.checkLiveness(req.Context(), cfg.Liveness)
+		case ProbeReadiness:
+			err = c.checkReadiness(cfg.Readiness)
+		case ProbeStartup:
+			err = c.checkStartup()
+		default:
+			err = fmt.Errorf("unknown probe %q", probe)
+		}
+
+		resp := ProbeResponse{
+			LivenessResponse: c.livenessResponse(c.State()),
+			Probe:            probe,
+		}
+		status := http.StatusOK
+		if err != nil {
+			status = http.StatusServiceUnavailable
+			resp.Reason = err.Error()
+		}
+		c.writeProbeResponse(wr, status, resp)
+	})
+}
 
+func (c *Coordinator) livenessResponse(s State) LivenessResponse {
+	c.probeMx.RLock()
+	defer c.probeMx.RUnlock()
+	return LivenessResponse{
+		ID:         c.agentInfo.AgentID(),
+		Status:     s.State.String(),
+		Message:    s.Message,
+		UpdateTime: c.stateChangedAt,
+	}
+}
+
+func (c *Coordinator) writeProbeResponse(wr http.ResponseWriter, status int, resp interface{}) {
 	wr.Header().Set("Content-Type", "application/json")
 	wr.WriteHeader(status)
 	enc := json.NewEncoder(wr)
-	if err := enc.Encode(lr); err != nil {
+	if err := enc.Encode(resp); err != nil {
 		c.logger.Errorf("Unable to encode liveness response: %v", err)
 	}
 }
+
+// checkLiveness returns an error when the coordinator loop doesn't answer
+// within the timeout or, when configured, the agent isn't healthy.
+func (c *Coordinator) checkLiveness(ctx context.Context, cfg monitoringCfg.LivenessConfig) error {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = monitoringCfg.DefaultHealthConfig().Liveness.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	heartbeat := make(chan struct{})
+	select {
+	case c.heartbeatChan <- heartbeat:
+	case <-ctx.Done():
+		return fmt.Errorf("coordinator didn't answer within %s", timeout)
+	}
+	select {
+	case <-heartbeat:
+	case <-ctx.Done():
+		return fmt.Errorf("coordinator didn't answer within %s", timeout)
+	}
+
+	if !cfg.CheckState {
+		return nil
+	}
+	s := c.State()
+	if s.State == client.Healthy || (cfg.TolerateDegraded && s.State == client.Degraded) {
+		return nil
+	}
+	return fmt.Errorf("agent is %s: %s", s.State, s.Message)
+}
+
+// checkReadiness returns an error until the agent started and all its output
+// units are healthy.
+func (c *Coordinator) checkReadiness(cfg monitoringCfg.ReadinessConfig) error {
+	if err := c.checkStartup(); err != nil {
+		return err
+	}
+	for _, comp := range c.State().Components {
+		for key, unit := range comp.State.Units {
+			if key.UnitType != eaclient.UnitTypeOutput {
+				continue
+			}
+			if unit.State == eaclient.UnitStateHealthy || (cfg.TolerateDegraded && unit.State == eaclient.UnitStateDegraded) {
+				continue
+			}
+			return fmt.Errorf("output unit %s is %s: %s", key.UnitID, unit.State, unit.Message)
+		}
+	}
+	return nil
+}
+
+// checkStartup returns an error until the agent applied its first policy.
+func (c *Coordinator) checkStartup() error {
+	c.probeMx.RLock()
+	defer c.probeMx.RUnlock()
+	if c.startedAt.IsZero() {
+		if c.isManaged {
+			return fmt.Errorf("waiting for the first policy from Fleet to be applied")
+		}
+		return fmt.Errorf("waiting for the first policy to be applied")
+	}
+	return nil
+}
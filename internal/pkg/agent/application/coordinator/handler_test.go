@@ -0,0 +1,207 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/utils/broadcaster"
+)
+
+func TestCoordinatorProbes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runtimeErrChan := make(chan error)
+	runtimeChan := make(chan runtime.ComponentComponentState)
+	state := State{State: agentclient.Starting, Message: "Starting"}
+	createdAt := time.Now().UTC()
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		state:            state,
+		stateBroadcaster: broadcaster.New(state, 64, 32),
+		managerChans: managerChans{
+			runtimeManagerError:  runtimeErrChan,
+			runtimeManagerUpdate: runtimeChan,
+		},
+		heartbeatChan:  make(chan chan struct{}),
+		stateChangedAt: createdAt,
+		reportedState:  state,
+	}
+	cfg := monitoringCfg.DefaultHealthConfig()
+	cfg.Liveness.Timeout = 100 * time.Millisecond
+
+	probe := func(p Probe) (int, ProbeResponse) {
+		rec := httptest.NewRecorder()
+		coord.ProbeHandler(p, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+string(p), nil))
+		var resp ProbeResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, p, resp.Probe)
+		return rec.Code, resp
+	}
+
+	// the coordinator loop isn't running
+	code, resp := probe(ProbeLiveness)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "coordinator didn't answer within 100ms", resp.Reason)
+
+	code, resp = probe(ProbeStartup)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "waiting for the first policy to be applied", resp.Reason)
+	assert.Equal(t, createdAt, resp.UpdateTime)
+
+	go func() {
+		for ctx.Err() == nil {
+			coord.runLoopIteration(ctx)
+		}
+	}()
+
+	code, resp = probe(ProbeLiveness)
+	assert.Equal(t, http.StatusOK, code, resp.Reason)
+	assert.Empty(t, resp.Reason)
+
+	// the first policy is applied
+	runtimeErrChan <- nil
+	output := runtime.ComponentComponentState{
+		Component: component.Component{ID: "filebeat-default"},
+		State: runtime.ComponentState{
+			State: client.UnitStateHealthy,
+			Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+				{UnitType: client.UnitTypeInput, UnitID: "filebeat-default-logfile"}: {State: client.UnitStateHealthy},
+				{UnitType: client.UnitTypeOutput, UnitID: "filebeat-default"}:        {State: client.UnitStateStarting, Message: "Connecting"},
+			},
+		},
+	}
+	runtimeChan <- output
+
+	require.Eventually(t, func() bool {
+		code, _ := probe(ProbeStartup)
+		return code == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, resp := probe(ProbeReadiness)
+		return resp.Reason == "output unit filebeat-default is STARTING: Connecting"
+	}, 5*time.Second, 10*time.Millisecond)
+	_, resp = probe(ProbeReadiness)
+	assert.True(t, resp.UpdateTime.After(createdAt), "the update time is when the state changed")
+
+	// a degraded output is only tolerated when configured
+	degraded := output.State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeOutput, UnitID: "filebeat-default"}]
+	degraded.State = client.UnitStateDegraded
+	degraded.Message = "Slow"
+	output.State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeOutput, UnitID: "filebeat-default"}] = degraded
+	runtimeChan <- output
+	require.Eventually(t, func() bool {
+		_, resp := probe(ProbeReadiness)
+		return resp.Reason == "output unit filebeat-default is DEGRADED: Slow"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cfg.Readiness.TolerateDegraded = true
+	code, resp = probe(ProbeReadiness)
+	assert.Equal(t, http.StatusOK, code, resp.Reason)
+
+	// the liveness probe checks the state only when configured
+	cfg.Liveness.CheckState = true
+	code, resp = probe(ProbeLiveness)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "agent is DEGRADED: 1 or more components/units in a degraded state", resp.Reason)
+
+	cfg.Liveness.TolerateDegraded = true
+	code, resp = probe(ProbeLiveness)
+	assert.Equal(t, http.StatusOK, code, resp.Reason)
+}
+
+func TestCoordinatorStartupProbeManaged(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runtimeErrChan := make(chan error, 1)
+	configErrChan := make(chan error, 1)
+	state := State{State: agentclient.Starting, Message: "Starting"}
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		isManaged:        true,
+		state:            state,
+		stateBroadcaster: broadcaster.New(state, 64, 32),
+		managerChans: managerChans{
+			runtimeManagerError: runtimeErrChan,
+			configManagerError:  configErrChan,
+		},
+	}
+	iterate := func(ch chan error, err error) {
+		ch <- err
+		coord.runLoopIteration(ctx)
+	}
+
+	// the policy is applied but Fleet wasn't reached
+	iterate(runtimeErrChan, nil)
+	assert.EqualError(t, coord.checkStartup(), "waiting for the first policy from Fleet to be applied")
+
+	// failed checkins are reported as degraded and failed, they don't end the startup
+	iterate(configErrChan, NewRetryAfterError(time.Minute, errors.New("status code: 503")))
+	assert.Equal(t, agentclient.Degraded, coord.state.FleetState)
+	assert.Error(t, coord.checkStartup())
+	iterate(configErrChan, errors.New("connection refused"))
+	assert.Error(t, coord.checkStartup())
+
+	// the first successful checkin ends it
+	iterate(configErrChan, NewPacingError(time.Minute, "checkin_interval"))
+	assert.NoError(t, coord.checkStartup())
+
+	// and later failures don't start it again
+	iterate(configErrChan, errors.New("connection refused"))
+	assert.NoError(t, coord.checkStartup())
+}
+
+func TestCoordinatorStartupProbeNeedsPolicy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runtimeErrChan := make(chan error, 1)
+	configErrChan := make(chan error, 1)
+	state := State{State: agentclient.Starting, Message: "Starting"}
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		isManaged:        true,
+		state:            state,
+		stateBroadcaster: broadcaster.New(state, 64, 32),
+		managerChans: managerChans{
+			runtimeManagerError: runtimeErrChan,
+			configManagerError:  configErrChan,
+		},
+	}
+
+	// a successful checkin without an applied policy isn't a complete startup
+	configErrChan <- nil
+	coord.runLoopIteration(ctx)
+	assert.Error(t, coord.checkStartup())
+
+	runtimeErrChan <- errors.New("failed to apply the policy")
+	coord.runLoopIteration(ctx)
+	assert.Error(t, coord.checkStartup())
+
+	runtimeErrChan <- nil
+	coord.runLoopIteration(ctx)
+	assert.NoError(t, coord.checkStartup())
+}
@@ -0,0 +1,169 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// criticalPolicy is the part of the policy marking a security-critical change, it's applied
+// outside of the maintenance windows.
+type criticalPolicy struct {
+	Agent struct {
+		CriticalChange bool `config:"critical_change"`
+	} `config:"agent"`
+}
+
+// deferConfig returns true when the policy change is deferred to the next maintenance window,
+// it's acknowledged once applied. Outside of the windows, the policy changes stopping or
+// restarting running components are deferred unless they are critical, the others are applied
+// and replace a deferred one.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) deferConfig(change ConfigChange) bool {
+	cfg := change.Config()
+	now := time.Now()
+	if c.maintenanceWindows.Contains(now) || c.vars == nil {
+		c.deferredConfig = nil
+		return false
+	}
+
+	var policy criticalPolicy
+	if err := cfg.Unpack(&policy); err == nil && policy.Agent.CriticalChange {
+		c.logger.Info("Applying a critical policy change outside of the maintenance windows")
+		c.deferredConfig = nil
+		return false
+	}
+
+	disrupted := c.disruptedComponents(cfg)
+	if len(disrupted) == 0 {
+		c.deferredConfig = nil
+		return false
+	}
+
+	c.deferredConfig = change
+	c.scheduleMaintenance(now)
+	c.logger.Infof("Deferring the policy change stopping or restarting components %s to the maintenance window starting at %s",
+		strings.Join(disrupted, ", "), c.maintenanceAt.Format(time.RFC3339))
+	return true
+}
+
+// disruptedComponents returns the running components the policy would stop or restart, with
+// the reason, errors are left to the policy update to report.
+func (c *Coordinator) disruptedComponents(cfg *config.Config) []string {
+	comps, err := c.policyComponents(cfg)
+	if err != nil {
+		return nil
+	}
+
+	updated := make(map[string]component.Component, len(comps))
+	for _, comp := range comps {
+		updated[comp.ID] = comp
+	}
+	var disrupted []string
+	for _, state := range c.state.Components {
+		comp, ok := updated[state.Component.ID]
+		if !ok {
+			disrupted = append(disrupted, state.Component.ID+" (removed)")
+			continue
+		}
+		if reason := componentDisruption(state.Component, comp); reason != "" {
+			disrupted = append(disrupted, state.Component.ID+" ("+reason+")")
+		}
+	}
+	return disrupted
+}
+
+// policyComponents returns the components of the policy.
+func (c *Coordinator) policyComponents(cfg *config.Config) ([]component.Component, error) {
+	if err := info.InjectAgentConfig(cfg); err != nil {
+		return nil, err
+	}
+	m, err := cfg.ToMapStr()
+	if err != nil {
+		return nil, err
+	}
+	ast, err := transpiler.NewAST(m)
+	if err != nil {
+		return nil, err
+	}
+	_, comps, err := c.renderComponents(ast)
+	return comps, err
+}
+
+// componentDisruption returns why the running component is restarted to run the updated one,
+// empty when the changes are applied to the running component, like the ones of its units.
+func componentDisruption(running component.Component, updated component.Component) string {
+	switch {
+	case !reflect.DeepEqual(running.InputSpec, updated.InputSpec):
+		return "input specification changed"
+	case !reflect.DeepEqual(running.ShipperSpec, updated.ShipperSpec):
+		return "shipper specification changed"
+	case running.OutputType != updated.OutputType:
+		return "output type changed"
+	case !reflect.DeepEqual(running.ShipperRef, updated.ShipperRef):
+		return "shipper changed"
+	case running.IsolationGroup != updated.IsolationGroup:
+		return "isolation group changed"
+	}
+	return ""
+}
+
+// scheduleMaintenance sets the timer of the deferred operations to the start of the next
+// maintenance window.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) scheduleMaintenance(now time.Time) {
+	if c.maintenanceTimer != nil {
+		c.maintenanceTimer.Stop()
+	}
+	c.maintenanceAt = c.maintenanceWindows.Next(now)
+	c.maintenanceTimer = time.NewTimer(c.maintenanceAt.Sub(now))
+	c.stateNeedsRefresh = true
+}
+
+// maintenanceC returns the channel of the maintenance timer, nil when no operations are deferred.
+func (c *Coordinator) maintenanceC() <-chan time.Time {
+	if c.maintenanceTimer == nil {
+		return nil
+	}
+	return c.maintenanceTimer.C
+}
+
+// runDeferredOperations applies and acknowledges the policy change deferred to the maintenance
+// window.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) runDeferredOperations(ctx context.Context) {
+	now := time.Now()
+	if !c.maintenanceWindows.Contains(now) {
+		// the wall clock changed since the timer was set
+		c.scheduleMaintenance(now)
+		return
+	}
+	c.maintenanceTimer = nil
+	c.maintenanceAt = time.Time{}
+	c.stateNeedsRefresh = true
+
+	if change := c.deferredConfig; change != nil {
+		c.deferredConfig = nil
+		c.logger.Info("Applying the policy change deferred to the maintenance window")
+		c.applyConfigChange(ctx, change)
+	}
+}
+
+// deferredOperations describes the operations deferred to the next maintenance window for the
+// state message, it's empty when there are none.
+func (c *Coordinator) deferredOperations() string {
+	if c.deferredConfig != nil {
+		return "policy change"
+	}
+	return ""
+}
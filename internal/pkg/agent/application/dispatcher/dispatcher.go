@@ -15,6 +15,7 @@ import (
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
@@ -44,6 +45,7 @@ type ActionDispatcher struct {
 	queue    priorityQueue
 	rt       *retryConfig
 	errCh    chan error
+	windows  configuration.MaintenanceWindows
 }
 
 // New creates a new action dispatcher.
@@ -74,6 +76,12 @@ func (ad *ActionDispatcher) Errors() <-chan error {
 	return ad.errCh
 }
 
+// SetMaintenanceWindows sets the maintenance windows, the upgrade actions are queued until the
+// next window unless they are critical.
+func (ad *ActionDispatcher) SetMaintenanceWindows(windows configuration.MaintenanceWindows) {
+	ad.windows = windows
+}
+
 // Register registers a new handler for action.
 func (ad *ActionDispatcher) Register(a fleetapi.Action, handler actions.Handler) error {
 	k := ad.key(a)
@@ -110,13 +118,16 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 		span.End()
 	}()
 
+	now := time.Now().UTC()
 	ad.removeQueuedUpgrades(actions)
+	ad.deferToMaintenanceWindow(actions, now)
 	reportNextScheduledUpgrade(actions, detailsSetter, ad.log)
 	actions = ad.queueScheduledActions(actions)
 	actions = ad.dispatchCancelActions(ctx, actions, acker)
-	queued, expired := ad.gatherQueuedActions(time.Now().UTC())
+	queued, expired := ad.gatherQueuedActions(now)
 	ad.log.Debugf("Gathered %d actions from queue, %d actions expired", len(queued), len(expired))
 	ad.log.Debugf("Expired actions: %v", expired)
+	queued = ad.requeueOutsideMaintenanceWindow(queued, detailsSetter, now)
 	actions = append(actions, queued...)
 
 	if err := ad.queue.Save(); err != nil {
@@ -223,7 +234,12 @@ func (ad *ActionDispatcher) dispatchCancelActions(ctx context.Context, actions [
 func (ad *ActionDispatcher) gatherQueuedActions(ts time.Time) (queued, expired []fleetapi.Action) {
 	actions := ad.queue.DequeueActions()
 	for _, action := range actions {
-		exp, _ := action.Expiration()
+		exp, err := action.Expiration()
+		if err != nil && ad.deferrable(action) {
+			// upgrades without expiration are kept until the maintenance window they were deferred to
+			queued = append(queued, action)
+			continue
+		}
 		if ts.After(exp) {
 			expired = append(expired, action)
 			continue
@@ -233,6 +249,63 @@ func (ad *ActionDispatcher) gatherQueuedActions(ts time.Time) (queued, expired [
 	return queued, expired
 }
 
+// deferrable returns true if the action is deferred to the maintenance windows.
+func (ad *ActionDispatcher) deferrable(action fleetapi.Action) bool {
+	upgrade, ok := action.(*fleetapi.ActionUpgrade)
+	return ok && !upgrade.Critical && len(ad.windows) > 0
+}
+
+// deferToMaintenanceWindow sets the start time of the upgrade actions that would start outside
+// of the maintenance windows to the start of the next window, critical upgrades are not deferred.
+func (ad *ActionDispatcher) deferToMaintenanceWindow(actions []fleetapi.Action, now time.Time) (deferred []fleetapi.Action) {
+	for _, action := range actions {
+		if !ad.deferrable(action) {
+			continue
+		}
+		upgrade := action.(*fleetapi.ActionUpgrade)
+		start, err := upgrade.StartTime()
+		if err != nil || start.Before(now) {
+			start = now
+		}
+		if ad.windows.Contains(start) {
+			continue
+		}
+		next := ad.windows.Next(start)
+		ad.log.Infof("Deferring upgrade action id %s to the maintenance window starting at %s", upgrade.ID(), next.Format(time.RFC3339))
+		upgrade.SetStartTime(next)
+		deferred = append(deferred, action)
+	}
+	return deferred
+}
+
+// requeueOutsideMaintenanceWindow queues again the queued upgrade actions that are dequeued
+// outside of the maintenance windows, like after the agent was stopped during a window, and
+// returns the rest.
+func (ad *ActionDispatcher) requeueOutsideMaintenanceWindow(queued []fleetapi.Action, detailsSetter details.Observer, now time.Time) []fleetapi.Action {
+	deferred := ad.deferToMaintenanceWindow(queued, now)
+	if len(deferred) == 0 {
+		return queued
+	}
+	reportNextScheduledUpgrade(deferred, detailsSetter, ad.log)
+	ad.queueScheduledActions(deferred)
+	actions := make([]fleetapi.Action, 0, len(queued))
+	for _, action := range queued {
+		if !containsAction(deferred, action) {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+func containsAction(actions []fleetapi.Action, action fleetapi.Action) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
 // removeQueuedUpgrades will scan the passed actions and if there is an upgrade action it will remove all upgrade actions in the queue but not alter the passed list.
 // this is done to try to only have the most recent upgrade action executed. However it does not eliminate duplicates in retrieved directly from the gateway
 func (ad *ActionDispatcher) removeQueuedUpgrades(actions []fleetapi.Action) {
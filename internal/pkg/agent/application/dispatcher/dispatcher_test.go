@@ -6,15 +6,18 @@ package dispatcher
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"go.uber.org/zap/zapcore"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
@@ -584,3 +587,116 @@ func TestReportNextScheduledUpgrade(t *testing.T) {
 		})
 	}
 }
+
+func TestActionDispatcherMaintenanceWindows(t *testing.T) {
+	ack := noop.New()
+	now := time.Now().UTC()
+	// a daily window of an hour starting in 2 hours
+	start := now.Add(2 * time.Hour)
+	windows := configuration.MaintenanceWindows{{
+		Schedule: fmt.Sprintf("%d %d * * *", start.Minute(), start.Hour()),
+		Duration: time.Hour,
+		Timezone: "UTC",
+	}}
+	require.NoError(t, windows[0].Validate())
+	next := windows.Next(now)
+
+	t.Run("upgrade is queued until the next window", func(t *testing.T) {
+		def := &mockHandler{}
+		queue := &mockQueue{}
+		queue.On("CancelType", fleetapi.ActionTypeUpgrade).Return(0).Once()
+		queue.On("Add", mock.Anything, next.Unix()).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		queue.On("Save").Return(nil).Once()
+		d, err := New(nil, def, queue)
+		require.NoError(t, err)
+		d.SetMaintenanceWindows(windows)
+
+		var reported *details.Details
+		action := &fleetapi.ActionUpgrade{ActionID: "upgrade", ActionType: fleetapi.ActionTypeUpgrade, Version: "8.13.0"}
+		d.Dispatch(context.Background(), func(d *details.Details) { reported = d }, ack, action)
+
+		require.NotNil(t, reported)
+		assert.Equal(t, details.StateScheduled, reported.State)
+		require.NotNil(t, reported.Metadata.ScheduledAt)
+		assert.True(t, next.Equal(*reported.Metadata.ScheduledAt))
+		def.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("critical upgrade is not deferred", func(t *testing.T) {
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		queue := &mockQueue{}
+		queue.On("CancelType", fleetapi.ActionTypeUpgrade).Return(0).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		queue.On("Save").Return(nil).Once()
+		d, err := New(nil, def, queue)
+		require.NoError(t, err)
+		d.SetMaintenanceWindows(windows)
+
+		action := &fleetapi.ActionUpgrade{ActionID: "upgrade", ActionType: fleetapi.ActionTypeUpgrade, Version: "8.13.0", Critical: true}
+		go d.Dispatch(context.Background(), func(*details.Details) {}, ack, action)
+		require.NoError(t, <-d.Errors())
+
+		def.AssertExpectations(t)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("upgrade dequeued outside of the windows is queued again", func(t *testing.T) {
+		def := &mockHandler{}
+		action := &fleetapi.ActionUpgrade{ActionID: "upgrade", ActionType: fleetapi.ActionTypeUpgrade, Version: "8.13.0"}
+		action.SetStartTime(now.Add(-time.Hour))
+		queue := &mockQueue{}
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{action}).Once()
+		queue.On("Add", action, next.Unix()).Once()
+		queue.On("Save").Return(nil).Once()
+		d, err := New(nil, def, queue)
+		require.NoError(t, err)
+		d.SetMaintenanceWindows(windows)
+
+		d.Dispatch(context.Background(), func(*details.Details) {}, ack)
+
+		def.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything)
+		queue.AssertExpectations(t)
+	})
+}
+
+func TestGatherQueuedActionsWithoutExpiration(t *testing.T) {
+	now := time.Now().UTC()
+	windows := configuration.MaintenanceWindows{{Schedule: "0 2 * * *", Duration: time.Hour, Timezone: "UTC"}}
+	require.NoError(t, windows[0].Validate())
+
+	newActions := func() (*mockScheduledAction, *fleetapi.ActionUpgrade, *fleetapi.ActionUpgrade) {
+		other := &mockScheduledAction{}
+		other.On("Expiration").Return(time.Time{}, fleetapi.ErrNoExpiration)
+		upgrade := &fleetapi.ActionUpgrade{ActionID: "upgrade", ActionType: fleetapi.ActionTypeUpgrade}
+		critical := &fleetapi.ActionUpgrade{ActionID: "critical", ActionType: fleetapi.ActionTypeUpgrade, Critical: true}
+		return other, upgrade, critical
+	}
+
+	t.Run("without maintenance windows", func(t *testing.T) {
+		other, upgrade, critical := newActions()
+		queue := &mockQueue{}
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{other, upgrade, critical}).Once()
+		d, err := New(nil, &mockHandler{}, queue)
+		require.NoError(t, err)
+
+		queued, expired := d.gatherQueuedActions(now)
+		assert.Empty(t, queued)
+		assert.Equal(t, []fleetapi.Action{other, upgrade, critical}, expired)
+	})
+
+	t.Run("deferred upgrades are kept", func(t *testing.T) {
+		other, upgrade, critical := newActions()
+		queue := &mockQueue{}
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{other, upgrade, critical}).Once()
+		d, err := New(nil, &mockHandler{}, queue)
+		require.NoError(t, err)
+		d.SetMaintenanceWindows(windows)
+
+		queued, expired := d.gatherQueuedActions(now)
+		assert.Equal(t, []fleetapi.Action{upgrade}, queued)
+		assert.Equal(t, []fleetapi.Action{other, critical}, expired)
+	})
+}
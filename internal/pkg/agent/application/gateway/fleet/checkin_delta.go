@@ -0,0 +1,202 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+)
+
+// acceptedComponents is the components state fleet-server confirmed it stored,
+// following checkins only send the changes against it.
+type acceptedComponents struct {
+	hash       string
+	components map[string]fleetapi.CheckinComponent
+	// fullAt is when the last full snapshot of the components was accepted.
+	fullAt time.Time
+}
+
+// setCheckinComponents fills the components of the checkin request, either with
+// the full state or, when fleet-server accepted a previous state recently enough,
+// with the changes since that state.
+func (f *FleetGateway) setCheckinComponents(req *fleetapi.CheckinRequest, components []fleetapi.CheckinComponent) {
+	sortCheckinComponents(components)
+	req.Components = components
+
+	hash, err := hashCheckinComponents(withoutResources(components))
+	if err != nil {
+		f.log.Warnf("failed to hash the checkin components, sending the full state: %v", err)
+		return
+	}
+	req.ComponentsHash = hash
+
+	f.componentsMx.Lock()
+	defer f.componentsMx.Unlock()
+	base := f.acceptedComponents
+	if !f.settings.Delta.Enabled || base == nil {
+		return
+	}
+	if time.Since(base.fullAt) >= f.settings.Delta.FullInterval {
+		f.log.Debug("Sending the full components state to fleet-server after the full state interval")
+		return
+	}
+
+	withResources := time.Since(f.resourcesSentAt) >= f.settings.Resources.Interval
+	req.Components, req.RemovedComponents = checkinComponentsDelta(base.components, components, withResources)
+	req.ComponentsBaseHash = base.hash
+}
+
+// acceptCheckinComponents records the components state sent with a successful
+// checkin, if fleet-server confirmed it stored it.
+func (f *FleetGateway) acceptCheckinComponents(req *fleetapi.CheckinRequest, resp *fleetapi.CheckinResponse, components []fleetapi.CheckinComponent) {
+	f.componentsMx.Lock()
+	defer f.componentsMx.Unlock()
+	for _, c := range req.Components {
+		if c.Resources != nil {
+			f.resourcesSentAt = time.Now()
+			break
+		}
+	}
+
+	if resp.RequestFullState || req.ComponentsHash == "" || resp.ComponentsHash != req.ComponentsHash {
+		f.acceptedComponents = nil
+		return
+	}
+
+	fullAt := time.Now()
+	if req.ComponentsBaseHash != "" && f.acceptedComponents != nil {
+		fullAt = f.acceptedComponents.fullAt
+	}
+
+	accepted := make(map[string]fleetapi.CheckinComponent, len(components))
+	for _, c := range withoutResources(components) {
+		accepted[c.ID] = c
+	}
+	f.acceptedComponents = &acceptedComponents{
+		hash:       req.ComponentsHash,
+		components: accepted,
+		fullAt:     fullAt,
+	}
+}
+
+// resetCheckinComponents forgets the accepted components state, the next checkin
+// sends the full state.
+func (f *FleetGateway) resetCheckinComponents() {
+	f.componentsMx.Lock()
+	defer f.componentsMx.Unlock()
+	f.acceptedComponents = nil
+}
+
+// sortCheckinComponents sorts the components by ID and their units by type and ID,
+// so the same state is always serialized the same way.
+func sortCheckinComponents(components []fleetapi.CheckinComponent) {
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ID < components[j].ID
+	})
+	for _, c := range components {
+		sort.Slice(c.Units, func(i, j int) bool {
+			if c.Units[i].Type != c.Units[j].Type {
+				return c.Units[i].Type < c.Units[j].Type
+			}
+			return c.Units[i].ID < c.Units[j].ID
+		})
+	}
+}
+
+// withoutResources returns a copy of the components without their resources. The resources
+// are sampled measures, not part of the state hashed and compared for the deltas.
+func withoutResources(components []fleetapi.CheckinComponent) []fleetapi.CheckinComponent {
+	stripped := make([]fleetapi.CheckinComponent, len(components))
+	for i, c := range components {
+		c.Resources = nil
+		stripped[i] = c
+	}
+	return stripped
+}
+
+// hashCheckinComponents returns the hash of sorted checkin components.
+func hashCheckinComponents(components []fleetapi.CheckinComponent) (string, error) {
+	data, err := json.Marshal(components)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkinComponentsDelta returns the components changed since the base state, holding
+// only their changed units, and the IDs of the removed components. The resources are
+// ignored by the comparison, they are sent for all the components when withResources is
+// set, and omitted otherwise.
+func checkinComponentsDelta(base map[string]fleetapi.CheckinComponent, components []fleetapi.CheckinComponent, withResources bool) ([]fleetapi.CheckinComponent, []string) {
+	changed := make([]fleetapi.CheckinComponent, 0)
+	seen := make(map[string]bool, len(components))
+	for _, c := range components {
+		seen[c.ID] = true
+		resources := c.Resources
+		c.Resources = nil
+		if withResources {
+			c.Resources = resources
+		}
+		prev, ok := base[c.ID]
+		if !ok {
+			changed = append(changed, c)
+			continue
+		}
+		if reflect.DeepEqual(prev, c) {
+			continue
+		}
+		if reflect.DeepEqual(prev, withoutResources([]fleetapi.CheckinComponent{c})[0]) {
+			// only the resources are sent
+			changed = append(changed, fleetapi.CheckinComponent{
+				ID:        c.ID,
+				Type:      c.Type,
+				Status:    c.Status,
+				Message:   c.Message,
+				Shipper:   c.Shipper,
+				Resources: c.Resources,
+			})
+			continue
+		}
+
+		type unitKey struct{ unitType, id string }
+		prevUnits := make(map[unitKey]fleetapi.CheckinUnit, len(prev.Units))
+		for _, u := range prev.Units {
+			prevUnits[unitKey{u.Type, u.ID}] = u
+		}
+
+		delta := c
+		delta.Units = nil
+		for _, u := range c.Units {
+			key := unitKey{u.Type, u.ID}
+			if pu, ok := prevUnits[key]; !ok || !reflect.DeepEqual(pu, u) {
+				delta.Units = append(delta.Units, u)
+			}
+			delete(prevUnits, key)
+		}
+		for _, u := range prev.Units {
+			if _, removed := prevUnits[unitKey{u.Type, u.ID}]; removed {
+				delta.RemovedUnits = append(delta.RemovedUnits, u.ID)
+			}
+		}
+		changed = append(changed, delta)
+	}
+
+	var removed []string
+	for id := range base {
+		if !seen[id] {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+
+	return changed, removed
+}
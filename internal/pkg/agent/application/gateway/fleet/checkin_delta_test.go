@@ -0,0 +1,332 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eaclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
+	"github.com/elastic/elastic-agent/internal/pkg/scheduler"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestCheckinComponentsDelta(t *testing.T) {
+	unit := func(id, status string) fleetapi.CheckinUnit {
+		return fleetapi.CheckinUnit{ID: id, Type: "input", Status: status}
+	}
+	base := map[string]fleetapi.CheckinComponent{
+		"unchanged": {ID: "unchanged", Status: "HEALTHY", Units: []fleetapi.CheckinUnit{unit("u1", "HEALTHY")}},
+		"changed": {ID: "changed", Status: "HEALTHY", Units: []fleetapi.CheckinUnit{
+			unit("u1", "HEALTHY"),
+			unit("u2", "HEALTHY"),
+			unit("u3", "HEALTHY"),
+		}},
+		"removed": {ID: "removed", Status: "HEALTHY"},
+	}
+	components := []fleetapi.CheckinComponent{
+		{ID: "added", Status: "STARTING"},
+		{ID: "changed", Status: "DEGRADED", Units: []fleetapi.CheckinUnit{
+			unit("u1", "HEALTHY"),
+			unit("u2", "FAILED"),
+			unit("u4", "STARTING"),
+		}},
+		{ID: "unchanged", Status: "HEALTHY", Units: []fleetapi.CheckinUnit{unit("u1", "HEALTHY")}},
+	}
+
+	changed, removed := checkinComponentsDelta(base, components, false)
+
+	assert.Equal(t, []string{"removed"}, removed)
+	assert.Equal(t, []fleetapi.CheckinComponent{
+		{ID: "added", Status: "STARTING"},
+		{
+			ID:           "changed",
+			Status:       "DEGRADED",
+			Units:        []fleetapi.CheckinUnit{unit("u2", "FAILED"), unit("u4", "STARTING")},
+			RemovedUnits: []string{"u3"},
+		},
+	}, changed)
+}
+
+func TestCheckinComponentsDeltaResources(t *testing.T) {
+	base := map[string]fleetapi.CheckinComponent{
+		"sampled": {ID: "sampled", Status: "HEALTHY"},
+		"changed": {ID: "changed", Status: "HEALTHY"},
+	}
+	resources := &fleetapi.CheckinResources{PID: 42, RSS: 1024}
+	components := []fleetapi.CheckinComponent{
+		{ID: "changed", Status: "FAILED", Resources: resources},
+		{ID: "sampled", Status: "HEALTHY", Resources: resources},
+	}
+
+	// a new sample isn't a change of the components
+	changed, removed := checkinComponentsDelta(base, components, false)
+	assert.Empty(t, removed)
+	assert.Equal(t, []fleetapi.CheckinComponent{{ID: "changed", Status: "FAILED"}}, changed)
+
+	changed, _ = checkinComponentsDelta(base, components, true)
+	assert.Equal(t, []fleetapi.CheckinComponent{
+		{ID: "changed", Status: "FAILED", Resources: resources},
+		{ID: "sampled", Status: "HEALTHY", Resources: resources},
+	}, changed)
+
+	h1, err := hashCheckinComponents(withoutResources(components))
+	require.NoError(t, err)
+	components[1].Resources = &fleetapi.CheckinResources{PID: 42, RSS: 2048}
+	h2, err := hashCheckinComponents(withoutResources(components))
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2, "the resources must not change the hash")
+	assert.Equal(t, resources, components[0].Resources, "the components must not be modified")
+}
+
+func TestHashCheckinComponentsIsStable(t *testing.T) {
+	newComponents := func(unitIDs ...string) []fleetapi.CheckinComponent {
+		c := fleetapi.CheckinComponent{ID: "component"}
+		for _, id := range unitIDs {
+			c.Units = append(c.Units, fleetapi.CheckinUnit{ID: id, Type: "input"})
+		}
+		return []fleetapi.CheckinComponent{c}
+	}
+
+	c1 := newComponents("a", "b", "c")
+	c2 := newComponents("c", "a", "b")
+	sortCheckinComponents(c1)
+	sortCheckinComponents(c2)
+
+	h1, err := hashCheckinComponents(c1)
+	require.NoError(t, err)
+	h2, err := hashCheckinComponents(c2)
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2)
+}
+
+func TestFleetGatewayComponentsDelta(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log, _ := logger.NewTesting("fleet_gateway")
+	scheduler := scheduler.NewStepper()
+	client := newTestingClient()
+
+	var mx sync.Mutex
+	unitState := eaclient.UnitStateHealthy
+	var rss uint64
+	stateFetcher := func() coordinator.State {
+		mx.Lock()
+		defer mx.Unlock()
+		// every sample of the resources differs
+		rss++
+		units := map[runtime.ComponentUnitKey]runtime.ComponentUnitState{}
+		for i := 0; i < 3; i++ {
+			key := runtime.ComponentUnitKey{UnitType: eaclient.UnitTypeInput, UnitID: fmt.Sprintf("unit-%d", i)}
+			units[key] = runtime.ComponentUnitState{State: eaclient.UnitStateHealthy}
+		}
+		units[runtime.ComponentUnitKey{UnitType: eaclient.UnitTypeInput, UnitID: "unit-changing"}] =
+			runtime.ComponentUnitState{State: unitState}
+		return coordinator.State{
+			Components: []runtime.ComponentComponentState{{
+				Component: component.Component{ID: "component"},
+				State: runtime.ComponentState{
+					State:     eaclient.UnitStateHealthy,
+					Units:     units,
+					Resources: &runtime.ComponentResources{PID: 42, RSS: rss},
+				},
+			}},
+		}
+	}
+
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		&fleetGatewaySettings{
+			Duration:  5 * time.Second,
+			Backoff:   backoffSettings{Init: 1 * time.Second, Max: 5 * time.Second},
+			Delta:     deltaSettings{Enabled: true, FullInterval: time.Hour},
+			Resources: resourcesSettings{Enabled: true, Interval: time.Hour},
+		},
+		&testAgentInfo{},
+		client,
+		scheduler,
+		noop.New(),
+		stateFetcher,
+		nil,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+
+	errCh := runFleetGateway(ctx, gateway)
+
+	// checkin sends the request it receives to requests and answers with
+	// the given components hash.
+	requests := make(chan fleetapi.CheckinRequest, 1)
+	checkin := func(echoHash bool) {
+		waitFn := ackSeq(client.Answer(func(_ http.Header, body io.Reader) (*http.Response, error) {
+			var req fleetapi.CheckinRequest
+			if err := json.NewDecoder(body).Decode(&req); err != nil {
+				return nil, err
+			}
+			requests <- req
+			resp := fleetapi.CheckinResponse{}
+			if echoHash {
+				resp.ComponentsHash = req.ComponentsHash
+			}
+			data, err := json.Marshal(resp)
+			if err != nil {
+				return nil, err
+			}
+			return wrapStrToResp(http.StatusOK, string(data)), nil
+		}))
+		scheduler.Next()
+		waitFn()
+	}
+
+	// fleet-server without delta support always gets the full state.
+	checkin(false)
+	req := <-requests
+	require.Len(t, req.Components, 1)
+	assert.Len(t, req.Components[0].Units, 4)
+	assert.NotNil(t, req.Components[0].Resources, "the full state holds the resources")
+	assert.Empty(t, req.ComponentsBaseHash)
+	fullHash := req.ComponentsHash
+
+	checkin(true)
+	req = <-requests
+	require.Len(t, req.Components, 1)
+	assert.Empty(t, req.ComponentsBaseHash)
+	assert.Equal(t, fullHash, req.ComponentsHash)
+
+	// nothing but the resources changed since the accepted state, they were sent less
+	// than the resources interval ago.
+	checkin(true)
+	req = <-requests
+	assert.Empty(t, req.Components)
+	assert.Equal(t, fullHash, req.ComponentsBaseHash)
+	assert.Equal(t, fullHash, req.ComponentsHash)
+
+	// only the changed unit is sent.
+	mx.Lock()
+	unitState = eaclient.UnitStateFailed
+	mx.Unlock()
+	checkin(false)
+	req = <-requests
+	require.Len(t, req.Components, 1)
+	require.Len(t, req.Components[0].Units, 1)
+	assert.Equal(t, "unit-changing", req.Components[0].Units[0].ID)
+	assert.Nil(t, req.Components[0].Resources)
+	assert.Equal(t, fullHash, req.ComponentsBaseHash)
+	assert.NotEqual(t, fullHash, req.ComponentsHash)
+
+	// fleet-server didn't confirm the last state, the full state is sent again.
+	checkin(true)
+	req = <-requests
+	require.Len(t, req.Components, 1)
+	assert.Len(t, req.Components[0].Units, 4)
+	assert.Empty(t, req.ComponentsBaseHash)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestFleetGatewayVerifyCheckin(t *testing.T) {
+	log, _ := logger.NewTesting("fleet_gateway")
+
+	var mx sync.Mutex
+	unitState := eaclient.UnitStateHealthy
+	stateFetcher := func() coordinator.State {
+		mx.Lock()
+		defer mx.Unlock()
+		return coordinator.State{
+			Components: []runtime.ComponentComponentState{{
+				Component: component.Component{ID: "component"},
+				State: runtime.ComponentState{
+					State: eaclient.UnitStateHealthy,
+					Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+						{UnitType: eaclient.UnitTypeInput, UnitID: "unit"}: {State: unitState},
+					},
+				},
+			}},
+		}
+	}
+
+	client := newTestingClient()
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		&fleetGatewaySettings{
+			Duration: 5 * time.Second,
+			Backoff:  backoffSettings{Init: 1 * time.Second, Max: 5 * time.Second},
+			Delta:    deltaSettings{Enabled: true, FullInterval: time.Hour},
+		},
+		&testAgentInfo{},
+		client,
+		scheduler.NewStepper(),
+		noop.New(),
+		stateFetcher,
+		nil,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+
+	// answer sends the requests received by c to requests and confirms their components hash.
+	requests := make(chan fleetapi.CheckinRequest, 1)
+	answer := func(c *testingClient) <-chan struct{} {
+		return c.Answer(func(_ http.Header, body io.Reader) (*http.Response, error) {
+			var req fleetapi.CheckinRequest
+			if err := json.NewDecoder(body).Decode(&req); err != nil {
+				return nil, err
+			}
+			requests <- req
+			data, err := json.Marshal(fleetapi.CheckinResponse{ComponentsHash: req.ComponentsHash})
+			if err != nil {
+				return nil, err
+			}
+			return wrapStrToResp(http.StatusOK, string(data)), nil
+		})
+	}
+
+	received := answer(client)
+	_, _, err = gateway.execute(context.Background(), client)
+	require.NoError(t, err)
+	<-received
+	req := <-requests
+	baseHash := req.ComponentsHash
+
+	// the verification only sends the changes against the accepted state.
+	mx.Lock()
+	unitState = eaclient.UnitStateFailed
+	mx.Unlock()
+	verifyClient := newTestingClient()
+	received = answer(verifyClient)
+	require.NoError(t, gateway.VerifyCheckin(context.Background(), verifyClient))
+	<-received
+	req = <-requests
+	assert.Empty(t, req.AckToken)
+	assert.Equal(t, baseHash, req.ComponentsBaseHash)
+	require.Len(t, req.Components, 1)
+	verifiedHash := req.ComponentsHash
+	assert.NotEqual(t, baseHash, verifiedHash)
+
+	// the following checkin is based on the state accepted with the verification.
+	received = answer(client)
+	_, _, err = gateway.execute(context.Background(), client)
+	require.NoError(t, err)
+	<-received
+	req = <-requests
+	assert.Equal(t, verifiedHash, req.ComponentsBaseHash)
+	assert.Empty(t, req.Components)
+}
@@ -6,6 +6,8 @@ package fleet
 
 import (
 	"context"
+	"math/rand"
+	"sync"
 	"time"
 
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -13,11 +15,15 @@ import (
 	eaclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/internal/pkg/scheduler"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -40,12 +46,34 @@ var defaultGatewaySettings = &fleetGatewaySettings{
 		Init: 60 * time.Second,
 		Max:  10 * time.Minute,
 	},
+	Expedited: expeditedSettings{ // checkins triggered by local state changes
+		Enabled:     true,
+		Debounce:    5 * time.Second,
+		MinInterval: 30 * time.Second,
+	},
+	Delta: deltaSettings{ // components state sent as changes since the last accepted one
+		Enabled:      true,
+		FullInterval: 1 * time.Hour,
+	},
+	Pacing: pacingSettings{ // delays requested by fleet-server
+		Enabled:  true,
+		MinDelay: 1 * time.Second,
+		MaxDelay: 30 * time.Minute,
+	},
+	Resources: resourcesSettings{ // resources used by the component processes
+		Enabled:  true,
+		Interval: 5 * time.Minute,
+	},
 }
 
 type fleetGatewaySettings struct {
-	Duration time.Duration   `config:"checkin_frequency"`
-	Jitter   time.Duration   `config:"jitter"`
-	Backoff  backoffSettings `config:"backoff"`
+	Duration  time.Duration     `config:"checkin_frequency"`
+	Jitter    time.Duration     `config:"jitter"`
+	Backoff   backoffSettings   `config:"backoff"`
+	Expedited expeditedSettings `config:"expedited"`
+	Delta     deltaSettings     `config:"delta"`
+	Pacing    pacingSettings    `config:"pacing"`
+	Resources resourcesSettings `config:"resources"`
 }
 
 type backoffSettings struct {
@@ -53,6 +81,52 @@ type backoffSettings struct {
 	Max  time.Duration `config:"max"`
 }
 
+// expeditedSettings controls the checkins triggered by significant changes of the
+// local state, outside of the regular checkin schedule.
+type expeditedSettings struct {
+	Enabled bool `config:"enabled"`
+	// Debounce is the time to wait after a state change before checking in, any
+	// other change happening in that window is sent with the same checkin.
+	Debounce time.Duration `config:"debounce"`
+	// MinInterval is the minimum time between two expedited checkins, it prevents
+	// flapping components from flooding fleet-server.
+	MinInterval time.Duration `config:"min_interval"`
+}
+
+// deltaSettings controls sending only the changed components and units on checkin.
+// Deltas are only sent once fleet-server confirmed it stored a previous state.
+type deltaSettings struct {
+	Enabled bool `config:"enabled"`
+	// FullInterval is the maximum time between two checkins with the full components state.
+	FullInterval time.Duration `config:"full_interval"`
+}
+
+// pacingSettings controls how the delays requested by fleet-server are honoured: the
+// Retry-After of a failed checkin replaces the backoff, and the checkin interval of a
+// successful checkin delays the next one.
+type pacingSettings struct {
+	Enabled bool `config:"enabled"`
+	// MinDelay and MaxDelay bound the delays requested by fleet-server.
+	MinDelay time.Duration `config:"min_delay"`
+	MaxDelay time.Duration `config:"max_delay"`
+}
+
+// resourcesSettings controls sending the resources used by the component processes,
+// as last sampled by the runtime, with the checkin components. The resources change with
+// every sample, they are not part of the components state compared by the deltas and are
+// sent with the full states and at most once per interval with the deltas.
+type resourcesSettings struct {
+	Enabled  bool          `config:"enabled"`
+	Interval time.Duration `config:"interval"`
+}
+
+// Sources of the delay before the next checkin.
+const (
+	delaySourceBackoff         = "backoff"
+	delaySourceRetryAfter      = "retry_after"
+	delaySourceCheckinInterval = "checkin_interval"
+)
+
 type agentInfo interface {
 	AgentID() string
 }
@@ -65,8 +139,13 @@ type stateStore interface {
 	Actions() []fleetapi.Action
 }
 
+// stateSubscriber returns a channel reporting the changes of the coordinator state,
+// see coordinator.Coordinator.StateSubscribe.
+type stateSubscriber func(ctx context.Context, bufferLen int) chan coordinator.State
+
 type FleetGateway struct {
 	log                *logger.Logger
+	clientMx           sync.Mutex
 	client             client.Sender
 	scheduler          scheduler.Scheduler
 	settings           *fleetGatewaySettings
@@ -75,9 +154,40 @@ type FleetGateway struct {
 	unauthCounter      int
 	checkinFailCounter int
 	stateFetcher       func() coordinator.State
+	stateSubscriber    stateSubscriber
 	stateStore         stateStore
 	errCh              chan error
 	actionCh           chan []fleetapi.Action
+
+	// expediteCh receives a value when an expedited checkin must be done on the
+	// next iteration of the run loop.
+	expediteCh chan struct{}
+
+	// checkinMx protects checkinCancel and checkinExpedited.
+	checkinMx sync.Mutex
+	// checkinCancel cancels the checkin request currently in flight, nil when
+	// no request is in flight.
+	checkinCancel context.CancelFunc
+	// checkinExpedited is set when the in flight request was cancelled to
+	// perform an expedited checkin.
+	checkinExpedited bool
+
+	// componentsMx guards acceptedComponents and resourcesSentAt, the checkins
+	// verifying a new API key update them outside of the gateway loop.
+	componentsMx sync.Mutex
+	// acceptedComponents is the last components state accepted by fleet-server,
+	// nil when the next checkin must send the full state.
+	acceptedComponents *acceptedComponents
+	// resourcesSentAt is when fleet-server last received the resources of the components.
+	resourcesSentAt time.Time
+
+	// nextCheckinDelay is the delay fleet-server requested before the next
+	// checkin, zero when it doesn't pace the checkins.
+	nextCheckinDelay time.Duration
+
+	// tamperLog holds the tamper events reported in the checkin message until
+	// fleet-server accepts a checkin, nil when they aren't reported.
+	tamperLog *protection.TamperLog
 }
 
 // New creates a new fleet gateway
@@ -87,11 +197,12 @@ func New(
 	client client.Sender,
 	acker acker.Acker,
 	stateFetcher func() coordinator.State,
+	stateSubscriber stateSubscriber,
 	stateStore stateStore,
 ) (*FleetGateway, error) {
 
 	scheduler := scheduler.NewPeriodicJitter(defaultGatewaySettings.Duration, defaultGatewaySettings.Jitter)
-	return newFleetGatewayWithScheduler(
+	gateway, err := newFleetGatewayWithScheduler(
 		log,
 		defaultGatewaySettings,
 		agentInfo,
@@ -99,8 +210,14 @@ func New(
 		scheduler,
 		acker,
 		stateFetcher,
+		stateSubscriber,
 		stateStore,
 	)
+	if err != nil {
+		return nil, err
+	}
+	gateway.tamperLog = protection.NewTamperLog(paths.TamperEventsFile())
+	return gateway, nil
 }
 
 func newFleetGatewayWithScheduler(
@@ -111,19 +228,22 @@ func newFleetGatewayWithScheduler(
 	scheduler scheduler.Scheduler,
 	acker acker.Acker,
 	stateFetcher func() coordinator.State,
+	stateSubscriber stateSubscriber,
 	stateStore stateStore,
 ) (*FleetGateway, error) {
 	return &FleetGateway{
-		log:          log,
-		client:       client,
-		settings:     settings,
-		agentInfo:    agentInfo,
-		scheduler:    scheduler,
-		acker:        acker,
-		stateFetcher: stateFetcher,
-		stateStore:   stateStore,
-		errCh:        make(chan error),
-		actionCh:     make(chan []fleetapi.Action, 1),
+		log:             log,
+		client:          client,
+		settings:        settings,
+		agentInfo:       agentInfo,
+		scheduler:       scheduler,
+		acker:           acker,
+		stateFetcher:    stateFetcher,
+		stateSubscriber: stateSubscriber,
+		stateStore:      stateStore,
+		errCh:           make(chan error),
+		actionCh:        make(chan []fleetapi.Action, 1),
+		expediteCh:      make(chan struct{}, 1),
 	}, nil
 }
 
@@ -145,31 +265,195 @@ func (f *FleetGateway) Run(ctx context.Context) error {
 		close(done)
 	}()
 
+	if f.settings.Expedited.Enabled && f.stateSubscriber != nil {
+		go f.watchState(ctx)
+	}
+
 	f.log.Info("Fleet gateway started")
+	expedited := false
+	for {
+		if !expedited {
+			select {
+			case <-ctx.Done():
+				f.scheduler.Stop()
+				f.log.Info("Fleet gateway stopped")
+				return ctx.Err()
+			case <-f.scheduler.WaitTick():
+				f.log.Debug("FleetGateway calling Checkin API")
+			case <-f.expediteCh:
+				f.log.Debug("FleetGateway calling Checkin API after a state change")
+			}
+		}
+		expedited = false
+
+		// Execute the checkin call and for any errors returned by the fleet-server API
+		// the function will retry to communicate with fleet-server with an exponential delay and some
+		// jitter to help better distribute the load from a fleet of agents.
+		resp, err := f.doExecute(ctx, backoff)
+		if err != nil {
+			continue
+		}
+
+		actions := make([]fleetapi.Action, len(resp.Actions))
+		copy(actions, resp.Actions)
+		if len(actions) > 0 {
+			f.actionCh <- actions
+		}
+
+		if f.nextCheckinDelay > 0 {
+			expedited = f.waitNextCheckin(ctx)
+		}
+	}
+}
+
+// waitNextCheckin waits for the delay fleet-server requested before the next checkin. It
+// returns true when an expedited checkin interrupted the wait.
+func (f *FleetGateway) waitNextCheckin(ctx context.Context) bool {
+	t := time.NewTimer(f.nextCheckinDelay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return false
+	case <-f.expediteCh:
+		f.log.Debug("FleetGateway calling Checkin API after a state change, before the requested delay")
+		return true
+	}
+}
+
+// watchState watches the coordinator state and triggers an expedited checkin when the
+// state changes significantly. Checkins are debounced and rate limited by the expedited
+// settings.
+func (f *FleetGateway) watchState(ctx context.Context) {
+	stateCh := f.stateSubscriber(ctx, 0)
+
+	var last coordinator.State
+	select {
+	case <-ctx.Done():
+		return
+	case s, ok := <-stateCh:
+		if !ok {
+			return
+		}
+		// The first state read is the current one, it will be sent by the next checkin.
+		last = s
+	}
+
+	var lastExpedited time.Time
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+	pending := false
+
 	for {
 		select {
 		case <-ctx.Done():
-			f.scheduler.Stop()
-			f.log.Info("Fleet gateway stopped")
-			return ctx.Err()
-		case <-f.scheduler.WaitTick():
-			f.log.Debug("FleetGateway calling Checkin API")
-
-			// Execute the checkin call and for any errors returned by the fleet-server API
-			// the function will retry to communicate with fleet-server with an exponential delay and some
-			// jitter to help better distribute the load from a fleet of agents.
-			resp, err := f.doExecute(ctx, backoff)
-			if err != nil {
+			return
+		case s, ok := <-stateCh:
+			if !ok {
+				return
+			}
+			significant := stateChangedSignificantly(last, s)
+			last = s
+			if !significant || pending {
 				continue
 			}
+			pending = true
+			wait := f.settings.Expedited.Debounce
+			if next := time.Until(lastExpedited.Add(f.settings.Expedited.MinInterval)); next > wait {
+				wait = next
+			}
+			f.log.Debugf("Significant state change, scheduling an expedited checkin in %s", wait)
+			timer.Reset(wait)
+		case <-timer.C:
+			pending = false
+			lastExpedited = time.Now()
+			f.expedite()
+		}
+	}
+}
 
-			actions := make([]fleetapi.Action, len(resp.Actions))
-			copy(actions, resp.Actions)
-			if len(actions) > 0 {
-				f.actionCh <- actions
+// expedite triggers a checkin as soon as possible. A checkin request in flight is
+// cancelled and sent again with the current state; otherwise the next run loop
+// iteration does not wait for the scheduler.
+func (f *FleetGateway) expedite() {
+	f.checkinMx.Lock()
+	defer f.checkinMx.Unlock()
+	if f.checkinCancel != nil {
+		f.checkinExpedited = true
+		f.checkinCancel()
+		return
+	}
+	select {
+	case f.expediteCh <- struct{}{}:
+	default:
+	}
+}
+
+// startCheckin returns the context used for a single checkin request. The returned
+// function must be called once the request is done, it reports if the request was
+// cancelled for an expedited checkin.
+func (f *FleetGateway) startCheckin(ctx context.Context) (context.Context, func() bool) {
+	// The checkin about to be sent already carries the latest state.
+	select {
+	case <-f.expediteCh:
+	default:
+	}
+
+	checkinCtx, cancel := context.WithCancel(ctx)
+	f.checkinMx.Lock()
+	f.checkinCancel = cancel
+	f.checkinExpedited = false
+	f.checkinMx.Unlock()
+
+	return checkinCtx, func() bool {
+		f.checkinMx.Lock()
+		defer f.checkinMx.Unlock()
+		cancel()
+		f.checkinCancel = nil
+		return f.checkinExpedited
+	}
+}
+
+// stateChangedSignificantly reports if the overall, component, unit or upgrade state
+// differs between two coordinator states. Messages and payloads are ignored so chatty
+// components don't trigger checkins.
+func stateChangedSignificantly(prev, cur coordinator.State) bool {
+	if prev.State != cur.State {
+		return true
+	}
+	upgradeState := func(s coordinator.State) details.State {
+		if s.UpgradeDetails == nil {
+			return ""
+		}
+		return s.UpgradeDetails.State
+	}
+	if upgradeState(prev) != upgradeState(cur) {
+		return true
+	}
+	if len(prev.Components) != len(cur.Components) {
+		return true
+	}
+	prevComponents := make(map[string]runtime.ComponentState, len(prev.Components))
+	for _, c := range prev.Components {
+		prevComponents[c.Component.ID] = c.State
+	}
+	for _, c := range cur.Components {
+		p, ok := prevComponents[c.Component.ID]
+		if !ok || p.State != c.State.State || len(p.Units) != len(c.State.Units) {
+			return true
+		}
+		for key, unit := range c.State.Units {
+			pu, ok := p.Units[key]
+			if !ok || pu.State != unit.State {
+				return true
 			}
 		}
 	}
+	return false
 }
 
 // Errors returns the channel to watch for reported errors.
@@ -184,19 +468,49 @@ func (f *FleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 	// this mean we are rebooting to change the log level or the system is shutting us down.
 	for ctx.Err() == nil {
 		f.log.Debugf("Checking started")
-		resp, took, err := f.execute(ctx)
+		// the client is replaced when the Fleet hosts change, the attempt uses
+		// the one set when it starts
+		sender := f.getClient()
+		checkinCtx, done := f.startCheckin(ctx)
+		resp, took, err := f.execute(checkinCtx, sender)
+		expedited := done()
+		if err != nil && ctx.Err() == nil && expedited {
+			// The request was interrupted to send the latest state, this is not a failure.
+			f.log.Debug("Checkin request interrupted for an expedited checkin")
+			continue
+		}
 		if err != nil {
 			f.checkinFailCounter++
 
+			// fleet-server may ask to wait longer than the backoff before retrying, the
+			// backoff keeps growing and the jitter spreads the retries of the agents.
+			delay, delaySource := bo.NextWait(), delaySourceBackoff
+			var extraDelay time.Duration
+			retryAfter, paced := f.retryAfter(err)
+			if paced {
+				if retryAfter > delay {
+					extraDelay = retryAfter - delay
+				}
+				extraDelay += jitter(retryAfter)
+				delay, delaySource = delay+extraDelay, delaySourceRetryAfter
+			}
+
 			// Report the first two failures at warn level as they may be recoverable with retries.
 			if f.checkinFailCounter <= 2 {
 				f.log.Warnw("Possible transient error during checkin with fleet-server, retrying",
 					"error.message", err, "request_duration_ns", took, "failed_checkins", f.checkinFailCounter,
-					"retry_after_ns", bo.NextWait())
+					"retry_after_ns", delay, "retry_after_source", delaySource)
 			} else {
 				f.log.Errorw("Cannot checkin in with fleet-server, retrying",
 					"error.message", err, "request_duration_ns", took, "failed_checkins", f.checkinFailCounter,
-					"retry_after_ns", bo.NextWait())
+					"retry_after_ns", delay, "retry_after_source", delaySource)
+			}
+
+			if paced {
+				f.errCh <- coordinator.NewRetryAfterError(delay, err)
+				if !wait(ctx, extraDelay) {
+					return nil, ctx.Err()
+				}
 			}
 
 			if !bo.Wait() {
@@ -204,14 +518,16 @@ func (f *FleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 				err := errors.New(
 					"checkin retry loop was stopped",
 					errors.TypeNetwork,
-					errors.M(errors.MetaKeyURI, f.client.URI()),
+					errors.M(errors.MetaKeyURI, sender.URI()),
 				)
 
 				f.log.Error(err)
 				f.errCh <- err
 				return nil, err
 			}
-			f.errCh <- err
+			if !paced {
+				f.errCh <- err
+			}
 			continue
 		}
 
@@ -221,8 +537,11 @@ func (f *FleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 		}
 
 		f.checkinFailCounter = 0
+		f.nextCheckinDelay = f.checkinInterval(resp)
 		if resp.FleetWarning != "" {
 			f.errCh <- coordinator.NewWarningError(resp.FleetWarning)
+		} else if f.nextCheckinDelay > 0 {
+			f.errCh <- coordinator.NewPacingError(f.nextCheckinDelay, delaySourceCheckinInterval)
 		} else {
 			f.errCh <- nil
 		}
@@ -236,7 +555,68 @@ func (f *FleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 	return nil, ctx.Err()
 }
 
+// retryAfter returns the delay fleet-server requested before retrying a failed
+// checkin, bounded by the pacing settings.
+func (f *FleetGateway) retryAfter(err error) (time.Duration, bool) {
+	if !f.settings.Pacing.Enabled {
+		return 0, false
+	}
+	delay, ok := client.RetryAfter(err)
+	if !ok {
+		return 0, false
+	}
+	return f.boundDelay(delay), true
+}
+
+// checkinInterval returns the delay fleet-server requested before the next checkin,
+// bounded by the pacing settings, or zero if it didn't request any.
+func (f *FleetGateway) checkinInterval(resp *fleetapi.CheckinResponse) time.Duration {
+	if !f.settings.Pacing.Enabled || resp.CheckinInterval == "" {
+		return 0
+	}
+	interval, err := time.ParseDuration(resp.CheckinInterval)
+	if err != nil {
+		f.log.Warnf("Ignoring invalid checkin interval %q from fleet-server: %v", resp.CheckinInterval, err)
+		return 0
+	}
+	delay := f.boundDelay(interval)
+	f.log.Infow("Fleet-server requested to delay the next checkin",
+		"delay_ns", delay, "delay_source", delaySourceCheckinInterval)
+	return delay
+}
+
+func (f *FleetGateway) boundDelay(d time.Duration) time.Duration {
+	if d < f.settings.Pacing.MinDelay {
+		return f.settings.Pacing.MinDelay
+	}
+	if f.settings.Pacing.MaxDelay > 0 && d > f.settings.Pacing.MaxDelay {
+		return f.settings.Pacing.MaxDelay
+	}
+	return d
+}
+
+// jitter returns a random duration up to a tenth of d.
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(d)/10 + 1)) //nolint:gosec // jitter doesn't need a secure random
+}
+
+// wait waits for d, it returns false if ctx is done before.
+func wait(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
 func (f *FleetGateway) convertToCheckinComponents(components []runtime.ComponentComponentState) []fleetapi.CheckinComponent {
+	return convertToCheckinComponents(components, f.settings.Resources.Enabled)
+}
+
+func convertToCheckinComponents(components []runtime.ComponentComponentState, withResources bool) []fleetapi.CheckinComponent {
 	if components == nil {
 		return nil
 	}
@@ -290,10 +670,26 @@ func (f *FleetGateway) convertToCheckinComponents(components []runtime.Component
 			Message: state.Message,
 			Shipper: shipperReference,
 		}
+		if r := state.Resources; r != nil && withResources {
+			checkinComponent.Resources = &fleetapi.CheckinResources{
+				PID:     r.PID,
+				RSS:     r.RSS,
+				CPU:     r.CPU,
+				OpenFDs: r.OpenFDs,
+			}
+		}
 
 		if state.Units != nil {
 			units := make([]fleetapi.CheckinUnit, 0, len(state.Units))
 
+			// label the units of the inputs that don't come from the Fleet policy
+			sources := make(map[string]string)
+			for _, unit := range component.Units {
+				if unit.Source != "" {
+					sources[unit.ID] = unit.Source
+				}
+			}
+
 			for unitKey, unitState := range state.Units {
 				units = append(units, fleetapi.CheckinUnit{
 					ID:      unitKey.UnitID,
@@ -301,6 +697,7 @@ func (f *FleetGateway) convertToCheckinComponents(components []runtime.Component
 					Status:  stateString(unitState.State),
 					Message: unitState.Message,
 					Payload: unitState.Payload,
+					Source:  sources[unitKey.UnitID],
 				})
 			}
 			checkinComponent.Units = units
@@ -311,7 +708,31 @@ func (f *FleetGateway) convertToCheckinComponents(components []runtime.Component
 	return checkinComponents
 }
 
-func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse, time.Duration, error) {
+// VerifyCheckin checks in with fleet-server through sender, reporting the current
+// state without the ack token so fleet-server answers right away. The components
+// state goes through the same delta bookkeeping as the gateway checkins, so the
+// following checkins stay based on the state fleet-server stored.
+func (f *FleetGateway) VerifyCheckin(ctx context.Context, sender client.Sender) error {
+	state := f.stateFetcher()
+	components := f.convertToCheckinComponents(state.Components)
+
+	req := &fleetapi.CheckinRequest{
+		Status:         agentStateToString(state.State),
+		Message:        state.Message,
+		UpgradeDetails: state.UpgradeDetails,
+	}
+	f.setCheckinComponents(req, components)
+
+	resp, _, err := fleetapi.NewCheckinCmd(f.agentInfo, sender).Execute(ctx, req)
+	if err != nil {
+		f.resetCheckinComponents()
+		return err
+	}
+	f.acceptCheckinComponents(req, resp, components)
+	return nil
+}
+
+func (f *FleetGateway) execute(ctx context.Context, sender client.Sender) (*fleetapi.CheckinResponse, time.Duration, error) {
 	ecsMeta, err := info.Metadata(ctx, f.log)
 	if err != nil {
 		f.log.Error(errors.New("failed to load metadata", err))
@@ -329,18 +750,30 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	// convert components into checkin components structure
 	components := f.convertToCheckinComponents(state.Components)
 
+	// report the operations blocked by the tamper protection until fleet-server
+	// accepts a checkin
+	message := state.Message
+	tamperEvents := f.unreportedTamperEvents()
+	if len(tamperEvents) > 0 {
+		message += "; " + protection.Summary(tamperEvents)
+	}
+
 	// checkin
-	cmd := fleetapi.NewCheckinCmd(f.agentInfo, f.client)
+	cmd := fleetapi.NewCheckinCmd(f.agentInfo, sender)
 	req := &fleetapi.CheckinRequest{
 		AckToken:       ackToken,
 		Metadata:       ecsMeta,
 		Status:         agentStateToString(state.State),
-		Message:        state.Message,
-		Components:     components,
+		Message:        message,
 		UpgradeDetails: state.UpgradeDetails,
 	}
+	f.setCheckinComponents(req, components)
 
 	resp, took, err := cmd.Execute(ctx, req)
+	if err != nil {
+		// fleet-server may not have the state we know about, send the full state next time.
+		f.resetCheckinComponents()
+	}
 	if isUnauth(err) {
 		f.unauthCounter++
 
@@ -359,6 +792,14 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 		return nil, took, err
 	}
 
+	f.acceptCheckinComponents(req, resp, components)
+
+	if len(tamperEvents) > 0 {
+		if err := f.tamperLog.MarkReported(tamperEvents[len(tamperEvents)-1]); err != nil {
+			f.log.Errorf("failed to mark the tamper events as reported, err: %v", err)
+		}
+	}
+
 	// Save the latest ackToken
 	if resp.AckToken != "" {
 		f.stateStore.SetAckToken(resp.AckToken)
@@ -371,6 +812,19 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	return resp, took, nil
 }
 
+// unreportedTamperEvents returns the tamper events not reported to fleet-server yet.
+func (f *FleetGateway) unreportedTamperEvents() []protection.TamperEvent {
+	if f.tamperLog == nil {
+		return nil
+	}
+	events, err := f.tamperLog.Unreported()
+	if err != nil {
+		f.log.Errorf("failed to read the tamper events, err: %v", err)
+		return nil
+	}
+	return events
+}
+
 // shouldUnenroll checks if the max number of trying an invalid key is reached
 func (f *FleetGateway) shouldUnenroll() bool {
 	return f.unauthCounter > maxUnauthCounter
@@ -380,10 +834,37 @@ func isUnauth(err error) bool {
 	return errors.Is(err, client.ErrInvalidAPIKey)
 }
 
+// getClient returns the client of the checkins.
+func (f *FleetGateway) getClient() client.Sender {
+	f.clientMx.Lock()
+	defer f.clientMx.Unlock()
+	return f.client
+}
+
 func (f *FleetGateway) SetClient(c client.Sender) {
+	f.clientMx.Lock()
+	defer f.clientMx.Unlock()
 	f.client = c
 }
 
+// hostsReporter is implemented by the clients reporting the health of their
+// hosts, see remote.Client.
+type hostsReporter interface {
+	Hosts() []remote.HostHealth
+}
+
+// FleetHosts returns the health of the Fleet Server hosts of the current
+// client, nil when the client doesn't report it.
+func (f *FleetGateway) FleetHosts() []remote.HostHealth {
+	f.clientMx.Lock()
+	defer f.clientMx.Unlock()
+	reporter, ok := f.client.(hostsReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.Hosts()
+}
+
 func agentStateToString(state agentclient.State) string {
 	switch state {
 	case agentclient.Healthy:
@@ -16,23 +16,34 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"gotest.tools/assert"
 
+	eaclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/internal/pkg/scheduler"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/testing/fleetservertest"
 )
 
 type clientCallbackFunc func(headers http.Header, body io.Reader) (*http.Response, error)
@@ -91,6 +102,7 @@ func withGateway(agentInfo agentInfo, settings *fleetGatewaySettings, fn withGat
 			scheduler,
 			noop.New(),
 			emptyStateFetcher,
+			nil,
 			stateStore,
 		)
 
@@ -230,6 +242,7 @@ func TestFleetGateway(t *testing.T) {
 			scheduler,
 			noop.New(),
 			emptyStateFetcher,
+			nil,
 			stateStore,
 		)
 		require.NoError(t, err)
@@ -282,6 +295,7 @@ func TestFleetGateway(t *testing.T) {
 			scheduler,
 			noop.New(),
 			emptyStateFetcher,
+			nil,
 			stateStore,
 		)
 		require.NoError(t, err)
@@ -341,6 +355,7 @@ func TestFleetGateway(t *testing.T) {
 			scheduler,
 			noop.New(),
 			stateFetcher,
+			nil,
 			stateStore,
 		)
 
@@ -465,6 +480,469 @@ func TestRetriesOnFailures(t *testing.T) {
 		}))
 }
 
+func TestDoExecuteWithFaults(t *testing.T) {
+	const upgradeAction = `{
+  "agent_id": "agent-secret",
+  "id": "upgrade-action",
+  "type": "UPGRADE",
+  "expiration": "2023-11-01T12:00:00Z",
+  "data": {"version": "8.12.0"}
+}`
+
+	testcases := []struct {
+		name             string
+		fault            fleetservertest.Fault
+		expectedFailures int
+		expectedExpiry   string
+	}{{
+		name: "429 with Retry-After",
+		fault: fleetservertest.Fault{Kind: fleetservertest.FaultStatus,
+			StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second, Count: 2},
+		expectedFailures: 2,
+	}, {
+		name: "5xx burst",
+		fault: fleetservertest.Fault{Kind: fleetservertest.FaultStatus,
+			StatusCode: http.StatusServiceUnavailable, Count: 3},
+		expectedFailures: 3,
+	}, {
+		name:             "connection reset",
+		fault:            fleetservertest.Fault{Kind: fleetservertest.FaultReset, Count: 2},
+		expectedFailures: 2,
+	}, {
+		name:             "truncated body",
+		fault:            fleetservertest.Fault{Kind: fleetservertest.FaultTruncate, Count: 2},
+		expectedFailures: 2,
+	}, {
+		name:             "invalid JSON",
+		fault:            fleetservertest.Fault{Kind: fleetservertest.FaultInvalidJSON, Count: 2},
+		expectedFailures: 2,
+	}, {
+		name:  "latency",
+		fault: fleetservertest.Fault{Kind: fleetservertest.FaultLatency, Latency: 100 * time.Millisecond},
+	}, {
+		name:           "clock skew",
+		fault:          fleetservertest.Fault{Kind: fleetservertest.FaultClockSkew, Skew: -time.Hour},
+		expectedExpiry: "2023-11-01T11:00:00Z",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			tc.fault.Route = "AgentCheckin"
+			scenario, err := fleetservertest.NewScenario(tc.fault)
+			require.NoError(t, err)
+			fs := fleetservertest.NewServer(&fleetservertest.Handlers{
+				AgentID: testAgentInfo{}.AgentID(),
+				CheckinFn: fleetservertest.NewHandlerCheckin(func() (fleetservertest.CheckinAction, *fleetservertest.HTTPError) {
+					return fleetservertest.CheckinAction{Actions: []string{upgradeAction}}, nil
+				}),
+			}, fleetservertest.WithScenario(scenario))
+			defer fs.Close()
+
+			log, _ := logger.New("fleet_gateway", false)
+			client, err := fleetclient.NewAuthWithConfig(log, "apiKey", remote.Config{Host: fs.LocalhostURL})
+			require.NoError(t, err)
+
+			gateway, err := newFleetGatewayWithScheduler(
+				log,
+				defaultGatewaySettings,
+				testAgentInfo{},
+				client,
+				scheduler.NewStepper(),
+				noop.New(),
+				emptyStateFetcher,
+				nil,
+				newStateStore(t, log),
+			)
+			require.NoError(t, err)
+
+			// doExecute reports every failed checkin, and the final success.
+			var failures int
+			reported := make(chan struct{})
+			go func() {
+				defer close(reported)
+				for err := range gateway.errCh {
+					if err == nil {
+						return
+					}
+					failures++
+				}
+			}()
+
+			bo := backoff.NewExpBackoff(ctx.Done(), 10*time.Millisecond, 50*time.Millisecond)
+			resp, err := gateway.doExecute(ctx, bo)
+			require.NoError(t, err)
+			<-reported
+
+			require.Equal(t, tc.expectedFailures, failures)
+			require.Equal(t, 0, gateway.checkinFailCounter)
+			require.NotZero(t, scenario.Injected("AgentCheckin"), "the fault was never injected")
+
+			require.Len(t, resp.Actions, 1)
+			upgrade, ok := resp.Actions[0].(*fleetapi.ActionUpgrade)
+			require.True(t, ok, "expected an UPGRADE action, got %T", resp.Actions[0])
+			expectedExpiry := tc.expectedExpiry
+			if expectedExpiry == "" {
+				expectedExpiry = "2023-11-01T12:00:00Z"
+			}
+			require.Equal(t, expectedExpiry, upgrade.ActionExpiration)
+		})
+	}
+}
+
+func TestCheckinPacing(t *testing.T) {
+	pacedSettings := func(enabled bool) *fleetGatewaySettings {
+		settings := *defaultGatewaySettings
+		// the backoff is long enough for the tests to time out if used
+		settings.Backoff = backoffSettings{Init: time.Hour, Max: time.Hour}
+		settings.Pacing = pacingSettings{Enabled: enabled, MinDelay: 10 * time.Millisecond, MaxDelay: 200 * time.Millisecond}
+		return &settings
+	}
+	newGateway := func(t *testing.T, settings *fleetGatewaySettings, sender fleetclient.Sender) *FleetGateway {
+		log, _ := logger.New("fleet_gateway", false)
+		gateway, err := newFleetGatewayWithScheduler(
+			log,
+			settings,
+			testAgentInfo{},
+			sender,
+			scheduler.NewStepper(),
+			noop.New(),
+			emptyStateFetcher,
+			nil,
+			newStateStore(t, log),
+		)
+		require.NoError(t, err)
+		return gateway
+	}
+	// doExecute runs a checkin and returns the errors it reported.
+	doExecute := func(t *testing.T, gateway *FleetGateway) []error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var reported []error
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for err := range gateway.errCh {
+				reported = append(reported, err)
+				var pErr *coordinator.PacingError
+				if err == nil || (errors.As(err, &pErr) && pErr.Source == delaySourceCheckinInterval) {
+					return
+				}
+			}
+		}()
+
+		bo := backoff.NewEqualJitterBackoff(ctx.Done(), gateway.settings.Backoff.Init, gateway.settings.Backoff.Max)
+		_, err := gateway.doExecute(ctx, bo)
+		require.NoError(t, err)
+		<-done
+		return reported
+	}
+	checkinIntervalClient := func() *testingClient {
+		client := newTestingClient()
+		client.Answer(func(_ http.Header, _ io.Reader) (*http.Response, error) {
+			return wrapStrToResp(http.StatusOK, `{"actions": [], "checkin_interval": "1h"}`), nil
+		})
+		return client
+	}
+
+	retryAfterClient := func(t *testing.T, retryAfter time.Duration) fleetclient.Sender {
+		scenario, err := fleetservertest.NewScenario(fleetservertest.Fault{
+			Route:      "AgentCheckin",
+			Kind:       fleetservertest.FaultStatus,
+			StatusCode: http.StatusServiceUnavailable,
+			RetryAfter: retryAfter,
+			Count:      1,
+		})
+		require.NoError(t, err)
+		fs := fleetservertest.NewServer(&fleetservertest.Handlers{
+			AgentID: testAgentInfo{}.AgentID(),
+			CheckinFn: fleetservertest.NewHandlerCheckin(func() (fleetservertest.CheckinAction, *fleetservertest.HTTPError) {
+				return fleetservertest.CheckinAction{}, nil
+			}),
+		}, fleetservertest.WithScenario(scenario))
+		t.Cleanup(fs.Close)
+		log, _ := logger.New("fleet_gateway", false)
+		client, err := fleetclient.NewAuthWithConfig(log, "apiKey", remote.Config{Host: fs.LocalhostURL})
+		require.NoError(t, err)
+		return client
+	}
+	retryAfterError := func(t *testing.T, err error) *coordinator.RetryAfterError {
+		var rErr *coordinator.RetryAfterError
+		require.True(t, errors.As(err, &rErr), "expected a RetryAfterError, got %v", err)
+		var pErr *coordinator.PacingError
+		require.False(t, errors.As(err, &pErr), "a failed checkin must not be reported as paced")
+		require.ErrorContains(t, err, "503")
+		return rErr
+	}
+
+	t.Run("Retry-After extends the backoff", func(t *testing.T) {
+		settings := pacedSettings(true)
+		settings.Backoff = backoffSettings{Init: 10 * time.Millisecond, Max: 10 * time.Millisecond}
+
+		start := time.Now()
+		reported := doExecute(t, newGateway(t, settings, retryAfterClient(t, time.Hour)))
+		require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond, "the Retry-After must be bounded by the max delay")
+		require.Len(t, reported, 2)
+		rErr := retryAfterError(t, reported[0])
+		require.GreaterOrEqual(t, rErr.Delay, 200*time.Millisecond)
+		require.LessOrEqual(t, rErr.Delay, 240*time.Millisecond, "the jitter must be a tenth of the Retry-After at most")
+		require.NoError(t, reported[1])
+	})
+
+	t.Run("backoff longer than Retry-After", func(t *testing.T) {
+		settings := pacedSettings(true)
+		settings.Backoff = backoffSettings{Init: 300 * time.Millisecond, Max: 300 * time.Millisecond}
+
+		start := time.Now()
+		reported := doExecute(t, newGateway(t, settings, retryAfterClient(t, time.Millisecond)))
+		require.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond, "the backoff must be used when longer")
+		require.Len(t, reported, 2)
+		rErr := retryAfterError(t, reported[0])
+		require.GreaterOrEqual(t, rErr.Delay, 300*time.Millisecond)
+		require.NoError(t, reported[1])
+	})
+
+	t.Run("checkin interval delays the next checkin", func(t *testing.T) {
+		gateway := newGateway(t, pacedSettings(true), checkinIntervalClient())
+		reported := doExecute(t, gateway)
+
+		require.Equal(t, 200*time.Millisecond, gateway.nextCheckinDelay)
+		require.Len(t, reported, 1)
+		var pErr *coordinator.PacingError
+		require.True(t, errors.As(reported[0], &pErr), "expected a PacingError, got %v", reported[0])
+		require.Equal(t, 200*time.Millisecond, pErr.Delay)
+		require.Equal(t, delaySourceCheckinInterval, pErr.Source)
+	})
+
+	t.Run("pacing disabled", func(t *testing.T) {
+		gateway := newGateway(t, pacedSettings(false), checkinIntervalClient())
+		reported := doExecute(t, gateway)
+
+		require.Zero(t, gateway.nextCheckinDelay)
+		require.Equal(t, []error{nil}, reported)
+	})
+}
+
+// blockingClient blocks every checkin request until its context is cancelled,
+// like a fleet-server long poll without actions.
+type blockingClient struct {
+	requests chan *fleetapi.CheckinRequest
+}
+
+func (b *blockingClient) Send(
+	ctx context.Context,
+	_ string,
+	_ string,
+	_ url.Values,
+	_ http.Header,
+	body io.Reader,
+) (*http.Response, error) {
+	var req fleetapi.CheckinRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, err
+	}
+	b.requests <- &req
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (b *blockingClient) URI() string {
+	return "http://localhost"
+}
+
+func TestExpeditedCheckin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log, _ := logger.NewTesting("fleet_gateway")
+	stateStore := newStateStore(t, log)
+	client := &blockingClient{requests: make(chan *fleetapi.CheckinRequest, 10)}
+	scheduler := scheduler.NewStepper()
+
+	var mx sync.Mutex
+	current := coordinator.State{State: agentclient.Healthy}
+	stateFetcher := func() coordinator.State {
+		mx.Lock()
+		defer mx.Unlock()
+		return current
+	}
+	stateCh := make(chan coordinator.State, 1)
+	stateSubscriber := func(_ context.Context, _ int) chan coordinator.State {
+		return stateCh
+	}
+	setState := func(s coordinator.State) {
+		mx.Lock()
+		current = s
+		mx.Unlock()
+		stateCh <- s
+	}
+
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		&fleetGatewaySettings{
+			Duration: 5 * time.Second,
+			Backoff:  backoffSettings{Init: 1 * time.Second, Max: 5 * time.Second},
+			Expedited: expeditedSettings{
+				Enabled:     true,
+				Debounce:    10 * time.Millisecond,
+				MinInterval: 500 * time.Millisecond,
+			},
+		},
+		&testAgentInfo{},
+		client,
+		scheduler,
+		noop.New(),
+		stateFetcher,
+		stateSubscriber,
+		stateStore,
+	)
+	require.NoError(t, err)
+
+	errCh := runFleetGateway(ctx, gateway)
+	setState(coordinator.State{State: agentclient.Healthy})
+
+	scheduler.Next()
+	req := <-client.requests
+	assert.Equal(t, fleetStateOnline, req.Status)
+
+	// A state change interrupts the long poll and checks in with the new state.
+	setState(coordinator.State{State: agentclient.Failed})
+	select {
+	case req = <-client.requests:
+		assert.Equal(t, fleetStateError, req.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an expedited checkin")
+	}
+
+	// A flapping state is rate limited.
+	start := time.Now()
+	setState(coordinator.State{State: agentclient.Healthy})
+	select {
+	case req = <-client.requests:
+		assert.Equal(t, fleetStateOnline, req.Status)
+		require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an expedited checkin")
+	}
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestExpeditedCheckinDuringCheckinInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log, _ := logger.NewTesting("fleet_gateway")
+	settings := *defaultGatewaySettings
+	settings.Pacing = pacingSettings{Enabled: true, MaxDelay: time.Hour}
+	client := newTestingClient()
+	received := client.Answer(func(_ http.Header, _ io.Reader) (*http.Response, error) {
+		return wrapStrToResp(http.StatusOK, `{"actions": [], "checkin_interval": "1h"}`), nil
+	})
+	scheduler := scheduler.NewStepper()
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		&settings,
+		&testAgentInfo{},
+		client,
+		scheduler,
+		noop.New(),
+		emptyStateFetcher,
+		nil,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+
+	errCh := runFleetGateway(ctx, gateway)
+	scheduler.Next()
+	<-received
+
+	// the checkin interval of the last checkin doesn't delay an expedited checkin
+	require.Eventually(t, func() bool {
+		gateway.expedite()
+		select {
+		case <-received:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestStateChangedSignificantly(t *testing.T) {
+	unitKey := runtime.ComponentUnitKey{UnitType: eaclient.UnitTypeInput, UnitID: "unit"}
+	withComponent := func(componentState, unitState eaclient.UnitState, message string) coordinator.State {
+		return coordinator.State{
+			State: agentclient.Healthy,
+			Components: []runtime.ComponentComponentState{{
+				Component: component.Component{ID: "component"},
+				State: runtime.ComponentState{
+					State:   componentState,
+					Message: message,
+					Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+						unitKey: {State: unitState, Message: message},
+					},
+				},
+			}},
+		}
+	}
+
+	testcases := map[string]struct {
+		prev     coordinator.State
+		cur      coordinator.State
+		expected bool
+	}{
+		"same state": {
+			prev:     withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateHealthy, "a"),
+			cur:      withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateHealthy, "a"),
+			expected: false,
+		},
+		"only messages changed": {
+			prev:     withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateHealthy, "a"),
+			cur:      withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateHealthy, "b"),
+			expected: false,
+		},
+		"overall state changed": {
+			prev:     coordinator.State{State: agentclient.Healthy},
+			cur:      coordinator.State{State: agentclient.Degraded},
+			expected: true,
+		},
+		"component added": {
+			prev:     coordinator.State{State: agentclient.Healthy},
+			cur:      withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateHealthy, "a"),
+			expected: true,
+		},
+		"component state changed": {
+			prev:     withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateHealthy, "a"),
+			cur:      withComponent(eaclient.UnitStateFailed, eaclient.UnitStateHealthy, "a"),
+			expected: true,
+		},
+		"unit state changed": {
+			prev:     withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateHealthy, "a"),
+			cur:      withComponent(eaclient.UnitStateHealthy, eaclient.UnitStateDegraded, "a"),
+			expected: true,
+		},
+		"upgrade failed": {
+			prev:     coordinator.State{UpgradeDetails: &details.Details{State: details.StateDownloading}},
+			cur:      coordinator.State{UpgradeDetails: &details.Details{State: details.StateFailed}},
+			expected: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, stateChangedSignificantly(tc.prev, tc.cur))
+		})
+	}
+}
+
 type testAgentInfo struct{}
 
 func (testAgentInfo) AgentID() string { return "agent-secret" }
@@ -570,3 +1048,201 @@ func TestAgentStateToString(t *testing.T) {
 		})
 	}
 }
+
+func TestFleetHosts(t *testing.T) {
+	log, _ := logger.New("fleet_gateway", false)
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		defaultGatewaySettings,
+		testAgentInfo{},
+		newTestingClient(),
+		scheduler.NewStepper(),
+		noop.New(),
+		emptyStateFetcher,
+		nil,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+	require.Nil(t, gateway.FleetHosts(), "the testing client doesn't report its hosts")
+
+	cfg := remote.DefaultClientConfig()
+	cfg.Hosts = []string{"fleet-1:8220", "fleet-2:8220"}
+	sender, err := remote.NewWithConfig(log, cfg, nil)
+	require.NoError(t, err)
+	gateway.SetClient(sender)
+
+	hosts := gateway.FleetHosts()
+	require.Len(t, hosts, 2)
+	assert.Equal(t, "http://fleet-1:8220/", hosts[0].Host)
+	assert.Equal(t, "http://fleet-2:8220/", hosts[1].Host)
+	assert.Equal(t, remote.CircuitClosed, hosts[0].Circuit)
+}
+
+func TestTamperEventsReported(t *testing.T) {
+	log, _ := logger.New("fleet_gateway", false)
+	client := newTestingClient()
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		defaultGatewaySettings,
+		testAgentInfo{},
+		client,
+		scheduler.NewStepper(),
+		noop.New(),
+		func() coordinator.State { return coordinator.State{Message: "Running"} },
+		nil,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+	gateway.tamperLog = protection.NewTamperLog(filepath.Join(t.TempDir(), "tamper_events.json"))
+	require.NoError(t, gateway.tamperLog.Record(protection.OperationUninstall, protection.ErrInvalidUninstallToken))
+
+	// checkin sends a checkin answered with status and returns its message.
+	checkin := func(status int) string {
+		var message string
+		client.Answer(func(_ http.Header, body io.Reader) (*http.Response, error) {
+			var req fleetapi.CheckinRequest
+			if err := json.NewDecoder(body).Decode(&req); err != nil {
+				return nil, err
+			}
+			message = req.Message
+			return wrapStrToResp(status, `{"actions": []}`), nil
+		})
+		_, _, _ = gateway.execute(context.Background(), client)
+		<-client.received
+		return message
+	}
+
+	message := checkin(http.StatusInternalServerError)
+	assert.Assert(t, strings.HasPrefix(message, "Running; tamper protection blocked 1 operation (uninstall: 1)"), message)
+
+	message = checkin(http.StatusOK)
+	assert.Assert(t, strings.Contains(message, "tamper protection blocked 1 operation"), "the events are reported until a checkin succeeds")
+
+	message = checkin(http.StatusOK)
+	assert.Equal(t, "Running", message)
+}
+
+func TestConvertToCheckinComponentsResources(t *testing.T) {
+	settings := *defaultGatewaySettings
+	gateway := &FleetGateway{settings: &settings}
+	components := []runtime.ComponentComponentState{
+		{
+			Component: component.Component{ID: "sampled"},
+			State: runtime.ComponentState{
+				State:     eaclient.UnitStateHealthy,
+				Resources: &runtime.ComponentResources{PID: 42, RSS: 1024, CPU: 12.5, OpenFDs: 7, SampledAt: time.Now()},
+			},
+		},
+		{
+			Component: component.Component{ID: "not-sampled"},
+			State:     runtime.ComponentState{State: eaclient.UnitStateHealthy},
+		},
+	}
+
+	checkinComponents := gateway.convertToCheckinComponents(components)
+	require.Len(t, checkinComponents, 2)
+	assert.DeepEqual(t, &fleetapi.CheckinResources{PID: 42, RSS: 1024, CPU: 12.5, OpenFDs: 7}, checkinComponents[0].Resources)
+	assert.Assert(t, checkinComponents[1].Resources == nil)
+
+	settings.Resources.Enabled = false
+	checkinComponents = gateway.convertToCheckinComponents(components)
+	assert.Assert(t, checkinComponents[0].Resources == nil)
+}
+
+func TestConvertToCheckinComponentsSource(t *testing.T) {
+	gateway := &FleetGateway{settings: defaultGatewaySettings}
+	components := []runtime.ComponentComponentState{
+		{
+			Component: component.Component{
+				ID: "filestream-default",
+				Units: []component.Unit{
+					{ID: "filestream-default-fleet", Type: eaclient.UnitTypeInput},
+					{ID: "filestream-default-local", Type: eaclient.UnitTypeInput, Source: component.SourceLocal},
+				},
+			},
+			State: runtime.ComponentState{
+				State: eaclient.UnitStateHealthy,
+				Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+					{UnitType: eaclient.UnitTypeInput, UnitID: "filestream-default-fleet"}: {State: eaclient.UnitStateHealthy},
+					{UnitType: eaclient.UnitTypeInput, UnitID: "filestream-default-local"}: {State: eaclient.UnitStateHealthy},
+				},
+			},
+		},
+	}
+
+	checkinComponents := gateway.convertToCheckinComponents(components)
+	require.Len(t, checkinComponents, 1)
+	sources := make(map[string]string)
+	for _, unit := range checkinComponents[0].Units {
+		sources[unit.ID] = unit.Source
+	}
+	assert.DeepEqual(t, map[string]string{
+		"filestream-default-fleet": "",
+		"filestream-default-local": "local",
+	}, sources)
+}
+
+// countingClient answers every checkin without actions and counts them.
+type countingClient struct {
+	checkins atomic.Int32
+}
+
+func (c *countingClient) Send(_ context.Context, _ string, _ string, _ url.Values, _ http.Header, _ io.Reader) (*http.Response, error) {
+	c.checkins.Add(1)
+	return wrapStrToResp(http.StatusOK, `{"actions": []}`), nil
+}
+
+func (c *countingClient) URI() string {
+	return "http://localhost"
+}
+
+func TestFleetGatewaySetClientDuringCheckin(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	log, _ := logger.New("fleet_gateway", false)
+	first, second := &countingClient{}, &countingClient{}
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		defaultGatewaySettings,
+		testAgentInfo{},
+		first,
+		scheduler.NewStepper(),
+		noop.New(),
+		emptyStateFetcher,
+		nil,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-gateway.errCh:
+			}
+		}
+	}()
+
+	// the Fleet hosts change while the gateway checks in
+	replaced := make(chan struct{})
+	go func() {
+		defer close(replaced)
+		for i := 0; i < 100; i++ {
+			if i%2 == 0 {
+				gateway.SetClient(second)
+			} else {
+				gateway.SetClient(first)
+			}
+		}
+	}()
+
+	const checkins = 20
+	for i := 0; i < checkins; i++ {
+		bo := backoff.NewEqualJitterBackoff(ctx.Done(), time.Millisecond, time.Millisecond)
+		_, err := gateway.doExecute(ctx, bo)
+		require.NoError(t, err)
+	}
+	<-replaced
+	require.Equal(t, int32(checkins), first.checkins.Load()+second.checkins.Load())
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/cloud"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/features"
 
@@ -25,6 +26,7 @@ type ECSMeta struct {
 	Elastic *ElasticECSMeta `json:"elastic"`
 	Host    *HostECSMeta    `json:"host"`
 	OS      *SystemECSMeta  `json:"os"`
+	Cloud   *cloud.Metadata `json:"cloud,omitempty"`
 }
 
 // ElasticECSMeta is a collection of elastic vendor metadata in ECS compliant object form.
@@ -137,6 +139,10 @@ func Metadata(ctx context.Context, l *logger.Logger) (*ECSMeta, error) {
 		return nil, errors.New(err, "failed to gather host metadata")
 	}
 
+	if features.CloudMetadata() {
+		meta.Cloud = cloud.Default().Metadata(ctx)
+	}
+
 	return meta, nil
 }
 
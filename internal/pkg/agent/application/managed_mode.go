@@ -90,6 +90,9 @@ func newManagedConfigManager(
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize action dispatcher: %w", err)
 	}
+	if cfg.Settings != nil {
+		actionDispatcher.SetMaintenanceWindows(cfg.Settings.MaintenanceWindows)
+	}
 
 	return &managedConfigManager{
 		log:                  log,
@@ -130,7 +133,7 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 	defer gatewayCancel()
 
 	// Initialize the actionDispatcher.
-	policyChanger := m.initDispatcher(gatewayCancel)
+	policyChanger, apiKeyRotator := m.initDispatcher(gatewayCancel)
 
 	// Create ackers to enqueue/retry failed acks
 	ack, err := fleet.NewAcker(m.log, m.agentInfo, m.client)
@@ -190,19 +193,25 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		m.client,
 		actionAcker,
 		m.coord.State,
+		m.coord.StateSubscribe,
 		m.stateStore,
 	)
 	if err != nil {
 		return err
 	}
+	m.coord.SetFleetHostsProvider(gateway.FleetHosts)
+	apiKeyRotator.SetCheckinVerifier(gateway)
 
 	// Not running a Fleet Server so the gateway and acker can be changed based on the configuration change.
 	if m.cfg.Fleet.Server == nil {
 		policyChanger.AddSetter(gateway)
 		policyChanger.AddSetter(ack)
+		apiKeyRotator.AddSetter(gateway)
+		apiKeyRotator.AddSetter(ack)
 
 		for _, cs := range m.initialClientSetters {
 			policyChanger.AddSetter(cs)
+			apiKeyRotator.AddSetter(cs)
 		}
 	} else {
 		// locally managed fleet server
@@ -333,7 +342,7 @@ func fleetServerRunning(state runtime.ComponentState) bool {
 	return false
 }
 
-func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *handlers.PolicyChangeHandler {
+func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) (*handlers.PolicyChangeHandler, *handlers.RotateAPIKey) {
 	policyChanger := handlers.NewPolicyChangeHandler(
 		m.log,
 		m.agentInfo,
@@ -392,6 +401,7 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 			m.coord,
 			m.cfg.Settings.MonitoringConfig.Diagnostics.Limit,
 			uploader.New(m.agentInfo.AgentID(), m.client, m.cfg.Settings.MonitoringConfig.Diagnostics.Uploader),
+			m.cfg.Settings.Diagnostics.RedactionPolicy(),
 		),
 	)
 
@@ -400,10 +410,21 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 		handlers.NewAppAction(m.log, m.coord, m.agentInfo.AgentID()),
 	)
 
+	apiKeyRotator := handlers.NewRotateAPIKey(
+		m.log,
+		m.agentInfo,
+		m.cfg,
+		storage.NewReplaceOnSuccessStore(paths.ConfigFile(), DefaultAgentFleetConfig, m.store),
+	)
+	m.dispatcher.MustRegister(
+		&fleetapi.ActionRotateAPIKey{},
+		apiKeyRotator,
+	)
+
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionUnknown{},
 		handlers.NewUnknown(m.log),
 	)
 
-	return policyChanger
+	return policyChanger, apiKeyRotator
 }
@@ -71,6 +71,14 @@ func exposeMetricsEndpoint(
 	statsHandler := statsHandler(ns("stats"))
 	r.Handle("/stats", createHandler(statsHandler))
 
+	health := monitoringCfg.DefaultHealthConfig()
+	if mcfg != nil && mcfg.HTTP != nil {
+		health = mcfg.HTTP.Health
+	}
+	for _, probe := range []coordinator.Probe{coordinator.ProbeLiveness, coordinator.ProbeReadiness, coordinator.ProbeStartup} {
+		r.Handle("/"+string(probe), coord.ProbeHandler(probe, health))
+	}
+
 	if enableProcessStats {
 		r.Handle("/processes", createHandler(processesHandler(coord)))
 		r.Handle("/processes/{componentID}", createHandler(processHandler(coord, statsHandler, operatingSystem)))
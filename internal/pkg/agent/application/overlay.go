@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/config/operations"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// overlayConfigManager is a decorator of the Fleet config manager that merges the inputs of the
+// inputs.d directory, the local overlay, into the Fleet policy. The capabilities define which
+// input types and outputs the local inputs can use, the inputs that can't be merged are reported
+// with setOverlayError.
+type overlayConfigManager struct {
+	log      *logger.Logger
+	inner    coordinator.ConfigManager
+	period   time.Duration
+	discover config.DiscoverFunc
+	loader   *config.Loader
+	caps     capabilities.Capabilities
+	ch       chan coordinator.ConfigChange
+
+	// setOverlayError reports the conflicts of the last merge, the coordinator
+	// requires the config manager so it is set once the coordinator is created.
+	setOverlayError func(error)
+
+	// policy is the last Fleet policy and overlay the inputs of the last load
+	// of the local overlay.
+	policy  *config.Config
+	overlay []map[string]interface{}
+	// conflicts are the conflicts of the last merge.
+	conflicts []string
+}
+
+func newOverlayConfigManager(
+	log *logger.Logger,
+	inner coordinator.ConfigManager,
+	period time.Duration,
+	discover config.DiscoverFunc,
+	loader *config.Loader,
+	caps capabilities.Capabilities,
+) *overlayConfigManager {
+	return &overlayConfigManager{
+		log:      log,
+		inner:    inner,
+		period:   period,
+		discover: discover,
+		loader:   loader,
+		caps:     caps,
+		ch:       make(chan coordinator.ConfigChange),
+	}
+}
+
+func (m *overlayConfigManager) Run(ctx context.Context) error {
+	go m.merge(ctx)
+	return m.inner.Run(ctx)
+}
+
+func (m *overlayConfigManager) Errors() <-chan error {
+	return m.inner.Errors()
+}
+
+func (m *overlayConfigManager) ActionErrors() <-chan error {
+	return m.inner.ActionErrors()
+}
+
+func (m *overlayConfigManager) Watch() <-chan coordinator.ConfigChange {
+	return m.ch
+}
+
+func (m *overlayConfigManager) merge(ctx context.Context) {
+	m.reload()
+
+	t := time.NewTicker(m.period)
+	defer t.Stop()
+	for {
+		var change coordinator.ConfigChange
+		select {
+		case <-ctx.Done():
+			return
+		case fleetChange := <-m.inner.Watch():
+			m.policy = fleetChange.Config()
+			change = &overlayConfigChange{ConfigChange: fleetChange, cfg: m.apply(m.policy)}
+		case <-t.C:
+			if !m.reload() || m.policy == nil {
+				continue
+			}
+			m.log.Info("Local overlay changes detected")
+			// the Fleet policy didn't change, there is nothing to acknowledge
+			change = &localConfigChange{cfg: m.apply(m.policy)}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case m.ch <- change:
+		}
+	}
+}
+
+// reload loads the local overlay and returns true when its inputs changed.
+func (m *overlayConfigManager) reload() bool {
+	overlay, err := operations.LoadLocalOverlay(m.discover, m.loader)
+	if err != nil {
+		// keep the last overlay, the file may be in the middle of a write
+		m.log.Errorf("Failed to load the local overlay: %s", err)
+		return false
+	}
+	if reflect.DeepEqual(overlay, m.overlay) {
+		return false
+	}
+	m.overlay = overlay
+	return true
+}
+
+// apply merges the local overlay into the policy, the inputs that can't be merged are
+// left out and reported.
+func (m *overlayConfigManager) apply(policy *config.Config) *config.Config {
+	if len(m.overlay) == 0 && len(m.conflicts) == 0 {
+		return policy
+	}
+
+	merged, conflicts, err := operations.MergeLocalOverlay(policy, m.overlay, m.caps)
+	if err != nil {
+		// the policy itself is reported by the coordinator
+		m.log.Errorf("Failed to merge the local overlay: %s", err)
+		return policy
+	}
+	for _, conflict := range conflicts {
+		m.log.Warnf("Local overlay: %s", conflict)
+	}
+	m.conflicts = conflicts
+	if m.setOverlayError != nil {
+		if len(conflicts) > 0 {
+			m.setOverlayError(fmt.Errorf("%s", strings.Join(conflicts, "; ")))
+		} else {
+			m.setOverlayError(nil)
+		}
+	}
+	return merged
+}
+
+// overlayConfigChange is a Fleet policy change with the local overlay merged into it.
+type overlayConfigChange struct {
+	coordinator.ConfigChange
+	cfg *config.Config
+}
+
+func (c *overlayConfigChange) Config() *config.Config {
+	return c.cfg
+}
@@ -0,0 +1,133 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const overlayCapabilities = `
+capabilities:
+- rule: allow
+  local_input: filestream
+- rule: allow
+  local_output: default
+`
+
+var overlayFleetPolicy = map[string]interface{}{
+	"outputs": map[string]interface{}{
+		"default":    map[string]interface{}{"type": "elasticsearch"},
+		"monitoring": map[string]interface{}{"type": "elasticsearch"},
+	},
+	"inputs": []interface{}{
+		map[string]interface{}{"type": "system/metrics", "id": "system-metrics"},
+	},
+}
+
+type fakeConfigManager struct {
+	ch chan coordinator.ConfigChange
+}
+
+func (f *fakeConfigManager) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *fakeConfigManager) Errors() <-chan error {
+	return nil
+}
+
+func (f *fakeConfigManager) ActionErrors() <-chan error {
+	return nil
+}
+
+func (f *fakeConfigManager) Watch() <-chan coordinator.ConfigChange {
+	return f.ch
+}
+
+type fakeConfigChange struct {
+	cfg   *config.Config
+	acked bool
+}
+
+func (f *fakeConfigChange) Config() *config.Config {
+	return f.cfg
+}
+
+func (f *fakeConfigChange) Ack() error {
+	f.acked = true
+	return nil
+}
+
+func (f *fakeConfigChange) Fail(_ error) {}
+
+func TestOverlayConfigManager(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log := logger.NewWithoutConfig("testing")
+	caps, err := capabilities.Load(strings.NewReader(overlayCapabilities), log)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "inputs.d", "*.yml")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "inputs.d"), 0755))
+	writeOverlay := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "inputs.d", "local.yml"), []byte(content), 0600))
+	}
+	writeOverlay(`
+inputs:
+- type: filestream
+  id: nginx-logs
+`)
+
+	inner := &fakeConfigManager{ch: make(chan coordinator.ConfigChange)}
+	overlayErrs := make(chan error, 10)
+	mgr := newOverlayConfigManager(log, inner, 10*time.Millisecond, config.Discoverer(pattern), config.NewLoader(log, pattern), caps)
+	mgr.setOverlayError = func(err error) { overlayErrs <- err }
+	go func() { _ = mgr.Run(ctx) }()
+
+	inputIDs := func(change coordinator.ConfigChange) []string {
+		cfg, err := change.Config().ToMapStr()
+		require.NoError(t, err)
+		var ids []string
+		for _, input := range cfg["inputs"].([]interface{}) {
+			ids = append(ids, input.(map[string]interface{})["id"].(string))
+		}
+		return ids
+	}
+
+	// a Fleet policy change gets the local overlay and is still acknowledged
+	fleetChange := &fakeConfigChange{cfg: config.MustNewConfigFrom(overlayFleetPolicy)}
+	inner.ch <- fleetChange
+	change := <-mgr.Watch()
+	assert.Equal(t, []string{"system-metrics", "nginx-logs"}, inputIDs(change))
+	assert.NoError(t, <-overlayErrs)
+	require.NoError(t, change.Ack())
+	assert.True(t, fleetChange.acked)
+
+	// a local overlay change is merged into the last Fleet policy
+	writeOverlay(`
+inputs:
+- type: log
+  id: legacy-logs
+`)
+	change = <-mgr.Watch()
+	assert.Equal(t, []string{"system-metrics"}, inputIDs(change))
+	assert.EqualError(t, <-overlayErrs, `local input "legacy-logs": input type "log" is not allowed`)
+}
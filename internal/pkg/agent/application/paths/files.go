@@ -35,6 +35,12 @@ const defaultAgentStateStoreFile = "state.enc"
 // defaultInputDPath return the location of the inputs.d.
 const defaultInputsDPath = "inputs.d"
 
+// defaultTamperEventsFile is the file that will contain the operations blocked by the agent protection.
+const defaultTamperEventsFile = "tamper_events.json"
+
+// defaultProtectionStateFile is the file that will contain the protection configuration applied by the agent.
+const defaultProtectionStateFile = "protection.json"
+
 // AgentConfigYmlFile is a name of file used to store agent information
 func AgentConfigYmlFile() string {
 	return filepath.Join(Config(), defaultAgentFleetYmlFile)
@@ -90,3 +96,13 @@ func AgentStateStoreFile() string {
 func AgentInputsDPath() string {
 	return filepath.Join(Config(), defaultInputsDPath)
 }
+
+// TamperEventsFile is the file that contains the operations blocked by the agent protection.
+func TamperEventsFile() string {
+	return filepath.Join(Config(), defaultTamperEventsFile)
+}
+
+// ProtectionStateFile is the file that contains the protection configuration applied by the agent.
+func ProtectionStateFile() string {
+	return filepath.Join(Config(), defaultProtectionStateFile)
+}
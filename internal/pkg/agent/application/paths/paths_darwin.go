@@ -51,3 +51,8 @@ func ArePathsEqual(expected, actual string) bool {
 func AgentVaultPath() string {
 	return defaultAgentVaultName
 }
+
+// AgentVaultPathFrom is keychain name on Mac OS, it doesn't depend on the config directory.
+func AgentVaultPathFrom(_ string) string {
+	return defaultAgentVaultName
+}
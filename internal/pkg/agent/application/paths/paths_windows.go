@@ -48,5 +48,10 @@ func ArePathsEqual(expected, actual string) bool {
 
 // AgentVaultPath is the directory that contains all the files for the value
 func AgentVaultPath() string {
-	return filepath.Join(Config(), defaultAgentVaultPath)
+	return AgentVaultPathFrom(Config())
+}
+
+// AgentVaultPathFrom is the vault directory of the agent with the config directory.
+func AgentVaultPathFrom(config string) string {
+	return filepath.Join(config, defaultAgentVaultPath)
 }
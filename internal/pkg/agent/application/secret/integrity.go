@@ -0,0 +1,22 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package secret
+
+import "context"
+
+// integritySecretKey stores the key signing the integrity manifests of the
+// installed versions.
+const integritySecretKey = "integrity"
+
+// CreateIntegritySecret creates the key signing the integrity manifests if it
+// doesn't exist.
+func CreateIntegritySecret(ctx context.Context, opts ...OptionFunc) error {
+	return Create(ctx, integritySecretKey, opts...)
+}
+
+// GetIntegritySecret reads the key signing the integrity manifests from the vault.
+func GetIntegritySecret(ctx context.Context, opts ...OptionFunc) (Secret, error) {
+	return Get(ctx, integritySecretKey, opts...)
+}
@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package secret
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
+)
+
+// agentSecretPendingKey stores the new agent secret during its rotation.
+const agentSecretPendingKey = "secret.pending"
+
+// CreatePendingAgentSecret creates a new agent secret and stores it aside the
+// current one, until CommitPendingAgentSecret replaces the agent secret with it.
+func CreatePendingAgentSecret(ctx context.Context, opts ...OptionFunc) (Secret, error) {
+	k, err := aesgcm.NewKey(aesgcm.AES256)
+	if err != nil {
+		return Secret{}, err
+	}
+
+	now := time.Now().UTC()
+	secret := Secret{
+		Value:     k,
+		CreatedOn: now,
+		RotatedOn: now,
+	}
+	return secret, Set(ctx, agentSecretPendingKey, secret, opts...)
+}
+
+// GetPendingAgentSecret reads the pending agent secret from the vault, it returns
+// false if there is no pending agent secret.
+func GetPendingAgentSecret(ctx context.Context, opts ...OptionFunc) (Secret, bool, error) {
+	options := applyOptions(opts...)
+	v, err := vault.New(ctx, options.vaultPath)
+	if err != nil {
+		return Secret{}, false, fmt.Errorf("could not create new vault: %w", err)
+	}
+	defer v.Close()
+
+	exists, err := v.Exists(ctx, agentSecretPendingKey)
+	if err != nil || !exists {
+		return Secret{}, false, err
+	}
+
+	secret, err := Get(ctx, agentSecretPendingKey, opts...)
+	return secret, err == nil, err
+}
+
+// CommitPendingAgentSecret replaces the agent secret with the pending one.
+// The pending agent secret is kept until RemovePendingAgentSecret is called.
+func CommitPendingAgentSecret(ctx context.Context, opts ...OptionFunc) error {
+	secret, ok, err := GetPendingAgentSecret(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no pending agent secret to commit")
+	}
+	return SetAgentSecret(ctx, secret, opts...)
+}
+
+// RemovePendingAgentSecret removes the pending agent secret from the vault.
+func RemovePendingAgentSecret(ctx context.Context, opts ...OptionFunc) error {
+	return Remove(ctx, agentSecretPendingKey, opts...)
+}
+
+// RotateVault replaces the seed of the vault holding the agent secrets,
+// re-encrypting them with the new one.
+func RotateVault(ctx context.Context, opts ...OptionFunc) error {
+	options := applyOptions(opts...)
+	v, err := vault.New(ctx, options.vaultPath)
+	if err != nil {
+		return fmt.Errorf("could not create new vault: %w", err)
+	}
+	defer v.Close()
+
+	return v.Rotate(ctx, []string{agentSecretKey, agentSecretPendingKey, integritySecretKey})
+}
@@ -27,6 +27,7 @@ var mxCreate sync.Mutex
 type Secret struct {
 	Value     []byte    `json:"v"` // binary value
 	CreatedOn time.Time `json:"t"` // date/time the secret was created on
+	RotatedOn time.Time `json:"r"` // date/time the secret was rotated on, zero if never rotated
 }
 
 type options struct {
@@ -8,42 +8,83 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/integrity"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
 // unpack unpacks archive correctly, skips root (symlink, config...) unpacks data/*
-func (u *Upgrader) unpack(version, archivePath string) (string, error) {
+// The hashes of the unpacked files are returned by their slash separated path
+// relative to the data directory.
+func (u *Upgrader) unpack(version, archivePath string) (string, map[string]string, error) {
 	// unpack must occur in directory that holds the installation directory
 	// or the extraction will be double nested
 	var hash string
 	var err error
+	files := map[string]string{}
 	if runtime.GOOS == windows {
-		hash, err = unzip(u.log, archivePath)
+		hash, err = unzip(u.log, archivePath, files)
 	} else {
-		hash, err = untar(u.log, version, archivePath)
+		hash, err = untar(u.log, version, archivePath, files)
 	}
 
 	if err != nil {
 		u.log.Errorw("Failed to unpack upgrade artifact", "error.message", err, "version", version, "file.path", archivePath, "hash", hash)
-		return "", err
+		return "", nil, err
 	}
 
 	u.log.Infow("Unpacked upgrade artifact", "version", version, "file.path", archivePath, "hash", hash)
-	return hash, nil
+	return hash, files, nil
+}
+
+// writeIntegrityManifest writes the signed manifest of the files unpacked in
+// the versioned home of hash, so elastic-agent verify can detect tampering.
+func (u *Upgrader) writeIntegrityManifest(ctx context.Context, version, hash, archivePath string, verified bool, files map[string]string) error {
+	homeDir := fmt.Sprintf("%s-%s", agentName, hash)
+	m := &integrity.Manifest{
+		Version:   version,
+		Commit:    hash,
+		CreatedAt: time.Now().UTC(),
+		Archive: integrity.Archive{
+			Name:     filepath.Base(archivePath),
+			Verified: verified,
+		},
+		Files: map[string]string{},
+	}
+	if sum, err := os.ReadFile(archivePath + ".sha512"); err == nil {
+		if fields := strings.Fields(string(sum)); len(fields) > 0 {
+			m.Archive.SHA512 = fields[0]
+		}
+	}
+	for name, fileHash := range files {
+		if rel, ok := strings.CutPrefix(name, homeDir+"/"); ok {
+			m.Files[rel] = fileHash
+		}
+	}
+
+	key, err := integrity.SigningKey(ctx, true)
+	if err != nil {
+		return err
+	}
+	if err := m.Sign(key); err != nil {
+		return err
+	}
+	return m.Write(filepath.Join(paths.Data(), homeDir))
 }
 
-func unzip(log *logger.Logger, archivePath string) (string, error) {
+func unzip(log *logger.Logger, archivePath string, files map[string]string) (string, error) {
 	var hash, rootDir string
 	r, err := zip.OpenReader(archivePath)
 	if err != nil {
@@ -101,10 +142,12 @@ func unzip(log *logger.Logger, archivePath string) (string, error) {
 				}
 			}()
 
+			h := integrity.NewHash()
 			//nolint:gosec // legacy
-			if _, err = io.Copy(f, rc); err != nil {
+			if _, err = io.Copy(io.MultiWriter(f, h), rc); err != nil {
 				return err
 			}
+			files[strings.TrimPrefix(fileName, "data/")] = integrity.EncodeHash(h)
 		}
 		return nil
 	}
@@ -126,7 +169,7 @@ func unzip(log *logger.Logger, archivePath string) (string, error) {
 	return hash, nil
 }
 
-func untar(log *logger.Logger, version string, archivePath string) (string, error) {
+func untar(log *logger.Logger, version string, archivePath string, files map[string]string) (string, error) {
 	r, err := os.Open(archivePath)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("artifact for 'elastic-agent' version '%s' could not be found at '%s'", version, archivePath), errors.TypeFilesystem, errors.M(errors.MetaKeyPath, archivePath))
@@ -203,14 +246,16 @@ func untar(log *logger.Logger, version string, archivePath string) (string, erro
 				return "", errors.New(err, "TarInstaller: creating file "+abs, errors.TypeFilesystem, errors.M(errors.MetaKeyPath, abs))
 			}
 
+			h := integrity.NewHash()
 			//nolint:gosec // legacy
-			_, err = io.Copy(wf, tr)
+			_, err = io.Copy(io.MultiWriter(wf, h), tr)
 			if closeErr := wf.Close(); closeErr != nil && err == nil {
 				err = closeErr
 			}
 			if err != nil {
 				return "", fmt.Errorf("TarInstaller: error writing to %s: %w", abs, err)
 			}
+			files[filepath.ToSlash(rel)] = integrity.EncodeHash(h)
 		case mode.IsDir():
 			log.Debugw("Unpacking directory", "archive", "tar", "file.path", abs)
 			// remove any world permissions from the directory
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upgrade
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/integrity"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestUntarHashesFiles(t *testing.T) {
+	topPath := paths.Top()
+	t.Cleanup(func() { paths.SetTop(topPath) })
+	paths.SetTop(t.TempDir())
+
+	const prefix = "elastic-agent-8.12.0-linux-x86_64/"
+	entries := []struct {
+		name    string
+		content string
+	}{
+		{name: prefix},
+		{name: prefix + agentCommitFile, content: "abc123def456"},
+		{name: prefix + "elastic-agent.yml", content: "outputs: {}"},
+		{name: prefix + "data/"},
+		{name: prefix + "data/elastic-agent-abc123/"},
+		{name: prefix + "data/elastic-agent-abc123/elastic-agent", content: "agent"},
+		{name: prefix + "data/elastic-agent-abc123/components/"},
+		{name: prefix + "data/elastic-agent-abc123/components/filebeat", content: "filebeat"},
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "elastic-agent-8.12.0-linux-x86_64.tar.gz")
+	f, err := os.Create(archivePath)
+	require.NoError(t, err)
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0750, Typeflag: tar.TypeDir}
+		if e.name[len(e.name)-1] != '/' {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Mode = 0640
+			hdr.Size = int64(len(e.content))
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	log, err := logger.New("test", false)
+	require.NoError(t, err)
+	files := map[string]string{}
+	hash, err := untar(log, "8.12.0", archivePath, files)
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", hash)
+
+	// only the unpacked files are hashed, with the hash of their content on disk
+	require.Len(t, files, 2)
+	for _, name := range []string{"elastic-agent-abc123/elastic-agent", "elastic-agent-abc123/components/filebeat"} {
+		expected, err := integrity.HashFile(filepath.Join(paths.Data(), filepath.FromSlash(name)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, files[name], name)
+	}
+}
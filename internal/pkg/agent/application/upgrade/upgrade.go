@@ -168,7 +168,7 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string
 
 	det.SetState(details.StateExtracting)
 
-	newHash, err := u.unpack(version, archivePath)
+	newHash, files, err := u.unpack(version, archivePath)
 	if err != nil {
 		return nil, err
 	}
@@ -182,6 +182,12 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string
 		return nil, nil
 	}
 
+	if err := u.writeIntegrityManifest(ctx, version, newHash, archivePath, !skipVerifyOverride, files); err != nil {
+		// the new version couldn't be verified
+		u.log.Errorw("Failed to write the integrity manifest", "error.message", err, "version", version)
+		return nil, errors.New(err, "failed to write the integrity manifest")
+	}
+
 	if err := copyActionStore(u.log, newHash); err != nil {
 		return nil, errors.New(err, "failed to copy action store")
 	}
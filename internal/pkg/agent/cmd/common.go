@@ -78,6 +78,8 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(newStatusCommand(args, streams))
 	cmd.AddCommand(newDiagnosticsCommand(args, streams))
 	cmd.AddCommand(newComponentCommandWithArgs(args, streams))
+	cmd.AddCommand(newVaultCommandWithArgs(args, streams))
+	cmd.AddCommand(newVerifyCommandWithArgs(args, streams))
 	cmd.AddCommand(newLogsCommandWithArgs(args, streams))
 
 	// windows special hidden sub-command (only added on Windows)
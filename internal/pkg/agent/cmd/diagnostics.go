@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -35,6 +36,9 @@ func newDiagnosticsCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 
 	cmd.Flags().StringP("file", "f", "", "name of the output diagnostics zip archive")
 	cmd.Flags().BoolP("cpu-profile", "p", false, "wait to collect a CPU profile")
+	cmd.Flags().String("redact-profile", diagnostics.RedactionProfileDefault, fmt.Sprintf("redaction profile (%s) or path of a redaction policy file", strings.Join(diagnostics.RedactionProfiles(), ", ")))
+
+	cmd.AddCommand(newDiagnosticsAnalyzeCommand(streams))
 
 	return cmd
 }
@@ -46,6 +50,16 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		filepath = "elastic-agent-diagnostics-" + ts.Format("2006-01-02T15-04-05Z07-00") + ".zip" // RFC3339 format that replaces : with -, so it will work on Windows
 	}
 
+	redactProfile, _ := cmd.Flags().GetString("redact-profile")
+	policy, err := diagnostics.LoadRedactionPolicy(redactProfile)
+	if err != nil {
+		return err
+	}
+	redactor, err := diagnostics.NewRedactor(policy)
+	if err != nil {
+		return fmt.Errorf("invalid redaction policy %q: %w", redactProfile, err)
+	}
+
 	ctx := handleSignal(context.Background())
 
 	// 1st create the file to store the diagnostics, if it fails, anything else
@@ -62,11 +76,13 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		return fmt.Errorf("failed collecting diagnostics: %w", err)
 	}
 
-	if err := diagnostics.ZipArchive(streams.Err, f, agentDiag, unitDiags, compDiags); err != nil {
+	if err := diagnostics.ZipArchive(streams.Err, f, redactor, agentDiag, unitDiags, compDiags); err != nil {
 		return fmt.Errorf("unable to create archive %q: %w", filepath, err)
 	}
+	report := redactor.Report()
 	fmt.Fprintf(streams.Out, "Created diagnostics archive %q\n", filepath)
-	fmt.Fprintln(streams.Out, "***** WARNING *****\nCreated archive may contain plain text credentials.\nEnsure that files in archive are redacted before sharing.\n*******************")
+	fmt.Fprintf(streams.Out, "Redacted %d values in %d files with the %q policy, see %s in the archive.\n", report.Total(), len(report.Files), redactProfile, diagnostics.RedactionReportFilename)
+	fmt.Fprintln(streams.Out, "***** WARNING *****\nThe redaction is pattern based and may miss credentials.\nEnsure that files in archive are redacted before sharing.\n*******************")
 	return nil
 }
 
@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/analyzer"
+)
+
+func newDiagnosticsAnalyzeCommand(streams *cli.IOStreams) *cobra.Command {
+	defaults := analyzer.DefaultOptions()
+	cmd := &cobra.Command{
+		Use:   "analyze <bundle.zip>",
+		Short: "Find the known issues in a diagnostics archive",
+		Long: `This command analyzes a diagnostics archive offline and reports the known issues it finds,
+like crash-looping components, units stuck in CONFIGURING or outputs rejecting their credentials.`,
+		Args: func(c *cobra.Command, args []string) error {
+			if list, _ := c.Flags().GetBool("list-rules"); list {
+				return cobra.NoArgs(c, args)
+			}
+			return cobra.ExactArgs(1)(c, args)
+		},
+		Run: func(c *cobra.Command, args []string) {
+			if err := diagnosticsAnalyzeCmd(streams, c, args); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String("output", "human", "Output the findings in either 'human' or 'json'")
+	cmd.Flags().StringSlice("rules", nil, "Run only the rules with these names, all the rules by default")
+	cmd.Flags().Bool("list-rules", false, "List the rules and exit")
+	cmd.Flags().Int("restarts", defaults.Restarts, "Number of exits of a component in the logs reported as a crash loop")
+	cmd.Flags().Duration("configuring", defaults.Configuring, "Time in the CONFIGURING state after which a unit is reported as stuck")
+	cmd.Flags().Int("goroutines", defaults.Goroutines, "Number of goroutines of a process reported as a goroutine leak")
+	cmd.Flags().Int64("heap-inuse", defaults.HeapInUse, "Bytes of in-use heap of a process reported by the heap rule")
+
+	return cmd
+}
+
+func diagnosticsAnalyzeCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error {
+	opts := analyzer.DefaultOptions()
+	opts.Restarts, _ = cmd.Flags().GetInt("restarts")
+	opts.Configuring, _ = cmd.Flags().GetDuration("configuring")
+	opts.Goroutines, _ = cmd.Flags().GetInt("goroutines")
+	opts.HeapInUse, _ = cmd.Flags().GetInt64("heap-inuse")
+	rules := analyzer.DefaultRules(opts)
+
+	if list, _ := cmd.Flags().GetBool("list-rules"); list {
+		return listAnalyzerRules(streams.Out, rules)
+	}
+
+	names, _ := cmd.Flags().GetStringSlice("rules")
+	rules, err := analyzer.SelectRules(rules, names)
+	if err != nil {
+		return err
+	}
+	output, _ := cmd.Flags().GetString("output")
+	if output != "human" && output != "json" {
+		return fmt.Errorf("unsupported output: %s", output)
+	}
+
+	bundle, closer, err := analyzer.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	report := analyzer.Analyze(bundle, rules)
+	if output == "json" {
+		enc := json.NewEncoder(streams.Out)
+		enc.SetIndent("", "    ")
+		return enc.Encode(report)
+	}
+	return report.WriteHuman(streams.Out)
+}
+
+func listAnalyzerRules(w io.Writer, rules []analyzer.Rule) error {
+	tw := tabwriter.NewWriter(w, 4, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tFINDS")
+	for _, r := range rules {
+		fmt.Fprintf(tw, "%s\t%s\n", r.Name(), r.Description())
+	}
+	return tw.Flush()
+}
@@ -7,6 +7,7 @@ package cmd
 import (
 	// include the composable providers
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/agent"
+	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/cloud"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/docker"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/env"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/host"
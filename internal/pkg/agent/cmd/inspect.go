@@ -162,6 +162,13 @@ func inspectConfig(ctx context.Context, cfgPath string, opts inspectConfigOpts,
 		if err != nil {
 			return fmt.Errorf("failed to detect inputs and outputs: %w", err)
 		}
+		caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
+		if err != nil {
+			return err
+		}
+		if max, ok := caps.MaxIsolationGroups(); ok {
+			specs.LimitIsolationGroups(max)
+		}
 
 		monitorFn, err := getMonitoringFn(ctx, cfg)
 		if err != nil {
@@ -229,7 +236,7 @@ type inspectComponentsOpts struct {
 
 // returns true if the given Capabilities config blocks the given component.
 func blockedByCaps(c component.Component, caps capabilities.Capabilities) bool {
-	return !caps.AllowInput(c.InputType) || !caps.AllowOutput(c.OutputType)
+	return !caps.AllowInput(c.InputType) || capabilities.BlockedOutputType(caps, c.OutputTypes()) != ""
 }
 
 func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponentsOpts, streams *cli.IOStreams) error {
@@ -264,6 +271,15 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 		return fmt.Errorf("could not load agent info: %w", err)
 	}
 
+	// The capabilities limit the isolation groups and block components.
+	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
+	if err != nil {
+		return err
+	}
+	if max, ok := caps.MaxIsolationGroups(); ok {
+		specs.LimitIsolationGroups(max)
+	}
+
 	// Compute the components from the computed configuration.
 	comps, err := specs.ToComponents(m, monitorFn, lvl, agentInfo)
 	if err != nil {
@@ -313,10 +329,6 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 	}
 
 	// Separate any components that are blocked by capabilities config
-	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
-	if err != nil {
-		return err
-	}
 	allowed := []component.Component{}
 	blocked := []component.Component{}
 	for _, c := range comps {
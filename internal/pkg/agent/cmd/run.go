@@ -179,6 +179,12 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 		return fmt.Errorf("failed to read/write secrets: %w", err)
 	}
 
+	// Complete or roll back an interrupted key rotation before the encrypted stores are read.
+	err = storage.RecoverKeyRotation(ctx, storage.EncryptedStores())
+	if err != nil {
+		return fmt.Errorf("failed to recover the agent keys rotation: %w", err)
+	}
+
 	// Migrate .yml files if the corresponding .enc does not exist
 
 	// the encrypted config does not exist but the unencrypted file does
@@ -240,7 +246,7 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 		l.Info("APM instrumentation disabled")
 	}
 
-	coord, configMgr, composable, err := application.New(ctx, l, baseLogger, logLvl, agentInfo, rex, tracer, testingMode, fleetInitTimeout, configuration.IsFleetServerBootstrap(cfg.Fleet), modifiers...)
+	coord, configMgr, composable, capturer, err := application.New(ctx, l, baseLogger, logLvl, agentInfo, rex, tracer, testingMode, fleetInitTimeout, configuration.IsFleetServerBootstrap(cfg.Fleet), modifiers...)
 	if err != nil {
 		return err
 	}
@@ -259,6 +265,7 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 
 	diagHooks := diagnostics.GlobalHooks()
 	diagHooks = append(diagHooks, coord.DiagnosticHooks()...)
+	diagHooks = append(diagHooks, vaultDiagnosticsHook())
 	control := server.New(l.Named("control"), agentInfo, coord, tracer, diagHooks, cfg.Settings.GRPC)
 
 	// if the configMgr implements the TestModeConfigSetter in means that Elastic Agent is in testing mode and
@@ -275,6 +282,32 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 	}
 	defer control.Stop()
 
+	if cfg.Settings.REST != nil && cfg.Settings.REST.Enabled {
+		if configuration.IsStandalone(cfg.Fleet) {
+			address := cfg.Settings.REST.Address
+			if address == "" {
+				address = configuration.RESTUnixPrefix + filepath.Join(paths.Run(), "rest.sock")
+			}
+			rest := server.NewREST(l.Named("rest"), control, address, cfg.Settings.REST.Token)
+			if err := rest.Start(); err != nil {
+				return err
+			}
+			defer rest.Stop()
+		} else {
+			l.Warn("The REST API is only available to standalone agents, ignoring agent.rest.enabled")
+		}
+	}
+
+	if cfg.Settings.Vault != nil {
+		go rotateKeysPeriodically(ctx, l.Named("vault"), cfg.Settings.Vault.Rotation.Interval)
+	}
+	if cfg.Settings.Integrity != nil {
+		go verifyIntegrityPeriodically(ctx, l.Named("integrity"), cfg.Settings.Integrity.Interval, coord.SetIntegrityError)
+	}
+	if capturer != nil {
+		go capturer.Run(ctx)
+	}
+
 	appDone := make(chan bool)
 	appErr := make(chan error)
 	// Spawn the main Coordinator goroutine
@@ -13,6 +13,8 @@ import (
 	"sort"
 	"time"
 
+	"github.com/docker/go-units"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -114,6 +116,9 @@ func listComponentState(l list.Writer, components []client.ComponentState, all b
 		l.AppendItem(c.ID)
 		l.Indent()
 		l.AppendItem(formatStatus(c.State, c.Message))
+		if all {
+			listComponentResources(l, c.Resources)
+		}
 		l.UnIndent()
 		for _, u := range c.Units {
 			if !all && (u.State == client.Healthy) {
@@ -134,6 +139,21 @@ func listComponentState(l list.Writer, components []client.ComponentState, all b
 	}
 }
 
+func listComponentResources(l list.Writer, r *client.ComponentResources) {
+	if r == nil {
+		return
+	}
+
+	l.AppendItem("resources")
+	l.Indent()
+	l.AppendItem(fmt.Sprintf("pid: %d", r.PID))
+	l.AppendItem(fmt.Sprintf("cpu: %.1f%%", r.CPU))
+	l.AppendItem("rss: " + units.BytesSize(float64(r.RSS)))
+	l.AppendItem(fmt.Sprintf("open_fds: %d", r.OpenFDs))
+	l.AppendItem("sampled_at: " + r.SampledAt.Format(time.RFC3339))
+	l.UnIndent()
+}
+
 func listAgentState(l list.Writer, state *client.AgentState, all bool) {
 	l.AppendItem("elastic-agent")
 	l.Indent()
@@ -197,6 +217,40 @@ func listFleetState(l list.Writer, state *client.AgentState, all bool) {
 	l.AppendItem("fleet")
 	l.Indent()
 	l.AppendItem(formatStatus(state.FleetState, state.FleetMessage))
+	if all {
+		listFleetHosts(l, state.FleetHosts)
+	}
+	l.UnIndent()
+}
+
+func listFleetHosts(l list.Writer, hosts []*cproto.FleetHost) {
+	if len(hosts) == 0 {
+		return
+	}
+
+	l.AppendItem("hosts")
+	l.Indent()
+	for _, h := range hosts {
+		if h.Selected {
+			l.AppendItem(h.Host + " (selected)")
+		} else {
+			l.AppendItem(h.Host)
+		}
+		l.Indent()
+		l.AppendItem("circuit: " + h.Circuit)
+		l.AppendItem(fmt.Sprintf("consecutive_failures: %d", h.ConsecutiveFailures))
+		if h.Latency > 0 {
+			l.AppendItem("latency: " + time.Duration(h.Latency).String())
+		}
+		if h.LastUsed != "" {
+			l.AppendItem("last_used: " + h.LastUsed)
+		}
+		if h.LastError != "" {
+			l.AppendItem("last_error: " + h.LastError)
+			l.AppendItem("last_error_at: " + h.LastErrorAt)
+		}
+		l.UnIndent()
+	}
 	l.UnIndent()
 }
 
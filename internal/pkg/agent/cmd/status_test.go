@@ -254,6 +254,62 @@ func TestListUpgradeDetails(t *testing.T) {
 	}
 }
 
+func TestListFleetHosts(t *testing.T) {
+	l := list.NewWriter()
+	l.SetStyle(list.StyleConnectedLight)
+
+	listFleetHosts(l, []*cproto.FleetHost{
+		{
+			Host:                "https://fleet-1:8220/",
+			Circuit:             "open",
+			ConsecutiveFailures: 3,
+			LastUsed:            "2023-12-01T10:00:00Z",
+			LastError:           "connection refused",
+			LastErrorAt:         "2023-12-01T10:00:00Z",
+		},
+		{
+			Host:     "https://fleet-2:8220/",
+			Priority: 1,
+			Selected: true,
+			Circuit:  "closed",
+			Latency:  int64(25 * time.Millisecond),
+			LastUsed: "2023-12-01T10:01:00Z",
+		},
+	})
+	require.Equal(t, `── hosts
+   ├─ https://fleet-1:8220/
+   │  ├─ circuit: open
+   │  ├─ consecutive_failures: 3
+   │  ├─ last_used: 2023-12-01T10:00:00Z
+   │  ├─ last_error: connection refused
+   │  └─ last_error_at: 2023-12-01T10:00:00Z
+   └─ https://fleet-2:8220/ (selected)
+      ├─ circuit: closed
+      ├─ consecutive_failures: 0
+      ├─ latency: 25ms
+      └─ last_used: 2023-12-01T10:01:00Z`, l.Render())
+}
+
+func TestListComponentResources(t *testing.T) {
+	l := list.NewWriter()
+	l.SetStyle(list.StyleConnectedLight)
+
+	listComponentResources(l, nil)
+	listComponentResources(l, &client.ComponentResources{
+		PID:       4242,
+		RSS:       150 * 1024 * 1024,
+		CPU:       12.345,
+		OpenFDs:   42,
+		SampledAt: time.Date(2023, 12, 1, 10, 0, 0, 0, time.UTC),
+	})
+	require.Equal(t, `── resources
+   ├─ pid: 4242
+   ├─ cpu: 12.3%
+   ├─ rss: 150MiB
+   ├─ open_fds: 42
+   └─ sampled_at: 2023-12-01T10:00:00Z`, l.Render())
+}
+
 func TestHumanDurationUntil(t *testing.T) {
 	now := time.Now()
 	cases := map[string]struct {
@@ -29,6 +29,7 @@ const (
 	flagPGPBytes       = "pgp"
 	flagPGPBytesPath   = "pgp-path"
 	flagPGPBytesURI    = "pgp-uri"
+	flagUninstallToken = "uninstall-token"
 )
 
 func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
@@ -51,6 +52,7 @@ func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comman
 	cmd.Flags().String(flagPGPBytes, "", "PGP to use for package verification")
 	cmd.Flags().String(flagPGPBytesURI, "", "Path to a web location containing PGP to use for package verification")
 	cmd.Flags().String(flagPGPBytesPath, "", "Path to a file containing PGP to use for package verification")
+	cmd.Flags().String(flagUninstallToken, "", "Uninstall token required to change the package verification or source of a protected agent")
 
 	return cmd
 }
@@ -105,7 +107,8 @@ func upgradeCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error
 		}
 	}
 	skipDefaultPgp, _ := cmd.Flags().GetBool(flagSkipDefaultPgp)
-	version, err = c.Upgrade(context.Background(), version, sourceURI, skipVerification, skipDefaultPgp, pgpChecks...)
+	uninstallToken, _ := cmd.Flags().GetString(flagUninstallToken)
+	version, err = c.Upgrade(context.Background(), version, sourceURI, skipVerification, skipDefaultPgp, uninstallToken, pgpChecks...)
 	if err != nil {
 		return errors.New(err, "Failed trigger upgrade of daemon")
 	}
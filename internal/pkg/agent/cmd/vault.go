@@ -0,0 +1,167 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// vaultRotationRetryInterval is the maximum delay before retrying a failed automatic key rotation.
+const vaultRotationRetryInterval = time.Hour
+
+func newVaultCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Manage the Elastic Agent vault",
+		Long:  "This command manages the vault holding the keys encrypting the Elastic Agent data at rest.",
+	}
+
+	cmd.AddCommand(newVaultRotateCommand(streams))
+
+	return cmd
+}
+
+func newVaultRotateCommand(streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the Elastic Agent encryption keys",
+		Long: `This command replaces the key encrypting the Elastic Agent stores and the vault seed, re-encrypting all the data with the new keys.
+The Elastic Agent must be stopped, use the agent.vault.rotation.interval setting to rotate the keys while it runs.`,
+		Args: cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			if err := vaultRotateCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func vaultRotateCmd(streams *cli.IOStreams) error {
+	isAdmin, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("unable to perform vault rotate command while checking for %s rights: %w", utils.PermissionUser, err)
+	}
+	if !isAdmin {
+		return fmt.Errorf("unable to perform vault rotate command, not executed with %s permissions", utils.PermissionUser)
+	}
+
+	// The running agent holds the keys in memory, hold its lock for the rotation.
+	locker := filelock.NewAppLocker(paths.Data(), paths.AgentLockFileName)
+	if err := locker.TryLock(); err != nil {
+		if errors.Is(err, filelock.ErrAppAlreadyRunning) {
+			return errors.New("Elastic Agent is running, stop it before rotating the keys")
+		}
+		return err
+	}
+	defer func() {
+		_ = locker.Unlock()
+	}()
+
+	ctx := handleSignal(context.Background())
+	if err := storage.RotateKey(ctx, storage.EncryptedStores()); err != nil {
+		return fmt.Errorf("failed to rotate the keys: %w", err)
+	}
+
+	fmt.Fprintln(streams.Out, "Elastic Agent keys rotated.")
+	return nil
+}
+
+// lastKeyRotation returns when the agent key was last rotated, or created
+// if it was never rotated.
+func lastKeyRotation(ctx context.Context) (time.Time, error) {
+	s, err := secret.GetAgentSecret(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if s.RotatedOn.IsZero() {
+		return s.CreatedOn, nil
+	}
+	return s.RotatedOn, nil
+}
+
+// rotateKeysPeriodically rotates the agent keys every interval, until ctx is done.
+func rotateKeysPeriodically(ctx context.Context, log *logger.Logger, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	retry := vaultRotationRetryInterval
+	if interval < retry {
+		retry = interval
+	}
+
+	var wait time.Duration
+	for {
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		last, err := lastKeyRotation(ctx)
+		switch {
+		case err != nil:
+			log.Errorw("Failed to read the last agent keys rotation", "error.message", err)
+			wait = retry
+		case time.Since(last) < interval:
+			wait = time.Until(last.Add(interval))
+		default:
+			if err := storage.RotateKey(ctx, storage.EncryptedStores()); err != nil {
+				log.Errorw("Failed to rotate the agent keys", "error.message", err)
+				wait = retry
+				break
+			}
+			log.Info("Agent keys rotated")
+			wait = interval
+		}
+	}
+}
+
+// vaultDiagnosticsHook reports when the agent keys were last rotated.
+func vaultDiagnosticsHook() diagnostics.Hook {
+	return diagnostics.Hook{
+		Name:        "vault",
+		Filename:    "vault.yaml",
+		Description: "encryption keys rotation information",
+		ContentType: "application/yaml",
+		Hook: func(ctx context.Context) []byte {
+			s, err := secret.GetAgentSecret(ctx)
+			if err != nil {
+				return []byte(fmt.Sprintf("error: %q", err))
+			}
+			info := struct {
+				KeyCreatedOn   time.Time  `yaml:"key_created_on"`
+				LastRotationOn *time.Time `yaml:"last_rotation_on"`
+			}{KeyCreatedOn: s.CreatedOn}
+			if !s.RotatedOn.IsZero() {
+				info.LastRotationOn = &s.RotatedOn
+			}
+			o, err := yaml.Marshal(info)
+			if err != nil {
+				return []byte(fmt.Sprintf("error: %q", err))
+			}
+			return o
+		},
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/integrity"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+func newVerifyCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the integrity of the installed Elastic Agent",
+		Long: `This command verifies that the files of the installed Elastic Agent version, its binary, component binaries and specifications, match the signed integrity manifest written when the version was installed or unpacked by an upgrade.
+Set agent.integrity.interval to verify them periodically while the Elastic Agent runs, it reports DEGRADED when they don't match.`,
+		Args: cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			if err := verifyCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func verifyCmd(streams *cli.IOStreams) error {
+	isAdmin, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("unable to perform verify command while checking for %s rights: %w", utils.PermissionUser, err)
+	}
+	if !isAdmin {
+		return fmt.Errorf("unable to perform verify command, not executed with %s permissions", utils.PermissionUser)
+	}
+
+	ctx := handleSignal(context.Background())
+	report, err := verifyIntegrity(ctx)
+	if errors.Is(err, integrity.ErrNoManifest) {
+		return fmt.Errorf("%s has no integrity manifest, it was installed or upgraded by a version not writing one", report.Home)
+	}
+	if err != nil {
+		return err
+	}
+
+	printIntegrityReport(streams.Out, report)
+	if err := report.Err(); err != nil {
+		return errors.New("integrity verification failed")
+	}
+	return nil
+}
+
+// verifyIntegrity verifies the files of the running version.
+func verifyIntegrity(ctx context.Context) (integrity.Report, error) {
+	home := paths.Home()
+	if _, err := integrity.ReadManifest(home); err != nil {
+		return integrity.Report{Home: home}, err
+	}
+	key, err := integrity.SigningKey(ctx, false)
+	if err != nil {
+		return integrity.Report{Home: home}, err
+	}
+	return integrity.Verify(home, key)
+}
+
+func printIntegrityReport(w io.Writer, report integrity.Report) {
+	for _, m := range report.Mismatches {
+		fmt.Fprintf(w, "%-9s %s\n", m.Kind, m.Path)
+	}
+	if len(report.Mismatches) > 0 {
+		fmt.Fprintf(w, "%d of the files of Elastic Agent %s (%s) don't match the integrity manifest.\n", len(report.Mismatches), report.Version, report.Commit)
+		return
+	}
+	fmt.Fprintf(w, "Verified %d files of Elastic Agent %s (%s) in %s.\n", report.Checked, report.Version, report.Commit, report.Home)
+}
+
+// verifyIntegrityPeriodically verifies the files of the running version every
+// interval and reports the result with setErr.
+func verifyIntegrityPeriodically(ctx context.Context, log *logger.Logger, interval time.Duration, setErr func(error)) {
+	if interval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		report, err := verifyIntegrity(ctx)
+		switch {
+		case errors.Is(err, integrity.ErrNoManifest):
+			log.Debugw("Integrity check skipped, the installed version has no manifest", "file.path", report.Home)
+			continue
+		case err != nil:
+			log.Errorw("Integrity check failed", "error.message", err)
+		default:
+			err = report.Err()
+			if err != nil {
+				log.Errorw("Installed files don't match the integrity manifest", "error.message", err, "mismatches", report.Mismatches)
+			}
+		}
+		setErr(err)
+	}
+}
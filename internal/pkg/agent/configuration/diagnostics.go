@@ -0,0 +1,183 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+// DiagnosticsConfig configures the diagnostics produced by the agent.
+type DiagnosticsConfig struct {
+	Capture *DiagnosticsCaptureConfig `yaml:"capture" config:"capture" json:"capture"`
+	// Redaction is the redaction policy of the bundles produced by the agent, for the Fleet
+	// actions and the captures, the default profile when nil.
+	Redaction *DiagnosticsRedactionConfig `yaml:"redaction" config:"redaction" json:"redaction"`
+}
+
+const (
+	// DiagnosticsRedactionProfileDefault is the redaction profile of the credentials.
+	DiagnosticsRedactionProfileDefault = "default"
+	// DiagnosticsRedactionProfileStrict is the redaction profile also redacting the hosts,
+	// users and addresses.
+	DiagnosticsRedactionProfileStrict = "strict"
+)
+
+// DiagnosticsRedactionProfiles returns the names of the built-in redaction profiles, their
+// rules are defined by the diagnostics package.
+func DiagnosticsRedactionProfiles() []string {
+	return []string{DiagnosticsRedactionProfileDefault, DiagnosticsRedactionProfileStrict}
+}
+
+// RedactionPolicy returns the redaction policy, nil for the default profile.
+func (c *DiagnosticsConfig) RedactionPolicy() *DiagnosticsRedactionConfig {
+	if c == nil {
+		return nil
+	}
+	return c.Redaction
+}
+
+// DiagnosticsRedactionConfig is the redaction policy of the diagnostics bundles, used as
+// diagnostics.RedactionPolicy. The rules are regular expressions added to the ones of the
+// profile.
+type DiagnosticsRedactionConfig struct {
+	// Profile is the built-in policy extended by the rules, the default profile when empty.
+	Profile string `yaml:"profile,omitempty" config:"profile" json:"profile,omitempty"`
+	// Keys redact the values of the matching keys of the YAML and JSON documents.
+	Keys []string `yaml:"keys,omitempty" config:"keys" json:"keys,omitempty"`
+	// Values redact their matches in the values of the documents and in the logs, only the
+	// "secret" group is redacted when the expression has one.
+	Values []string `yaml:"values,omitempty" config:"values" json:"values,omitempty"`
+	// Allow are the keys never redacted, even when they match Keys.
+	Allow []string `yaml:"allow,omitempty" config:"allow" json:"allow,omitempty"`
+}
+
+// Validate validates settings of configuration.
+func (c *DiagnosticsRedactionConfig) Validate() error {
+	switch c.Profile {
+	case "", DiagnosticsRedactionProfileDefault, DiagnosticsRedactionProfileStrict:
+	default:
+		return errors.New(fmt.Sprintf("unknown diagnostics redaction profile %q, expected one of %s",
+			c.Profile, strings.Join(DiagnosticsRedactionProfiles(), ", ")), errors.TypeConfig)
+	}
+	for _, rules := range [][]string{c.Keys, c.Values, c.Allow} {
+		for _, rule := range rules {
+			if _, err := regexp.Compile(rule); err != nil {
+				return errors.New(err, fmt.Sprintf("invalid diagnostics redaction rule %q", rule), errors.TypeConfig)
+			}
+		}
+	}
+	return nil
+}
+
+// DiagnosticsCaptureConfig configures the automatic capture of diagnostics bundles when the
+// triggers fire.
+type DiagnosticsCaptureConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Directory keeps the bundles, the diagnostics directory of the data path when empty.
+	Directory string `yaml:"directory" config:"directory" json:"directory"`
+	// MaxBundles is the number of bundles kept in the directory, the oldest are removed.
+	MaxBundles int `yaml:"max_bundles" config:"max_bundles" json:"max_bundles"`
+	// MaxAge removes the bundles older than it, they are kept regardless of their age when zero.
+	MaxAge time.Duration `yaml:"max_age" config:"max_age" json:"max_age"`
+	// Cooldown is the minimum interval between two captures, the triggers firing in between
+	// are ignored.
+	Cooldown time.Duration `yaml:"cooldown" config:"cooldown" json:"cooldown"`
+	// Upload uploads the bundles to Fleet, Fleet-managed agents only.
+	Upload   bool                       `yaml:"upload" config:"upload" json:"upload"`
+	Triggers DiagnosticsCaptureTriggers `yaml:"triggers" config:"triggers" json:"triggers"`
+}
+
+// DiagnosticsCaptureTriggers are the events capturing a diagnostics bundle.
+type DiagnosticsCaptureTriggers struct {
+	// ComponentFailed captures a bundle when a component enters the FAILED state.
+	ComponentFailed bool `yaml:"component_failed" config:"component_failed" json:"component_failed"`
+	// Restarts captures a bundle when a component restarts too often.
+	Restarts RestartsTrigger `yaml:"restarts" config:"restarts" json:"restarts"`
+	// Memory captures a bundle when the agent or a component uses too much memory.
+	Memory MemoryTrigger `yaml:"memory" config:"memory" json:"memory"`
+	// Schedule captures a bundle at the times of a cron-like schedule, in the local time zone
+	// of the host, like "0 3 * * *" for every day at 3am.
+	Schedule string `yaml:"schedule" config:"schedule" json:"schedule"`
+
+	schedule *cronSchedule
+}
+
+// RestartsTrigger fires when a component starts Count times within Period, disabled when
+// Count is zero.
+type RestartsTrigger struct {
+	Count  int           `yaml:"count" config:"count" json:"count"`
+	Period time.Duration `yaml:"period" config:"period" json:"period"`
+}
+
+// MemoryTrigger fires when the resident set size of the agent or of a component exceeds RSS
+// bytes, disabled when zero.
+type MemoryTrigger struct {
+	RSS uint64 `yaml:"rss" config:"rss" json:"rss"`
+}
+
+// DefaultDiagnosticsConfig creates a config with the capture disabled.
+func DefaultDiagnosticsConfig() *DiagnosticsConfig {
+	return &DiagnosticsConfig{
+		Capture: DefaultDiagnosticsCaptureConfig(),
+	}
+}
+
+// DefaultDiagnosticsCaptureConfig creates a disabled capture keeping 5 bundles, at most one
+// every 10 minutes, on failures and restarts of the components.
+func DefaultDiagnosticsCaptureConfig() *DiagnosticsCaptureConfig {
+	return &DiagnosticsCaptureConfig{
+		MaxBundles: 5,
+		Cooldown:   10 * time.Minute,
+		Triggers: DiagnosticsCaptureTriggers{
+			ComponentFailed: true,
+			Restarts: RestartsTrigger{
+				Count:  5,
+				Period: 10 * time.Minute,
+			},
+		},
+	}
+}
+
+// Validate validates settings of configuration.
+func (c *DiagnosticsCaptureConfig) Validate() error {
+	if c.MaxBundles <= 0 {
+		return errors.New("the diagnostics capture must keep at least one bundle", errors.TypeConfig)
+	}
+	if c.MaxAge < 0 || c.Cooldown < 0 {
+		return errors.New("the max_age and cooldown of the diagnostics capture can't be negative", errors.TypeConfig)
+	}
+	if c.Triggers.Restarts.Count < 0 || (c.Triggers.Restarts.Count > 0 && c.Triggers.Restarts.Period <= 0) {
+		return errors.New("the restarts trigger of the diagnostics capture requires a positive count and period", errors.TypeConfig)
+	}
+	c.Triggers.schedule = nil
+	if c.Triggers.Schedule != "" {
+		schedule, err := parseCronSchedule(c.Triggers.Schedule)
+		if err != nil {
+			return errors.New(err, "invalid diagnostics capture schedule", errors.TypeConfig)
+		}
+		c.Triggers.schedule = schedule
+	}
+	return nil
+}
+
+// NextScheduled returns the first time of the schedule after t, the zero time without schedule.
+func (t *DiagnosticsCaptureTriggers) NextScheduled(now time.Time) time.Time {
+	if t.schedule == nil {
+		if t.Schedule == "" {
+			return time.Time{}
+		}
+		schedule, err := parseCronSchedule(t.Schedule)
+		if err != nil {
+			return time.Time{}
+		}
+		t.schedule = schedule
+	}
+	return t.schedule.next(now, time.Local)
+}
@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestDiagnosticsCaptureConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name   string
+		modify func(*DiagnosticsCaptureConfig)
+		err    string
+	}{
+		{name: "default", modify: func(*DiagnosticsCaptureConfig) {}},
+		{name: "schedule", modify: func(c *DiagnosticsCaptureConfig) { c.Triggers.Schedule = "0 3 * * *" }},
+		{name: "restarts disabled", modify: func(c *DiagnosticsCaptureConfig) { c.Triggers.Restarts = RestartsTrigger{} }},
+		{name: "no bundles", modify: func(c *DiagnosticsCaptureConfig) { c.MaxBundles = 0 }, err: "at least one bundle"},
+		{name: "negative cooldown", modify: func(c *DiagnosticsCaptureConfig) { c.Cooldown = -time.Second }, err: "can't be negative"},
+		{name: "restarts without period", modify: func(c *DiagnosticsCaptureConfig) { c.Triggers.Restarts.Period = 0 }, err: "positive count and period"},
+		{name: "invalid schedule", modify: func(c *DiagnosticsCaptureConfig) { c.Triggers.Schedule = "0 3 * *" }, err: "must have 5 fields"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultDiagnosticsCaptureConfig()
+			tc.modify(cfg)
+			err := cfg.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsCaptureConfig(t *testing.T) {
+	c, err := config.NewConfigFrom(`
+agent.diagnostics.capture:
+  enabled: true
+  triggers:
+    memory.rss: 1073741824
+    schedule: "30 3 * * *"
+`)
+	require.NoError(t, err)
+	cfg, err := NewFromConfig(c)
+	require.NoError(t, err)
+
+	capture := cfg.Settings.Diagnostics.Capture
+	assert.True(t, capture.Enabled)
+	assert.Equal(t, 5, capture.MaxBundles)
+	assert.True(t, capture.Triggers.ComponentFailed)
+	assert.Equal(t, uint64(1073741824), capture.Triggers.Memory.RSS)
+
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2024, time.March, 2, 3, 30, 0, 0, time.Local), capture.Triggers.NextScheduled(now))
+	assert.True(t, (&DiagnosticsCaptureTriggers{}).NextScheduled(now).IsZero())
+}
+
+func TestDiagnosticsRedactionConfig(t *testing.T) {
+	c, err := config.NewConfigFrom(`
+agent.diagnostics.redaction:
+  profile: strict
+  keys: ['(?i)^tenant$']
+  values: ['acct-(?P<secret>[0-9]+)']
+`)
+	require.NoError(t, err)
+	cfg, err := NewFromConfig(c)
+	require.NoError(t, err)
+
+	redaction := cfg.Settings.Diagnostics.RedactionPolicy()
+	require.NotNil(t, redaction)
+	assert.Equal(t, "strict", redaction.Profile)
+	assert.Equal(t, []string{"(?i)^tenant$"}, redaction.Keys)
+	assert.Nil(t, DefaultDiagnosticsConfig().RedactionPolicy())
+	assert.Nil(t, (*DiagnosticsConfig)(nil).RedactionPolicy())
+
+	c, err = config.NewConfigFrom(`agent.diagnostics.redaction.values: ['(']`)
+	require.NoError(t, err)
+	_, err = NewFromConfig(c)
+	assert.ErrorContains(t, err, "invalid diagnostics redaction rule")
+
+	// a typo of the profile is rejected when the configuration is loaded
+	c, err = config.NewConfigFrom(`agent.diagnostics.redaction.profile: stict`)
+	require.NoError(t, err)
+	_, err = NewFromConfig(c)
+	assert.ErrorContains(t, err, `unknown diagnostics redaction profile "stict", expected one of default, strict`)
+}
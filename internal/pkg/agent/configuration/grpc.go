@@ -4,21 +4,100 @@
 
 package configuration
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
 
 // GRPCConfig is a configuration of GRPC server.
 type GRPCConfig struct {
 	Address    string `config:"address"`
 	Port       uint16 `config:"port"`
 	MaxMsgSize int    `config:"max_message_size"`
+
+	// Access configures the access control of the control protocol.
+	Access *ControlAccessConfig `config:"access"`
+
+	// Certificates configures the certificates of the connections of the
+	// components, to the agent and to the shipper.
+	Certificates *CertificatesConfig `config:"certificates"`
+}
+
+// CertificatesConfig configures the lifetime of the certificates of the connections of the
+// components, they are issued again before their expiry while the components run.
+type CertificatesConfig struct {
+	Lifetime time.Duration `config:"lifetime"`
+	// RenewBefore is how long before their expiry the certificates are issued again, a tenth
+	// of the lifetime when zero.
+	RenewBefore time.Duration `config:"renew_before"`
+}
+
+// Validate validates settings of configuration.
+func (c *CertificatesConfig) Validate() error {
+	if c.Lifetime <= 0 {
+		return errors.New("the lifetime of the certificates must be positive", errors.TypeConfig)
+	}
+	if c.RenewBefore < 0 || c.RenewBefore >= c.Lifetime {
+		return errors.New("the certificates must be renewed between their issuance and their expiry", errors.TypeConfig)
+	}
+	return nil
+}
+
+// Renewal returns how long before their expiry the certificates are issued again.
+func (c *CertificatesConfig) Renewal() time.Duration {
+	if c.RenewBefore > 0 {
+		return c.RenewBefore
+	}
+	return c.Lifetime / 10
+}
+
+// ControlAccessConfig configures the authorization of the control protocol RPCs
+// with the credentials of the process calling them, it's only supported on Linux.
+// Root and the user and group running the agent are granted all the RPCs, the
+// other users only the RPCs of their roles.
+type ControlAccessConfig struct {
+	Enabled bool `config:"enabled"`
+	// Roles maps the role names to the users and groups granted them.
+	Roles map[string]ControlRole `config:"roles"`
+}
+
+// ControlRole grants RPCs to users and groups, given by name or ID.
+type ControlRole struct {
+	Users  []string `config:"users"`
+	Groups []string `config:"groups"`
+	// Methods are the names of the granted RPCs, like State, and SetLogLevel for
+	// the log level endpoint of the REST API.
+	Methods []string `config:"methods"`
 }
 
 // DefaultGRPCConfig creates a default server configuration.
 func DefaultGRPCConfig() *GRPCConfig {
 	return &GRPCConfig{
-		Address:    "localhost",
-		Port:       6789,
-		MaxMsgSize: 1024 * 1024 * 100, // grpc default 4MB is unsufficient for diagnostics
+		Address:      "localhost",
+		Port:         6789,
+		MaxMsgSize:   1024 * 1024 * 100, // grpc default 4MB is unsufficient for diagnostics
+		Access:       DefaultControlAccessConfig(),
+		Certificates: DefaultCertificatesConfig(),
+	}
+}
+
+// DefaultCertificatesConfig creates a config with certificates valid for 90 days and
+// renewed 9 days before their expiry.
+func DefaultCertificatesConfig() *CertificatesConfig {
+	return &CertificatesConfig{
+		Lifetime: 90 * 24 * time.Hour,
+	}
+}
+
+// DefaultControlAccessConfig creates a config with the access control disabled
+// and a monitoring role, granting the read-only RPCs, without users.
+func DefaultControlAccessConfig() *ControlAccessConfig {
+	return &ControlAccessConfig{
+		Roles: map[string]ControlRole{
+			"monitoring": {Methods: []string{"Version", "State", "StateWatch", "Status"}},
+		},
 	}
 }
 
@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificatesConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		cfg     CertificatesConfig
+		renewal time.Duration
+		err     string
+	}{
+		{name: "default", cfg: *DefaultCertificatesConfig(), renewal: 9 * 24 * time.Hour},
+		{name: "renew before", cfg: CertificatesConfig{Lifetime: 24 * time.Hour, RenewBefore: 6 * time.Hour}, renewal: 6 * time.Hour},
+		{name: "no lifetime", cfg: CertificatesConfig{}, err: "must be positive"},
+		{name: "negative renew before", cfg: CertificatesConfig{Lifetime: time.Hour, RenewBefore: -time.Minute}, err: "between their issuance and their expiry"},
+		{name: "renew before issuance", cfg: CertificatesConfig{Lifetime: time.Hour, RenewBefore: time.Hour}, err: "between their issuance and their expiry"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.renewal, tc.cfg.Renewal())
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+// IntegrityConfig is the configuration of the integrity check of the installed files.
+type IntegrityConfig struct {
+	// Interval between integrity checks while the agent runs, 0 disables the periodic check.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+}
+
+// DefaultIntegrityConfig creates a config with the periodic integrity check disabled.
+func DefaultIntegrityConfig() *IntegrityConfig {
+	return &IntegrityConfig{}
+}
@@ -0,0 +1,252 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+// maxScheduleDays is how far ahead the start of a maintenance window is searched, it covers
+// the schedules of a given day of the week and of the month, like the 29th of February.
+const maxScheduleDays = 8 * 366
+
+// MaintenanceWindows are the windows in which the disruptive operations of the agent, the
+// upgrades from Fleet and the policy changes stopping components, are allowed. There are no
+// restrictions without windows.
+type MaintenanceWindows []*MaintenanceWindow
+
+// Contains returns true when t is in one of the windows, or when there are no windows.
+func (w MaintenanceWindows) Contains(t time.Time) bool {
+	if len(w) == 0 {
+		return true
+	}
+	for _, window := range w {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the start of the first window after t, the zero time when there are no windows.
+func (w MaintenanceWindows) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, window := range w {
+		start := window.Next(t)
+		if !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next
+}
+
+// MaintenanceWindow is a window starting at the times of a cron-like schedule and lasting for
+// a duration.
+type MaintenanceWindow struct {
+	// Schedule is the start of the window, in the minute, hour, day of the month, month and
+	// day of the week format of cron, like "0 2 * * 6" for every Saturday at 2am.
+	Schedule string        `yaml:"schedule" config:"schedule" json:"schedule"`
+	Duration time.Duration `yaml:"duration" config:"duration" json:"duration"`
+	// Timezone is the IANA time zone of the schedule, like Europe/Paris. The local time zone of
+	// the host is used when empty.
+	Timezone string `yaml:"timezone" config:"timezone" json:"timezone"`
+
+	schedule *cronSchedule
+	location *time.Location
+}
+
+// Validate validates settings of configuration.
+func (w *MaintenanceWindow) Validate() error {
+	w.schedule = nil
+	if err := w.parse(); err != nil {
+		return errors.New(err, "invalid maintenance window", errors.TypeConfig)
+	}
+	if w.Duration <= 0 {
+		return errors.New("the duration of a maintenance window must be positive", errors.TypeConfig)
+	}
+	if w.Next(time.Now()).IsZero() {
+		return errors.New(fmt.Sprintf("maintenance window schedule %q never starts", w.Schedule), errors.TypeConfig)
+	}
+	return nil
+}
+
+// Contains returns true when t is in the window.
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	if w.parse() != nil {
+		return false
+	}
+	start := w.schedule.next(t.Add(-w.Duration), w.location)
+	return !start.IsZero() && !start.After(t)
+}
+
+// Next returns the start of the first window after t, the zero time when the window never starts.
+func (w *MaintenanceWindow) Next(t time.Time) time.Time {
+	if w.parse() != nil {
+		return time.Time{}
+	}
+	return w.schedule.next(t, w.location)
+}
+
+func (w *MaintenanceWindow) parse() error {
+	if w.schedule != nil {
+		return nil
+	}
+	location := time.Local
+	if w.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(w.Timezone)
+		if err != nil {
+			return fmt.Errorf("unknown time zone %q: %w", w.Timezone, err)
+		}
+	}
+	schedule, err := parseCronSchedule(w.Schedule)
+	if err != nil {
+		return err
+	}
+	w.schedule = schedule
+	w.location = location
+	return nil
+}
+
+// cronSchedule is a parsed cron-like schedule, every field is a bitset of the allowed values.
+type cronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// anyDay and anyWeekday are true when the field is "*", as with cron a day matches either
+	// the day of the month or the day of the week when both are restricted.
+	anyDay     bool
+	anyWeekday bool
+}
+
+func parseCronSchedule(s string) (*cronSchedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields: minute, hour, day of the month, month and day of the week", s)
+	}
+	var schedule cronSchedule
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute of schedule %q: %w", s, err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour of schedule %q: %w", s, err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of the month of schedule %q: %w", s, err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month of schedule %q: %w", s, err)
+	}
+	// 7 is also Sunday
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of the week of schedule %q: %w", s, err)
+	}
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+	return &schedule, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges like 1-5 and steps like */15
+// or 0-30/10.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			rng = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		low, high := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(bounds[0])
+			high, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || low > high {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			low = value
+			if step == 1 {
+				high = value
+			}
+		}
+		if low < min || high > max {
+			return 0, fmt.Errorf("%q is out of the %d-%d range", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// next returns the first time of the schedule strictly after t, the zero time when there is
+// none in the next years.
+func (s *cronSchedule) next(t time.Time, location *time.Location) time.Time {
+	t = t.In(location)
+	year, month, day := t.Date()
+	for i := 0; i < maxScheduleDays; i++ {
+		date := time.Date(year, month, day+i, 0, 0, 0, 0, location)
+		if !s.matchDate(date) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if s.hours&(1<<hour) == 0 {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if s.minutes&(1<<minute) == 0 {
+					continue
+				}
+				start := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, location)
+				if start.After(t) {
+					return start
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) matchDate(date time.Time) bool {
+	if s.months&(1<<uint(date.Month())) == 0 {
+		return false
+	}
+	day := s.days&(1<<uint(date.Day())) != 0
+	weekday := s.weekdays&(1<<uint(date.Weekday())) != 0
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
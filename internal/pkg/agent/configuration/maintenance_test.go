@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	testcases := []struct {
+		name   string
+		window MaintenanceWindow
+		err    string
+	}{
+		{name: "weekly", window: MaintenanceWindow{Schedule: "0 2 * * 6", Duration: 4 * time.Hour, Timezone: "Europe/Paris"}},
+		{name: "lists, ranges and steps", window: MaintenanceWindow{Schedule: "*/15 1-5 1,15 * 1-5/2", Duration: time.Minute}},
+		{name: "sunday as 7", window: MaintenanceWindow{Schedule: "0 0 * * 7", Duration: time.Hour}},
+		{name: "missing field", window: MaintenanceWindow{Schedule: "0 2 * *", Duration: time.Hour}, err: "must have 5 fields"},
+		{name: "out of range", window: MaintenanceWindow{Schedule: "0 24 * * *", Duration: time.Hour}, err: "out of the 0-23 range"},
+		{name: "invalid step", window: MaintenanceWindow{Schedule: "*/0 * * * *", Duration: time.Hour}, err: "invalid step"},
+		{name: "unknown time zone", window: MaintenanceWindow{Schedule: "0 2 * * *", Duration: time.Hour, Timezone: "Mars/Olympus"}, err: "unknown time zone"},
+		{name: "no duration", window: MaintenanceWindow{Schedule: "0 2 * * *"}, err: "must be positive"},
+		{name: "never starts", window: MaintenanceWindow{Schedule: "0 2 31 2 *", Duration: time.Hour}, err: "never starts"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.window.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	windows := MaintenanceWindows{
+		// Saturdays from 2am to 6am in Paris
+		{Schedule: "0 2 * * 6", Duration: 4 * time.Hour, Timezone: "Europe/Paris"},
+		// the 1st of the month from 10pm to midnight in UTC
+		{Schedule: "0 22 1 * *", Duration: 2 * time.Hour, Timezone: "UTC"},
+	}
+	for _, w := range windows {
+		require.NoError(t, w.Validate())
+	}
+
+	// Friday 1st of March 2024
+	friday := time.Date(2024, time.March, 1, 12, 0, 0, 0, paris)
+	assert.False(t, windows.Contains(friday))
+	assert.Equal(t, time.Date(2024, time.March, 1, 22, 0, 0, 0, time.UTC), windows.Next(friday))
+	assert.True(t, windows.Contains(time.Date(2024, time.March, 1, 23, 59, 0, 0, time.UTC)))
+	assert.False(t, windows.Contains(time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)))
+
+	saturday := time.Date(2024, time.March, 2, 2, 0, 0, 0, paris)
+	assert.Equal(t, saturday, windows.Next(time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, windows.Contains(saturday))
+	assert.True(t, windows.Contains(saturday.Add(4*time.Hour-time.Second)))
+	assert.False(t, windows.Contains(saturday.Add(4*time.Hour)))
+	assert.Equal(t, saturday.AddDate(0, 0, 7), windows.Next(saturday))
+
+	// no windows, no restrictions
+	assert.True(t, MaintenanceWindows(nil).Contains(friday))
+	assert.True(t, MaintenanceWindows(nil).Next(friday).IsZero())
+}
+
+func TestMaintenanceWindowsConfig(t *testing.T) {
+	c, err := config.NewConfigFrom(`
+agent.maintenance_windows:
+  - schedule: "30 3 * * 0"
+    duration: 1h
+    timezone: America/New_York
+`)
+	require.NoError(t, err)
+	cfg, err := NewFromConfig(c)
+	require.NoError(t, err)
+	require.Len(t, cfg.Settings.MaintenanceWindows, 1)
+	assert.Equal(t, time.Hour, cfg.Settings.MaintenanceWindows[0].Duration)
+
+	c, err = config.NewConfigFrom(`
+agent.maintenance_windows:
+  - schedule: "30 3 * *"
+    duration: 1h
+`)
+	require.NoError(t, err)
+	_, err = NewFromConfig(c)
+	assert.ErrorContains(t, err, "must have 5 fields")
+}
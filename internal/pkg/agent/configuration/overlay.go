@@ -0,0 +1,31 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+// OverlayConfig is the configuration of the local overlay of a Fleet-managed agent, the inputs of
+// the inputs.d directory merged into the Fleet policy. The capabilities define which input types
+// and outputs they can use.
+type OverlayConfig struct {
+	Enabled bool          `yaml:"enabled" config:"enabled" json:"enabled"`
+	Period  time.Duration `yaml:"period" config:"period" json:"period"`
+}
+
+// Validate validates settings of configuration.
+func (o *OverlayConfig) Validate() error {
+	if o.Enabled && o.Period <= 0 {
+		return ErrInvalidPeriod
+	}
+	return nil
+}
+
+// DefaultOverlayConfig creates a config with the local overlay disabled.
+func DefaultOverlayConfig() *OverlayConfig {
+	return &OverlayConfig{
+		Enabled: false,
+		Period:  10 * time.Second,
+	}
+}
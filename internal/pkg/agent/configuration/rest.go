@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"net"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+// RESTUnixPrefix is the prefix of the REST API addresses of unix sockets.
+const RESTUnixPrefix = "unix://"
+
+// RESTConfig is the configuration of the local REST management API of standalone agents, an
+// HTTP/JSON mapping of the control protocol.
+type RESTConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Address is a unix socket, like unix:///run/elastic-agent/rest.sock, or a localhost TCP
+	// address, like localhost:6792. The rest.sock socket of the run directory is used when empty.
+	Address string `yaml:"address" config:"address" json:"address"`
+	// Token is the bearer token of the requests, required with a TCP address.
+	Token string `yaml:"token" config:"token" json:"-"`
+}
+
+// Validate validates settings of configuration.
+func (r *RESTConfig) Validate() error {
+	if !r.Enabled || r.Address == "" || strings.HasPrefix(r.Address, RESTUnixPrefix) {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(r.Address)
+	if err != nil {
+		return errors.New(err, "invalid REST API address", errors.TypeConfig)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return errors.New("the REST API can only listen on localhost", errors.TypeConfig)
+	}
+	if r.Token == "" {
+		return errors.New("the REST API requires a token with a TCP address", errors.TypeConfig)
+	}
+	return nil
+}
+
+// DefaultRESTConfig creates a config with the REST API disabled.
+func DefaultRESTConfig() *RESTConfig {
+	return &RESTConfig{}
+}
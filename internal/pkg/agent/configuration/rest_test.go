@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRESTConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name string
+		cfg  RESTConfig
+		err  string
+	}{
+		{name: "disabled", cfg: RESTConfig{Address: "0.0.0.0:6792"}},
+		{name: "default socket", cfg: RESTConfig{Enabled: true}},
+		{name: "unix socket without token", cfg: RESTConfig{Enabled: true, Address: "unix:///run/elastic-agent/rest.sock"}},
+		{name: "localhost with token", cfg: RESTConfig{Enabled: true, Address: "localhost:6792", Token: "secret"}},
+		{name: "loopback with token", cfg: RESTConfig{Enabled: true, Address: "[::1]:6792", Token: "secret"}},
+		{name: "localhost without token", cfg: RESTConfig{Enabled: true, Address: "127.0.0.1:6792"}, err: "requires a token"},
+		{name: "remote address", cfg: RESTConfig{Enabled: true, Address: "0.0.0.0:6792", Token: "secret"}, err: "only listen on localhost"},
+		{name: "invalid address", cfg: RESTConfig{Enabled: true, Address: "localhost", Token: "secret"}, err: "invalid REST API address"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
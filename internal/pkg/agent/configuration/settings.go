@@ -14,13 +14,20 @@ import (
 
 // SettingsConfig is an collection of agent settings configuration.
 type SettingsConfig struct {
-	ID               string                          `yaml:"id" config:"id" json:"id"`
-	DownloadConfig   *artifact.Config                `yaml:"download" config:"download" json:"download"`
-	ProcessConfig    *process.Config                 `yaml:"process" config:"process" json:"process"`
-	GRPC             *GRPCConfig                     `yaml:"grpc" config:"grpc" json:"grpc"`
-	MonitoringConfig *monitoringCfg.MonitoringConfig `yaml:"monitoring" config:"monitoring" json:"monitoring"`
-	LoggingConfig    *logger.Config                  `yaml:"logging,omitempty" config:"logging,omitempty" json:"logging,omitempty"`
-	Upgrade          *UpgradeConfig                  `yaml:"upgrade" config:"upgrade" json:"upgrade"`
+	ID                 string                          `yaml:"id" config:"id" json:"id"`
+	DownloadConfig     *artifact.Config                `yaml:"download" config:"download" json:"download"`
+	ProcessConfig      *process.Config                 `yaml:"process" config:"process" json:"process"`
+	GRPC               *GRPCConfig                     `yaml:"grpc" config:"grpc" json:"grpc"`
+	MonitoringConfig   *monitoringCfg.MonitoringConfig `yaml:"monitoring" config:"monitoring" json:"monitoring"`
+	LoggingConfig      *logger.Config                  `yaml:"logging,omitempty" config:"logging,omitempty" json:"logging,omitempty"`
+	Upgrade            *UpgradeConfig                  `yaml:"upgrade" config:"upgrade" json:"upgrade"`
+	Vault              *VaultConfig                    `yaml:"vault" config:"vault" json:"vault"`
+	Integrity          *IntegrityConfig                `yaml:"integrity" config:"integrity" json:"integrity"`
+	Overlay            *OverlayConfig                  `yaml:"overlay" config:"overlay" json:"overlay"`
+	REST               *RESTConfig                     `yaml:"rest" config:"rest" json:"rest"`
+	MaintenanceWindows MaintenanceWindows              `yaml:"maintenance_windows" config:"maintenance_windows" json:"maintenance_windows"`
+	Diagnostics        *DiagnosticsConfig              `yaml:"diagnostics" config:"diagnostics" json:"diagnostics"`
+	TamperProtection   *TamperProtectionConfig         `yaml:"tamper_protection" config:"tamper_protection" json:"tamper_protection"`
 
 	// standalone config
 	Reload              *ReloadConfig `config:"reload" yaml:"reload" json:"reload"`
@@ -37,6 +44,12 @@ func DefaultSettingsConfig() *SettingsConfig {
 		MonitoringConfig:    monitoringCfg.DefaultConfig(),
 		GRPC:                DefaultGRPCConfig(),
 		Upgrade:             DefaultUpgradeConfig(),
+		Vault:               DefaultVaultConfig(),
+		Integrity:           DefaultIntegrityConfig(),
+		Overlay:             DefaultOverlayConfig(),
+		REST:                DefaultRESTConfig(),
+		Diagnostics:         DefaultDiagnosticsConfig(),
+		TamperProtection:    DefaultTamperProtectionConfig(),
 		Reload:              DefaultReloadConfig(),
 		V1MonitoringEnabled: true,
 	}
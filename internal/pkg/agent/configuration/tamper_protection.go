@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+// TamperProtectionConfig is the local configuration of the agent protection, the
+// protection itself is configured by the policy.
+type TamperProtectionConfig struct {
+	// AllowUnsignedDisable accepts the policies disabling the protection without
+	// being signed when the agent knows no key to verify their signature.
+	AllowUnsignedDisable bool `yaml:"allow_unsigned_disable" config:"allow_unsigned_disable" json:"allow_unsigned_disable"`
+}
+
+// DefaultTamperProtectionConfig creates a config requiring signed policies to disable the protection.
+func DefaultTamperProtectionConfig() *TamperProtectionConfig {
+	return &TamperProtectionConfig{}
+}
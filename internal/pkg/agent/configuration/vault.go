@@ -0,0 +1,23 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+// VaultConfig is the configuration related to the vault holding the agent secrets.
+type VaultConfig struct {
+	Rotation VaultRotationConfig `yaml:"rotation" config:"rotation" json:"rotation"`
+}
+
+// VaultRotationConfig is the configuration of the automatic agent key rotation.
+type VaultRotationConfig struct {
+	// Interval between agent key rotations, 0 disables the automatic rotation.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+}
+
+// DefaultVaultConfig creates a config with the automatic key rotation disabled.
+func DefaultVaultConfig() *VaultConfig {
+	return &VaultConfig{}
+}
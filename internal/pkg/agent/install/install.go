@@ -5,11 +5,13 @@
 package install
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/jaypipes/ghw"
 	"github.com/kardianos/service"
@@ -17,8 +19,11 @@ import (
 	"github.com/schollz/progressbar/v3"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/integrity"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
@@ -139,6 +144,16 @@ func Install(cfgFile, topPath string, unprivileged bool, pt *progressbar.Progres
 	}
 	pt.Describe("Successfully copied files")
 
+	// sign the manifest of the installed files before they are used, so
+	// elastic-agent verify can detect tampering
+	pt.Describe("Writing integrity manifest")
+	err = writeIntegrityManifest(topPath, dir)
+	if err != nil {
+		pt.Describe("Failed to write integrity manifest")
+		return utils.FileOwner{}, errors.New(err, "failed to write the integrity manifest", errors.TypeFilesystem)
+	}
+	pt.Describe("Successfully wrote integrity manifest")
+
 	// place shell wrapper, if present on platform
 	if paths.ShellWrapperPath != "" {
 		pathDir := filepath.Dir(paths.ShellWrapperPath)
@@ -234,6 +249,34 @@ func Install(cfgFile, topPath string, unprivileged bool, pt *progressbar.Progres
 	return ownership, nil
 }
 
+// writeIntegrityManifest signs and writes the manifest of the files of the
+// versioned home installed from the package directory, with the key of the
+// vault of the installed agent.
+func writeIntegrityManifest(topPath, packageDir string) error {
+	home := paths.VersionedHome(topPath)
+	files, err := integrity.HashDir(home)
+	if err != nil {
+		return err
+	}
+	m := &integrity.Manifest{
+		Version:   release.Version(),
+		Commit:    release.ShortCommit(),
+		CreatedAt: time.Now().UTC(),
+		Archive: integrity.Archive{
+			Name: filepath.Base(packageDir),
+		},
+		Files: files,
+	}
+	key, err := integrity.SigningKey(context.Background(), true, secret.WithVaultPath(paths.AgentVaultPathFrom(topPath)))
+	if err != nil {
+		return err
+	}
+	if err := m.Sign(key); err != nil {
+		return err
+	}
+	return m.Write(home)
+}
+
 // StartService starts the installed service.
 //
 // This should only be called after Install is successful.
@@ -5,11 +5,21 @@
 package install
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/jaypipes/ghw"
 	"github.com/jaypipes/ghw/pkg/block"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/integrity"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
 )
 
 func TestHasAllSSDs(t *testing.T) {
@@ -57,3 +67,31 @@ func TestHasAllSSDs(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteIntegrityManifest(t *testing.T) {
+	if runtime.GOOS == darwin {
+		t.Skip("the vault is the system keychain on darwin")
+	}
+
+	topPath := t.TempDir()
+	home := paths.VersionedHome(topPath)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "components"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "elastic-agent"), []byte("agent"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "components", "filebeat"), []byte("filebeat"), 0o600))
+
+	require.NoError(t, writeIntegrityManifest(topPath, "/tmp/elastic-agent-8.12.0-linux-x86_64"))
+
+	m, err := integrity.ReadManifest(home)
+	require.NoError(t, err)
+	assert.Equal(t, release.ShortCommit(), m.Commit)
+	assert.Equal(t, "elastic-agent-8.12.0-linux-x86_64", m.Archive.Name)
+	assert.False(t, m.Archive.Verified, "the package isn't verified by the install")
+
+	// the manifest is signed with the key of the vault of the installed agent
+	key, err := integrity.SigningKey(context.Background(), false, secret.WithVaultPath(paths.AgentVaultPathFrom(topPath)))
+	require.NoError(t, err)
+	report, err := integrity.Verify(home, key)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	assert.NoError(t, report.Err())
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	aerrors "github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vars"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
@@ -34,6 +35,12 @@ import (
 
 // Uninstall uninstalls persistently Elastic Agent on the system.
 func Uninstall(cfgFile, topPath, uninstallToken string, pt *progressbar.ProgressBar) error {
+	// verify the uninstall token before stopping anything
+	if err := verifyUninstallToken(context.Background(), cfgFile, uninstallToken); err != nil {
+		pt.Describe("Uninstall token verification failed")
+		return fmt.Errorf("failed to verify the uninstall token: %w", err)
+	}
+
 	// uninstall the current service
 	// not creating the service, so no need to set the username and group to any value
 	svc, err := newService(topPath)
@@ -180,6 +187,59 @@ func containsString(str string, a []string, caseSensitive bool) bool {
 	return false
 }
 
+// verifyUninstallToken verifies uninstallToken when the agent is protected by
+// its policy, whether Endpoint is part of the policy or not. A failed
+// verification is recorded to be reported to Fleet by the running agent.
+// When the configuration can't be loaded, the token is verified with the
+// protection last persisted by the running agent, an agent that never
+// persisted it was never protected.
+func verifyUninstallToken(ctx context.Context, cfgFile string, uninstallToken string) error {
+	log, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
+	if err != nil {
+		return fmt.Errorf("error creating logger: %w", err)
+	}
+
+	cfg, err := operations.LoadFullAgentConfig(ctx, log, cfgFile, false)
+	if err != nil {
+		return verifyPersistedUninstallToken(uninstallToken, err)
+	}
+	if err := features.Apply(cfg); err != nil {
+		return fmt.Errorf("could not parse and apply feature flags config: %w", err)
+	}
+	if !features.TamperProtection() {
+		return nil
+	}
+
+	m, err := cfg.ToMapStr()
+	if err != nil {
+		return fmt.Errorf("could not create the map from the configuration: %w", err)
+	}
+	protectionCfg, err := protection.GetAgentProtectionConfig(m)
+	if errors.Is(err, protection.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read the agent protection configuration: %w", err)
+	}
+
+	return protection.VerifyOperation(protectionCfg, protection.OperationUninstall, uninstallToken,
+		protection.NewTamperLog(paths.TamperEventsFile()))
+}
+
+// verifyPersistedUninstallToken verifies uninstallToken with the persisted protection
+// when the configuration failed to load with cfgErr.
+func verifyPersistedUninstallToken(uninstallToken string, cfgErr error) error {
+	protectionCfg, err := protection.NewState(paths.ProtectionStateFile()).Load()
+	if errors.Is(err, protection.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not load the agent configuration to verify its protection: %w, nor its persisted protection: %w", cfgErr, err)
+	}
+	return protection.VerifyOperation(protectionCfg, protection.OperationUninstall, uninstallToken,
+		protection.NewTamperLog(paths.TamperEventsFile()))
+}
+
 func uninstallComponents(ctx context.Context, cfgFile string, uninstallToken string, pt *progressbar.ProgressBar) error {
 	log, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
 	if err != nil {
@@ -230,7 +290,7 @@ func uninstallComponents(ctx context.Context, cfgFile string, uninstallToken str
 
 	// remove each service component
 	for _, comp := range comps {
-		if !caps.AllowInput(comp.InputType) || !caps.AllowOutput(comp.OutputType) {
+		if !caps.AllowInput(comp.InputType) || capabilities.BlockedOutputType(caps, comp.OutputTypes()) != "" {
 			// This component is not active
 			continue
 		}
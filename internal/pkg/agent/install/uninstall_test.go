@@ -0,0 +1,43 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
+)
+
+func TestVerifyPersistedUninstallToken(t *testing.T) {
+	prevConfig := paths.Config()
+	paths.SetConfig(t.TempDir())
+	defer paths.SetConfig(prevConfig)
+
+	cfgErr := errors.New("invalid configuration")
+
+	// never protected
+	assert.NoError(t, verifyPersistedUninstallToken("", cfgErr))
+
+	state := protection.NewState(paths.ProtectionStateFile())
+	require.NoError(t, state.Save(protection.Config{
+		Enabled:            true,
+		UninstallTokenHash: protection.HashUninstallToken("secret"),
+	}))
+	assert.ErrorIs(t, verifyPersistedUninstallToken("", cfgErr), protection.ErrUninstallTokenRequired)
+	assert.ErrorIs(t, verifyPersistedUninstallToken("wrong", cfgErr), protection.ErrInvalidUninstallToken)
+	assert.NoError(t, verifyPersistedUninstallToken("secret", cfgErr))
+
+	events, err := protection.NewTamperLog(paths.TamperEventsFile()).Events()
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	require.NoError(t, state.Save(protection.Config{}))
+	assert.NoError(t, verifyPersistedUninstallToken("", cfgErr))
+}
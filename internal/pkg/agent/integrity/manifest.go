@@ -0,0 +1,204 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package integrity verifies that the files of an installed Elastic Agent
+// version weren't modified since they were unpacked from the verified package.
+package integrity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+)
+
+// ManifestFileName is the name of the manifest in the versioned home.
+const ManifestFileName = "integrity.json"
+
+var (
+	// ErrNoManifest is returned when the versioned home has no manifest, it
+	// wasn't installed or upgraded by a version writing one.
+	ErrNoManifest = errors.New("no integrity manifest")
+	// ErrInvalidSignature is returned when the manifest signature doesn't
+	// match its content.
+	ErrInvalidSignature = errors.New("invalid integrity manifest signature")
+)
+
+// Manifest lists the hashes of the files of a versioned home, it's signed
+// with a key stored in the agent vault.
+type Manifest struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	CreatedAt time.Time `json:"created_at"`
+	Archive   Archive   `json:"archive"`
+	// Files maps the path of the files, relative to the versioned home and
+	// slash separated, to their SHA-256.
+	Files     map[string]string `json:"files"`
+	Signature string            `json:"signature,omitempty"`
+}
+
+// Archive is the package the files were unpacked from.
+type Archive struct {
+	Name string `json:"name"`
+	// SHA512 is the checksum published with the package.
+	SHA512 string `json:"sha512,omitempty"`
+	// Verified is set when the package signature was verified before it was
+	// unpacked.
+	Verified bool `json:"verified"`
+}
+
+// NewHash returns the hash used for the files of the manifest.
+func NewHash() hash.Hash {
+	return sha256.New()
+}
+
+// EncodeHash returns the encoding of h used in the manifest.
+func EncodeHash(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashFile returns the hash of the file at path, as stored in the manifest.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := NewHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return EncodeHash(h), nil
+}
+
+// Sign signs the manifest with key.
+func (m *Manifest) Sign(key []byte) error {
+	sig, err := m.signature(key)
+	if err != nil {
+		return err
+	}
+	m.Signature = sig
+	return nil
+}
+
+// VerifySignature returns ErrInvalidSignature when the manifest isn't signed
+// with key.
+func (m *Manifest) VerifySignature(key []byte) error {
+	expected, err := m.signature(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(m.Signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (m *Manifest) signature(key []byte) (string, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode integrity manifest: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Write writes the manifest in the versioned home.
+func (m *Manifest) Write(home string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode integrity manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ManifestFileName), data, 0600); err != nil {
+		return fmt.Errorf("failed to write integrity manifest: %w", err)
+	}
+	return nil
+}
+
+// HashDir returns the hashes of the files of the versioned home, keyed by
+// their slash separated path relative to it. The files written at runtime are
+// left out.
+func HashDir(home string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(home, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(home, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if ignored(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		h, err := HashFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", rel, err)
+		}
+		files[rel] = h
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files of %s: %w", home, err)
+	}
+	return files, nil
+}
+
+// ReadManifest reads the manifest of the versioned home, it returns
+// ErrNoManifest when it has none.
+func ReadManifest(home string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(home, ManifestFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoManifest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read integrity manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode integrity manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// SigningKey returns the key signing the manifests, it's created when create
+// is set and the vault doesn't hold it yet.
+func SigningKey(ctx context.Context, create bool, opts ...secret.OptionFunc) ([]byte, error) {
+	if create {
+		if err := secret.CreateIntegritySecret(ctx, opts...); err != nil {
+			return nil, fmt.Errorf("failed to create the integrity key: %w", err)
+		}
+	}
+	s, err := secret.GetIntegritySecret(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the integrity key: %w", err)
+	}
+	return s.Value, nil
+}
@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package integrity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestSignature(t *testing.T) {
+	key := []byte("signing key")
+	m := &Manifest{
+		Version:   "8.12.0",
+		Commit:    "abc123",
+		CreatedAt: time.Date(2023, 12, 1, 10, 0, 0, 0, time.UTC),
+		Archive:   Archive{Name: "elastic-agent-8.12.0-linux-x86_64.tar.gz", SHA512: "ffff", Verified: true},
+		Files:     map[string]string{"elastic-agent": "0123"},
+	}
+	require.NoError(t, m.Sign(key))
+	assert.NotEmpty(t, m.Signature)
+	assert.NoError(t, m.VerifySignature(key))
+	assert.ErrorIs(t, m.VerifySignature([]byte("other key")), ErrInvalidSignature)
+
+	// the signature survives the round trip to the disk
+	home := t.TempDir()
+	require.NoError(t, m.Write(home))
+	read, err := ReadManifest(home)
+	require.NoError(t, err)
+	assert.Equal(t, m, read)
+	assert.NoError(t, read.VerifySignature(key))
+
+	// any change of the content invalidates it
+	read.Files["elastic-agent"] = "4567"
+	assert.ErrorIs(t, read.VerifySignature(key), ErrInvalidSignature)
+	read.Files["elastic-agent"] = "0123"
+	read.Archive.Verified = false
+	assert.ErrorIs(t, read.VerifySignature(key), ErrInvalidSignature)
+}
+
+func TestReadManifestMissing(t *testing.T) {
+	_, err := ReadManifest(t.TempDir())
+	assert.ErrorIs(t, err, ErrNoManifest)
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0600))
+
+	h, err := HashFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", h)
+}
+
+func TestHashDir(t *testing.T) {
+	home := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "components"), 0o750))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "logs"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "elastic-agent"), []byte("hello"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "components", "filebeat"), []byte("hello"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "logs", "elastic-agent.ndjson"), []byte("{}"), 0o600))
+
+	files, err := HashDir(home)
+	require.NoError(t, err)
+	hello := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	assert.Equal(t, map[string]string{
+		"elastic-agent":       hello,
+		"components/filebeat": hello,
+	}, files, "the files written at runtime are left out")
+
+	_, err = HashDir(filepath.Join(home, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package integrity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// maxReportedMismatches is the number of mismatches listed by Report.Err.
+const maxReportedMismatches = 5
+
+// MismatchKind is the kind of difference between a file and the manifest.
+type MismatchKind string
+
+const (
+	// Modified is a file whose hash differs from the manifest.
+	Modified MismatchKind = "modified"
+	// Missing is a file of the manifest that was removed.
+	Missing MismatchKind = "missing"
+	// Added is a file that isn't in the manifest.
+	Added MismatchKind = "added"
+)
+
+// Mismatch is a file that doesn't match the manifest.
+type Mismatch struct {
+	// Path is relative to the versioned home and slash separated.
+	Path string       `json:"path" yaml:"path"`
+	Kind MismatchKind `json:"kind" yaml:"kind"`
+}
+
+// Report is the result of the verification of a versioned home.
+type Report struct {
+	Home       string     `json:"home" yaml:"home"`
+	Version    string     `json:"version" yaml:"version"`
+	Commit     string     `json:"commit" yaml:"commit"`
+	Checked    int        `json:"checked" yaml:"checked"`
+	Mismatches []Mismatch `json:"mismatches,omitempty" yaml:"mismatches,omitempty"`
+}
+
+// Err returns an error listing the mismatches, nil when there are none.
+func (r Report) Err() error {
+	if len(r.Mismatches) == 0 {
+		return nil
+	}
+	files := make([]string, 0, maxReportedMismatches)
+	for i, m := range r.Mismatches {
+		if i == maxReportedMismatches {
+			files = append(files, fmt.Sprintf("and %d more", len(r.Mismatches)-i))
+			break
+		}
+		files = append(files, fmt.Sprintf("%s (%s)", m.Path, m.Kind))
+	}
+	return fmt.Errorf("%d files don't match the integrity manifest of version %s: %s",
+		len(r.Mismatches), r.Version, strings.Join(files, ", "))
+}
+
+// ignored returns true for the files of the versioned home written at
+// runtime, they aren't part of the package.
+func ignored(rel string) bool {
+	top, _, _ := strings.Cut(rel, "/")
+	switch top {
+	case "run", "logs", "downloads", ManifestFileName,
+		filepath.Base(paths.AgentActionStoreFile()),
+		filepath.Base(paths.AgentStateStoreYmlFile()),
+		filepath.Base(paths.AgentStateStoreFile()):
+		return true
+	}
+	return false
+}
+
+// Verify verifies the files of the versioned home against its manifest signed
+// with key. It returns an error when the manifest can't be trusted, the files
+// not matching it are listed in the report.
+func Verify(home string, key []byte) (Report, error) {
+	m, err := ReadManifest(home)
+	if err != nil {
+		return Report{Home: home}, err
+	}
+	report := Report{
+		Home:    home,
+		Version: m.Version,
+		Commit:  m.Commit,
+	}
+	if err := m.VerifySignature(key); err != nil {
+		return report, err
+	}
+
+	for rel, expected := range m.Files {
+		report.Checked++
+		actual, err := HashFile(filepath.Join(home, filepath.FromSlash(rel)))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			report.Mismatches = append(report.Mismatches, Mismatch{Path: rel, Kind: Missing})
+		case err != nil:
+			return report, fmt.Errorf("failed to hash %s: %w", rel, err)
+		case actual != expected:
+			report.Mismatches = append(report.Mismatches, Mismatch{Path: rel, Kind: Modified})
+		}
+	}
+
+	err = filepath.WalkDir(home, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(home, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if ignored(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, ok := m.Files[rel]; !ok {
+			report.Mismatches = append(report.Mismatches, Mismatch{Path: rel, Kind: Added})
+		}
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to list the files of %s: %w", home, err)
+	}
+
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Path < report.Mismatches[j].Path
+	})
+	return report, nil
+}
@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package integrity
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHome creates a versioned home with the given files and its manifest
+// signed with key.
+func newHome(t *testing.T, key []byte, files map[string]string) string {
+	home := t.TempDir()
+	m := &Manifest{Version: "8.12.0", Commit: "abc123", Files: map[string]string{}}
+	for name, content := range files {
+		path := filepath.Join(home, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		h, err := HashFile(path)
+		require.NoError(t, err)
+		m.Files[name] = h
+	}
+	require.NoError(t, m.Sign(key))
+	require.NoError(t, m.Write(home))
+	return home
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("signing key")
+	files := map[string]string{
+		"elastic-agent":                "agent",
+		"package.version":              "8.12.0",
+		"components/filebeat":          "filebeat",
+		"components/filebeat.spec.yml": "version: 2",
+	}
+	write := func(t *testing.T, home, name, content string) {
+		path := filepath.Join(home, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		home := newHome(t, key, files)
+		// the files written at runtime are ignored
+		write(t, home, "run/filebeat-default/state", "{}")
+		write(t, home, "state.enc", "encrypted")
+
+		report, err := Verify(home, key)
+		require.NoError(t, err)
+		assert.Equal(t, 4, report.Checked)
+		assert.Empty(t, report.Mismatches)
+		assert.NoError(t, report.Err())
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		home := newHome(t, key, files)
+		write(t, home, "components/filebeat", "backdoored filebeat")
+		write(t, home, "components/backdoor.spec.yml", "version: 2")
+		require.NoError(t, os.Remove(filepath.Join(home, "package.version")))
+
+		report, err := Verify(home, key)
+		require.NoError(t, err)
+		assert.Equal(t, []Mismatch{
+			{Path: "components/backdoor.spec.yml", Kind: Added},
+			{Path: "components/filebeat", Kind: Modified},
+			{Path: "package.version", Kind: Missing},
+		}, report.Mismatches)
+		assert.EqualError(t, report.Err(), "3 files don't match the integrity manifest of version 8.12.0: "+
+			"components/backdoor.spec.yml (added), components/filebeat (modified), package.version (missing)")
+	})
+
+	t.Run("many mismatches", func(t *testing.T) {
+		home := newHome(t, key, files)
+		for i := 0; i < 7; i++ {
+			write(t, home, fmt.Sprintf("components/added-%d", i), "")
+		}
+
+		report, err := Verify(home, key)
+		require.NoError(t, err)
+		assert.Len(t, report.Mismatches, 7)
+		assert.ErrorContains(t, report.Err(), "components/added-4 (added), and 2 more")
+	})
+
+	t.Run("forged manifest", func(t *testing.T) {
+		home := newHome(t, []byte("attacker key"), files)
+
+		_, err := Verify(home, key)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("no manifest", func(t *testing.T) {
+		_, err := Verify(t.TempDir(), key)
+		assert.ErrorIs(t, err, ErrNoManifest)
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package protection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// State persists the protection configuration applied by the running agent, so
+// the commands verifying the protected operations know whether the agent is
+// protected when its configuration can't be loaded.
+type State struct {
+	mx   sync.Mutex
+	path string
+}
+
+// NewState returns a State stored at path.
+func NewState(path string) *State {
+	return &State{path: path}
+}
+
+// Save persists c.
+func (s *State) Save(c Config) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode the protection state: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write the protection state: %w", err)
+	}
+	return nil
+}
+
+// Load returns the persisted protection configuration, ErrNotFound when the
+// running agent never persisted it.
+func (s *State) Load() (Config, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var c Config
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, ErrNotFound
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to read the protection state: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to decode the protection state: %w", err)
+	}
+	return c, nil
+}
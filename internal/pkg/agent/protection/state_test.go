@@ -0,0 +1,36 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package protection
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	s := NewState(filepath.Join(t.TempDir(), "protection.json"))
+
+	_, err := s.Load()
+	require.ErrorIs(t, err, ErrNotFound)
+
+	c := Config{
+		Enabled:                true,
+		SignatureValidationKey: []byte("key"),
+		UninstallTokenHash:     HashUninstallToken("secret"),
+	}
+	require.NoError(t, s.Save(c))
+	loaded, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, c, loaded)
+	assert.NoError(t, loaded.VerifyUninstallToken("secret"))
+
+	require.NoError(t, s.Save(Config{}))
+	loaded, err = s.Load()
+	require.NoError(t, err)
+	assert.False(t, loaded.Protected())
+}
@@ -0,0 +1,190 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package protection
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTamperEvents is the number of events kept by a TamperLog.
+const maxTamperEvents = 100
+
+// Operation is an operation protected by the uninstall token.
+type Operation string
+
+const (
+	// OperationUninstall is the uninstall of the agent.
+	OperationUninstall Operation = "uninstall"
+	// OperationUpgrade is an upgrade skipping the package verification.
+	OperationUpgrade Operation = "upgrade"
+	// OperationPolicyChange is a policy change disabling the protection
+	// without being signed by Fleet.
+	OperationPolicyChange Operation = "policy_change"
+)
+
+// TamperEvent is a protected operation that was blocked.
+type TamperEvent struct {
+	Time      time.Time `json:"time"`
+	Operation Operation `json:"operation"`
+	Reason    string    `json:"reason"`
+}
+
+// TamperLog records the tamper events on disk, so the ones blocked by the
+// command line, like uninstall, are reported by the running agent.
+//
+// The events are appended to the file as JSON lines, only the last
+// maxTamperEvents are kept. The time of the last reported event is stored
+// next to it, in a ".reported" file.
+type TamperLog struct {
+	mx   sync.Mutex
+	path string
+}
+
+// NewTamperLog returns a TamperLog stored at path.
+func NewTamperLog(path string) *TamperLog {
+	return &TamperLog{path: path}
+}
+
+// Record records that op was blocked because of reason.
+func (l *TamperLog) Record(op Operation, reason error) error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	events, err := l.read()
+	if err != nil {
+		return err
+	}
+	events = append(events, TamperEvent{
+		Time:      time.Now().UTC(),
+		Operation: op,
+		Reason:    reason.Error(),
+	})
+	if len(events) > maxTamperEvents {
+		events = events[len(events)-maxTamperEvents:]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode tamper event: %w", err)
+		}
+	}
+	if err := os.WriteFile(l.path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write tamper events: %w", err)
+	}
+	return nil
+}
+
+// Events returns the recorded events, oldest first.
+func (l *TamperLog) Events() ([]TamperEvent, error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return l.read()
+}
+
+// Unreported returns the events recorded after the last one marked as
+// reported, oldest first.
+func (l *TamperLog) Unreported() ([]TamperEvent, error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	events, err := l.read()
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+
+	var reported time.Time
+	data, err := os.ReadFile(l.reportedPath())
+	switch {
+	case err == nil:
+		reported, _ = time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read reported tamper events: %w", err)
+	}
+
+	i := sort.Search(len(events), func(i int) bool {
+		return events[i].Time.After(reported)
+	})
+	return events[i:], nil
+}
+
+// MarkReported marks the events up to the given one as reported.
+func (l *TamperLog) MarkReported(last TamperEvent) error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	err := os.WriteFile(l.reportedPath(), []byte(last.Time.Format(time.RFC3339Nano)), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write reported tamper events: %w", err)
+	}
+	return nil
+}
+
+func (l *TamperLog) reportedPath() string {
+	return l.path + ".reported"
+}
+
+func (l *TamperLog) read() ([]TamperEvent, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tamper events: %w", err)
+	}
+	defer f.Close()
+
+	var events []TamperEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e TamperEvent
+		// skip the lines that can't be decoded, a truncated write must not
+		// prevent recording new events.
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			events = append(events, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tamper events: %w", err)
+	}
+	return events, nil
+}
+
+// Summary returns a one line summary of events, as reported to Fleet:
+//
+//	tamper protection blocked 3 operations (uninstall: 2, upgrade: 1), last at 2023-12-01T10:00:00Z: invalid uninstall token
+func Summary(events []TamperEvent) string {
+	if len(events) == 0 {
+		return ""
+	}
+
+	counts := map[Operation]int{}
+	for _, e := range events {
+		counts[e.Operation]++
+	}
+	ops := make([]string, 0, len(counts))
+	for op, n := range counts {
+		ops = append(ops, fmt.Sprintf("%s: %d", op, n))
+	}
+	sort.Strings(ops)
+
+	noun := "operations"
+	if len(events) == 1 {
+		noun = "operation"
+	}
+	last := events[len(events)-1]
+	return fmt.Sprintf("tamper protection blocked %d %s (%s), last at %s: %s",
+		len(events), noun, strings.Join(ops, ", "), last.Time.Format(time.RFC3339), last.Reason)
+}
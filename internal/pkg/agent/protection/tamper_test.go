@@ -0,0 +1,118 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package protection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTamperLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tamper_events.json")
+	tamperLog := NewTamperLog(path)
+
+	events, err := tamperLog.Unreported()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("want no events, got %v", events)
+	}
+
+	for i := 0; i < maxTamperEvents+5; i++ {
+		if err := tamperLog.Record(OperationUninstall, ErrInvalidUninstallToken); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err = tamperLog.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != maxTamperEvents {
+		t.Fatalf("want %d events, got %d", maxTamperEvents, len(events))
+	}
+
+	if err := tamperLog.MarkReported(events[len(events)-1]); err != nil {
+		t.Fatal(err)
+	}
+	// make sure the next event comes after the reported one
+	time.Sleep(time.Millisecond)
+	if err := tamperLog.Record(OperationPolicyChange, ErrUninstallTokenRequired); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new log on the same file sees the same events, like the running agent
+	// sees the ones recorded by the uninstall command
+	unreported, err := NewTamperLog(path).Unreported()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unreported) != 1 {
+		t.Fatalf("want 1 unreported event, got %d", len(unreported))
+	}
+	if unreported[0].Operation != OperationPolicyChange {
+		t.Fatalf("unexpected event %+v", unreported[0])
+	}
+}
+
+func TestTamperLogSkipsCorruptedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tamper_events.json")
+	content := `{"time":"2023-12-01T10:00:00Z","operation":"uninstall","reason":"invalid uninstall token"}
+{"time":"2023-12-01T10:0`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := NewTamperLog(path).Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TamperEvent{{
+		Time:      time.Date(2023, 12, 1, 10, 0, 0, 0, time.UTC),
+		Operation: OperationUninstall,
+		Reason:    "invalid uninstall token",
+	}}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	last := time.Date(2023, 12, 1, 10, 0, 0, 0, time.UTC)
+	events := []TamperEvent{
+		{Time: last.Add(-2 * time.Minute), Operation: OperationUninstall, Reason: "invalid uninstall token"},
+		{Time: last.Add(-time.Minute), Operation: OperationUpgrade, Reason: "invalid uninstall token"},
+		{Time: last, Operation: OperationUninstall, Reason: "the agent is protected, an uninstall token is required"},
+	}
+
+	tests := []struct {
+		name   string
+		events []TamperEvent
+		want   string
+	}{
+		{name: "no events", want: ""},
+		{
+			name:   "one event",
+			events: events[:1],
+			want:   "tamper protection blocked 1 operation (uninstall: 1), last at 2023-12-01T09:58:00Z: invalid uninstall token",
+		},
+		{
+			name:   "events",
+			events: events,
+			want:   "tamper protection blocked 3 operations (uninstall: 2, upgrade: 1), last at 2023-12-01T10:00:00Z: the agent is protected, an uninstall token is required",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Summary(tc.events)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package protection
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	// ErrUninstallTokenRequired is returned when a protected operation is
+	// attempted without an uninstall token.
+	ErrUninstallTokenRequired = errors.New("the agent is protected, an uninstall token is required")
+	// ErrInvalidUninstallToken is returned when a protected operation is
+	// attempted with a token not matching the uninstall token hash.
+	ErrInvalidUninstallToken = errors.New("invalid uninstall token")
+)
+
+// Protected returns true when the agent is protected from uninstall: the
+// protected operations require the uninstall token.
+func (c Config) Protected() bool {
+	return c.Enabled && c.UninstallTokenHash != ""
+}
+
+// HashUninstallToken returns the hash of token, as sent by Fleet in
+// agent.protection.uninstall_token_hash: the base64 encoded SHA-256 of the token.
+func HashUninstallToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// VerifyUninstallToken returns an error if the agent is protected and token
+// doesn't match the uninstall token hash.
+func (c Config) VerifyUninstallToken(token string) error {
+	if !c.Protected() {
+		return nil
+	}
+	if token == "" {
+		return ErrUninstallTokenRequired
+	}
+	hash := HashUninstallToken(token)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(c.UninstallTokenHash)) != 1 {
+		return ErrInvalidUninstallToken
+	}
+	return nil
+}
+
+// VerifyOperation verifies the uninstall token of the protected operation op,
+// a failed verification is recorded in tamperLog when not nil.
+func VerifyOperation(c Config, op Operation, token string, tamperLog *TamperLog) error {
+	err := c.VerifyUninstallToken(token)
+	if err != nil && tamperLog != nil {
+		_ = tamperLog.Record(op, err)
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package protection
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyUninstallToken(t *testing.T) {
+	protected := Config{Enabled: true, UninstallTokenHash: HashUninstallToken("secret")}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		token   string
+		wantErr error
+	}{
+		{name: "not protected", cfg: Config{}},
+		{name: "disabled", cfg: Config{UninstallTokenHash: protected.UninstallTokenHash}},
+		{name: "no token hash", cfg: Config{Enabled: true}},
+		{name: "valid token", cfg: protected, token: "secret"},
+		{name: "missing token", cfg: protected, wantErr: ErrUninstallTokenRequired},
+		{name: "invalid token", cfg: protected, token: "guess", wantErr: ErrInvalidUninstallToken},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.VerifyUninstallToken(tc.token)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyOperation(t *testing.T) {
+	cfg := Config{Enabled: true, UninstallTokenHash: HashUninstallToken("secret")}
+	tamperLog := NewTamperLog(filepath.Join(t.TempDir(), "tamper_events.json"))
+
+	if err := VerifyOperation(cfg, OperationUninstall, "secret", tamperLog); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyOperation(cfg, OperationUpgrade, "guess", tamperLog); !errors.Is(err, ErrInvalidUninstallToken) {
+		t.Fatalf("want error %v, got %v", ErrInvalidUninstallToken, err)
+	}
+	// a nil log doesn't record the failures
+	if err := VerifyOperation(cfg, OperationUninstall, "", nil); !errors.Is(err, ErrUninstallTokenRequired) {
+		t.Fatalf("want error %v, got %v", ErrUninstallTokenRequired, err)
+	}
+
+	events, err := tamperLog.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("want 1 event, got %d", len(events))
+	}
+	if events[0].Operation != OperationUpgrade || events[0].Reason != ErrInvalidUninstallToken.Error() {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+}
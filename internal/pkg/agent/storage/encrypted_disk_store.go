@@ -77,13 +77,16 @@ func (d *EncryptedDiskStore) Exists() (bool, error) {
 	return true, nil
 }
 
+// ensureKey loads the agent key, again if it was rotated since it was loaded.
+// The caller must hold keyMx.
 func (d *EncryptedDiskStore) ensureKey(ctx context.Context) error {
-	if d.key == nil {
+	if d.key == nil || d.keyGeneration != keyGeneration {
 		key, err := secret.GetAgentSecret(ctx, secret.WithVaultPath(d.vaultPath))
 		if err != nil {
 			return fmt.Errorf("could not get agent key: %w", err)
 		}
 		d.key = key.Value
+		d.keyGeneration = keyGeneration
 	}
 	return nil
 }
@@ -91,6 +94,13 @@ func (d *EncryptedDiskStore) ensureKey(ctx context.Context) error {
 // Save will write the encrypted storage to disk.
 // Specifically it will write to a .tmp file then rotate the file to the target name to ensure that an error does not corrupt the previously written file.
 func (d *EncryptedDiskStore) Save(in io.Reader) error {
+	keyMx.RLock()
+	defer keyMx.RUnlock()
+
+	return d.save(in)
+}
+
+func (d *EncryptedDiskStore) save(in io.Reader) error {
 	// Ensure has agent key
 	err := d.ensureKey(d.ctx)
 	if err != nil {
@@ -161,6 +171,13 @@ func (d *EncryptedDiskStore) Save(in io.Reader) error {
 
 // Load returns an io.ReadCloser for the target.
 func (d *EncryptedDiskStore) Load() (rc io.ReadCloser, err error) {
+	keyMx.RLock()
+	defer keyMx.RUnlock()
+
+	return d.load()
+}
+
+func (d *EncryptedDiskStore) load() (rc io.ReadCloser, err error) {
 	fd, err := os.OpenFile(d.target, os.O_RDONLY, perms)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
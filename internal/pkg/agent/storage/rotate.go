@@ -0,0 +1,191 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/elastic/elastic-agent-libs/file"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+// rotatedSuffix is appended to an encrypted store path to write it re-encrypted
+// with the new agent secret.
+const rotatedSuffix = ".rotated"
+
+var (
+	// keyMx guards the agent secret used by the encrypted disk stores against its rotation.
+	keyMx sync.RWMutex
+	// keyGeneration is incremented every time the agent secret is rotated, so the
+	// encrypted disk stores load it again.
+	keyGeneration uint64
+)
+
+// EncryptedStores returns the paths of the stores encrypted with the agent secret.
+func EncryptedStores() []string {
+	return []string{paths.AgentConfigFile(), paths.AgentStateStoreFile()}
+}
+
+// RotateKey replaces the agent secret encrypting the stores at targets with a new
+// one, then rotates the seed of the vault holding the agent secret.
+// The stores are re-encrypted aside and the new agent secret is only committed once
+// all of them are written, then they replace the stores. A rotation interrupted
+// before its commit is rolled back, after it is completed, by RecoverKeyRotation.
+func RotateKey(ctx context.Context, targets []string, opts ...OptionFunc) error {
+	keyMx.Lock()
+	defer keyMx.Unlock()
+
+	vaultOpts := secretOptions(opts...)
+	if err := recoverKeyRotation(ctx, targets, vaultOpts); err != nil {
+		return errors.New(err, "could not recover the previous agent key rotation")
+	}
+
+	if encryptionDisabled {
+		targets = nil
+	}
+
+	current, err := secret.GetAgentSecret(ctx, vaultOpts...)
+	if err != nil {
+		return errors.New(err, "could not get agent key", errors.TypeSecurity)
+	}
+	pending, err := secret.CreatePendingAgentSecret(ctx, vaultOpts...)
+	if err != nil {
+		return errors.New(err, "could not create new agent key", errors.TypeSecurity)
+	}
+
+	for _, target := range targets {
+		if err := reencrypt(ctx, target, current.Value, pending.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := secret.CommitPendingAgentSecret(ctx, vaultOpts...); err != nil {
+		return errors.New(err, "could not commit new agent key", errors.TypeSecurity)
+	}
+	keyGeneration++
+
+	if err := replaceRotated(targets); err != nil {
+		return err
+	}
+	if err := secret.RemovePendingAgentSecret(ctx, vaultOpts...); err != nil {
+		return errors.New(err, "could not remove the previous agent key", errors.TypeSecurity)
+	}
+
+	if err := secret.RotateVault(ctx, vaultOpts...); err != nil {
+		return errors.New(err, "could not rotate the vault seed", errors.TypeSecurity)
+	}
+	return nil
+}
+
+// RecoverKeyRotation rolls back or completes an agent secret rotation that was
+// interrupted, it must be called before the encrypted stores at targets are used.
+func RecoverKeyRotation(ctx context.Context, targets []string, opts ...OptionFunc) error {
+	keyMx.Lock()
+	defer keyMx.Unlock()
+
+	return recoverKeyRotation(ctx, targets, secretOptions(opts...))
+}
+
+func recoverKeyRotation(ctx context.Context, targets []string, vaultOpts []secret.OptionFunc) error {
+	pending, ok, err := secret.GetPendingAgentSecret(ctx, vaultOpts...)
+	if err != nil {
+		return errors.New(err, "could not get pending agent key", errors.TypeSecurity)
+	}
+	if !ok {
+		return removeRotated(targets)
+	}
+
+	current, err := secret.GetAgentSecret(ctx, vaultOpts...)
+	if err != nil {
+		return errors.New(err, "could not get agent key", errors.TypeSecurity)
+	}
+
+	if bytes.Equal(current.Value, pending.Value) {
+		// the new agent key was committed, the re-encrypted stores must replace the stores.
+		err = replaceRotated(targets)
+	} else {
+		err = removeRotated(targets)
+	}
+	if err != nil {
+		return err
+	}
+
+	return secret.RemovePendingAgentSecret(ctx, vaultOpts...)
+}
+
+// reencrypt writes the store at target, encrypted with the new key, aside it.
+func reencrypt(ctx context.Context, target string, from []byte, to []byte) error {
+	src := &EncryptedDiskStore{ctx: ctx, target: target, key: from, keyGeneration: keyGeneration}
+	exists, err := src.Exists()
+	if err != nil || !exists {
+		return err
+	}
+
+	rc, err := src.load()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	dst := &EncryptedDiskStore{ctx: ctx, target: target + rotatedSuffix, key: to, keyGeneration: keyGeneration}
+	if err := dst.save(rc); err != nil {
+		return errors.New(err,
+			fmt.Sprintf("could not re-encrypt %s", target),
+			errors.TypeFilesystem,
+			errors.M(errors.MetaKeyPath, target))
+	}
+	return nil
+}
+
+// replaceRotated replaces the stores at targets with their re-encrypted version.
+func replaceRotated(targets []string) error {
+	for _, target := range targets {
+		rotated := target + rotatedSuffix
+		if _, err := os.Stat(rotated); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if err := file.SafeFileRotate(target, rotated); err != nil {
+			return errors.New(err,
+				fmt.Sprintf("could not replace target file %s", target),
+				errors.TypeFilesystem,
+				errors.M(errors.MetaKeyPath, target))
+		}
+	}
+	return nil
+}
+
+// removeRotated removes the re-encrypted version of the stores at targets.
+func removeRotated(targets []string) error {
+	for _, target := range targets {
+		err := os.Remove(target + rotatedSuffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.New(err,
+				fmt.Sprintf("could not remove %s", target+rotatedSuffix),
+				errors.TypeFilesystem,
+				errors.M(errors.MetaKeyPath, target+rotatedSuffix))
+		}
+	}
+	return nil
+}
+
+// secretOptions returns the options to access the vault the encrypted disk stores
+// created with opts use.
+func secretOptions(opts ...OptionFunc) []secret.OptionFunc {
+	s := &EncryptedDiskStore{vaultPath: paths.AgentVaultPath()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return []secret.OptionFunc{secret.WithVaultPath(s.vaultPath)}
+}
@@ -0,0 +1,124 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux || windows
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+)
+
+func TestRotateKey(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	require.NoError(t, secret.CreateAgentSecret(ctx, secret.WithVaultPath(dir)))
+	prev, err := secret.GetAgentSecret(ctx, secret.WithVaultPath(dir))
+	require.NoError(t, err)
+
+	data := map[string][]byte{
+		filepath.Join(dir, "fleet.enc"): []byte("fleet config"),
+		filepath.Join(dir, "state.enc"): []byte("state"),
+	}
+	targets := append([]string{filepath.Join(dir, "missing.enc")}, keys(data)...)
+	stores := map[string]Storage{}
+	for target, content := range data {
+		stores[target] = NewEncryptedDiskStore(ctx, target, WithVaultPath(dir))
+		require.NoError(t, stores[target].Save(bytes.NewReader(content)))
+	}
+
+	require.NoError(t, RotateKey(ctx, targets, WithVaultPath(dir)))
+
+	rotated, err := secret.GetAgentSecret(ctx, secret.WithVaultPath(dir))
+	require.NoError(t, err)
+	assert.NotEqual(t, prev.Value, rotated.Value)
+	assert.False(t, rotated.RotatedOn.IsZero())
+
+	for target, content := range data {
+		// the stores in use load the new key, new ones use it
+		assert.Equal(t, content, load(t, stores[target]))
+		assert.Equal(t, content, load(t, NewEncryptedDiskStore(ctx, target, WithVaultPath(dir))))
+		assert.NoFileExists(t, target+rotatedSuffix)
+	}
+	assert.NoFileExists(t, filepath.Join(dir, "missing.enc"))
+
+	_, pending, err := secret.GetPendingAgentSecret(ctx, secret.WithVaultPath(dir))
+	require.NoError(t, err)
+	assert.False(t, pending)
+}
+
+func TestRecoverKeyRotation(t *testing.T) {
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	content := []byte("fleet config")
+
+	// setup writes a store and re-encrypts it aside with a pending key,
+	// as an interrupted rotation would.
+	setup := func(t *testing.T, commit bool) (string, string) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "fleet.enc")
+
+		require.NoError(t, secret.CreateAgentSecret(ctx, secret.WithVaultPath(dir)))
+		require.NoError(t, NewEncryptedDiskStore(ctx, target, WithVaultPath(dir)).Save(bytes.NewReader(content)))
+
+		current, err := secret.GetAgentSecret(ctx, secret.WithVaultPath(dir))
+		require.NoError(t, err)
+		pending, err := secret.CreatePendingAgentSecret(ctx, secret.WithVaultPath(dir))
+		require.NoError(t, err)
+		require.NoError(t, reencrypt(ctx, target, current.Value, pending.Value))
+
+		if commit {
+			require.NoError(t, secret.CommitPendingAgentSecret(ctx, secret.WithVaultPath(dir)))
+		}
+		return dir, target
+	}
+
+	for _, commit := range []bool{false, true} {
+		name := "not committed rotation is rolled back"
+		if commit {
+			name = "committed rotation is completed"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir, target := setup(t, commit)
+
+			require.NoError(t, RecoverKeyRotation(ctx, []string{target}, WithVaultPath(dir)))
+
+			assert.Equal(t, content, load(t, NewEncryptedDiskStore(ctx, target, WithVaultPath(dir))))
+			assert.NoFileExists(t, target+rotatedSuffix)
+			_, pending, err := secret.GetPendingAgentSecret(ctx, secret.WithVaultPath(dir))
+			require.NoError(t, err)
+			assert.False(t, pending)
+		})
+	}
+}
+
+func load(t *testing.T, s Storage) []byte {
+	t.Helper()
+	r, err := s.Load()
+	require.NoError(t, err)
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return b
+}
+
+func keys(m map[string][]byte) []string {
+	var k []string
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
@@ -41,4 +41,6 @@ type EncryptedDiskStore struct {
 	target    string
 	vaultPath string
 	key       []byte
+	// keyGeneration is the agent secret rotation the key belongs to.
+	keyGeneration uint64
 }
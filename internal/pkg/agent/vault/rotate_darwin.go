@@ -0,0 +1,15 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build darwin
+
+package vault
+
+import "context"
+
+// Rotate is a noop on darwin, the keychain items are not encrypted with a seed
+// managed by the vault.
+func (v *Vault) Rotate(ctx context.Context, keys []string) error {
+	return nil
+}
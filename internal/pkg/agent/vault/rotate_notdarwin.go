@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !darwin
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
+)
+
+const (
+	// seedPendingFile holds the new seed while a rotation re-encrypts the vault entries.
+	seedPendingFile = ".seed.pending"
+	// seedOldFile holds the previous seed once a rotation is committed, until the
+	// entries encrypted with it are removed.
+	seedOldFile = ".seed.old"
+)
+
+// Rotate replaces the vault seed with a new one and re-encrypts the entries stored
+// under keys with it.
+// The entries file names are derived from the seed and their key, all the vault
+// entries must be listed in keys, otherwise they could not be found after the rotation.
+// Rotate is crash safe: the new seed is only committed once every entry is written
+// with it, an interrupted rotation is rolled back or completed by the next one.
+func (v *Vault) Rotate(ctx context.Context, keys []string) (err error) {
+	err = v.tryLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = v.unlockAndJoinErrors(err)
+	}()
+
+	if err := v.recoverRotation(keys); err != nil {
+		return fmt.Errorf("could not recover the previous vault rotation: %w", err)
+	}
+
+	if err := v.checkKnownEntries(keys); err != nil {
+		return err
+	}
+
+	seed, err := aesgcm.NewKey(aesgcm.AES256)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(v.path, seedPendingFile), seed); err != nil {
+		return fmt.Errorf("could not write the pending vault seed: %w", err)
+	}
+
+	rotated := &Vault{path: v.path, seed: seed}
+	for _, key := range keys {
+		enc, err := os.ReadFile(v.filepathFromKey(key))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		data, err := v.decrypt(enc)
+		if err != nil {
+			return fmt.Errorf("could not decrypt vault entry %q: %w", key, err)
+		}
+		enc, err = rotated.encrypt(data)
+		if err != nil {
+			return fmt.Errorf("could not encrypt vault entry %q: %w", key, err)
+		}
+		if err := writeFile(rotated.filepathFromKey(key), enc); err != nil {
+			return fmt.Errorf("could not write vault entry %q: %w", key, err)
+		}
+	}
+
+	// Keep the current seed until the entries encrypted with it are removed, then
+	// commit the rotation replacing the seed.
+	if err := writeFile(filepath.Join(v.path, seedOldFile), v.seed); err != nil {
+		return fmt.Errorf("could not write the previous vault seed: %w", err)
+	}
+	mxSeed.Lock()
+	err = os.Rename(filepath.Join(v.path, seedPendingFile), filepath.Join(v.path, seedFile))
+	mxSeed.Unlock()
+	if err != nil {
+		return fmt.Errorf("could not commit the vault seed: %w", err)
+	}
+
+	prev := v.seed
+	v.seed = seed
+	return removeRotated(v.path, prev, keys)
+}
+
+// recoverRotation rolls back a rotation interrupted before it was committed and
+// completes one interrupted after.
+func (v *Vault) recoverRotation(keys []string) error {
+	pending, err := os.ReadFile(filepath.Join(v.path, seedPendingFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err := removeEntries(v.path, pending, keys); err != nil {
+			return err
+		}
+		if err := os.Remove(filepath.Join(v.path, seedPendingFile)); err != nil {
+			return err
+		}
+	}
+
+	old, err := os.ReadFile(filepath.Join(v.path, seedOldFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if bytes.Equal(old, v.seed) {
+		// interrupted before replacing the seed, nothing was rotated.
+		return os.Remove(filepath.Join(v.path, seedOldFile))
+	}
+	return removeRotated(v.path, old, keys)
+}
+
+// checkKnownEntries returns an error if the vault has entries not stored under any of keys.
+func (v *Vault) checkKnownEntries(keys []string) error {
+	known := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		known[fileNameFromKey(v.seed, key)] = true
+	}
+
+	entries, err := os.ReadDir(v.path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if isEntryFileName(e.Name()) && !known[e.Name()] {
+			return fmt.Errorf("vault at %s has entries not listed for rotation, cannot rotate the seed", v.path)
+		}
+	}
+	return nil
+}
+
+// removeRotated removes the entries encrypted with the previous seed, then the
+// previous seed itself.
+func removeRotated(path string, seed []byte, keys []string) error {
+	if err := removeEntries(path, seed, keys); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(path, seedOldFile))
+}
+
+func removeEntries(path string, seed []byte, keys []string) error {
+	for _, key := range keys {
+		err := os.Remove(filepath.Join(path, fileNameFromKey(seed, key)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEntryFileName reports whether name is the file name of a vault entry, see fileNameFromKey.
+func isEntryFileName(name string) bool {
+	if len(name) != hex.EncodedLen(32) {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
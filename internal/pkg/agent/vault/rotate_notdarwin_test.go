@@ -0,0 +1,209 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !darwin
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
+)
+
+func TestVaultRotate(t *testing.T) {
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	vaultPath := getTestVaultPath(t)
+	v, err := New(ctx, vaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	values := map[string][]byte{"foo": []byte("bar"), "baz": []byte("qux")}
+	for k, val := range values {
+		if err := v.Set(ctx, k, val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prevSeed := readSeed(t, vaultPath)
+
+	if err := v.Rotate(ctx, []string{"foo", "baz", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(prevSeed, readSeed(t, vaultPath)) {
+		t.Fatal("the vault seed was not rotated")
+	}
+
+	// The rotated vault and a new instance read the re-encrypted entries
+	v2, err := New(ctx, vaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v2.Close()
+	for _, vault := range []*Vault{v, v2} {
+		for k, want := range values {
+			got, err := vault.Get(ctx, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		}
+	}
+
+	// Only the entries encrypted with the new seed remain
+	if diff := cmp.Diff(len(values), countEntries(t, vaultPath)); diff != "" {
+		t.Fatal(diff)
+	}
+	for _, f := range []string{seedPendingFile, seedOldFile} {
+		if _, err := os.Stat(filepath.Join(vaultPath, f)); !os.IsNotExist(err) {
+			t.Fatalf("%s should have been removed: %v", f, err)
+		}
+	}
+}
+
+func TestVaultRotateUnknownEntries(t *testing.T) {
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	vaultPath := getTestVaultPath(t)
+	v, err := New(ctx, vaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	if err := v.Set(ctx, "foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	prevSeed := readSeed(t, vaultPath)
+
+	if err := v.Rotate(ctx, []string{"other"}); err == nil {
+		t.Fatal("rotating with unlisted entries should fail")
+	}
+	if !bytes.Equal(prevSeed, readSeed(t, vaultPath)) {
+		t.Fatal("the vault seed should not change when the rotation fails")
+	}
+	if _, err := v.Get(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultRotateRecovery(t *testing.T) {
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	keys := []string{"foo"}
+
+	t.Run("rotation interrupted before commit is rolled back", func(t *testing.T) {
+		vaultPath := getTestVaultPath(t)
+		v, err := New(ctx, vaultPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer v.Close()
+		if err := v.Set(ctx, "foo", []byte("bar")); err != nil {
+			t.Fatal(err)
+		}
+
+		// simulate a rotation which wrote the entries with the pending seed
+		// and the previous seed, but did not replace the seed.
+		pending, err := aesgcm.NewKey(aesgcm.AES256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, filepath.Join(vaultPath, seedPendingFile), pending)
+		writeTestFile(t, filepath.Join(vaultPath, seedOldFile), readSeed(t, vaultPath))
+		writeTestFile(t, filepath.Join(vaultPath, fileNameFromKey(pending, "foo")), []byte("partial"))
+
+		if err := v.recoverRotation(keys); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(1, countEntries(t, vaultPath)); diff != "" {
+			t.Fatal(diff)
+		}
+		got, err := v.Get(ctx, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]byte("bar"), got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("rotation interrupted after commit is completed", func(t *testing.T) {
+		vaultPath := getTestVaultPath(t)
+		v, err := New(ctx, vaultPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer v.Close()
+		if err := v.Set(ctx, "foo", []byte("bar")); err != nil {
+			t.Fatal(err)
+		}
+
+		// simulate a rotation which replaced the seed but did not remove
+		// the entries encrypted with the previous one.
+		old, err := aesgcm.NewKey(aesgcm.AES256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, filepath.Join(vaultPath, seedOldFile), old)
+		writeTestFile(t, filepath.Join(vaultPath, fileNameFromKey(old, "foo")), []byte("stale"))
+
+		if err := v.recoverRotation(keys); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(1, countEntries(t, vaultPath)); diff != "" {
+			t.Fatal(diff)
+		}
+		if _, err := os.Stat(filepath.Join(vaultPath, seedOldFile)); !os.IsNotExist(err) {
+			t.Fatalf("%s should have been removed: %v", seedOldFile, err)
+		}
+	})
+}
+
+func readSeed(t *testing.T, vaultPath string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(vaultPath, seedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func writeTestFile(t *testing.T, fp string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(fp, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countEntries(t *testing.T, vaultPath string) int {
+	t.Helper()
+	entries, err := os.ReadDir(vaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	for _, e := range entries {
+		if isEntryFileName(e.Name()) {
+			count++
+		}
+	}
+	return count
+}
@@ -19,6 +19,15 @@ type Capabilities interface {
 	AllowUpgrade(version string, sourceURI string) bool
 	AllowInput(name string) bool
 	AllowOutput(name string) bool
+	// AllowLocalInput returns true when the local overlay of a Fleet-managed
+	// agent may add inputs of the given type, the default is to deny them.
+	AllowLocalInput(name string) bool
+	// AllowLocalOutput returns true when the inputs of the local overlay may
+	// write to the output with the given name, the default is to deny them.
+	AllowLocalOutput(name string) bool
+	// MaxIsolationGroups returns the maximum number of isolation groups of the
+	// agent, false when there is no limit.
+	MaxIsolationGroups() (int, bool)
 }
 
 type capabilitiesManager struct {
@@ -26,6 +35,11 @@ type capabilitiesManager struct {
 	inputChecks  []*stringMatcher
 	outputChecks []*stringMatcher
 	upgradeCaps  []*upgradeCapability
+
+	localInputChecks  []*stringMatcher
+	localOutputChecks []*stringMatcher
+
+	isolationGroupLimits []int
 }
 
 func (cm *capabilitiesManager) AllowInput(inputType string) bool {
@@ -36,10 +50,41 @@ func (cm *capabilitiesManager) AllowOutput(outputType string) bool {
 	return matchString(outputType, cm.outputChecks)
 }
 
+func (cm *capabilitiesManager) AllowLocalInput(inputType string) bool {
+	return matchStringOrDeny(inputType, cm.localInputChecks)
+}
+
+func (cm *capabilitiesManager) AllowLocalOutput(outputName string) bool {
+	return matchStringOrDeny(outputName, cm.localOutputChecks)
+}
+
 func (cm *capabilitiesManager) AllowUpgrade(version string, uri string) bool {
 	return allowUpgrade(cm.log, version, uri, cm.upgradeCaps)
 }
 
+func (cm *capabilitiesManager) MaxIsolationGroups() (int, bool) {
+	// the lowest limit wins
+	max, found := 0, false
+	for _, limit := range cm.isolationGroupLimits {
+		if !found || limit < max {
+			max, found = limit, true
+		}
+	}
+	return max, found
+}
+
+// BlockedOutputType returns the first output type not allowed by caps, empty when
+// they all are. A component writing to several outputs, see
+// (*component.Component).OutputTypes, is blocked when one of them isn't allowed.
+func BlockedOutputType(caps Capabilities, outputTypes []string) string {
+	for _, outputType := range outputTypes {
+		if !caps.AllowOutput(outputType) {
+			return outputType
+		}
+	}
+	return ""
+}
+
 func LoadFile(capsFile string, log *logger.Logger) (Capabilities, error) {
 	// load capabilities from file
 	fd, err := os.Open(capsFile)
@@ -71,5 +116,10 @@ func Load(capsReader io.Reader, log *logger.Logger) (Capabilities, error) {
 		inputChecks:  caps.inputChecks,
 		outputChecks: caps.outputChecks,
 		upgradeCaps:  caps.upgradeChecks,
+
+		localInputChecks:  caps.localInputChecks,
+		localOutputChecks: caps.localOutputChecks,
+
+		isolationGroupLimits: caps.isolationGroupLimits,
 	}, nil
 }
@@ -102,6 +102,63 @@ capabilities:
 
 }
 
+func TestLocalOverlay(t *testing.T) {
+	yml := `
+capabilities:
+- rule: deny
+  local_input: filestream/secret
+- rule: allow
+  local_input: filestream/*
+- rule: allow
+  local_output: default
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	assert.True(t, caps.AllowLocalInput("filestream/logs"))
+	assert.False(t, caps.AllowLocalInput("filestream/secret"))
+	assert.False(t, caps.AllowLocalInput("system/metrics"), "local inputs should be denied by default")
+	assert.True(t, caps.AllowLocalOutput("default"))
+	assert.False(t, caps.AllowLocalOutput("monitoring"), "local outputs should be denied by default")
+	assert.True(t, caps.AllowInput("system/metrics"), "local rules should not apply to the policy inputs")
+}
+
+func TestMaxIsolationGroups(t *testing.T) {
+	yml := `
+capabilities:
+- max_isolation_groups: 4
+- rule: allow
+  input: system/metrics
+- max_isolation_groups: 2
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	max, ok := caps.MaxIsolationGroups()
+	assert.True(t, ok)
+	assert.Equal(t, 2, max, "the lowest limit should be used")
+
+	_, err = Load(strings.NewReader(`
+capabilities:
+- max_isolation_groups: -1
+`), logger.NewWithoutConfig("testing"))
+	assert.Error(t, err, "a negative limit should be rejected")
+}
+
+func TestBlockedOutputType(t *testing.T) {
+	yml := `
+capabilities:
+- rule: deny
+  output: kafka
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	assert.Empty(t, BlockedOutputType(caps, []string{"elasticsearch"}))
+	assert.Empty(t, BlockedOutputType(caps, []string{"elasticsearch", "logstash"}))
+	assert.Equal(t, "kafka", BlockedOutputType(caps, []string{"elasticsearch", "kafka"}), "one of the outputs is denied")
+}
+
 func TestNoCaps(t *testing.T) {
 	// Make sure capabilities loaded from a nonexistent file don't interfere
 	// with anything
@@ -112,4 +169,7 @@ func TestNoCaps(t *testing.T) {
 	assert.True(t, caps.AllowInput("system/metrics"))
 	assert.True(t, caps.AllowInput("system/logs"))
 	assert.True(t, caps.AllowOutput("elasticsearch"))
+	assert.False(t, caps.AllowLocalInput("system/logs"))
+	_, ok := caps.MaxIsolationGroups()
+	assert.False(t, ok)
 }
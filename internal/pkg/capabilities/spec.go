@@ -16,6 +16,11 @@ type capabilitiesList struct {
 	inputChecks   []*stringMatcher
 	outputChecks  []*stringMatcher
 	upgradeChecks []*upgradeCapability
+
+	localInputChecks  []*stringMatcher
+	localOutputChecks []*stringMatcher
+
+	isolationGroupLimits []int
 }
 
 // a type for capability values that must equal "allow" or "deny", enforced
@@ -66,6 +71,26 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 			}
 			r.outputChecks = append(r.outputChecks,
 				&stringMatcher{pattern: spec.Output, rule: spec.Type})
+		} else if _, found = mm["local_input"]; found {
+			spec := struct {
+				Type  allowOrDeny `yaml:"rule"`
+				Input string      `yaml:"local_input"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			r.localInputChecks = append(r.localInputChecks,
+				&stringMatcher{pattern: spec.Input, rule: spec.Type})
+		} else if _, found = mm["local_output"]; found {
+			spec := struct {
+				Type   allowOrDeny `yaml:"rule"`
+				Output string      `yaml:"local_output"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			r.localOutputChecks = append(r.localOutputChecks,
+				&stringMatcher{pattern: spec.Output, rule: spec.Type})
 		} else if _, found = mm["upgrade"]; found {
 			// Serialize upgrade constraints to a temporary struct so we can
 			// safely assemble the associated EQL expression
@@ -81,6 +106,17 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 				return err
 			}
 			r.upgradeChecks = append(r.upgradeChecks, cap)
+		} else if _, found = mm["max_isolation_groups"]; found {
+			spec := struct {
+				Max int `yaml:"max_isolation_groups"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			if spec.Max < 0 {
+				return fmt.Errorf("capability definition number '%d' has a negative max_isolation_groups", i)
+			}
+			r.isolationGroupLimits = append(r.isolationGroupLimits, spec.Max)
 		} else {
 			return fmt.Errorf("unexpected capability type for definition number '%d'", i)
 		}
@@ -25,3 +25,14 @@ func matchString(str string, matchers []*stringMatcher) bool {
 	// If nothing blocked it, default to allow.
 	return true
 }
+
+// matchStringOrDeny is matchString for the allow-lists, a string that
+// doesn't match any pattern is denied.
+func matchStringOrDeny(str string, matchers []*stringMatcher) bool {
+	for _, matcher := range matchers {
+		if matchesExpr(matcher.pattern, str) {
+			return matcher.rule == ruleTypeAllow
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	awsBaseURL = "http://169.254.169.254"
+
+	awsTokenPath    = "/latest/api/token"
+	awsIdentityPath = "/latest/dynamic/instance-identity/document"
+
+	awsTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	awsTokenHeader    = "X-aws-ec2-metadata-token"
+)
+
+// fetchAWS queries the AWS EC2 instance metadata service, using IMDSv2.
+func fetchAWS(ctx context.Context, client *http.Client, baseURL string) (*Metadata, error) {
+	token, err := request(ctx, client, http.MethodPut, baseURL+awsTokenPath, map[string]string{awsTokenTTLHeader: "60"})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := request(ctx, client, http.MethodGet, baseURL+awsIdentityPath, map[string]string{awsTokenHeader: string(token)})
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		AccountID        string `json:"accountId"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	return &Metadata{
+		Region:           doc.Region,
+		AvailabilityZone: doc.AvailabilityZone,
+		Account:          AccountMetadata{ID: doc.AccountID},
+		Instance:         InstanceMetadata{ID: doc.InstanceID},
+		Machine:          MachineMetadata{Type: doc.InstanceType},
+	}, nil
+}
@@ -0,0 +1,44 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	azureBaseURL = "http://169.254.169.254"
+
+	azureComputePath = "/metadata/instance/compute?api-version=2021-02-01"
+)
+
+// fetchAzure queries the Azure instance metadata service.
+func fetchAzure(ctx context.Context, client *http.Client, baseURL string) (*Metadata, error) {
+	b, err := request(ctx, client, http.MethodGet, baseURL+azureComputePath, map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		VMID           string `json:"vmId"`
+		Name           string `json:"name"`
+		Location       string `json:"location"`
+		Zone           string `json:"zone"`
+		SubscriptionID string `json:"subscriptionId"`
+		VMSize         string `json:"vmSize"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	return &Metadata{
+		Region:           doc.Location,
+		AvailabilityZone: doc.Zone,
+		Account:          AccountMetadata{ID: doc.SubscriptionID},
+		Instance:         InstanceMetadata{ID: doc.VMID, Name: doc.Name},
+		Machine:          MachineMetadata{Type: doc.VMSize},
+	}, nil
+}
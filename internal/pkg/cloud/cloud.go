@@ -0,0 +1,300 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package cloud collects the metadata of the cloud instance the agent runs on from the
+// metadata services of the cloud providers.
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// DefaultTimeout is the default timeout of the metadata services queries.
+	DefaultTimeout = 3 * time.Second
+	// DefaultCacheTTL is the default duration the collected metadata is cached for.
+	DefaultCacheTTL = time.Hour
+	// DefaultNegativeCacheTTL is the default duration no provider answering is cached for.
+	DefaultNegativeCacheTTL = time.Minute
+
+	// maxResponseSize limits the size of a metadata service response.
+	maxResponseSize = 1 << 20
+)
+
+// Metadata is the metadata of a cloud instance in ECS compliant object form.
+type Metadata struct {
+	// Provider is the name of the cloud provider (e.g. aws, gcp, azure, openstack).
+	Provider string `json:"provider" yaml:"provider"`
+	// Region is the region the instance runs in.
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+	// AvailabilityZone is the availability zone the instance runs in.
+	AvailabilityZone string `json:"availability_zone,omitempty" yaml:"availability_zone,omitempty"`
+	// Account is the cloud account the instance belongs to.
+	Account AccountMetadata `json:"account" yaml:"account"`
+	// Instance identifies the instance.
+	Instance InstanceMetadata `json:"instance" yaml:"instance"`
+	// Machine describes the instance machine.
+	Machine MachineMetadata `json:"machine" yaml:"machine"`
+	// Project is the project the instance belongs to.
+	Project ProjectMetadata `json:"project" yaml:"project"`
+}
+
+// AccountMetadata is the cloud account metadata.
+type AccountMetadata struct {
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+}
+
+// InstanceMetadata is the cloud instance identity.
+type InstanceMetadata struct {
+	ID   string `json:"id,omitempty" yaml:"id,omitempty"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// MachineMetadata is the cloud instance machine description.
+type MachineMetadata struct {
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// ProjectMetadata is the cloud project metadata.
+type ProjectMetadata struct {
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+}
+
+// Mapping returns the metadata as a mapping usable as variables, empty values are omitted.
+func (m *Metadata) Mapping() map[string]interface{} {
+	mapping := map[string]interface{}{
+		"provider": m.Provider,
+	}
+	set := func(key string, value string) {
+		if value != "" {
+			mapping[key] = value
+		}
+	}
+	setNested := func(key string, values map[string]string) {
+		nested := map[string]interface{}{}
+		for k, v := range values {
+			if v != "" {
+				nested[k] = v
+			}
+		}
+		if len(nested) > 0 {
+			mapping[key] = nested
+		}
+	}
+	set("region", m.Region)
+	set("availability_zone", m.AvailabilityZone)
+	setNested("account", map[string]string{"id": m.Account.ID})
+	setNested("instance", map[string]string{"id": m.Instance.ID, "name": m.Instance.Name})
+	setNested("machine", map[string]string{"type": m.Machine.Type})
+	setNested("project", map[string]string{"id": m.Project.ID})
+	return mapping
+}
+
+// fetchFunc queries the metadata service at baseURL, it returns an error if the agent
+// does not run on this provider.
+type fetchFunc func(ctx context.Context, client *http.Client, baseURL string) (*Metadata, error)
+
+// provider is a cloud provider metadata service.
+type provider struct {
+	name    string
+	baseURL string
+	fetch   fetchFunc
+}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithProviderURL replaces the base URL of the metadata service of the named provider.
+func WithProviderURL(name string, baseURL string) Option {
+	return func(c *Collector) {
+		for i := range c.providers {
+			if c.providers[i].name == name {
+				c.providers[i].baseURL = baseURL
+			}
+		}
+	}
+}
+
+// WithTimeout sets the timeout of the metadata services queries.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Collector) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheTTL sets the duration the collected metadata is cached for.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Collector) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeCacheTTL sets the duration no provider answering is cached for.
+func WithNegativeCacheTTL(ttl time.Duration) Option {
+	return func(c *Collector) {
+		c.negativeTTL = ttl
+	}
+}
+
+// Collector collects the metadata of the cloud instance, querying all the providers
+// metadata services concurrently and caching the result.
+type Collector struct {
+	providers   []provider
+	timeout     time.Duration
+	ttl         time.Duration
+	negativeTTL time.Duration
+	client      *http.Client
+
+	// collecting shares a collection between the concurrent callers.
+	collecting singleflight.Group
+
+	mx          sync.Mutex
+	metadata    *Metadata
+	collectedAt time.Time
+}
+
+// NewCollector creates a new cloud metadata collector.
+func NewCollector(opts ...Option) *Collector {
+	c := &Collector{
+		// the providers are listed by priority, the first one to answer is used.
+		providers: []provider{
+			{name: "aws", baseURL: awsBaseURL, fetch: fetchAWS},
+			{name: "gcp", baseURL: gcpBaseURL, fetch: fetchGCP},
+			{name: "azure", baseURL: azureBaseURL, fetch: fetchAzure},
+			{name: "openstack", baseURL: openstackBaseURL, fetch: fetchOpenStack},
+		},
+		timeout:     DefaultTimeout,
+		ttl:         DefaultCacheTTL,
+		negativeTTL: DefaultNegativeCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.client = &http.Client{
+		Timeout: c.timeout,
+		Transport: &http.Transport{
+			// the metadata services are link local, they must not go through a proxy.
+			Proxy: nil,
+		},
+	}
+	return c
+}
+
+var (
+	defaultCollector     *Collector
+	defaultCollectorOnce sync.Once
+)
+
+// Default returns the collector shared by the agent.
+func Default() *Collector {
+	defaultCollectorOnce.Do(func() {
+		defaultCollector = NewCollector()
+	})
+	return defaultCollector
+}
+
+// Metadata returns the metadata of the cloud instance, or nil if the agent does not
+// run on a known cloud provider. The result is cached for the cache TTL, or for the
+// negative cache TTL when no provider answered.
+//
+// The concurrent callers share one collection, it isn't bound to their context: a
+// caller whose context is done gets the last collected metadata, the collection goes
+// on for the following callers.
+func (c *Collector) Metadata(ctx context.Context) *Metadata {
+	if m, ok := c.cached(); ok {
+		return m
+	}
+
+	ch := c.collecting.DoChan("metadata", func() (interface{}, error) {
+		m := c.collect(context.Background())
+		c.mx.Lock()
+		defer c.mx.Unlock()
+		c.metadata = m
+		c.collectedAt = time.Now()
+		return m, nil
+	})
+	select {
+	case <-ctx.Done():
+		c.mx.Lock()
+		defer c.mx.Unlock()
+		return c.metadata
+	case res := <-ch:
+		m, _ := res.Val.(*Metadata)
+		return m
+	}
+}
+
+// cached returns the cached metadata, false when it expired.
+func (c *Collector) cached() (*Metadata, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	ttl := c.ttl
+	if c.metadata == nil {
+		ttl = c.negativeTTL
+	}
+	if c.collectedAt.IsZero() || time.Since(c.collectedAt) >= ttl {
+		return nil, false
+	}
+	return c.metadata, true
+}
+
+// collect queries all the metadata services concurrently and returns the metadata of
+// the provider with the highest priority that answered.
+func (c *Collector) collect(ctx context.Context) *Metadata {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]*Metadata, len(c.providers))
+	var wg sync.WaitGroup
+	for i, p := range c.providers {
+		wg.Add(1)
+		go func(i int, p provider) {
+			defer wg.Done()
+			m, err := p.fetch(ctx, c.client, p.baseURL)
+			if err != nil || m == nil {
+				return
+			}
+			m.Provider = p.name
+			results[i] = m
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, m := range results {
+		if m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// request sends a request to a metadata service and returns the response body.
+func request(ctx context.Context, client *http.Client, method string, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: unexpected status code %d", method, url, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+}
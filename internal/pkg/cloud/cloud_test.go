@@ -0,0 +1,277 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// awsStandIn serves the AWS IMDSv2 API, it requires a session token.
+func awsStandIn() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(awsTokenPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get(awsTokenTTLHeader) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("token"))
+	})
+	mux.HandleFunc("/latest/dynamic/instance-identity/document", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(awsTokenHeader) != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"accountId": "123456789012",
+			"availabilityZone": "us-east-1a",
+			"instanceId": "i-0123456789abcdef0",
+			"instanceType": "t3.medium",
+			"region": "us-east-1"
+		}`))
+	})
+	return mux
+}
+
+func gcpStandIn() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/computeMetadata/v1/" || r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"instance": {
+				"id": 4567890123456789012,
+				"name": "my-instance",
+				"machineType": "projects/123456/machineTypes/e2-medium",
+				"zone": "projects/123456/zones/europe-west1-b"
+			},
+			"project": {"projectId": "my-project", "numericProjectId": 123456}
+		}`))
+	})
+}
+
+func azureStandIn() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/instance/compute" || r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"location": "westeurope",
+			"name": "my-vm",
+			"subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d",
+			"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+			"vmSize": "Standard_D2s_v3",
+			"zone": "1"
+		}`))
+	})
+}
+
+func openstackStandIn() http.Handler {
+	values := map[string]string{
+		"instance-id":                 "i-0000001",
+		"hostname":                    "my-server.novalocal",
+		"placement/availability-zone": "nova",
+		"instance-type":               "m1.small",
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := values[r.URL.Path[len(openstackMetadataPath):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(v + "\n"))
+	})
+}
+
+// collector returns a collector querying the stand-ins, the providers without
+// stand-in are served by a server answering 404.
+func collector(t *testing.T, standIns map[string]http.Handler, opts ...Option) *Collector {
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(notFound.Close)
+
+	for _, name := range []string{"aws", "gcp", "azure", "openstack"} {
+		url := notFound.URL
+		if h, ok := standIns[name]; ok {
+			srv := httptest.NewServer(h)
+			t.Cleanup(srv.Close)
+			url = srv.URL
+		}
+		opts = append(opts, WithProviderURL(name, url))
+	}
+	return NewCollector(opts...)
+}
+
+func TestCollectorMetadata(t *testing.T) {
+	testCases := map[string]struct {
+		standIns map[string]http.Handler
+		want     *Metadata
+	}{
+		"aws": {
+			standIns: map[string]http.Handler{"aws": awsStandIn()},
+			want: &Metadata{
+				Provider:         "aws",
+				Region:           "us-east-1",
+				AvailabilityZone: "us-east-1a",
+				Account:          AccountMetadata{ID: "123456789012"},
+				Instance:         InstanceMetadata{ID: "i-0123456789abcdef0"},
+				Machine:          MachineMetadata{Type: "t3.medium"},
+			},
+		},
+		"gcp": {
+			standIns: map[string]http.Handler{"gcp": gcpStandIn()},
+			want: &Metadata{
+				Provider:         "gcp",
+				Region:           "europe-west1",
+				AvailabilityZone: "europe-west1-b",
+				Account:          AccountMetadata{ID: "my-project"},
+				Instance:         InstanceMetadata{ID: "4567890123456789012", Name: "my-instance"},
+				Machine:          MachineMetadata{Type: "e2-medium"},
+				Project:          ProjectMetadata{ID: "my-project"},
+			},
+		},
+		"azure": {
+			standIns: map[string]http.Handler{"azure": azureStandIn()},
+			want: &Metadata{
+				Provider:         "azure",
+				Region:           "westeurope",
+				AvailabilityZone: "1",
+				Account:          AccountMetadata{ID: "8d10da13-8125-4ba9-a717-bf7490507b3d"},
+				Instance:         InstanceMetadata{ID: "02aab8a4-74ef-476e-8182-f6d2ba4166a6", Name: "my-vm"},
+				Machine:          MachineMetadata{Type: "Standard_D2s_v3"},
+			},
+		},
+		"openstack": {
+			standIns: map[string]http.Handler{"openstack": openstackStandIn()},
+			want: &Metadata{
+				Provider:         "openstack",
+				AvailabilityZone: "nova",
+				Instance:         InstanceMetadata{ID: "i-0000001", Name: "my-server.novalocal"},
+				Machine:          MachineMetadata{Type: "m1.small"},
+			},
+		},
+		"the provider with the highest priority is used": {
+			standIns: map[string]http.Handler{"aws": awsStandIn(), "openstack": openstackStandIn()},
+			want: &Metadata{
+				Provider:         "aws",
+				Region:           "us-east-1",
+				AvailabilityZone: "us-east-1a",
+				Account:          AccountMetadata{ID: "123456789012"},
+				Instance:         InstanceMetadata{ID: "i-0123456789abcdef0"},
+				Machine:          MachineMetadata{Type: "t3.medium"},
+			},
+		},
+		"not on a cloud provider": {},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := collector(t, tc.standIns)
+			assert.Equal(t, tc.want, c.Metadata(context.Background()))
+		})
+	}
+}
+
+func TestCollectorTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	})
+
+	c := collector(t, map[string]http.Handler{"aws": slow, "azure": azureStandIn()}, WithTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	m := c.Metadata(context.Background())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	require.NotNil(t, m)
+	assert.Equal(t, "azure", m.Provider)
+}
+
+func TestCollectorCache(t *testing.T) {
+	var requests atomic.Int32
+	counting := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	c := collector(t, map[string]http.Handler{"azure": counting(azureStandIn())})
+	first := c.Metadata(context.Background())
+	require.NotNil(t, first)
+	assert.Equal(t, first, c.Metadata(context.Background()))
+	assert.EqualValues(t, 1, requests.Load(), "the metadata should be cached")
+
+	c.ttl = 0
+	assert.Equal(t, first, c.Metadata(context.Background()))
+	assert.EqualValues(t, 2, requests.Load(), "the expired metadata should be collected again")
+}
+
+func TestCollectorNegativeCache(t *testing.T) {
+	c := collector(t, nil, WithNegativeCacheTTL(time.Hour))
+	assert.Nil(t, c.Metadata(context.Background()))
+	_, cached := c.cached()
+	assert.True(t, cached, "no provider answering should be cached for the negative TTL")
+
+	c.negativeTTL = 0
+	_, cached = c.cached()
+	assert.False(t, cached, "no provider answering should not be cached for the TTL")
+}
+
+func TestCollectorSharedCollection(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		azureStandIn().ServeHTTP(w, r)
+	})
+	c := collector(t, map[string]http.Handler{"azure": slow})
+
+	// a caller whose context is done doesn't wait for the collection
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, c.Metadata(ctx))
+
+	results := make(chan *Metadata, 5)
+	for i := 0; i < cap(results); i++ {
+		go func() {
+			results <- c.Metadata(context.Background())
+		}()
+	}
+	// the callers wait for the collection started by the cancelled caller
+	require.Eventually(t, func() bool { return requests.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	close(release)
+	for i := 0; i < cap(results); i++ {
+		m := <-results
+		require.NotNil(t, m)
+		assert.Equal(t, "azure", m.Provider)
+	}
+	assert.EqualValues(t, 1, requests.Load(), "the concurrent callers should share one collection")
+}
+
+func TestMetadataMapping(t *testing.T) {
+	m := &Metadata{
+		Provider: "aws",
+		Region:   "us-east-1",
+		Account:  AccountMetadata{ID: "123456789012"},
+		Machine:  MachineMetadata{Type: "t3.medium"},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"provider": "aws",
+		"region":   "us-east-1",
+		"account":  map[string]interface{}{"id": "123456789012"},
+		"machine":  map[string]interface{}{"type": "t3.medium"},
+	}, m.Mapping())
+}
@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	gcpBaseURL = "http://metadata.google.internal"
+
+	gcpMetadataPath = "/computeMetadata/v1/?recursive=true&alt=json"
+)
+
+// fetchGCP queries the Google Compute Engine metadata server.
+func fetchGCP(ctx context.Context, client *http.Client, baseURL string) (*Metadata, error) {
+	b, err := request(ctx, client, http.MethodGet, baseURL+gcpMetadataPath, map[string]string{"Metadata-Flavor": "Google"})
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Instance struct {
+			ID          json.Number `json:"id"`
+			Name        string      `json:"name"`
+			MachineType string      `json:"machineType"`
+			Zone        string      `json:"zone"`
+		} `json:"instance"`
+		Project struct {
+			ProjectID string `json:"projectId"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	// the zone and the machine type are resource paths like projects/<id>/zones/us-east1-b
+	zone := lastPathElement(doc.Instance.Zone)
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return &Metadata{
+		Region:           region,
+		AvailabilityZone: zone,
+		Account:          AccountMetadata{ID: doc.Project.ProjectID},
+		Instance:         InstanceMetadata{ID: doc.Instance.ID.String(), Name: doc.Instance.Name},
+		Machine:          MachineMetadata{Type: lastPathElement(doc.Instance.MachineType)},
+		Project:          ProjectMetadata{ID: doc.Project.ProjectID},
+	}, nil
+}
+
+func lastPathElement(p string) string {
+	return p[strings.LastIndex(p, "/")+1:]
+}
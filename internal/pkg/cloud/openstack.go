@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	openstackBaseURL = "http://169.254.169.254"
+
+	openstackMetadataPath = "/2009-04-04/meta-data/"
+)
+
+// fetchOpenStack queries the OpenStack Nova metadata service, through its EC2 compatible API.
+func fetchOpenStack(ctx context.Context, client *http.Client, baseURL string) (*Metadata, error) {
+	values := map[string]string{
+		"instance-id":                 "",
+		"hostname":                    "",
+		"placement/availability-zone": "",
+		"instance-type":               "",
+	}
+	for key := range values {
+		b, err := request(ctx, client, http.MethodGet, baseURL+openstackMetadataPath+key, nil)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = strings.TrimSpace(string(b))
+	}
+
+	return &Metadata{
+		AvailabilityZone: values["placement/availability-zone"],
+		Instance:         InstanceMetadata{ID: values["instance-id"], Name: values["hostname"]},
+		Machine:          MachineMetadata{Type: values["instance-type"]},
+	}, nil
+}
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	cloudmeta "github.com/elastic/elastic-agent/internal/pkg/cloud"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	corecomp "github.com/elastic/elastic-agent/internal/pkg/core/composable"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/features"
+)
+
+const (
+	// DefaultCheckInterval is the default interval used to check if the cloud metadata has changed.
+	DefaultCheckInterval = cloudmeta.DefaultCacheTTL
+
+	cloudMetadataFeatureFlagCallbackID = "cloud_provider"
+)
+
+func init() {
+	composable.Providers.MustAddContextProvider("cloud", ContextProviderBuilder)
+}
+
+type contextProvider struct {
+	logger *logger.Logger
+
+	CheckInterval time.Duration `config:"check_interval"`
+
+	// cloudMetadataFFChangeCh is used to signal when the cloud metadata
+	// feature flag has changed
+	cloudMetadataFFChangeCh chan struct{}
+
+	// used by testing
+	collector *cloudmeta.Collector
+}
+
+// Run runs the cloud context provider.
+func (c *contextProvider) Run(ctx context.Context, comm corecomp.ContextProviderComm) error {
+	current := c.fetch(ctx)
+	err := comm.Set(current)
+	if err != nil {
+		return errors.New(err, "failed to set mapping", errors.TypeUnexpected)
+	}
+
+	// Update context when the cloud metadata changes.
+	for {
+		t := time.NewTimer(c.CheckInterval)
+		select {
+		case <-comm.Done():
+			t.Stop()
+			return comm.Err()
+		case <-c.cloudMetadataFFChangeCh:
+			t.Stop()
+		case <-t.C:
+		}
+
+		updated := c.fetch(ctx)
+		if reflect.DeepEqual(current, updated) {
+			// nothing to do
+			continue
+		}
+		current = updated
+		err = comm.Set(updated)
+		if err != nil {
+			c.logger.Errorf("Failed updating mapping to latest cloud metadata: %s", err)
+		}
+	}
+}
+
+// fetch returns the cloud metadata mapping, empty when the agent does not run on a cloud provider.
+// The metadata services are not queried when the cloud_metadata feature flag is disabled.
+func (c *contextProvider) fetch(ctx context.Context) map[string]interface{} {
+	if !features.CloudMetadata() {
+		return map[string]interface{}{}
+	}
+	meta := c.collector.Metadata(ctx)
+	if meta == nil {
+		c.logger.Debug("No cloud provider metadata service answered, cloud variables are not available")
+		return map[string]interface{}{}
+	}
+	return meta.Mapping()
+}
+
+func (c *contextProvider) onCloudMetadataFeatureFlagChange(new, old bool) {
+	// cloud metadata feature flag was toggled, so notify on channel
+	select {
+	case c.cloudMetadataFFChangeCh <- struct{}{}:
+	default:
+	}
+}
+
+func (c *contextProvider) Close() error {
+	features.RemoveCloudMetadataOnChangeCallback(cloudMetadataFeatureFlagCallbackID)
+	close(c.cloudMetadataFFChangeCh)
+
+	return nil
+}
+
+// ContextProviderBuilder builds the context provider.
+func ContextProviderBuilder(log *logger.Logger, c *config.Config, _ bool) (corecomp.ContextProvider, error) {
+	p := &contextProvider{
+		logger:    log,
+		collector: cloudmeta.Default(),
+	}
+	if c != nil {
+		err := c.Unpack(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack config: %w", err)
+		}
+	}
+	if p.CheckInterval <= 0 {
+		p.CheckInterval = DefaultCheckInterval
+	}
+
+	p.cloudMetadataFFChangeCh = make(chan struct{}, 1)
+	err := features.AddCloudMetadataOnChangeCallback(
+		p.onCloudMetadataFeatureFlagChange,
+		cloudMetadataFeatureFlagCallbackID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to add cloud metadata onChange callback in cloud provider: %w", err)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,108 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cloudmeta "github.com/elastic/elastic-agent/internal/pkg/cloud"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/features"
+)
+
+func TestContextProvider(t *testing.T) {
+	// stand-in for the Azure instance metadata service
+	var probes atomic.Int32
+	azure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		if r.URL.Path != "/metadata/instance/compute" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"location": "westeurope", "vmId": "vm-id", "vmSize": "Standard_D2s_v3"}`))
+	}))
+	defer azure.Close()
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+
+	log, err := logger.New("cloud_test", false)
+	require.NoError(t, err)
+	setCloudMetadata(t, false)
+	c, err := config.NewConfigFrom(map[string]interface{}{
+		"check_interval": 10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	builder, _ := composable.Providers.GetContextProvider("cloud")
+	provider, err := builder(log, c, true)
+	require.NoError(t, err)
+	cloudProvider, _ := provider.(*contextProvider)
+	defer cloudProvider.Close()
+	require.Equal(t, 10*time.Minute, cloudProvider.CheckInterval)
+	cloudProvider.collector = cloudmeta.NewCollector(
+		cloudmeta.WithProviderURL("aws", notFound.URL),
+		cloudmeta.WithProviderURL("gcp", notFound.URL),
+		cloudmeta.WithProviderURL("azure", azure.URL),
+		cloudmeta.WithProviderURL("openstack", notFound.URL),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	comm := ctesting.NewContextComm(ctx)
+	setChan := make(chan map[string]interface{}, 1)
+	comm.CallOnSet(func(value map[string]interface{}) {
+		setChan <- value
+	})
+	go func() {
+		_ = provider.Run(ctx, comm)
+	}()
+
+	// the metadata services are not queried while the feature flag is disabled
+	var current map[string]interface{}
+	select {
+	case current = <-setChan:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for provider to call Set")
+	}
+	assert.Empty(t, current)
+	assert.Zero(t, probes.Load())
+
+	setCloudMetadata(t, true)
+	select {
+	case current = <-setChan:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for provider to call Set")
+	}
+	assert.Equal(t, map[string]interface{}{
+		"provider": "azure",
+		"region":   "westeurope",
+		"instance": map[string]interface{}{"id": "vm-id"},
+		"machine":  map[string]interface{}{"type": "Standard_D2s_v3"},
+	}, current)
+}
+
+func setCloudMetadata(t *testing.T, enabled bool) {
+	t.Helper()
+	c, err := config.NewConfigFrom(map[string]interface{}{
+		"agent.features.cloud_metadata.enabled": enabled,
+	})
+	require.NoError(t, err)
+	require.NoError(t, features.Apply(c))
+	t.Cleanup(func() {
+		c, _ := config.NewConfigFrom(map[string]interface{}{})
+		_ = features.Apply(c)
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -72,9 +73,37 @@ func LoadFullAgentConfig(ctx context.Context, logger *logger.Logger, cfgPath str
 	if err != nil {
 		return nil, fmt.Errorf("failed to merge latest fleet policy with local configuration: %w", err)
 	}
+
+	if cfg.Settings.Overlay.Enabled {
+		return mergeLocalOverlay(logger, rawConfig)
+	}
 	return rawConfig, nil
 }
 
+// mergeLocalOverlay merges the local overlay into the configuration like the running agent does,
+// the conflicts are logged.
+func mergeLocalOverlay(logger *logger.Logger, rawConfig *config.Config) (*config.Config, error) {
+	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine capabilities: %w", err)
+	}
+	overlay, err := LoadLocalOverlay(config.Discoverer(paths.ExternalInputs()), config.NewLoader(logger, paths.ExternalInputs()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the local overlay: %w", err)
+	}
+	if len(overlay) == 0 {
+		return rawConfig, nil
+	}
+	merged, conflicts, err := MergeLocalOverlay(rawConfig, overlay, caps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge the local overlay: %w", err)
+	}
+	for _, conflict := range conflicts {
+		logger.Warnf("Local overlay: %s", conflict)
+	}
+	return merged, nil
+}
+
 func loadConfig(ctx context.Context, configPath string) (*config.Config, error) {
 	rawConfig, err := config.LoadFile(configPath)
 	if err != nil {
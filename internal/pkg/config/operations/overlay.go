@@ -0,0 +1,107 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package operations
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// LoadLocalOverlay loads the inputs of the local overlay of a Fleet-managed agent, the inputs
+// of the files found by discover.
+func LoadLocalOverlay(discover config.DiscoverFunc, loader *config.Loader) ([]map[string]interface{}, error) {
+	files, err := discover()
+	if err != nil {
+		return nil, errors.New(err, "could not discover the local overlay files", errors.TypeConfig)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	cfg, err := loader.Load(files)
+	if err != nil {
+		return nil, err
+	}
+	overlay := struct {
+		Inputs []map[string]interface{} `config:"inputs"`
+	}{}
+	if err := cfg.Unpack(&overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse the local overlay inputs: %w", err)
+	}
+	return overlay.Inputs, nil
+}
+
+// MergeLocalOverlay merges the inputs of the local overlay of a Fleet-managed agent into the
+// policy. The capabilities define which input types and outputs the local inputs can use, the
+// inputs are labelled with component.SourceLocal. The inputs that can't be merged are left out
+// and returned as conflicts.
+func MergeLocalOverlay(policy *config.Config, overlay []map[string]interface{}, caps capabilities.Capabilities) (*config.Config, []string, error) {
+	policyMap, err := policy.ToMapStr()
+	if err != nil {
+		return nil, nil, err
+	}
+	outputs, _ := policyMap["outputs"].(map[string]interface{})
+	inputs, _ := policyMap["inputs"].([]interface{})
+
+	ids := make(map[string]bool, len(inputs)+len(overlay))
+	for _, input := range inputs {
+		if inputMap, ok := input.(map[string]interface{}); ok {
+			ids[inputID(inputMap)] = true
+		}
+	}
+
+	var conflicts []string
+	for _, input := range overlay {
+		id := inputID(input)
+		inputType, _ := input["type"].(string)
+		output, _ := input["use_output"].(string)
+		if output == "" {
+			output = "default"
+		}
+
+		switch {
+		case inputType == "":
+			conflicts = append(conflicts, fmt.Sprintf("local input %q has no type", id))
+		case !caps.AllowLocalInput(inputType):
+			conflicts = append(conflicts, fmt.Sprintf("local input %q: input type %q is not allowed", id, inputType))
+		case !caps.AllowLocalOutput(output):
+			conflicts = append(conflicts, fmt.Sprintf("local input %q: output %q is not allowed", id, output))
+		case outputs[output] == nil:
+			conflicts = append(conflicts, fmt.Sprintf("local input %q: output %q is not in the policy", id, output))
+		case ids[id]:
+			conflicts = append(conflicts, fmt.Sprintf("local input %q: the ID is already used", id))
+		default:
+			ids[id] = true
+			local := make(map[string]interface{}, len(input)+1)
+			for k, v := range input {
+				local[k] = v
+			}
+			local[component.SourceKey] = component.SourceLocal
+			inputs = append(inputs, local)
+		}
+	}
+	if len(inputs) > 0 {
+		policyMap["inputs"] = inputs
+	}
+
+	merged, err := config.NewConfigFrom(policyMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	return merged, conflicts, nil
+}
+
+// inputID returns the ID of the input, the input type when it doesn't have one, like the
+// component model does.
+func inputID(input map[string]interface{}) string {
+	if id, ok := input["id"].(string); ok && id != "" {
+		return id
+	}
+	inputType, _ := input["type"].(string)
+	return inputType
+}
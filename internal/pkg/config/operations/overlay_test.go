@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package operations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestMergeLocalOverlay(t *testing.T) {
+	caps, err := capabilities.Load(strings.NewReader(`
+capabilities:
+- rule: allow
+  local_input: filestream
+- rule: allow
+  local_output: default
+`), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err)
+	policy := config.MustNewConfigFrom(map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"default":    map[string]interface{}{"type": "elasticsearch"},
+			"monitoring": map[string]interface{}{"type": "elasticsearch"},
+		},
+		"inputs": []interface{}{
+			map[string]interface{}{"type": "system/metrics", "id": "system-metrics"},
+		},
+	})
+	overlay := []map[string]interface{}{
+		{"type": "filestream", "id": "nginx-logs"},
+		{"type": "log", "id": "legacy-logs"},
+		{"type": "filestream", "id": "audit-logs", "use_output": "monitoring"},
+		{"type": "filestream", "id": "system-metrics"},
+		{"type": "filestream", "id": "missing-output", "use_output": "other"},
+	}
+	merged, conflicts, err := MergeLocalOverlay(policy, overlay, caps)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`local input "legacy-logs": input type "log" is not allowed`,
+		`local input "audit-logs": output "monitoring" is not allowed`,
+		`local input "system-metrics": the ID is already used`,
+		`local input "missing-output": output "other" is not allowed`,
+	}, conflicts)
+
+	mergedMap, err := merged.ToMapStr()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "system/metrics", "id": "system-metrics"},
+		map[string]interface{}{"type": "filestream", "id": "nginx-logs", component.SourceKey: component.SourceLocal},
+	}, mergedMap["inputs"])
+}
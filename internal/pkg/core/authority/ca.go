@@ -28,11 +28,17 @@ type CertificateAuthority struct {
 	caPEM      []byte
 }
 
+// DefaultLifetime is the validity of the certificate authorities and of the certificates
+// generated without a lifetime.
+const DefaultLifetime = 10 * 365 * 24 * time.Hour
+
 // Pair is a x509 Key/Cert pair
 type Pair struct {
 	Crt         []byte
 	Key         []byte
 	Certificate *tls.Certificate
+	// NotAfter is the expiry of the certificate.
+	NotAfter time.Time
 }
 
 // NewCA creates a new certificate authority capable of generating child certificates
@@ -45,7 +51,7 @@ func NewCA() (*CertificateAuthority, error) {
 			CommonName:   "localhost",
 		},
 		NotBefore:             time.Now(),
-		NotAfter:              time.Now().AddDate(10, 0, 0),
+		NotAfter:              time.Now().Add(DefaultLifetime),
 		IsCA:                  true,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
@@ -103,16 +109,32 @@ func (c *CertificateAuthority) GeneratePair() (*Pair, error) {
 
 // GeneratePairWithName generates child certificate with provided name as the common name.
 func (c *CertificateAuthority) GeneratePairWithName(name string) (*Pair, error) {
+	return c.GeneratePairWithLifetime(name, DefaultLifetime)
+}
+
+// GeneratePairWithLifetime generates child certificate with provided name as the common name,
+// valid for lifetime. The certificate doesn't outlive the certificate authority.
+func (c *CertificateAuthority) GeneratePairWithLifetime(name string, lifetime time.Duration) (*Pair, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.New(err, "generating serial number", errors.TypeSecurity)
+	}
+	now := time.Now()
+	notAfter := now.Add(lifetime)
+	if notAfter.After(c.caCert.NotAfter) {
+		notAfter = c.caCert.NotAfter
+	}
+
 	// Prepare certificate
 	certTemplate := &x509.Certificate{
-		SerialNumber: big.NewInt(1658),
+		SerialNumber: serialNumber,
 		DNSNames:     []string{name},
 		Subject: pkix.Name{
 			Organization: []string{"elastic-fleet"},
 			CommonName:   name,
 		},
-		NotBefore:   time.Now(),
-		NotAfter:    time.Now().AddDate(10, 0, 0),
+		NotBefore:   now,
+		NotAfter:    notAfter,
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:    x509.KeyUsageDigitalSignature,
 	}
@@ -152,6 +174,7 @@ func (c *CertificateAuthority) GeneratePairWithName(name string) (*Pair, error)
 		Crt:         certOut.Bytes(),
 		Key:         keyOut.Bytes(),
 		Certificate: &tlsCert,
+		NotAfter:    notAfter,
 	}, nil
 }
 
@@ -159,3 +182,8 @@ func (c *CertificateAuthority) GeneratePairWithName(name string) (*Pair, error)
 func (c *CertificateAuthority) Crt() []byte {
 	return c.caPEM
 }
+
+// NotAfter returns the expiry of the certificate authority.
+func (c *CertificateAuthority) NotAfter() time.Time {
+	return c.caCert.NotAfter
+}
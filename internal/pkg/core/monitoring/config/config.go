@@ -15,6 +15,8 @@ const (
 	defaultPort      = 6791
 	defaultNamespace = "default"
 
+	defaultLivenessTimeout = 10 * time.Second
+
 	// DefaultHost is used when host is not defined or empty
 	DefaultHost = "localhost"
 )
@@ -41,6 +43,7 @@ type MonitoringHTTPConfig struct {
 	Host    string        `yaml:"host" config:"host"`
 	Port    int           `yaml:"port" config:"port" validate:"min=0,max=65535,nonzero"`
 	Buffer  *BufferConfig `yaml:"buffer" config:"buffer"`
+	Health  HealthConfig  `yaml:"health" config:"health"`
 }
 
 // Unpack reads a config object into the settings.
@@ -51,11 +54,13 @@ func (c *MonitoringHTTPConfig) Unpack(cfg *c.C) error {
 		Host    string        `yaml:"host" config:"host"`
 		Port    int           `yaml:"port" config:"port" validate:"min=0,max=65535,nonzero"`
 		Buffer  *BufferConfig `yaml:"buffer" config:"buffer"`
+		Health  HealthConfig  `yaml:"health" config:"health"`
 	}{
 		Enabled: c.Enabled,
 		Host:    c.Host,
 		Port:    c.Port,
 		Buffer:  c.Buffer,
+		Health:  c.Health,
 	}
 
 	if err := cfg.Unpack(&tmp); err != nil {
@@ -71,6 +76,7 @@ func (c *MonitoringHTTPConfig) Unpack(cfg *c.C) error {
 		Host:    tmp.Host,
 		Port:    tmp.Port,
 		Buffer:  tmp.Buffer,
+		Health:  tmp.Health,
 	}
 
 	return nil
@@ -83,6 +89,39 @@ type PprofConfig struct {
 	Enabled bool `yaml:"enabled" config:"enabled"`
 }
 
+// HealthConfig configures the failure criteria of the health probes served on
+// /liveness, /readiness and /startup.
+type HealthConfig struct {
+	Liveness  LivenessConfig  `yaml:"liveness" config:"liveness"`
+	Readiness ReadinessConfig `yaml:"readiness" config:"readiness"`
+}
+
+// LivenessConfig configures the liveness probe, it fails when the coordinator
+// doesn't answer within the timeout.
+type LivenessConfig struct {
+	Timeout time.Duration `yaml:"timeout" config:"timeout"`
+	// CheckState also fails the probe when the agent isn't healthy.
+	CheckState bool `yaml:"check_state" config:"check_state"`
+	// TolerateDegraded accepts a degraded agent when CheckState is set.
+	TolerateDegraded bool `yaml:"tolerate_degraded" config:"tolerate_degraded"`
+}
+
+// ReadinessConfig configures the readiness probe, it fails until the output
+// units are healthy.
+type ReadinessConfig struct {
+	// TolerateDegraded accepts degraded output units.
+	TolerateDegraded bool `yaml:"tolerate_degraded" config:"tolerate_degraded"`
+}
+
+// DefaultHealthConfig creates the default health probes configuration.
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		Liveness: LivenessConfig{
+			Timeout: defaultLivenessTimeout,
+		},
+	}
+}
+
 // BufferConfig is a struct for for the metrics buffer endpoint
 type BufferConfig struct {
 	Enabled bool `yaml:"enabled" config:"enabled"`
@@ -100,6 +139,7 @@ func DefaultConfig() *MonitoringConfig {
 			Enabled: false,
 			Host:    DefaultHost,
 			Port:    defaultPort,
+			Health:  DefaultHealthConfig(),
 		},
 		Namespace:   defaultNamespace,
 		APM:         defaultAPMConfig(),
@@ -0,0 +1,190 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package analyzer finds the known issues of the agents in their diagnostics bundles.
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity is the severity of a finding.
+type Severity string
+
+const (
+	// SeverityError is an issue breaking the ingestion.
+	SeverityError Severity = "error"
+	// SeverityWarning is an issue that may break the ingestion.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo is a detail worth looking at.
+	SeverityInfo Severity = "info"
+)
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Finding is an issue found in a bundle.
+type Finding struct {
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Component string   `json:"component,omitempty"`
+	Unit      string   `json:"unit,omitempty"`
+	Message   string   `json:"message"`
+	// Evidence are the lines of the bundle supporting the finding, like log messages.
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+// Rule finds a kind of issue in the bundles.
+type Rule interface {
+	// Name identifies the rule, to select it and in its findings.
+	Name() string
+	// Description explains the issues found by the rule.
+	Description() string
+	// Analyze returns the issues found in the bundle.
+	Analyze(b *Bundle) ([]Finding, error)
+}
+
+// Options are the thresholds of the built-in rules.
+type Options struct {
+	// Restarts is the number of restarts of a component found in the logs for a crash loop.
+	Restarts int
+	// Configuring is the time a unit can stay in the CONFIGURING state before being stuck.
+	Configuring time.Duration
+	// Goroutines is the number of goroutines of a process for a goroutine leak.
+	Goroutines int
+	// HeapInUse is the number of in-use heap bytes of a process reported by the heap rule.
+	HeapInUse int64
+}
+
+// DefaultOptions returns the default thresholds.
+func DefaultOptions() Options {
+	return Options{
+		Restarts:    3,
+		Configuring: 5 * time.Minute,
+		Goroutines:  10000,
+		HeapInUse:   1024 * 1024 * 1024,
+	}
+}
+
+// DefaultRules returns the built-in rules with the thresholds.
+func DefaultRules(opts Options) []Rule {
+	return []Rule{
+		&crashLoopRule{threshold: opts.Restarts},
+		&stuckConfiguringRule{timeout: opts.Configuring},
+		&outputAuthRule{},
+		&versionSkewRule{},
+		&goroutineLeakRule{threshold: opts.Goroutines},
+		&heapRule{threshold: opts.HeapInUse},
+		&componentMismatchRule{},
+	}
+}
+
+// SelectRules returns the rules with the names, in the order of the rules.
+func SelectRules(rules []Rule, names []string) ([]Rule, error) {
+	if len(names) == 0 {
+		return rules, nil
+	}
+	byName := make(map[string]Rule, len(rules))
+	for _, r := range rules {
+		byName[r.Name()] = r
+	}
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		selected[name] = true
+	}
+	var out []Rule
+	for _, r := range rules {
+		if selected[r.Name()] {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// Report is the result of the analysis of a bundle.
+type Report struct {
+	Bundle       string    `json:"bundle"`
+	CapturedAt   time.Time `json:"captured_at,omitempty"`
+	AgentVersion string    `json:"agent_version,omitempty"`
+	Findings     []Finding `json:"findings"`
+	// Errors are the files of the bundle and the rules that failed, the findings may be partial.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Analyze runs the rules on the bundle, the findings are sorted by severity. A failing rule
+// is reported in the errors of the report without stopping the analysis.
+func Analyze(b *Bundle, rules []Rule) Report {
+	report := Report{
+		Bundle:       b.Path,
+		CapturedAt:   b.CapturedAt,
+		AgentVersion: b.AgentVersion,
+		Findings:     []Finding{},
+		Errors:       append([]string(nil), b.Errors...),
+	}
+	for _, rule := range rules {
+		findings, err := rule.Analyze(b)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("rule %s: %s", rule.Name(), err))
+		}
+		for _, f := range findings {
+			f.Rule = rule.Name()
+			report.Findings = append(report.Findings, f)
+		}
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Severity.rank() < report.Findings[j].Severity.rank()
+	})
+	return report
+}
+
+// WriteHuman writes the report in a human readable form.
+func (r Report) WriteHuman(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Bundle: %s\n", r.Bundle)
+	if r.AgentVersion != "" {
+		fmt.Fprintf(&sb, "Agent version: %s\n", r.AgentVersion)
+	}
+	if !r.CapturedAt.IsZero() {
+		fmt.Fprintf(&sb, "Captured at: %s\n", r.CapturedAt.UTC().Format(time.RFC3339))
+	}
+	sb.WriteString("\n")
+	if len(r.Findings) == 0 {
+		sb.WriteString("No issues found.\n")
+	}
+	for _, f := range r.Findings {
+		fmt.Fprintf(&sb, "[%s] %s: %s\n", strings.ToUpper(string(f.Severity)), f.Rule, f.Message)
+		if f.Component != "" {
+			fmt.Fprintf(&sb, "    component: %s\n", f.Component)
+		}
+		if f.Unit != "" {
+			fmt.Fprintf(&sb, "    unit: %s\n", f.Unit)
+		}
+		for _, e := range f.Evidence {
+			fmt.Fprintf(&sb, "    > %s\n", e)
+		}
+	}
+	if len(r.Errors) > 0 {
+		sb.WriteString("\nThe analysis may be incomplete:\n")
+		for _, e := range r.Errors {
+			fmt.Fprintf(&sb, "  - %s\n", e)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
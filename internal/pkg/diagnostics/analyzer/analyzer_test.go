@@ -0,0 +1,241 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package analyzer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+var capturedAt = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func TestAnalyze(t *testing.T) {
+	b := testBundle(t, map[string][]byte{
+		"version.txt":              []byte("version: 8.12.0\ncommit: abc\nsnapshot: false\n"),
+		"state.yaml":               stateYAML(t),
+		"computed-config.yaml":     []byte("outputs:\n  default:\n    type: elasticsearch\n  monitoring:\n    type: elasticsearch\n"),
+		"components-expected.yaml": componentsYAML(t, testComponent("log-default", "log-default", "log-default-logfile"), testComponent("system/metrics-default", "system/metrics-default"), testComponent("http/metrics-monitoring", "http/metrics-monitoring")),
+		"components-actual.yaml":   componentsYAML(t, testComponent("log-default", "log-default", "log-default-logfile"), testComponent("system/metrics-default", "system/metrics-default", "system/metrics-default-cpu")),
+		"logs/elastic-agent-abc/elastic-agent-20240301.ndjson": logLines(
+			logLine(capturedAt.Add(-time.Hour), "info", "Unit state changed log-default-logfile (STARTING->CONFIGURING): Configuring", ""),
+			logLine(capturedAt.Add(-3*time.Minute), "info", "Component state changed system/metrics-default (HEALTHY->STOPPED): Suppressing FAILED state due to restart for '10' exited with code '2'", ""),
+			logLine(capturedAt.Add(-2*time.Minute), "info", "Component state changed system/metrics-default (STARTING->STOPPED): Suppressing FAILED state due to restart for '11' exited with code '2'", ""),
+			logLine(capturedAt.Add(-time.Minute), "error", "Component state changed system/metrics-default (STARTING->FAILED): Failed: pid '12' exited with code '2'", ""),
+			logLine(capturedAt.Add(-time.Minute), "error", "Failed to connect to backoff(elasticsearch(https://es:9200)): 401 Unauthorized: security_exception", "log-default"),
+			logLine(capturedAt.Add(-time.Minute), "info", "Non-zero metrics in the last 30s", "log-default"),
+			"not a json line",
+		),
+	})
+
+	report := Analyze(b, DefaultRules(DefaultOptions()))
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, "8.12.0", report.AgentVersion)
+
+	findings := map[string][]Finding{}
+	for _, f := range report.Findings {
+		findings[f.Rule] = append(findings[f.Rule], f)
+	}
+
+	require.Len(t, findings["crash-loop"], 1)
+	assert.Equal(t, "system/metrics-default", findings["crash-loop"][0].Component)
+	assert.Equal(t, "the component exited 3 times", findings["crash-loop"][0].Message)
+	assert.Len(t, findings["crash-loop"][0].Evidence, 3)
+
+	require.Len(t, findings["stuck-configuring"], 1)
+	assert.Equal(t, "input-log-default-logfile", findings["stuck-configuring"][0].Unit)
+	assert.Equal(t, SeverityError, findings["stuck-configuring"][0].Severity)
+	assert.Contains(t, findings["stuck-configuring"][0].Message, "1h0m0s before the capture")
+
+	require.Len(t, findings["output-auth"], 1)
+	assert.Equal(t, "log-default", findings["output-auth"][0].Component)
+	assert.Contains(t, findings["output-auth"][0].Message, `the output "default"`)
+	assert.Len(t, findings["output-auth"][0].Evidence, 2)
+
+	require.Len(t, findings["version-skew"], 1)
+	assert.Equal(t, "system/metrics-default", findings["version-skew"][0].Component)
+
+	mismatch := findings["component-mismatch"]
+	require.Len(t, mismatch, 2)
+	assert.Equal(t, "http/metrics-monitoring", mismatch[0].Component)
+	assert.Equal(t, SeverityError, mismatch[0].Severity)
+	assert.Equal(t, "system/metrics-default", mismatch[1].Component)
+	assert.Equal(t, []string{"unexpected units: system/metrics-default-cpu"}, mismatch[1].Evidence)
+
+	// errors first
+	assert.Equal(t, SeverityError, report.Findings[0].Severity)
+	assert.Equal(t, SeverityWarning, report.Findings[len(report.Findings)-1].Severity)
+}
+
+func TestAnalyzeProfiles(t *testing.T) {
+	var goroutines, heap bytes.Buffer
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(&goroutines, 0))
+	require.NoError(t, pprof.Lookup("heap").WriteTo(&heap, 0))
+	b := testBundle(t, map[string][]byte{
+		"goroutine.pprof.gz":                         goroutines.Bytes(),
+		"components/log-default/goroutine.pprof.gz":  goroutines.Bytes(),
+		"components/log-default/heap.pprof.gz":       heap.Bytes(),
+		"components/http-default/goroutine.pprof.gz": []byte("invalid"),
+	})
+
+	opts := DefaultOptions()
+	opts.Goroutines = 1
+	opts.HeapInUse = -1
+	rules, err := SelectRules(DefaultRules(opts), []string{"goroutine-leak", "heap"})
+	require.NoError(t, err)
+	report := Analyze(b, rules)
+
+	require.Len(t, report.Findings, 3)
+	assert.Equal(t, "goroutine-leak", report.Findings[0].Rule)
+	assert.Equal(t, "log-default", report.Findings[0].Component)
+	assert.NotEmpty(t, report.Findings[0].Evidence)
+	// the agent
+	assert.Equal(t, "", report.Findings[1].Component)
+	assert.Equal(t, "heap", report.Findings[2].Rule)
+	assert.Equal(t, "log-default", report.Findings[2].Component)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], "components/http-default/goroutine.pprof.gz")
+}
+
+func TestSelectRules(t *testing.T) {
+	rules := DefaultRules(DefaultOptions())
+	all, err := SelectRules(rules, nil)
+	require.NoError(t, err)
+	assert.Len(t, all, len(rules))
+
+	_, err = SelectRules(rules, []string{"crash-loop", "unknown"})
+	assert.ErrorContains(t, err, `unknown rule "unknown"`)
+}
+
+type failingRule struct{}
+
+func (failingRule) Name() string        { return "failing" }
+func (failingRule) Description() string { return "always fails" }
+func (failingRule) Analyze(*Bundle) ([]Finding, error) {
+	return []Finding{{Severity: SeverityInfo, Message: "partial"}}, fmt.Errorf("broken")
+}
+
+func TestAnalyzeCustomRule(t *testing.T) {
+	b := testBundle(t, map[string][]byte{"state.yaml": []byte("state: [")})
+	report := Analyze(b, []Rule{failingRule{}})
+
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "failing", report.Findings[0].Rule)
+	require.Len(t, report.Errors, 2)
+	assert.Contains(t, report.Errors[0], "state.yaml")
+	assert.Equal(t, "rule failing: broken", report.Errors[1])
+
+	var human bytes.Buffer
+	require.NoError(t, report.WriteHuman(&human))
+	assert.Contains(t, human.String(), "[INFO] failing: partial")
+	assert.Contains(t, human.String(), "The analysis may be incomplete")
+
+	out, err := json.Marshal(report)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"rule":"failing"`)
+}
+
+func testBundle(t *testing.T, files map[string][]byte) *Bundle {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: capturedAt})
+		require.NoError(t, err)
+		_, err = w.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return Load(zr)
+}
+
+// stateYAML marshals the state like the state diagnostic hook of the coordinator, with yaml.v2.
+func stateYAML(t *testing.T) []byte {
+	type stateComponent struct {
+		ID    string                 `yaml:"id"`
+		State runtime.ComponentState `yaml:"state"`
+	}
+	state := struct {
+		State      int              `yaml:"state"`
+		Message    string           `yaml:"message"`
+		Components []stateComponent `yaml:"components"`
+	}{
+		State:   3,
+		Message: "1 or more components/units in a degraded state",
+		Components: []stateComponent{
+			{
+				ID: "log-default",
+				State: runtime.ComponentState{
+					State: client.UnitStateHealthy,
+					Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+						{UnitType: client.UnitTypeInput, UnitID: "log-default-logfile"}: {State: client.UnitStateConfiguring, Message: "Configuring"},
+						{UnitType: client.UnitTypeOutput, UnitID: "log-default"}:        {State: client.UnitStateDegraded, Message: "401 Unauthorized"},
+					},
+					VersionInfo: runtime.ComponentVersionInfo{Name: "beat-v2-client", Version: "8.12.0-SNAPSHOT"},
+				},
+			},
+			{
+				ID: "system/metrics-default",
+				State: runtime.ComponentState{
+					State:       client.UnitStateFailed,
+					Message:     "Failed: pid '12' exited with code '2'",
+					VersionInfo: runtime.ComponentVersionInfo{Name: "beat-v2-client", Version: "8.11.3"},
+				},
+			},
+		},
+	}
+	out, err := yaml.Marshal(state)
+	require.NoError(t, err)
+	return out
+}
+
+func testComponent(id string, units ...string) component.Component {
+	c := component.Component{ID: id, InputType: strings.Split(id, "-")[0], OutputType: "elasticsearch"}
+	for _, u := range units {
+		c.Units = append(c.Units, component.Unit{ID: u})
+	}
+	return c
+}
+
+func componentsYAML(t *testing.T, comps ...component.Component) []byte {
+	out, err := yaml.Marshal(struct {
+		Components []component.Component `yaml:"components"`
+	}{Components: comps})
+	require.NoError(t, err)
+	return out
+}
+
+func logLine(ts time.Time, level, msg, comp string) string {
+	entry := map[string]interface{}{
+		"@timestamp": ts.Format(time.RFC3339Nano),
+		"log.level":  level,
+		"message":    msg,
+	}
+	if comp != "" {
+		entry["component"] = map[string]interface{}{"id": comp, "type": "filestream"}
+	}
+	out, _ := json.Marshal(entry)
+	return string(out)
+}
+
+func logLines(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,277 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package analyzer
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+const (
+	versionFile            = "version.txt"
+	stateFile              = "state.yaml"
+	computedConfigFile     = "computed-config.yaml"
+	componentsExpectedFile = "components-expected.yaml"
+	componentsActualFile   = "components-actual.yaml"
+
+	// maxLogLine is the longest log line read from the bundle, a longer line ends the reading
+	// of its file with an error.
+	maxLogLine = 1024 * 1024
+)
+
+// State is a state of the agent, of a component or of a unit. The bundles have the numeric
+// values of the states, the names are accepted as well.
+type State cproto.State
+
+// String returns the name of the state.
+func (s State) String() string {
+	return cproto.State(s).String()
+}
+
+// MarshalJSON marshals the state as its name.
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalYAML unmarshals the numeric value or the name of a state.
+func (s *State) UnmarshalYAML(value *yaml.Node) error {
+	var n int32
+	if err := value.Decode(&n); err == nil {
+		*s = State(n)
+		return nil
+	}
+	var name string
+	if err := value.Decode(&name); err != nil {
+		return err
+	}
+	n, ok := cproto.State_value[strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("unknown state %q", name)
+	}
+	*s = State(n)
+	return nil
+}
+
+// AgentState is the state of the agent and of its components, from state.yaml.
+type AgentState struct {
+	State      State            `yaml:"state"`
+	Message    string           `yaml:"message"`
+	Components []ComponentState `yaml:"components"`
+}
+
+// ComponentState is the state of a component and of its units.
+type ComponentState struct {
+	ID    string `yaml:"id"`
+	State struct {
+		State       State                `yaml:"state"`
+		Message     string               `yaml:"message"`
+		Units       map[string]UnitState `yaml:"units"`
+		VersionInfo struct {
+			Name    string `yaml:"name"`
+			Version string `yaml:"version"`
+		} `yaml:"version_info"`
+	} `yaml:"state"`
+}
+
+// UnitState is the state of a unit, keyed by "<type>-<id>" in the component state.
+type UnitState struct {
+	State   State  `yaml:"state"`
+	Message string `yaml:"message"`
+}
+
+// Component is a component of the expected or actual model.
+type Component struct {
+	ID         string `yaml:"id"`
+	InputType  string `yaml:"input_type"`
+	OutputType string `yaml:"output_type"`
+	Units      []struct {
+		ID string `yaml:"id"`
+	} `yaml:"units"`
+}
+
+// LogEntry is a line of the logs of the bundle.
+type LogEntry struct {
+	// Path is the path of the log file in the bundle.
+	Path string
+	// Time is zero when the line isn't a JSON entry with a timestamp.
+	Time      time.Time
+	Level     string
+	Message   string
+	Component string
+}
+
+// Bundle is a diagnostics bundle produced by the agent. The files missing from the bundle
+// leave their fields empty, their parsing errors are kept in Errors.
+type Bundle struct {
+	// Path is the path of the archive.
+	Path string
+	// CapturedAt is the time the state was written to the bundle, zero when unknown.
+	CapturedAt     time.Time
+	AgentVersion   string
+	State          *AgentState
+	ComputedConfig map[string]interface{}
+	Expected       []Component
+	Actual         []Component
+	// Errors are the files of the bundle that couldn't be read.
+	Errors []string
+
+	files map[string]*zip.File
+}
+
+// Open reads the diagnostics bundle at path. The logs and profiles are read when the rules
+// need them, the bundle must be closed once analyzed.
+func Open(path string) (*Bundle, io.Closer, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the diagnostics bundle %s: %w", path, err)
+	}
+	b := Load(&zr.Reader)
+	b.Path = path
+	return b, zr, nil
+}
+
+// Load reads the diagnostics bundle of the zip archive.
+func Load(zr *zip.Reader) *Bundle {
+	b := &Bundle{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		b.files[f.Name] = f
+	}
+
+	var version struct {
+		Version string `yaml:"version"`
+	}
+	if b.decode(versionFile, &version) {
+		b.AgentVersion = version.Version
+	}
+	var state AgentState
+	if b.decode(stateFile, &state) {
+		b.State = &state
+		b.CapturedAt = b.files[stateFile].Modified
+	}
+	b.decode(computedConfigFile, &b.ComputedConfig)
+	var expected, actual struct {
+		Components []Component `yaml:"components"`
+	}
+	if b.decode(componentsExpectedFile, &expected) {
+		b.Expected = expected.Components
+	}
+	if b.decode(componentsActualFile, &actual) {
+		b.Actual = actual.Components
+	}
+	return b
+}
+
+// decode decodes the YAML file of the bundle, it returns false when the file is missing or
+// invalid.
+func (b *Bundle) decode(name string, out interface{}) bool {
+	content, err := b.read(name)
+	if err != nil {
+		if _, ok := b.files[name]; ok {
+			b.Errors = append(b.Errors, fmt.Sprintf("%s: %s", name, err))
+		}
+		return false
+	}
+	if err := yaml.Unmarshal(content, out); err != nil {
+		b.Errors = append(b.Errors, fmt.Sprintf("%s: %s", name, err))
+		return false
+	}
+	return true
+}
+
+func (b *Bundle) read(name string) ([]byte, error) {
+	f, ok := b.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing from the bundle", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Files returns the paths of the files of the bundle with the suffix, sorted.
+func (b *Bundle) Files(suffix string) []string {
+	var names []string
+	for name, f := range b.files {
+		if !f.FileInfo().IsDir() && strings.HasSuffix(name, suffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile parses the pprof profile of the bundle at path.
+func (b *Bundle) Profile(path string) (*profile.Profile, error) {
+	f, ok := b.files[path]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing from the bundle", path)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return profile.Parse(rc)
+}
+
+// Logs calls fn with the lines of the ndjson logs of the bundle, file by file.
+func (b *Bundle) Logs(fn func(LogEntry)) error {
+	for _, path := range b.Files(".ndjson") {
+		if !strings.HasPrefix(path, "logs/") {
+			continue
+		}
+		if err := b.logs(path, fn); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (b *Bundle) logs(path string, fn func(LogEntry)) error {
+	rc, err := b.files[path].Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLine)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		entry := LogEntry{Path: path}
+		var e struct {
+			Timestamp time.Time `json:"@timestamp"`
+			Level     string    `json:"log.level"`
+			Message   string    `json:"message"`
+			Component struct {
+				ID string `json:"id"`
+			} `json:"component"`
+		}
+		if err := json.Unmarshal(line, &e); err == nil {
+			entry.Time = e.Timestamp
+			entry.Level = strings.ToLower(e.Level)
+			entry.Message = e.Message
+			entry.Component = e.Component.ID
+		} else {
+			entry.Message = string(line)
+		}
+		fn(entry)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,478 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+// maxEvidence is the number of lines supporting a finding.
+const maxEvidence = 3
+
+var (
+	exitedRe      = regexp.MustCompile(`^Component state changed (\S+) \(\w+->\w+\): (?:Suppressing FAILED state due to restart for|Failed: pid) '\d+' exited with code`)
+	configuringRe = regexp.MustCompile(`^(?:Unit|Component) state changed (\S+) \(\w+->CONFIGURING\)`)
+	authErrorRe   = regexp.MustCompile(`(?i)(\b40[13]\b|unauthori[sz]ed|forbidden|security_exception|authentication (failed|error|required)|failed to authenticate|(invalid|expired) api[ _]?key|api[ _]?key.{0,20}(invalid|expired|revoked))`)
+)
+
+// crashLoopRule finds the components exiting again and again, from the logs of the agent.
+type crashLoopRule struct {
+	threshold int
+}
+
+func (r *crashLoopRule) Name() string { return "crash-loop" }
+
+func (r *crashLoopRule) Description() string {
+	return fmt.Sprintf("components exiting %d times or more in the logs", r.threshold)
+}
+
+func (r *crashLoopRule) Analyze(b *Bundle) ([]Finding, error) {
+	exits := map[string][]string{}
+	err := b.Logs(func(e LogEntry) {
+		m := exitedRe.FindStringSubmatch(e.Message)
+		if m == nil {
+			return
+		}
+		exits[m[1]] = append(exits[m[1]], logEvidence(e))
+	})
+	var findings []Finding
+	for _, id := range sortedKeys(exits) {
+		if len(exits[id]) < r.threshold {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity:  SeverityError,
+			Component: id,
+			Message:   fmt.Sprintf("the component exited %d times", len(exits[id])),
+			Evidence:  lastN(exits[id], maxEvidence),
+		})
+	}
+	return findings, err
+}
+
+// stuckConfiguringRule finds the units in the CONFIGURING state for too long, the time they
+// entered it comes from the logs.
+type stuckConfiguringRule struct {
+	timeout time.Duration
+}
+
+func (r *stuckConfiguringRule) Name() string { return "stuck-configuring" }
+
+func (r *stuckConfiguringRule) Description() string {
+	return fmt.Sprintf("units in the CONFIGURING state for more than %s", r.timeout)
+}
+
+func (r *stuckConfiguringRule) Analyze(b *Bundle) ([]Finding, error) {
+	if b.State == nil {
+		return nil, nil
+	}
+	var configuring []Finding
+	for _, comp := range b.State.Components {
+		for _, key := range sortedKeys(comp.State.Units) {
+			if comp.State.Units[key].State != State(cproto.State_CONFIGURING) {
+				continue
+			}
+			configuring = append(configuring, Finding{
+				Component: comp.ID,
+				Unit:      key,
+				Evidence:  []string{comp.State.Units[key].Message},
+			})
+		}
+	}
+	if len(configuring) == 0 {
+		return nil, nil
+	}
+
+	since := map[string]time.Time{}
+	err := b.Logs(func(e LogEntry) {
+		m := configuringRe.FindStringSubmatch(e.Message)
+		if m != nil && e.Time.After(since[m[1]]) {
+			since[m[1]] = e.Time
+		}
+	})
+	var findings []Finding
+	for _, f := range configuring {
+		_, unitID := splitUnitKey(f.Unit)
+		entered, ok := since[unitID]
+		switch {
+		case !ok || b.CapturedAt.IsZero():
+			f.Severity = SeverityWarning
+			f.Message = "the unit was in the CONFIGURING state when the bundle was captured"
+		case b.CapturedAt.Sub(entered) >= r.timeout:
+			f.Severity = SeverityError
+			f.Message = fmt.Sprintf("the unit is in the CONFIGURING state since %s, %s before the capture",
+				entered.UTC().Format(time.RFC3339), b.CapturedAt.Sub(entered).Round(time.Second))
+		default:
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings, err
+}
+
+// outputAuthRule finds the components whose output fails to authenticate, from the state of
+// the output units and the logs of the components.
+type outputAuthRule struct{}
+
+func (r *outputAuthRule) Name() string { return "output-auth" }
+
+func (r *outputAuthRule) Description() string {
+	return "outputs rejecting the credentials of the components"
+}
+
+func (r *outputAuthRule) Analyze(b *Bundle) ([]Finding, error) {
+	evidence := map[string][]string{}
+	if b.State != nil {
+		for _, comp := range b.State.Components {
+			for _, key := range sortedKeys(comp.State.Units) {
+				unit := comp.State.Units[key]
+				if typ, _ := splitUnitKey(key); typ == "output" && authErrorRe.MatchString(unit.Message) {
+					evidence[comp.ID] = append(evidence[comp.ID], fmt.Sprintf("%s %s: %s", key, unit.State, unit.Message))
+				}
+			}
+		}
+	}
+	err := b.Logs(func(e LogEntry) {
+		if e.Component == "" || (e.Level != "error" && e.Level != "warn") || !authErrorRe.MatchString(e.Message) {
+			return
+		}
+		evidence[e.Component] = append(evidence[e.Component], logEvidence(e))
+	})
+
+	outputs := outputNames(b)
+	var findings []Finding
+	for _, id := range sortedKeys(evidence) {
+		output := "the output"
+		for _, name := range outputs {
+			if strings.HasSuffix(id, "-"+name) {
+				output = fmt.Sprintf("the output %q", name)
+				break
+			}
+		}
+		if typ := outputType(b, id); typ != "" {
+			output += " (" + typ + ")"
+		}
+		findings = append(findings, Finding{
+			Severity:  SeverityError,
+			Component: id,
+			Message:   fmt.Sprintf("%s rejects the credentials of the component, %d errors", output, len(evidence[id])),
+			Evidence:  lastN(evidence[id], maxEvidence),
+		})
+	}
+	return findings, err
+}
+
+// versionSkewRule finds the components running another version than the agent.
+type versionSkewRule struct{}
+
+func (r *versionSkewRule) Name() string { return "version-skew" }
+
+func (r *versionSkewRule) Description() string {
+	return "components running another version than the agent"
+}
+
+func (r *versionSkewRule) Analyze(b *Bundle) ([]Finding, error) {
+	if b.State == nil || b.AgentVersion == "" {
+		return nil, nil
+	}
+	agent := coreVersion(b.AgentVersion)
+	var findings []Finding
+	for _, comp := range b.State.Components {
+		version := comp.State.VersionInfo.Version
+		if version == "" || coreVersion(version) == agent {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity:  SeverityWarning,
+			Component: comp.ID,
+			Message:   fmt.Sprintf("%s %s runs with the agent %s", comp.State.VersionInfo.Name, version, b.AgentVersion),
+		})
+	}
+	return findings, nil
+}
+
+// goroutineLeakRule finds the processes with too many goroutines, from the goroutine profiles
+// of the agent and of the components.
+type goroutineLeakRule struct {
+	threshold int
+}
+
+func (r *goroutineLeakRule) Name() string { return "goroutine-leak" }
+
+func (r *goroutineLeakRule) Description() string {
+	return fmt.Sprintf("processes with more than %d goroutines", r.threshold)
+}
+
+func (r *goroutineLeakRule) Analyze(b *Bundle) ([]Finding, error) {
+	var findings []Finding
+	var errs []string
+	for _, p := range b.Files("goroutine.pprof.gz") {
+		prof, err := b.Profile(p)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", p, err))
+			continue
+		}
+		total, top := profileTotals(prof, 0)
+		if total <= int64(r.threshold) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity:  SeverityWarning,
+			Component: profileComponent(p),
+			Message:   fmt.Sprintf("%d goroutines are running, over %d", total, r.threshold),
+			Evidence:  top,
+		})
+	}
+	return findings, joinErrors(errs)
+}
+
+// heapRule finds the processes using too much heap, from the heap profiles of the agent and of
+// the components.
+type heapRule struct {
+	threshold int64
+}
+
+func (r *heapRule) Name() string { return "heap" }
+
+func (r *heapRule) Description() string {
+	return fmt.Sprintf("processes with more than %d bytes of in-use heap", r.threshold)
+}
+
+func (r *heapRule) Analyze(b *Bundle) ([]Finding, error) {
+	var findings []Finding
+	var errs []string
+	for _, p := range b.Files("heap.pprof.gz") {
+		prof, err := b.Profile(p)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", p, err))
+			continue
+		}
+		index := -1
+		for i, st := range prof.SampleType {
+			if st.Type == "inuse_space" {
+				index = i
+			}
+		}
+		if index < 0 {
+			continue
+		}
+		total, top := profileTotals(prof, index)
+		if total <= r.threshold {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity:  SeverityWarning,
+			Component: profileComponent(p),
+			Message:   fmt.Sprintf("%d bytes of heap are in use, over %d", total, r.threshold),
+			Evidence:  top,
+		})
+	}
+	return findings, joinErrors(errs)
+}
+
+// componentMismatchRule compares the expected components model with the running one.
+type componentMismatchRule struct{}
+
+func (r *componentMismatchRule) Name() string { return "component-mismatch" }
+
+func (r *componentMismatchRule) Description() string {
+	return "differences between the expected and the running components"
+}
+
+func (r *componentMismatchRule) Analyze(b *Bundle) ([]Finding, error) {
+	if _, ok := b.files[componentsExpectedFile]; !ok {
+		return nil, nil
+	}
+	if _, ok := b.files[componentsActualFile]; !ok {
+		return nil, nil
+	}
+	actual := make(map[string]Component, len(b.Actual))
+	for _, c := range b.Actual {
+		actual[c.ID] = c
+	}
+	expected := make(map[string]bool, len(b.Expected))
+	var findings []Finding
+	for _, exp := range b.Expected {
+		expected[exp.ID] = true
+		act, ok := actual[exp.ID]
+		if !ok {
+			findings = append(findings, Finding{
+				Severity:  SeverityError,
+				Component: exp.ID,
+				Message:   "the component is expected but isn't running",
+			})
+			continue
+		}
+		missing, extra := diffUnits(exp, act)
+		if len(missing) == 0 && len(extra) == 0 {
+			continue
+		}
+		f := Finding{
+			Severity:  SeverityWarning,
+			Component: exp.ID,
+			Message:   "the units of the running component differ from the expected ones",
+		}
+		if len(missing) > 0 {
+			f.Evidence = append(f.Evidence, "missing units: "+strings.Join(missing, ", "))
+		}
+		if len(extra) > 0 {
+			f.Evidence = append(f.Evidence, "unexpected units: "+strings.Join(extra, ", "))
+		}
+		findings = append(findings, f)
+	}
+	for _, act := range b.Actual {
+		if !expected[act.ID] {
+			findings = append(findings, Finding{
+				Severity:  SeverityWarning,
+				Component: act.ID,
+				Message:   "the component is running but isn't expected",
+			})
+		}
+	}
+	return findings, nil
+}
+
+func diffUnits(expected, actual Component) (missing, extra []string) {
+	units := map[string]bool{}
+	for _, u := range actual.Units {
+		units[u.ID] = true
+	}
+	for _, u := range expected.Units {
+		if !units[u.ID] {
+			missing = append(missing, u.ID)
+		}
+		delete(units, u.ID)
+	}
+	return missing, sortedKeys(units)
+}
+
+// profileTotals sums the values at index of the samples, and lists the top functions by
+// value. The top function of a sample is its first function outside of the Go runtime.
+func profileTotals(prof *profile.Profile, index int) (int64, []string) {
+	var total int64
+	byFunc := map[string]int64{}
+	for _, s := range prof.Sample {
+		if index >= len(s.Value) {
+			continue
+		}
+		total += s.Value[index]
+		byFunc[sampleFunction(s)] += s.Value[index]
+	}
+	funcs := sortedKeys(byFunc)
+	sort.SliceStable(funcs, func(i, j int) bool { return byFunc[funcs[i]] > byFunc[funcs[j]] })
+	var top []string
+	if len(funcs) > maxEvidence {
+		funcs = funcs[:maxEvidence]
+	}
+	for _, fn := range funcs {
+		top = append(top, fmt.Sprintf("%d in %s", byFunc[fn], fn))
+	}
+	return total, top
+}
+
+func sampleFunction(s *profile.Sample) string {
+	first := ""
+	for _, loc := range s.Location {
+		for _, line := range loc.Line {
+			if line.Function == nil {
+				continue
+			}
+			if first == "" {
+				first = line.Function.Name
+			}
+			if !strings.HasPrefix(line.Function.Name, "runtime.") {
+				return line.Function.Name
+			}
+		}
+	}
+	if first == "" {
+		return "unknown"
+	}
+	return first
+}
+
+// profileComponent returns the directory of the component of a profile, empty for the agent.
+func profileComponent(p string) string {
+	dir, found := strings.CutPrefix(path.Dir(p), "components/")
+	if !found {
+		return ""
+	}
+	comp, _, _ := strings.Cut(dir, "/")
+	return comp
+}
+
+// outputNames returns the names of the outputs of the computed configuration.
+func outputNames(b *Bundle) []string {
+	outputs, _ := b.ComputedConfig["outputs"].(map[string]interface{})
+	names := sortedKeys(outputs)
+	// the longest names first, for the names being the suffix of another one
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return names
+}
+
+func outputType(b *Bundle, id string) string {
+	for _, c := range b.Expected {
+		if c.ID == id {
+			return c.OutputType
+		}
+	}
+	return ""
+}
+
+// splitUnitKey splits the "<type>-<id>" key of a unit in the state.
+func splitUnitKey(key string) (string, string) {
+	typ, id, found := strings.Cut(key, "-")
+	if !found {
+		return "", key
+	}
+	return typ, id
+}
+
+// coreVersion removes the pre-release and the build metadata of a version.
+func coreVersion(v string) string {
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		return v[:i]
+	}
+	return v
+}
+
+func logEvidence(e LogEntry) string {
+	if e.Time.IsZero() {
+		return e.Message
+	}
+	return e.Time.UTC().Format(time.RFC3339) + " " + e.Message
+}
+
+func lastN(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -14,7 +14,6 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"reflect"
 	"runtime/pprof"
 	"strings"
 	"time"
@@ -161,11 +160,19 @@ func CreateCPUProfile(ctx context.Context, period time.Duration) ([]byte, error)
 }
 
 // ZipArchive creates a zipped diagnostics bundle using the passed writer with the passed diagnostics and local logs.
+// The files are redacted by the redactor, the default profile when nil, and the redactions are reported in
+// redaction-report.yaml.
 // If any error is encountered when writing the contents of the archive it is returned.
-func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, unitDiags []client.DiagnosticUnitResult, compDiags []client.DiagnosticComponentResult) error {
+func ZipArchive(errOut, w io.Writer, redactor *Redactor, agentDiag []client.DiagnosticFileResult, unitDiags []client.DiagnosticUnitResult, compDiags []client.DiagnosticComponentResult) error {
 	ts := time.Now().UTC()
 	zw := zip.NewWriter(w)
 	defer zw.Close()
+	if redactor == nil {
+		var err error
+		if redactor, err = NewRedactor(nil); err != nil {
+			return fmt.Errorf("error creating the default redactor: %w", err)
+		}
+	}
 	// Write agent diagnostics content
 	for _, ad := range agentDiag {
 		zf, err := zw.CreateHeader(&zip.FileHeader{
@@ -176,7 +183,7 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 		if err != nil {
 			return fmt.Errorf("error creating header for agent diagnostics: %w", err)
 		}
-		err = writeRedacted(errOut, zf, ad.Filename, ad)
+		err = writeRedacted(errOut, zf, redactor, ad.Filename, ad)
 		if err != nil {
 			return fmt.Errorf("error writing file for agent diagnostics: %w", err)
 		}
@@ -236,7 +243,7 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 					if err != nil {
 						return fmt.Errorf("error creating .zip header for %s: %w", res.Filename, err)
 					}
-					err = writeRedacted(errOut, resFileWriter, filePath, res)
+					err = writeRedacted(errOut, resFileWriter, redactor, filePath, res)
 					if err != nil {
 						return fmt.Errorf("error writing %s in zip file: %w", res.Filename, err)
 					}
@@ -273,7 +280,7 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 				if err != nil {
 					return err
 				}
-				err = writeRedacted(errOut, w, filePath, fr)
+				err = writeRedacted(errOut, w, redactor, filePath, fr)
 				if err != nil {
 					return err
 				}
@@ -282,7 +289,10 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 	}
 
 	// Gather Logs:
-	return zipLogs(zw, ts)
+	if err := zipLogs(zw, ts, redactor); err != nil {
+		return err
+	}
+	return writeRedactionReport(zw, ts, redactor)
 }
 
 func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
@@ -303,89 +313,35 @@ func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
 	return nil
 }
 
-func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResult client.DiagnosticFileResult) error {
-	out := &fileResult.Content
-
-	// Should we support json too?
-	if fileResult.ContentType == "application/yaml" {
-		unmarshalled := map[interface{}]interface{}{}
-		err := yaml.Unmarshal(fileResult.Content, &unmarshalled)
-		if err != nil {
-			// Best effort, output a warning but still include the file
-			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", fullFilePath, err)
-		} else {
-			redacted, err := yaml.Marshal(redactMap(errOut, unmarshalled))
-			if err != nil {
-				// Best effort, output a warning but still include the file
-				fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", fullFilePath, err)
-			} else {
-				out = &redacted
-			}
-		}
-	}
-
-	_, err := resultWriter.Write(*out)
+func writeRedacted(errOut, resultWriter io.Writer, redactor *Redactor, fullFilePath string, fileResult client.DiagnosticFileResult) error {
+	_, err := resultWriter.Write(redactor.redactFile(errOut, fullFilePath, fileResult.ContentType, fileResult.Content))
 	return err
 }
 
-// redactMap sensitive values from the underlying map
-// the whole generic function here is out of paranoia. Although extremely unlikely,
-// we have no way of guaranteeing we'll get a "normal" map[string]interface{},
-// since the diagnostic interface is a bit of a free-for-all
-func redactMap[K comparable](errOut io.Writer, inputMap map[K]interface{}) map[K]interface{} {
-	if inputMap == nil {
-		return nil
-	}
-	for rootKey, rootValue := range inputMap {
-		if rootValue != nil {
-			switch cast := rootValue.(type) {
-			case map[string]interface{}:
-				rootValue = redactMap(errOut, cast)
-			case map[interface{}]interface{}:
-				rootValue = redactMap(errOut, cast)
-			case map[int]interface{}:
-				rootValue = redactMap(errOut, cast)
-			case string:
-				if keyString, ok := any(rootKey).(string); ok {
-					if redactKey(keyString) {
-						rootValue = REDACTED
-					}
-				}
-			default:
-				// in cases where we got some weird kind of map we couldn't parse, print a warning
-				if reflect.TypeOf(rootValue).Kind() == reflect.Map {
-					fmt.Fprintf(errOut, "[WARNING]: file may be partly redacted, could not cast value %v of type %T", rootKey, rootValue)
-				}
-
-			}
-		}
-
-		inputMap[rootKey] = rootValue
-
+// writeRedactionReport writes the redactions made in the bundle.
+func writeRedactionReport(zw *zip.Writer, ts time.Time, redactor *Redactor) error {
+	report, err := yaml.Marshal(redactor.Report())
+	if err != nil {
+		return fmt.Errorf("error marshalling the redaction report: %w", err)
 	}
-	return inputMap
-}
-
-func redactKey(k string) bool {
-	// "routekey" shouldn't be redacted.
-	// Add any other exceptions here.
-	if k == "routekey" {
-		return false
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     RedactionReportFilename,
+		Method:   zip.Deflate,
+		Modified: ts,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating .zip header for %s: %w", RedactionReportFilename, err)
 	}
-
-	return strings.Contains(k, "certificate") ||
-		strings.Contains(k, "passphrase") ||
-		strings.Contains(k, "password") ||
-		strings.Contains(k, "token") ||
-		strings.Contains(k, "key")
+	_, err = w.Write(report)
+	return err
 }
 
-func zipLogs(zw *zip.Writer, ts time.Time) error {
+func zipLogs(zw *zip.Writer, ts time.Time, redactor *Redactor) error {
 	currentDir := fmt.Sprintf("%s-%s", agentName, release.ShortCommit())
 	if !paths.IsVersionHome() {
 		// running in a container with custom top path set
 		// logs are directly under top path
-		return zipLogsWithPath(paths.Home(), currentDir, true, zw, ts)
+		return zipLogsWithPath(paths.Home(), currentDir, true, zw, ts, redactor)
 	}
 
 	dataDir, err := os.Open(paths.Data())
@@ -406,7 +362,7 @@ func zipLogs(zw *zip.Writer, ts time.Time) error {
 		}
 		collectServices := dir == currentDir
 		path := filepath.Join(paths.Data(), dir)
-		if err := zipLogsWithPath(path, dir, collectServices, zw, ts); err != nil {
+		if err := zipLogsWithPath(path, dir, collectServices, zw, ts, redactor); err != nil {
 			return err
 		}
 	}
@@ -415,7 +371,7 @@ func zipLogs(zw *zip.Writer, ts time.Time) error {
 }
 
 // zipLogs walks paths.Logs() and copies the file structure into zw in "logs/"
-func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip.Writer, ts time.Time) error {
+func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip.Writer, ts time.Time, redactor *Redactor) error {
 	_, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     "logs/",
 		Method:   zip.Deflate,
@@ -426,7 +382,7 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip
 	}
 
 	if collectServices {
-		if err := collectServiceComponentsLogs(zw); err != nil {
+		if err := collectServiceComponentsLogs(zw, redactor); err != nil {
 			return fmt.Errorf("failed to collect endpoint-security logs: %w", err)
 		}
 	}
@@ -471,11 +427,11 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip
 			return nil
 		}
 
-		return saveLogs(name, path, zw)
+		return saveLogs(name, path, zw, redactor)
 	})
 }
 
-func collectServiceComponentsLogs(zw *zip.Writer) error {
+func collectServiceComponentsLogs(zw *zip.Writer, redactor *Redactor) error {
 	platform, err := component.LoadPlatformDetail()
 	if err != nil {
 		return fmt.Errorf("failed to gather system information: %w", err)
@@ -509,7 +465,7 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 				return nil
 			}
 
-			return saveLogs("services/"+name, path, zw)
+			return saveLogs("services/"+name, path, zw, redactor)
 		})
 		if err != nil {
 			return err
@@ -518,7 +474,8 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 	return nil
 }
 
-func saveLogs(name string, logPath string, zw *zip.Writer) error {
+// saveLogs copies the redacted log file to the archive.
+func saveLogs(name string, logPath string, zw *zip.Writer, redactor *Redactor) error {
 	ts := time.Now().UTC()
 	lf, err := os.Open(logPath)
 	if err != nil {
@@ -528,18 +485,14 @@ func saveLogs(name string, logPath string, zw *zip.Writer) error {
 	if li, err := lf.Stat(); err == nil {
 		ts = li.ModTime()
 	}
+	zipPath := "logs/" + filepath.ToSlash(name)
 	zf, err := zw.CreateHeader(&zip.FileHeader{
-		Name:     "logs/" + filepath.ToSlash(name),
+		Name:     zipPath,
 		Method:   zip.Deflate,
 		Modified: ts,
 	})
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(zf, lf)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return redactor.redactLog(zipPath, lf, zf)
 }
@@ -109,7 +109,7 @@ i4EFZLWrFRsAAAARYWxleGtAZ3JlbWluLm5lc3QBAg==
 	outWriter := strings.Builder{}
 	res := client.DiagnosticFileResult{Content: formatted, ContentType: "application/yaml"}
 
-	err = writeRedacted(&errOut, &outWriter, "test/path", res)
+	err = writeRedacted(&errOut, &outWriter, testRedactor(t), "test/path", res)
 	require.NoError(t, err)
 
 	require.Empty(t, errOut.String())
@@ -134,7 +134,7 @@ mapping:
 	outWriter := strings.Builder{}
 
 	res := client.DiagnosticFileResult{Content: []byte(testComplexKey), ContentType: "application/yaml"}
-	err := writeRedacted(&errOut, &outWriter, "test/path", res)
+	err := writeRedacted(&errOut, &outWriter, testRedactor(t), "test/path", res)
 	require.NoError(t, err)
 
 	require.Empty(t, errOut.String())
@@ -159,7 +159,7 @@ func TestUnitAndStateMapping(t *testing.T) {
 	outWriter := strings.Builder{}
 	res := client.DiagnosticFileResult{Content: formatted, ContentType: "application/yaml"}
 
-	err = writeRedacted(&errOut, &outWriter, "test/path", res)
+	err = writeRedacted(&errOut, &outWriter, testRedactor(t), "test/path", res)
 	require.NoError(t, err)
 
 	require.Empty(t, errOut.String())
@@ -177,7 +177,7 @@ func TestZipLogs(t *testing.T) {
 	// Zip the logs directory.
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)
-	require.NoError(t, zipLogs(w, time.Now()))
+	require.NoError(t, zipLogs(w, time.Now(), testRedactor(t)))
 	require.NoError(t, w.Close())
 
 	type zippedItem struct {
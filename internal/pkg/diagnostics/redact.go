@@ -0,0 +1,406 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package diagnostics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+const (
+	// RedactionProfileDefault is the profile redacting the credentials.
+	RedactionProfileDefault = configuration.DiagnosticsRedactionProfileDefault
+	// RedactionProfileStrict is the profile also redacting the hosts, users and addresses.
+	RedactionProfileStrict = configuration.DiagnosticsRedactionProfileStrict
+
+	// RedactionReportFilename is the file of the bundle reporting the redactions.
+	RedactionReportFilename = "redaction-report.yaml"
+
+	// secretGroup is the group of a value expression limiting the redaction to a part of the match.
+	secretGroup = "secret"
+)
+
+// RedactionPolicy configures the redaction of the diagnostics bundles, it's the redaction
+// policy of the agent configuration.
+type RedactionPolicy = configuration.DiagnosticsRedactionConfig
+
+// redactionProfiles are the built-in policies, one per name of
+// configuration.DiagnosticsRedactionProfiles.
+var redactionProfiles = map[string]RedactionPolicy{
+	RedactionProfileDefault: {
+		Profile: "",
+		Keys: []string{
+			`(?i)certificate`,
+			`(?i)passphrase`,
+			`(?i)passw(or)?d`,
+			`(?i)secret`,
+			`(?i)token`,
+			`(?i)credential`,
+			`(?i)authorization`,
+			`(?i)cookie`,
+			`(?i)(^|[_.-])(api|access|private)?_?key($|[_.-])`,
+		},
+		Values: []string{
+			// PEM blocks, certificates and private keys
+			`(?s)-----BEGIN [A-Z0-9 ]+-----.*?-----END [A-Z0-9 ]+-----`,
+			// credentials in URLs
+			`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@"']*:(?P<secret>[^/\s@"']+)@`,
+			// authorization headers
+			`(?i)\b(bearer|basic|apikey)\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`,
+			// secrets in query strings
+			`(?i)[?&](api_?key(_id)?|(access_)?token|secret|passw(or)?d|sig(nature)?)=(?P<secret>[^&\s"']+)`,
+		},
+		Allow: []string{
+			`^routekey$`,
+		},
+	},
+	RedactionProfileStrict: {
+		Profile: RedactionProfileDefault,
+		Keys: []string{
+			`(?i)^(user(name)?|email|hosts?|hostname|urls?)$`,
+		},
+		Values: []string{
+			// email addresses
+			`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+			// IPv4 addresses
+			`\b(25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])(\.(25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])){3}\b`,
+		},
+	},
+}
+
+// LoadRedactionPolicy returns the built-in profile with the name, or the policy of the YAML file
+// at the path.
+func LoadRedactionPolicy(profile string) (*RedactionPolicy, error) {
+	if _, ok := redactionProfiles[profile]; ok {
+		return &RedactionPolicy{Profile: profile}, nil
+	}
+	content, err := os.ReadFile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a redaction profile (%s) nor a readable policy file: %w",
+			profile, strings.Join(RedactionProfiles(), ", "), err)
+	}
+	var policy RedactionPolicy
+	if err := yaml.Unmarshal(content, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse the redaction policy %s: %w", profile, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid redaction policy %s: %w", profile, err)
+	}
+	return &policy, nil
+}
+
+// RedactionProfiles returns the names of the built-in profiles.
+func RedactionProfiles() []string {
+	return configuration.DiagnosticsRedactionProfiles()
+}
+
+// Redactor redacts the files of a bundle following a policy and reports the redactions.
+type Redactor struct {
+	profile string
+	keys    []*regexp.Regexp
+	values  []*regexp.Regexp
+	allow   []*regexp.Regexp
+
+	// report counts the redactions by file and rule.
+	report map[string]map[string]int
+}
+
+// NewRedactor compiles the policy, a nil policy is the default profile.
+func NewRedactor(policy *RedactionPolicy) (*Redactor, error) {
+	if policy == nil {
+		policy = &RedactionPolicy{}
+	}
+	r := &Redactor{report: make(map[string]map[string]int)}
+	if err := r.add(*policy, 0); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Redactor) add(policy RedactionPolicy, depth int) error {
+	profile := policy.Profile
+	if profile == "" && depth == 0 {
+		profile = RedactionProfileDefault
+	}
+	if profile != "" {
+		base, ok := redactionProfiles[profile]
+		if !ok {
+			return fmt.Errorf("unknown redaction profile %q, expected one of %s", profile, strings.Join(RedactionProfiles(), ", "))
+		}
+		if depth == 0 {
+			r.profile = profile
+		}
+		if err := r.add(base, depth+1); err != nil {
+			return err
+		}
+	}
+
+	compile := func(kind string, exprs []string, dst *[]*regexp.Regexp) error {
+		for _, expr := range exprs {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return fmt.Errorf("invalid redaction %s expression %q: %w", kind, expr, err)
+			}
+			*dst = append(*dst, re)
+		}
+		return nil
+	}
+	if err := compile("key", policy.Keys, &r.keys); err != nil {
+		return err
+	}
+	if err := compile("value", policy.Values, &r.values); err != nil {
+		return err
+	}
+	return compile("allow", policy.Allow, &r.allow)
+}
+
+// redactFile returns the redacted content of a file of the bundle. The YAML and JSON documents are
+// redacted by key and value, the text by value, the other content types are kept.
+func (r *Redactor) redactFile(errOut io.Writer, path, contentType string, content []byte) []byte {
+	switch {
+	case contentType == "application/yaml":
+		doc := map[interface{}]interface{}{}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			// Best effort, output a warning but still include the file
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", path, err)
+			return r.redactText(path, content)
+		}
+		redacted, err := yaml.Marshal(r.redactValue(errOut, path, "", doc, ""))
+		if err != nil {
+			// Best effort, output a warning but still include the file
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", path, err)
+			return r.redactText(path, content)
+		}
+		return redacted
+	case contentType == "application/json":
+		var doc interface{}
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", path, err)
+			return r.redactText(path, content)
+		}
+		var redacted bytes.Buffer
+		enc := json.NewEncoder(&redacted)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r.redactValue(errOut, path, "", doc, "")); err != nil {
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", path, err)
+			return r.redactText(path, content)
+		}
+		return redacted.Bytes()
+	case strings.HasPrefix(contentType, "text/"):
+		return r.redactText(path, content)
+	default:
+		return content
+	}
+}
+
+// redactLog copies the log file to w, redacting the keys and values of the ndjson lines and the
+// values of the other lines. The unchanged lines are copied as they are.
+func (r *Redactor) redactLog(path string, src io.Reader, w io.Writer) error {
+	br := bufio.NewReader(src)
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, err := w.Write(r.redactLogLine(path, line)); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+func (r *Redactor) redactLogLine(path string, line []byte) []byte {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+		if dec.Decode(&doc) == nil {
+			before := r.count(path)
+			redacted := r.redactValue(io.Discard, path, "", doc, "")
+			if r.count(path) == before {
+				return line
+			}
+			var out bytes.Buffer
+			enc := json.NewEncoder(&out)
+			enc.SetEscapeHTML(false)
+			if enc.Encode(redacted) == nil {
+				return out.Bytes()
+			}
+		}
+	}
+	return r.redactText(path, line)
+}
+
+// redactValue redacts the scalars of the matching keys and the matches of the value expressions
+// in the strings. keyRule is the expression matching key or one of its parents, empty when none
+// matches: all the scalars under a matching key are redacted.
+func (r *Redactor) redactValue(errOut io.Writer, path, key string, value interface{}, keyRule string) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = r.redactValue(errOut, path, k, item, r.childKeyRule(keyRule, k))
+		}
+		return v
+	case map[interface{}]interface{}:
+		for k, item := range v {
+			ks, _ := k.(string)
+			v[k] = r.redactValue(errOut, path, ks, item, r.childKeyRule(keyRule, ks))
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(errOut, path, key, item, keyRule)
+		}
+		return v
+	case string:
+		if keyRule != "" {
+			r.record(path, keyRule)
+			return REDACTED
+		}
+		return r.redactString(path, v)
+	case int, int64, uint64, float64, json.Number:
+		if keyRule != "" {
+			r.record(path, keyRule)
+			return REDACTED
+		}
+		return v
+	default:
+		// in cases where we got some weird kind of map we couldn't parse, print a warning
+		if reflect.TypeOf(v).Kind() == reflect.Map {
+			fmt.Fprintf(errOut, "[WARNING]: file may be partly redacted, could not cast value %v of type %T", key, v)
+		}
+		return v
+	}
+}
+
+// childKeyRule returns the rule redacting the values of the key under a parent redacted by
+// parentRule: the parent's rule, or the one of the key.
+func (r *Redactor) childKeyRule(parentRule, key string) string {
+	if parentRule != "" {
+		return parentRule
+	}
+	return r.sensitiveKey(key)
+}
+
+// sensitiveKey returns the key expression matching the key, empty when none matches or an
+// allow expression matches it.
+func (r *Redactor) sensitiveKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	for _, allow := range r.allow {
+		if allow.MatchString(key) {
+			return ""
+		}
+	}
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return "key " + re.String()
+		}
+	}
+	return ""
+}
+
+func (r *Redactor) redactString(path, s string) string {
+	return string(r.redactText(path, []byte(s)))
+}
+
+// redactText replaces the matches of the value expressions, or only their secret group.
+func (r *Redactor) redactText(path string, text []byte) []byte {
+	for _, re := range r.values {
+		group := re.SubexpIndex(secretGroup)
+		text = re.ReplaceAllFunc(text, func(match []byte) []byte {
+			r.record(path, "value "+re.String())
+			if group < 0 {
+				return []byte(REDACTED)
+			}
+			sub := re.FindSubmatchIndex(match)
+			if sub == nil || sub[2*group] < 0 {
+				return []byte(REDACTED)
+			}
+			redacted := make([]byte, 0, len(match))
+			redacted = append(redacted, match[:sub[2*group]]...)
+			redacted = append(redacted, REDACTED...)
+			return append(redacted, match[sub[2*group+1]:]...)
+		})
+	}
+	return text
+}
+
+func (r *Redactor) record(path, rule string) {
+	rules, ok := r.report[path]
+	if !ok {
+		rules = make(map[string]int)
+		r.report[path] = rules
+	}
+	rules[rule]++
+}
+
+func (r *Redactor) count(path string) int {
+	n := 0
+	for _, c := range r.report[path] {
+		n += c
+	}
+	return n
+}
+
+// RedactionReport lists the redactions of a bundle, without the redacted values.
+type RedactionReport struct {
+	Profile string           `yaml:"profile"`
+	Files   []FileRedactions `yaml:"files"`
+}
+
+// FileRedactions are the redactions of a file of the bundle, counted by rule.
+type FileRedactions struct {
+	Path  string         `yaml:"path"`
+	Rules map[string]int `yaml:"rules"`
+}
+
+// Total returns the number of redactions.
+func (r RedactionReport) Total() int {
+	n := 0
+	for _, f := range r.Files {
+		for _, c := range f.Rules {
+			n += c
+		}
+	}
+	return n
+}
+
+// Report returns the redactions made so far, sorted by file.
+func (r *Redactor) Report() RedactionReport {
+	report := RedactionReport{Profile: r.profile, Files: make([]FileRedactions, 0, len(r.report))}
+	for path, rules := range r.report {
+		copied := make(map[string]int, len(rules))
+		for rule, c := range rules {
+			copied[rule] = c
+		}
+		report.Files = append(report.Files, FileRedactions{Path: path, Rules: copied})
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Path < report.Files[j].Path })
+	return report
+}
@@ -21,6 +21,7 @@ FLOAT: [\-]? [0-9]+ '.' [0-9]+;
 NUMBER: [\-]? [0-9]+;
 WHITESPACE: [ \r\n\t]+ -> skip;
 NOT: 'NOT' | 'not';
+IN: 'in' | 'IN';
 NAME: [a-zA-Z_] [a-zA-Z0-9_]*;
 VNAME: [a-zA-Z0-9_\-/]+('.'[a-zA-Z0-9_\-/]+)*;
 STEXT: '\'' ~[\r\n']* '\'';
@@ -68,6 +69,7 @@ exp
 | left=exp GTE right=exp # ExpArithmeticGTE
 | left=exp LT right=exp # ExpArithmeticLT
 | left=exp GT right=exp # ExpArithmeticGT
+| left=exp NOT? IN right=exp # ExpIn
 | left=exp AND right=exp # ExpLogicalAnd
 | left=exp OR right=exp # ExpLogicalOR
 | boolean # ExpBoolean
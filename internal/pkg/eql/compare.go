@@ -365,6 +365,27 @@ func compareGTE(left, right operand) (bool, error) {
 	}
 }
 
+// compareIN returns true if left is equal to one of the values of the right array, values
+// of a type incompatible with left are not equal to it.
+func compareIN(left, right operand) (bool, error) {
+	switch r := right.(type) {
+	case *null:
+		return false, nil
+	case []interface{}:
+		for _, val := range r {
+			if b, err := compareEQ(left, val); err == nil && b {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf(
+		"in: incompatible type, right operand must be an array, left=%T, right=%T",
+		left,
+		right,
+	)
+}
+
 func logicalAND(left, right operand) (bool, error) {
 	switch l := left.(type) {
 	case bool:
@@ -10,9 +10,9 @@ package eql
 // parser is created, if you want to reuse the parsed tree see the `New` method.
 // If allowMissingVars is true, then variables not found in the VarStore will
 // evaluate to Null. Otherwise, they will produce an error.
-// Evaluation does not use logical short circuiting: for example,
-// the expression "${validVariable} or ${invalidVariable}" will generate
-// an error even if ${validVariable} is true.
+// Evaluation uses logical short circuiting: for example, the expression
+// "${validVariable} or ${invalidVariable}" will not generate an error
+// if ${validVariable} is true.
 func Eval(expression string, store VarStore, allowMissingVars bool) (bool, error) {
 	e, err := New(expression)
 	if err != nil {
@@ -222,13 +222,31 @@ func TestEql(t *testing.T) {
 		{expression: "((1 == 1) AND (2 == 2)) OR (2 != 3)", result: true},
 		{expression: "1 == 1 OR 2 == 2 AND 2 != 3", result: true},
 
-		// evaluation doesn't use logical short-circuits
+		// evaluation uses logical short-circuits
 		{expression: "${host.name} == 'asdf'", result: false},
-		{expression: "${host.name} == 'asdf' AND ${missing} == 'qwer'", err: true},
+		{expression: "${host.name} == 'asdf' AND ${missing} == 'qwer'", result: false},
 		{expression: "${host.name} == 'asdf' AND ${missing} == 'qwer'", allowMissingVars: true, result: false},
+		{expression: "${host.name} == 'host-name' AND ${missing} == 'qwer'", err: true},
 		{expression: "${host.name} == 'host-name'", result: true},
-		{expression: "${host.name} == 'host-name' OR ${missing} == 'qwer'", err: true},
+		{expression: "${host.name} == 'host-name' OR ${missing} == 'qwer'", result: true},
 		{expression: "${host.name} == 'host-name' OR ${missing} == 'qwer'", allowMissingVars: true, result: true},
+		{expression: "${host.name} == 'asdf' OR ${missing} == 'qwer'", err: true},
+		{expression: "${missing} == 'qwer' OR ${host.name} == 'host-name'", err: true},
+		{expression: "false AND length('hello', 'too many') == 5", err: true},
+
+		// in
+		{expression: "${host.name} in ['asdf', 'host-name']", result: true},
+		{expression: "${host.name} IN ['asdf', 'qwer']", result: false},
+		{expression: "${host.name} not in ['asdf', 'qwer']", result: true},
+		{expression: "${host.name} NOT IN ['asdf', 'host-name']", result: false},
+		{expression: "'array2' in ${data.array}", result: true},
+		{expression: "1 in [true, 'str', 1.0]", result: true},
+		{expression: "2 in []", result: false},
+		{expression: "1 + 1 in [2, 3] and 'a' not in ['b']", result: true},
+		{expression: "not ${host.name} in ['host-name'] == false", err: true},
+		{expression: "${missing} in ['asdf']", allowMissingVars: true, result: false},
+		{expression: "'asdf' not in ${missing}", allowMissingVars: true, result: true},
+		{expression: "'asdf' in 'asdf'", err: true},
 
 		// arrays
 		{expression: "[true, false, 1, 1.0, 'test'] == [true, false, 1, 1.0, 'test']", result: true},
@@ -344,6 +362,36 @@ func TestEql(t *testing.T) {
 		{expression: "stringContains(0, 'o w', 'too many')", err: true},
 		{expression: "stringContains('hello world', 0)", result: false},
 
+		{expression: "regexReplace('hello world', 'o', '0') == 'hell0 w0rld'", result: true},
+		{expression: "regexReplace(${host.name}, '^([a-z]+)-.*$', '${1}') == 'host'", result: true},
+		{expression: "regexReplace('hello', 0, '0')", err: true},
+		{expression: "regexReplace('hello', '[', '0')", err: true},
+		{expression: "regexReplace('hello', 'l')", err: true},
+
+		// net
+		{expression: "cidrMatch('10.1.2.3', '10.0.0.0/8')", result: true},
+		{expression: "cidrMatch('192.168.1.2', '10.0.0.0/8', '192.168.0.0/16')", result: true},
+		{expression: "cidrMatch('172.16.1.2', '10.0.0.0/8', '192.168.0.0/16')", result: false},
+		{expression: "cidrMatch('fd00::1', 'fd00::/8')", result: true},
+		{expression: "cidrMatch('not an ip', '10.0.0.0/8')", result: false},
+		{expression: "cidrMatch(${missing}, '10.0.0.0/8')", allowMissingVars: true, result: false},
+		{expression: "cidrMatch('10.1.2.3', '10.0.0.0')", err: true},
+		{expression: "cidrMatch(10, '10.0.0.0/8')", err: true},
+		{expression: "cidrMatch('10.1.2.3')", err: true},
+
+		// version
+		{expression: "semverCompare('8.12.1', '>=8.12.0')", result: true},
+		{expression: "semverCompare('8.11.4', '>=8.12.0')", result: false},
+		{expression: "semverCompare('8.12.0', '8.12.0')", result: true},
+		{expression: "semverCompare('8.12.0-SNAPSHOT', '<8.12.0')", result: true},
+		{expression: "semverCompare('8.12.0', '>=8.0.0, <9.0.0')", result: true},
+		{expression: "semverCompare('9.0.0', '>=8.0.0, <9.0.0')", result: false},
+		{expression: "semverCompare('8.12.0', '!=8.12.0')", result: false},
+		{expression: "semverCompare(${missing}, '>=8.12.0')", allowMissingVars: true, result: false},
+		{expression: "semverCompare('8.12', '>=8.12.0')", err: true},
+		{expression: "semverCompare('8.12.0', '>=8.12')", err: true},
+		{expression: "semverCompare('8.12.0')", err: true},
+
 		// Bad expression and malformed expression
 		{expression: "length('hello')", err: true},
 		{expression: "length()", err: true},
@@ -394,6 +442,22 @@ func TestEql(t *testing.T) {
 	}
 }
 
+func TestEqlNewChecksFunctionCalls(t *testing.T) {
+	testCases := map[string]string{
+		"donotexist()":                           "call to unknown function donotexist",
+		"length('hello', 'world') == 5":          "length: accepts exactly 1 argument; received 2",
+		"true or indexOf('hello')":               "indexOf: accepts 2-3 arguments; received 1",
+		"false and arrayContains(${data.array})": "arrayContains: accepts minimum 2 arguments; received 1",
+	}
+	for expression, msg := range testCases {
+		_, err := New(expression)
+		assert.ErrorContains(t, err, msg, "expression %q", expression)
+	}
+
+	_, err := New("concat() == '' and semverCompare(${agent.version}, '>=8.0.0')")
+	assert.NoError(t, err)
+}
+
 func debug(t *testing.T, expression string) {
 	raw := antlr.NewInputStream(expression)
 
@@ -41,9 +41,9 @@ type Expression struct {
 // Variable expressions that don't exist in the VarStore will evaluate to
 // Null, but will still be considered valid if allowMissingVars is true.
 // Otherwise they will return an error.
-// Evaluation does not use logical short circuiting: for example,
-// the expression "${validVariable} or ${invalidVariable}" will generate
-// an error even if ${validVariable} is true.
+// Evaluation uses logical short circuiting: for example, the expression
+// "${validVariable} or ${invalidVariable}" will not generate an error
+// if ${validVariable} is true.
 func (e *Expression) Eval(store VarStore, allowMissingVars bool) (result bool, err error) {
 	// Antlr can panic on errors so we have to recover somehow.
 	defer func() {
@@ -84,6 +84,13 @@ func New(expression string) (*Expression, error) {
 		return nil, errorListener.errors
 	}
 
+	// Report calls that would always fail before the expression is evaluated.
+	checker := &callChecker{}
+	antlr.ParseTreeWalkerDefault.Walk(checker, tree)
+	if checker.errors != nil {
+		return nil, checker.errors
+	}
+
 	return &Expression{expression: expression, tree: tree}, nil
 }
 
@@ -111,3 +118,33 @@ func (el *errorListener) SyntaxError(
 	el.errors = multierror.Append(el.errors,
 		fmt.Errorf("condition line %d column %d: %v", line, column, msg))
 }
+
+// callChecker is a listener that collects the calls to unknown functions and the calls
+// with a wrong number of arguments, for reporting from eql.New.
+type callChecker struct {
+	parser.BaseEqlListener
+
+	errors error
+}
+
+func (c *callChecker) EnterExpFunction(ctx *parser.ExpFunctionContext) {
+	name := ctx.NAME().GetText()
+	line := ctx.GetStart().GetLine()
+	column := ctx.GetStart().GetColumn()
+
+	m, ok := methods[name]
+	if !ok {
+		c.errors = multierror.Append(c.errors,
+			fmt.Errorf("condition line %d column %d: call to unknown function %s", line, column, name))
+		return
+	}
+
+	var n int
+	if ctx.Arguments() != nil {
+		n = len(ctx.Arguments().AllExp())
+	}
+	if !m.acceptsArgs(n) {
+		c.errors = multierror.Append(c.errors,
+			fmt.Errorf("condition line %d column %d: %s: %s; received %d", line, column, name, m.arityText(), n))
+	}
+}
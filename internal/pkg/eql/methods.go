@@ -4,35 +4,74 @@
 
 package eql
 
+import "fmt"
+
 // callFunc is a function called while the expression evaluation is done, the function is responsible
 // of doing the type conversion and allow checking the arity of the function.
 type callFunc func(args []interface{}) (interface{}, error)
 
+// unlimitedArgs is the maximum number of arguments of a method accepting any number of arguments.
+const unlimitedArgs = -1
+
+// method is a function callable in EQL with the number of arguments it accepts, the arity
+// is checked when the expression is parsed.
+type method struct {
+	call    callFunc
+	minArgs int
+	maxArgs int
+}
+
+// acceptsArgs returns true if the method can be called with n arguments.
+func (m method) acceptsArgs(n int) bool {
+	return n >= m.minArgs && (m.maxArgs == unlimitedArgs || n <= m.maxArgs)
+}
+
+// arityText describes the number of arguments the method accepts.
+func (m method) arityText() string {
+	switch {
+	case m.maxArgs == unlimitedArgs:
+		return fmt.Sprintf("accepts minimum %d arguments", m.minArgs)
+	case m.minArgs == m.maxArgs && m.minArgs == 1:
+		return "accepts exactly 1 argument"
+	case m.minArgs == m.maxArgs:
+		return fmt.Sprintf("accepts exactly %d arguments", m.minArgs)
+	default:
+		return fmt.Sprintf("accepts %d-%d arguments", m.minArgs, m.maxArgs)
+	}
+}
+
 // methods are the methods enabled in EQL.
-var methods = map[string]callFunc{
+var methods = map[string]method{
 	// array
-	"arrayContains": arrayContains,
+	"arrayContains": {arrayContains, 2, unlimitedArgs},
 
 	// dict
-	"hasKey": hasKey,
+	"hasKey": {hasKey, 2, unlimitedArgs},
 
 	// length:
-	"length": length,
+	"length": {length, 1, 1},
 
 	// math
-	"add":      add,
-	"subtract": subtract,
-	"multiply": multiply,
-	"divide":   divide,
-	"modulo":   modulo,
+	"add":      {add, 2, 2},
+	"subtract": {subtract, 2, 2},
+	"multiply": {multiply, 2, 2},
+	"divide":   {divide, 2, 2},
+	"modulo":   {modulo, 2, 2},
+
+	// net
+	"cidrMatch": {cidrMatch, 2, unlimitedArgs},
 
 	// str
-	"concat":         concat,
-	"endsWith":       endsWith,
-	"indexOf":        indexOf,
-	"match":          match,
-	"number":         number,
-	"startsWith":     startsWith,
-	"string":         str,
-	"stringContains": stringContains,
+	"concat":         {concat, 0, unlimitedArgs},
+	"endsWith":       {endsWith, 2, 2},
+	"indexOf":        {indexOf, 2, 3},
+	"match":          {match, 2, unlimitedArgs},
+	"number":         {number, 1, 2},
+	"regexReplace":   {regexReplace, 3, 3},
+	"startsWith":     {startsWith, 2, 2},
+	"string":         {str, 1, 1},
+	"stringContains": {stringContains, 2, 2},
+
+	// version
+	"semverCompare": {semverCompare, 2, 2},
 }
@@ -0,0 +1,41 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package eql
+
+import (
+	"fmt"
+	"net"
+)
+
+// cidrMatch returns true if the IP address is in any of the provided CIDR blocks.
+func cidrMatch(args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("cidrMatch: accepts minimum 2 arguments; received %d", len(args))
+	}
+	var ip net.IP
+	switch a := args[0].(type) {
+	case *null:
+		return false, nil
+	case string:
+		ip = net.ParseIP(a)
+	default:
+		return nil, fmt.Errorf("cidrMatch: first argument must be a string; received %T", args[0])
+	}
+
+	for i, arg := range args[1:] {
+		cidr, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("cidrMatch: argument %d must be a string; received %T", i+1, arg)
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("cidrMatch: argument %d is not a valid CIDR: %w", i+1, err)
+		}
+		if ip != nil && network.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
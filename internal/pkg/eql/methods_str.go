@@ -93,6 +93,22 @@ func number(args []interface{}) (interface{}, error) {
 	return int(n), nil
 }
 
+// regexReplace replaces the matches of the regular expression in the string with the replacement
+func regexReplace(args []interface{}) (interface{}, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("regexReplace: accepts exactly 3 arguments; received %d", len(args))
+	}
+	pattern, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("regexReplace: argument 1 must be a string; received %T", args[1])
+	}
+	exp, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("regexReplace: failed to compile regexp: %w", err)
+	}
+	return exp.ReplaceAllString(toString(args[0]), toString(args[2])), nil
+}
+
 // startsWith returns true if the string starts with given prefix
 func startsWith(args []interface{}) (interface{}, error) {
 	if len(args) != 2 {
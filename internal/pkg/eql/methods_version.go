@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package eql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elastic/elastic-agent/pkg/version"
+)
+
+// semverOperators are the operators of a semverCompare constraint, the longest first.
+var semverOperators = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// semverCompare returns true if the version satisfies the constraint, the constraint is a
+// comma separated list of comparisons like '>=8.12.0, <9.0.0' which must all be satisfied.
+func semverCompare(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("semverCompare: accepts exactly 2 arguments; received %d", len(args))
+	}
+	var v *version.ParsedSemVer
+	switch a := args[0].(type) {
+	case *null:
+		return false, nil
+	case string:
+		parsed, err := version.ParseVersion(a)
+		if err != nil {
+			return nil, fmt.Errorf("semverCompare: failed to parse version '%s': %w", a, err)
+		}
+		v = parsed
+	default:
+		return nil, fmt.Errorf("semverCompare: first argument must be a string; received %T", args[0])
+	}
+	constraint, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("semverCompare: argument 1 must be a string; received %T", args[1])
+	}
+
+	for _, c := range strings.Split(constraint, ",") {
+		satisfied, err := semverSatisfies(*v, strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("semverCompare: invalid constraint '%s': %w", constraint, err)
+		}
+		if !satisfied {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func semverSatisfies(v version.ParsedSemVer, constraint string) (bool, error) {
+	op := "=="
+	for _, o := range semverOperators {
+		if strings.HasPrefix(constraint, o) {
+			op = o
+			constraint = constraint[len(o):]
+			break
+		}
+	}
+	c, err := version.ParseVersion(constraint)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case ">=":
+		return !v.Less(*c), nil
+	case "<=":
+		return !c.Less(v), nil
+	case ">":
+		return c.Less(v), nil
+	case "<":
+		return v.Less(*c), nil
+	case "!=":
+		return v.Less(*c) || c.Less(v), nil
+	default:
+		return !v.Less(*c) && !c.Less(v), nil
+	}
+}
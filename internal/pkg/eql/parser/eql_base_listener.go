@@ -175,6 +175,12 @@ func (s *BaseEqlListener) EnterExpArithmeticLT(ctx *ExpArithmeticLTContext) {}
 // ExitExpArithmeticLT is called when production ExpArithmeticLT is exited.
 func (s *BaseEqlListener) ExitExpArithmeticLT(ctx *ExpArithmeticLTContext) {}
 
+// EnterExpIn is called when production ExpIn is entered.
+func (s *BaseEqlListener) EnterExpIn(ctx *ExpInContext) {}
+
+// ExitExpIn is called when production ExpIn is exited.
+func (s *BaseEqlListener) ExitExpIn(ctx *ExpInContext) {}
+
 // EnterArguments is called when production arguments is entered.
 func (s *BaseEqlListener) EnterArguments(ctx *ArgumentsContext) {}
 
@@ -112,6 +112,10 @@ func (v *BaseEqlVisitor) VisitExpArithmeticLT(ctx *ExpArithmeticLTContext) inter
 	return v.VisitChildren(ctx)
 }
 
+func (v *BaseEqlVisitor) VisitExpIn(ctx *ExpInContext) interface{} {
+	return v.VisitChildren(ctx)
+}
+
 func (v *BaseEqlVisitor) VisitArguments(ctx *ArgumentsContext) interface{} {
 	return v.VisitChildren(ctx)
 }
@@ -87,6 +87,9 @@ type EqlListener interface {
 	// EnterExpArithmeticLT is called when entering the ExpArithmeticLT production.
 	EnterExpArithmeticLT(c *ExpArithmeticLTContext)
 
+	// EnterExpIn is called when entering the ExpIn production.
+	EnterExpIn(c *ExpInContext)
+
 	// EnterArguments is called when entering the arguments production.
 	EnterArguments(c *ArgumentsContext)
 
@@ -174,6 +177,9 @@ type EqlListener interface {
 	// ExitExpArithmeticLT is called when exiting the ExpArithmeticLT production.
 	ExitExpArithmeticLT(c *ExpArithmeticLTContext)
 
+	// ExitExpIn is called when exiting the ExpIn production.
+	ExitExpIn(c *ExpInContext)
+
 	// ExitArguments is called when exiting the arguments production.
 	ExitArguments(c *ArgumentsContext)
 
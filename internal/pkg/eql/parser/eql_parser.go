@@ -39,13 +39,13 @@ func eqlParserInit() {
 	staticData.literalNames = []string{
 		"", "'|'", "','", "':'", "'=='", "'!='", "'>'", "'<'", "'>='", "'<='",
 		"'+'", "'-'", "'*'", "'/'", "'%'", "", "", "", "", "", "", "", "", "",
-		"", "", "", "'('", "')'", "'['", "']'", "'{'", "'}'", "'${'",
+		"", "", "", "", "'('", "')'", "'['", "']'", "'{'", "'}'", "'${'",
 	}
 	staticData.symbolicNames = []string{
 		"", "", "", "", "EQ", "NEQ", "GT", "LT", "GTE", "LTE", "ADD", "SUB",
 		"MUL", "DIV", "MOD", "AND", "OR", "TRUE", "FALSE", "FLOAT", "NUMBER",
-		"WHITESPACE", "NOT", "NAME", "VNAME", "STEXT", "DTEXT", "LPAR", "RPAR",
-		"LARR", "RARR", "LDICT", "RDICT", "BEGIN_VARIABLE",
+		"WHITESPACE", "NOT", "IN", "NAME", "VNAME", "STEXT", "DTEXT", "LPAR",
+		"RPAR", "LARR", "RARR", "LDICT", "RDICT", "BEGIN_VARIABLE",
 	}
 	staticData.ruleNames = []string{
 		"expList", "boolean", "constant", "variable", "variableExp", "exp",
@@ -53,7 +53,7 @@ func eqlParserInit() {
 	}
 	staticData.predictionContextCache = antlr.NewPredictionContextCache()
 	staticData.serializedATN = []int32{
-		4, 1, 33, 142, 2, 0, 7, 0, 2, 1, 7, 1, 2, 2, 7, 2, 2, 3, 7, 3, 2, 4, 7,
+		4, 1, 34, 148, 2, 0, 7, 0, 2, 1, 7, 1, 2, 2, 7, 2, 2, 3, 7, 3, 2, 4, 7,
 		4, 2, 5, 7, 5, 2, 6, 7, 6, 2, 7, 7, 7, 2, 8, 7, 8, 2, 9, 7, 9, 1, 0, 1,
 		0, 1, 0, 1, 1, 1, 1, 1, 2, 1, 2, 1, 2, 1, 2, 1, 2, 3, 2, 31, 8, 2, 1, 3,
 		1, 3, 1, 3, 3, 3, 36, 8, 3, 1, 4, 1, 4, 1, 4, 5, 4, 41, 8, 4, 10, 4, 12,
@@ -66,44 +66,45 @@ func eqlParserInit() {
 		112, 9, 5, 1, 6, 1, 6, 1, 6, 5, 6, 117, 8, 6, 10, 6, 12, 6, 120, 9, 6,
 		1, 7, 1, 7, 1, 7, 5, 7, 125, 8, 7, 10, 7, 12, 7, 128, 9, 7, 1, 8, 1, 8,
 		1, 8, 1, 8, 1, 9, 1, 9, 1, 9, 5, 9, 137, 8, 9, 10, 9, 12, 9, 140, 9, 9,
-		1, 9, 0, 1, 10, 10, 0, 2, 4, 6, 8, 10, 12, 14, 16, 18, 0, 5, 1, 0, 17,
-		18, 1, 0, 25, 26, 1, 0, 12, 14, 1, 0, 10, 11, 2, 0, 23, 23, 25, 26, 163,
-		0, 20, 1, 0, 0, 0, 2, 23, 1, 0, 0, 0, 4, 30, 1, 0, 0, 0, 6, 35, 1, 0, 0,
-		0, 8, 37, 1, 0, 0, 0, 10, 76, 1, 0, 0, 0, 12, 113, 1, 0, 0, 0, 14, 121,
-		1, 0, 0, 0, 16, 129, 1, 0, 0, 0, 18, 133, 1, 0, 0, 0, 20, 21, 3, 10, 5,
-		0, 21, 22, 5, 0, 0, 1, 22, 1, 1, 0, 0, 0, 23, 24, 7, 0, 0, 0, 24, 3, 1,
-		0, 0, 0, 25, 31, 5, 25, 0, 0, 26, 31, 5, 26, 0, 0, 27, 31, 5, 19, 0, 0,
-		28, 31, 5, 20, 0, 0, 29, 31, 3, 2, 1, 0, 30, 25, 1, 0, 0, 0, 30, 26, 1,
-		0, 0, 0, 30, 27, 1, 0, 0, 0, 30, 28, 1, 0, 0, 0, 30, 29, 1, 0, 0, 0, 31,
-		5, 1, 0, 0, 0, 32, 36, 5, 23, 0, 0, 33, 36, 5, 24, 0, 0, 34, 36, 3, 4,
-		2, 0, 35, 32, 1, 0, 0, 0, 35, 33, 1, 0, 0, 0, 35, 34, 1, 0, 0, 0, 36, 7,
-		1, 0, 0, 0, 37, 42, 3, 6, 3, 0, 38, 39, 5, 1, 0, 0, 39, 41, 3, 6, 3, 0,
-		40, 38, 1, 0, 0, 0, 41, 44, 1, 0, 0, 0, 42, 40, 1, 0, 0, 0, 42, 43, 1,
-		0, 0, 0, 43, 9, 1, 0, 0, 0, 44, 42, 1, 0, 0, 0, 45, 46, 6, 5, -1, 0, 46,
-		47, 5, 27, 0, 0, 47, 48, 3, 10, 5, 0, 48, 49, 5, 28, 0, 0, 49, 77, 1, 0,
-		0, 0, 50, 51, 5, 22, 0, 0, 51, 77, 3, 10, 5, 17, 52, 77, 3, 2, 1, 0, 53,
-		54, 5, 33, 0, 0, 54, 55, 3, 8, 4, 0, 55, 56, 5, 32, 0, 0, 56, 77, 1, 0,
-		0, 0, 57, 58, 5, 23, 0, 0, 58, 60, 5, 27, 0, 0, 59, 61, 3, 12, 6, 0, 60,
-		59, 1, 0, 0, 0, 60, 61, 1, 0, 0, 0, 61, 62, 1, 0, 0, 0, 62, 77, 5, 28,
-		0, 0, 63, 65, 5, 29, 0, 0, 64, 66, 3, 14, 7, 0, 65, 64, 1, 0, 0, 0, 65,
-		66, 1, 0, 0, 0, 66, 67, 1, 0, 0, 0, 67, 77, 5, 30, 0, 0, 68, 70, 5, 31,
-		0, 0, 69, 71, 3, 18, 9, 0, 70, 69, 1, 0, 0, 0, 70, 71, 1, 0, 0, 0, 71,
-		72, 1, 0, 0, 0, 72, 77, 5, 32, 0, 0, 73, 77, 7, 1, 0, 0, 74, 77, 5, 19,
-		0, 0, 75, 77, 5, 20, 0, 0, 76, 45, 1, 0, 0, 0, 76, 50, 1, 0, 0, 0, 76,
-		52, 1, 0, 0, 0, 76, 53, 1, 0, 0, 0, 76, 57, 1, 0, 0, 0, 76, 63, 1, 0, 0,
-		0, 76, 68, 1, 0, 0, 0, 76, 73, 1, 0, 0, 0, 76, 74, 1, 0, 0, 0, 76, 75,
-		1, 0, 0, 0, 77, 110, 1, 0, 0, 0, 78, 79, 10, 19, 0, 0, 79, 80, 7, 2, 0,
-		0, 80, 109, 3, 10, 5, 20, 81, 82, 10, 18, 0, 0, 82, 83, 7, 3, 0, 0, 83,
-		109, 3, 10, 5, 19, 84, 85, 10, 16, 0, 0, 85, 86, 5, 4, 0, 0, 86, 109, 3,
-		10, 5, 17, 87, 88, 10, 15, 0, 0, 88, 89, 5, 5, 0, 0, 89, 109, 3, 10, 5,
-		16, 90, 91, 10, 14, 0, 0, 91, 92, 5, 9, 0, 0, 92, 109, 3, 10, 5, 15, 93,
-		94, 10, 13, 0, 0, 94, 95, 5, 8, 0, 0, 95, 109, 3, 10, 5, 14, 96, 97, 10,
-		12, 0, 0, 97, 98, 5, 7, 0, 0, 98, 109, 3, 10, 5, 13, 99, 100, 10, 11, 0,
-		0, 100, 101, 5, 6, 0, 0, 101, 109, 3, 10, 5, 12, 102, 103, 10, 10, 0, 0,
-		103, 104, 5, 15, 0, 0, 104, 109, 3, 10, 5, 11, 105, 106, 10, 9, 0, 0, 106,
-		107, 5, 16, 0, 0, 107, 109, 3, 10, 5, 10, 108, 78, 1, 0, 0, 0, 108, 81,
-		1, 0, 0, 0, 108, 84, 1, 0, 0, 0, 108, 87, 1, 0, 0, 0, 108, 90, 1, 0, 0,
-		0, 108, 93, 1, 0, 0, 0, 108, 96, 1, 0, 0, 0, 108, 99, 1, 0, 0, 0, 108,
+		1, 9, 1, 5, 3, 5, 145, 1, 5, 8, 5, 1, 5, 1, 5, 0, 1, 10, 10, 0, 2, 4, 6,
+		8, 10, 12, 14, 16, 18, 0, 5, 1, 0, 17, 18, 1, 0, 26, 27, 1, 0, 12, 14,
+		1, 0, 10, 11, 2, 0, 24, 24, 26, 27, 171, 0, 20, 1, 0, 0, 0, 2, 23, 1, 0,
+		0, 0, 4, 30, 1, 0, 0, 0, 6, 35, 1, 0, 0, 0, 8, 37, 1, 0, 0, 0, 10, 76,
+		1, 0, 0, 0, 12, 113, 1, 0, 0, 0, 14, 121, 1, 0, 0, 0, 16, 129, 1, 0, 0,
+		0, 18, 133, 1, 0, 0, 0, 20, 21, 3, 10, 5, 0, 21, 22, 5, 0, 0, 1, 22, 1,
+		1, 0, 0, 0, 23, 24, 7, 0, 0, 0, 24, 3, 1, 0, 0, 0, 25, 31, 5, 26, 0, 0,
+		26, 31, 5, 27, 0, 0, 27, 31, 5, 19, 0, 0, 28, 31, 5, 20, 0, 0, 29, 31,
+		3, 2, 1, 0, 30, 25, 1, 0, 0, 0, 30, 26, 1, 0, 0, 0, 30, 27, 1, 0, 0, 0,
+		30, 28, 1, 0, 0, 0, 30, 29, 1, 0, 0, 0, 31, 5, 1, 0, 0, 0, 32, 36, 5, 24,
+		0, 0, 33, 36, 5, 25, 0, 0, 34, 36, 3, 4, 2, 0, 35, 32, 1, 0, 0, 0, 35,
+		33, 1, 0, 0, 0, 35, 34, 1, 0, 0, 0, 36, 7, 1, 0, 0, 0, 37, 42, 3, 6, 3,
+		0, 38, 39, 5, 1, 0, 0, 39, 41, 3, 6, 3, 0, 40, 38, 1, 0, 0, 0, 41, 44,
+		1, 0, 0, 0, 42, 40, 1, 0, 0, 0, 42, 43, 1, 0, 0, 0, 43, 9, 1, 0, 0, 0,
+		44, 42, 1, 0, 0, 0, 45, 46, 6, 5, -1, 0, 46, 47, 5, 28, 0, 0, 47, 48, 3,
+		10, 5, 0, 48, 49, 5, 29, 0, 0, 49, 77, 1, 0, 0, 0, 50, 51, 5, 22, 0, 0,
+		51, 77, 3, 10, 5, 18, 52, 77, 3, 2, 1, 0, 53, 54, 5, 34, 0, 0, 54, 55,
+		3, 8, 4, 0, 55, 56, 5, 33, 0, 0, 56, 77, 1, 0, 0, 0, 57, 58, 5, 24, 0,
+		0, 58, 60, 5, 28, 0, 0, 59, 61, 3, 12, 6, 0, 60, 59, 1, 0, 0, 0, 60, 61,
+		1, 0, 0, 0, 61, 62, 1, 0, 0, 0, 62, 77, 5, 29, 0, 0, 63, 65, 5, 30, 0,
+		0, 64, 66, 3, 14, 7, 0, 65, 64, 1, 0, 0, 0, 65, 66, 1, 0, 0, 0, 66, 67,
+		1, 0, 0, 0, 67, 77, 5, 31, 0, 0, 68, 70, 5, 32, 0, 0, 69, 71, 3, 18, 9,
+		0, 70, 69, 1, 0, 0, 0, 70, 71, 1, 0, 0, 0, 71, 72, 1, 0, 0, 0, 72, 77,
+		5, 33, 0, 0, 73, 77, 7, 1, 0, 0, 74, 77, 5, 19, 0, 0, 75, 77, 5, 20, 0,
+		0, 76, 45, 1, 0, 0, 0, 76, 50, 1, 0, 0, 0, 76, 52, 1, 0, 0, 0, 76, 53,
+		1, 0, 0, 0, 76, 57, 1, 0, 0, 0, 76, 63, 1, 0, 0, 0, 76, 68, 1, 0, 0, 0,
+		76, 73, 1, 0, 0, 0, 76, 74, 1, 0, 0, 0, 76, 75, 1, 0, 0, 0, 77, 110, 1,
+		0, 0, 0, 78, 79, 10, 20, 0, 0, 79, 80, 7, 2, 0, 0, 80, 109, 3, 10, 5, 21,
+		81, 82, 10, 19, 0, 0, 82, 83, 7, 3, 0, 0, 83, 109, 3, 10, 5, 20, 84, 85,
+		10, 17, 0, 0, 85, 86, 5, 4, 0, 0, 86, 109, 3, 10, 5, 18, 87, 88, 10, 16,
+		0, 0, 88, 89, 5, 5, 0, 0, 89, 109, 3, 10, 5, 17, 90, 91, 10, 15, 0, 0,
+		91, 92, 5, 9, 0, 0, 92, 109, 3, 10, 5, 16, 93, 94, 10, 14, 0, 0, 94, 95,
+		5, 8, 0, 0, 95, 109, 3, 10, 5, 15, 96, 97, 10, 13, 0, 0, 97, 98, 5, 7,
+		0, 0, 98, 109, 3, 10, 5, 14, 99, 100, 10, 12, 0, 0, 100, 101, 5, 6, 0,
+		0, 101, 109, 3, 10, 5, 13, 102, 103, 10, 10, 0, 0, 103, 104, 5, 15, 0,
+		0, 104, 109, 3, 10, 5, 11, 105, 106, 10, 9, 0, 0, 106, 107, 5, 16, 0, 0,
+		107, 109, 3, 10, 5, 10, 108, 78, 1, 0, 0, 0, 108, 81, 1, 0, 0, 0, 108,
+		84, 1, 0, 0, 0, 108, 87, 1, 0, 0, 0, 108, 90, 1, 0, 0, 0, 108, 93, 1, 0,
+		0, 0, 108, 96, 1, 0, 0, 0, 108, 99, 1, 0, 0, 0, 108, 142, 1, 0, 0, 0, 108,
 		102, 1, 0, 0, 0, 108, 105, 1, 0, 0, 0, 109, 112, 1, 0, 0, 0, 110, 108,
 		1, 0, 0, 0, 110, 111, 1, 0, 0, 0, 111, 11, 1, 0, 0, 0, 112, 110, 1, 0,
 		0, 0, 113, 118, 3, 10, 5, 0, 114, 115, 5, 2, 0, 0, 115, 117, 3, 10, 5,
@@ -115,8 +116,10 @@ func eqlParserInit() {
 		3, 0, 0, 131, 132, 3, 4, 2, 0, 132, 17, 1, 0, 0, 0, 133, 138, 3, 16, 8,
 		0, 134, 135, 5, 2, 0, 0, 135, 137, 3, 16, 8, 0, 136, 134, 1, 0, 0, 0, 137,
 		140, 1, 0, 0, 0, 138, 136, 1, 0, 0, 0, 138, 139, 1, 0, 0, 0, 139, 19, 1,
-		0, 0, 0, 140, 138, 1, 0, 0, 0, 12, 30, 35, 42, 60, 65, 70, 76, 108, 110,
-		118, 126, 138,
+		0, 0, 0, 140, 138, 1, 0, 0, 0, 142, 143, 10, 11, 0, 0, 143, 144, 1, 0,
+		0, 0, 143, 145, 1, 0, 0, 0, 144, 145, 5, 22, 0, 0, 145, 146, 1, 0, 0, 0,
+		146, 147, 5, 23, 0, 0, 147, 109, 3, 10, 5, 12, 13, 30, 35, 42, 60, 65,
+		70, 76, 108, 110, 118, 126, 138, 143,
 	}
 	deserializer := antlr.NewATNDeserializer(nil)
 	staticData.atn = deserializer.Deserialize(staticData.serializedATN)
@@ -177,17 +180,18 @@ const (
 	EqlParserNUMBER         = 20
 	EqlParserWHITESPACE     = 21
 	EqlParserNOT            = 22
-	EqlParserNAME           = 23
-	EqlParserVNAME          = 24
-	EqlParserSTEXT          = 25
-	EqlParserDTEXT          = 26
-	EqlParserLPAR           = 27
-	EqlParserRPAR           = 28
-	EqlParserLARR           = 29
-	EqlParserRARR           = 30
-	EqlParserLDICT          = 31
-	EqlParserRDICT          = 32
-	EqlParserBEGIN_VARIABLE = 33
+	EqlParserIN             = 23
+	EqlParserNAME           = 24
+	EqlParserVNAME          = 25
+	EqlParserSTEXT          = 26
+	EqlParserDTEXT          = 27
+	EqlParserLPAR           = 28
+	EqlParserRPAR           = 29
+	EqlParserLARR           = 30
+	EqlParserRARR           = 31
+	EqlParserLDICT          = 32
+	EqlParserRDICT          = 33
+	EqlParserBEGIN_VARIABLE = 34
 )
 
 // EqlParser rules.
@@ -2531,6 +2535,105 @@ func (s *ExpArithmeticLTContext) Accept(visitor antlr.ParseTreeVisitor) interfac
 	}
 }
 
+type ExpInContext struct {
+	*ExpContext
+	left  IExpContext
+	right IExpContext
+}
+
+func NewExpInContext(parser antlr.Parser, ctx antlr.ParserRuleContext) *ExpInContext {
+	var p = new(ExpInContext)
+
+	p.ExpContext = NewEmptyExpContext()
+	p.parser = parser
+	p.CopyFrom(ctx.(*ExpContext))
+
+	return p
+}
+
+func (s *ExpInContext) GetLeft() IExpContext { return s.left }
+
+func (s *ExpInContext) GetRight() IExpContext { return s.right }
+
+func (s *ExpInContext) SetLeft(v IExpContext) { s.left = v }
+
+func (s *ExpInContext) SetRight(v IExpContext) { s.right = v }
+
+func (s *ExpInContext) GetRuleContext() antlr.RuleContext {
+	return s
+}
+
+func (s *ExpInContext) IN() antlr.TerminalNode {
+	return s.GetToken(EqlParserIN, 0)
+}
+
+func (s *ExpInContext) NOT() antlr.TerminalNode {
+	return s.GetToken(EqlParserNOT, 0)
+}
+
+func (s *ExpInContext) AllExp() []IExpContext {
+	children := s.GetChildren()
+	len := 0
+	for _, ctx := range children {
+		if _, ok := ctx.(IExpContext); ok {
+			len++
+		}
+	}
+
+	tst := make([]IExpContext, len)
+	i := 0
+	for _, ctx := range children {
+		if t, ok := ctx.(IExpContext); ok {
+			tst[i] = t.(IExpContext)
+			i++
+		}
+	}
+
+	return tst
+}
+
+func (s *ExpInContext) Exp(i int) IExpContext {
+	var t antlr.RuleContext
+	j := 0
+	for _, ctx := range s.GetChildren() {
+		if _, ok := ctx.(IExpContext); ok {
+			if j == i {
+				t = ctx.(antlr.RuleContext)
+				break
+			}
+			j++
+		}
+	}
+
+	if t == nil {
+		return nil
+	}
+
+	return t.(IExpContext)
+}
+
+func (s *ExpInContext) EnterRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(EqlListener); ok {
+		listenerT.EnterExpIn(s)
+	}
+}
+
+func (s *ExpInContext) ExitRule(listener antlr.ParseTreeListener) {
+	if listenerT, ok := listener.(EqlListener); ok {
+		listenerT.ExitExpIn(s)
+	}
+}
+
+func (s *ExpInContext) Accept(visitor antlr.ParseTreeVisitor) interface{} {
+	switch t := visitor.(type) {
+	case EqlVisitor:
+		return t.VisitExpIn(s)
+
+	default:
+		return t.VisitChildren(s)
+	}
+}
+
 func (p *EqlParser) Exp() (localctx IExpContext) {
 	return p.exp(0)
 }
@@ -2599,7 +2702,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 		}
 		{
 			p.SetState(51)
-			p.exp(17)
+			p.exp(18)
 		}
 
 	case EqlParserTRUE, EqlParserFALSE:
@@ -2644,7 +2747,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 		p.GetErrorHandler().Sync(p)
 		_la = p.GetTokenStream().LA(1)
 
-		if (int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&11523719168) != 0 {
+		if (int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&23041277952) != 0 {
 			{
 				p.SetState(59)
 				p.Arguments()
@@ -2668,7 +2771,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 		p.GetErrorHandler().Sync(p)
 		_la = p.GetTokenStream().LA(1)
 
-		if (int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&102629376) != 0 {
+		if (int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&203292672) != 0 {
 			{
 				p.SetState(64)
 				p.Array()
@@ -2692,7 +2795,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 		p.GetErrorHandler().Sync(p)
 		_la = p.GetTokenStream().LA(1)
 
-		if (int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&109051904) != 0 {
+		if (int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&218103808) != 0 {
 			{
 				p.SetState(69)
 				p.Dict()
@@ -2762,8 +2865,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(78)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 19)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 19)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 20)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 20)", ""))
 				}
 				{
 					p.SetState(79)
@@ -2779,7 +2882,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(80)
 
-					var _x = p.exp(20)
+					var _x = p.exp(21)
 
 					localctx.(*ExpArithmeticMulDivModContext).right = _x
 				}
@@ -2791,8 +2894,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(81)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 18)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 18)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 19)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 19)", ""))
 				}
 				{
 					p.SetState(82)
@@ -2808,7 +2911,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(83)
 
-					var _x = p.exp(19)
+					var _x = p.exp(20)
 
 					localctx.(*ExpArithmeticAddSubContext).right = _x
 				}
@@ -2820,8 +2923,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(84)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 16)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 16)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 17)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 17)", ""))
 				}
 				{
 					p.SetState(85)
@@ -2830,7 +2933,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(86)
 
-					var _x = p.exp(17)
+					var _x = p.exp(18)
 
 					localctx.(*ExpArithmeticEQContext).right = _x
 				}
@@ -2842,8 +2945,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(87)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 15)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 15)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 16)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 16)", ""))
 				}
 				{
 					p.SetState(88)
@@ -2852,7 +2955,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(89)
 
-					var _x = p.exp(16)
+					var _x = p.exp(17)
 
 					localctx.(*ExpArithmeticNEQContext).right = _x
 				}
@@ -2864,8 +2967,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(90)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 14)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 14)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 15)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 15)", ""))
 				}
 				{
 					p.SetState(91)
@@ -2874,7 +2977,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(92)
 
-					var _x = p.exp(15)
+					var _x = p.exp(16)
 
 					localctx.(*ExpArithmeticLTEContext).right = _x
 				}
@@ -2886,8 +2989,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(93)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 13)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 13)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 14)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 14)", ""))
 				}
 				{
 					p.SetState(94)
@@ -2896,7 +2999,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(95)
 
-					var _x = p.exp(14)
+					var _x = p.exp(15)
 
 					localctx.(*ExpArithmeticGTEContext).right = _x
 				}
@@ -2908,8 +3011,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(96)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 12)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 12)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 13)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 13)", ""))
 				}
 				{
 					p.SetState(97)
@@ -2918,7 +3021,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(98)
 
-					var _x = p.exp(13)
+					var _x = p.exp(14)
 
 					localctx.(*ExpArithmeticLTContext).right = _x
 				}
@@ -2930,8 +3033,8 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
 				p.SetState(99)
 
-				if !(p.Precpred(p.GetParserRuleContext(), 11)) {
-					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 11)", ""))
+				if !(p.Precpred(p.GetParserRuleContext(), 12)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 12)", ""))
 				}
 				{
 					p.SetState(100)
@@ -2940,12 +3043,45 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 				{
 					p.SetState(101)
 
-					var _x = p.exp(12)
+					var _x = p.exp(13)
 
 					localctx.(*ExpArithmeticGTContext).right = _x
 				}
 
 			case 9:
+				localctx = NewExpInContext(p, NewExpContext(p, _parentctx, _parentState))
+				localctx.(*ExpInContext).left = _prevctx
+
+				p.PushNewRecursionContext(localctx, _startState, EqlParserRULE_exp)
+				p.SetState(142)
+
+				if !(p.Precpred(p.GetParserRuleContext(), 11)) {
+					panic(antlr.NewFailedPredicateException(p, "p.Precpred(p.GetParserRuleContext(), 11)", ""))
+				}
+				p.SetState(143)
+				p.GetErrorHandler().Sync(p)
+				_la = p.GetTokenStream().LA(1)
+
+				if _la == EqlParserNOT {
+					{
+						p.SetState(144)
+						p.Match(EqlParserNOT)
+					}
+
+				}
+				{
+					p.SetState(146)
+					p.Match(EqlParserIN)
+				}
+				{
+					p.SetState(147)
+
+					var _x = p.exp(12)
+
+					localctx.(*ExpInContext).right = _x
+				}
+
+			case 10:
 				localctx = NewExpLogicalAndContext(p, NewExpContext(p, _parentctx, _parentState))
 				localctx.(*ExpLogicalAndContext).left = _prevctx
 
@@ -2967,7 +3103,7 @@ func (p *EqlParser) exp(_p int) (localctx IExpContext) {
 					localctx.(*ExpLogicalAndContext).right = _x
 				}
 
-			case 10:
+			case 11:
 				localctx = NewExpLogicalORContext(p, NewExpContext(p, _parentctx, _parentState))
 				localctx.(*ExpLogicalORContext).left = _prevctx
 
@@ -3459,7 +3595,7 @@ func (p *EqlParser) Key() (localctx IKeyContext) {
 		p.SetState(129)
 		_la = p.GetTokenStream().LA(1)
 
-		if !((int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&109051904) != 0) {
+		if !((int64(_la) & ^0x3f) == 0 && ((int64(1)<<_la)&218103808) != 0) {
 			p.GetErrorHandler().RecoverInline(p)
 		} else {
 			p.GetErrorHandler().ReportMatch(p)
@@ -3662,33 +3798,36 @@ func (p *EqlParser) Exp_Sempred(localctx antlr.RuleContext, predIndex int) bool
 
 	switch predIndex {
 	case 0:
-		return p.Precpred(p.GetParserRuleContext(), 19)
+		return p.Precpred(p.GetParserRuleContext(), 20)
 
 	case 1:
-		return p.Precpred(p.GetParserRuleContext(), 18)
+		return p.Precpred(p.GetParserRuleContext(), 19)
 
 	case 2:
-		return p.Precpred(p.GetParserRuleContext(), 16)
+		return p.Precpred(p.GetParserRuleContext(), 17)
 
 	case 3:
-		return p.Precpred(p.GetParserRuleContext(), 15)
+		return p.Precpred(p.GetParserRuleContext(), 16)
 
 	case 4:
-		return p.Precpred(p.GetParserRuleContext(), 14)
+		return p.Precpred(p.GetParserRuleContext(), 15)
 
 	case 5:
-		return p.Precpred(p.GetParserRuleContext(), 13)
+		return p.Precpred(p.GetParserRuleContext(), 14)
 
 	case 6:
-		return p.Precpred(p.GetParserRuleContext(), 12)
+		return p.Precpred(p.GetParserRuleContext(), 13)
 
 	case 7:
-		return p.Precpred(p.GetParserRuleContext(), 11)
+		return p.Precpred(p.GetParserRuleContext(), 12)
 
 	case 8:
-		return p.Precpred(p.GetParserRuleContext(), 10)
+		return p.Precpred(p.GetParserRuleContext(), 11)
 
 	case 9:
+		return p.Precpred(p.GetParserRuleContext(), 10)
+
+	case 10:
 		return p.Precpred(p.GetParserRuleContext(), 9)
 
 	default:
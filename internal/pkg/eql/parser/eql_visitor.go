@@ -87,6 +87,9 @@ type EqlVisitor interface {
 	// Visit a parse tree produced by EqlParser#ExpArithmeticLT.
 	VisitExpArithmeticLT(ctx *ExpArithmeticLTContext) interface{}
 
+	// Visit a parse tree produced by EqlParser#ExpIn.
+	VisitExpIn(ctx *ExpInContext) interface{}
+
 	// Visit a parse tree produced by EqlParser#arguments.
 	VisitArguments(ctx *ArgumentsContext) interface{}
 
@@ -164,7 +164,15 @@ func (v *expVisitor) VisitExpFloat(ctx *parser.ExpFloatContext) interface{} {
 }
 
 func (v *expVisitor) VisitExpLogicalAnd(ctx *parser.ExpLogicalAndContext) interface{} {
-	r, err := logicalAND(ctx.GetLeft().Accept(v), ctx.GetRight().Accept(v))
+	left := ctx.GetLeft().Accept(v)
+	if v.hasErr() {
+		return nil
+	}
+	// the right operand is not evaluated when the left one is false.
+	if l, ok := left.(bool); ok && !l {
+		return false
+	}
+	r, err := logicalAND(left, ctx.GetRight().Accept(v))
 	if err != nil {
 		v.err = err
 		return nil
@@ -173,7 +181,15 @@ func (v *expVisitor) VisitExpLogicalAnd(ctx *parser.ExpLogicalAndContext) interf
 }
 
 func (v *expVisitor) VisitExpLogicalOR(ctx *parser.ExpLogicalORContext) interface{} {
-	r, err := logicalOR(ctx.GetLeft().Accept(v), ctx.GetRight().Accept(v))
+	left := ctx.GetLeft().Accept(v)
+	if v.hasErr() {
+		return nil
+	}
+	// the right operand is not evaluated when the left one is true.
+	if l, ok := left.(bool); ok && l {
+		return true
+	}
+	r, err := logicalOR(left, ctx.GetRight().Accept(v))
 	if err != nil {
 		v.err = err
 		return nil
@@ -181,6 +197,18 @@ func (v *expVisitor) VisitExpLogicalOR(ctx *parser.ExpLogicalORContext) interfac
 	return r
 }
 
+func (v *expVisitor) VisitExpIn(ctx *parser.ExpInContext) interface{} {
+	r, err := compareIN(ctx.GetLeft().Accept(v), ctx.GetRight().Accept(v))
+	if err != nil {
+		v.err = err
+		return nil
+	}
+	if ctx.NOT() != nil {
+		return !r
+	}
+	return r
+}
+
 func (v *expVisitor) VisitExpInParen(ctx *parser.ExpInParenContext) interface{} {
 	return ctx.Exp().Accept(v)
 }
@@ -191,7 +219,7 @@ func (v *expVisitor) VisitExpBoolean(ctx *parser.ExpBooleanContext) interface{}
 
 func (v *expVisitor) VisitExpFunction(ctx *parser.ExpFunctionContext) interface{} {
 	name := ctx.NAME().GetText()
-	method, ok := methods[name]
+	m, ok := methods[name]
 	if !ok {
 		v.err = fmt.Errorf("call to unknown function %s", name)
 		return nil
@@ -201,9 +229,9 @@ func (v *expVisitor) VisitExpFunction(ctx *parser.ExpFunctionContext) interface{
 	var val interface{}
 	if ctx.Arguments() != nil {
 		args := ctx.Arguments().Accept(v).([]interface{})
-		val, err = method(args)
+		val, err = m.call(args)
 	} else {
-		val, err = method(make([]interface{}, 0))
+		val, err = m.call(make([]interface{}, 0))
 	}
 
 	if err != nil {
@@ -14,7 +14,11 @@ import (
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/fleet"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/testing/fleetservertest"
 )
 
 var (
@@ -136,3 +140,119 @@ func TestRetrier(t *testing.T) {
 		})
 	}
 }
+
+type testAgentInfo struct{}
+
+func (testAgentInfo) AgentID() string { return "agent-secret" }
+
+func TestRetrierWithFaults(t *testing.T) {
+	log, _ := logger.New("", false)
+
+	const maxRetries = 3
+	tests := []struct {
+		name         string
+		fault        fleetservertest.Fault
+		expectedAcks int
+	}{
+		{
+			name: "429 with Retry-After",
+			fault: fleetservertest.Fault{Kind: fleetservertest.FaultStatus,
+				StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second, Count: maxRetries - 1},
+			expectedAcks: 1,
+		},
+		{
+			name: "5xx burst",
+			fault: fleetservertest.Fault{Kind: fleetservertest.FaultStatus,
+				StatusCode: http.StatusInternalServerError, Count: maxRetries - 1},
+			expectedAcks: 1,
+		},
+		{
+			name: "5xx until the retries are exhausted",
+			fault: fleetservertest.Fault{Kind: fleetservertest.FaultStatus,
+				StatusCode: http.StatusServiceUnavailable},
+			expectedAcks: 0,
+		},
+		{
+			name:         "connection reset",
+			fault:        fleetservertest.Fault{Kind: fleetservertest.FaultReset, Count: maxRetries - 1},
+			expectedAcks: 1,
+		},
+		{
+			name:         "truncated body",
+			fault:        fleetservertest.Fault{Kind: fleetservertest.FaultTruncate, Count: maxRetries - 1},
+			expectedAcks: maxRetries,
+		},
+		{
+			name:         "invalid JSON",
+			fault:        fleetservertest.Fault{Kind: fleetservertest.FaultInvalidJSON, Count: maxRetries - 1},
+			expectedAcks: maxRetries,
+		},
+		{
+			name:         "latency",
+			fault:        fleetservertest.Fault{Kind: fleetservertest.FaultLatency, Latency: 100 * time.Millisecond},
+			expectedAcks: 1,
+		},
+		{
+			name:         "clock skew",
+			fault:        fleetservertest.Fault{Kind: fleetservertest.FaultClockSkew, Skew: -time.Hour},
+			expectedAcks: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cn := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cn()
+
+			tc.fault.Route = "AgentAcks"
+			scenario, err := fleetservertest.NewScenario(tc.fault)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// acked counts the acks processed by fleet-server, even the
+			// ones whose response never reached the agent.
+			acked := 0
+			fs := fleetservertest.NewServer(&fleetservertest.Handlers{
+				AgentID: testAgentInfo{}.AgentID(),
+				AckFn: fleetservertest.NewHandlerAckWithAcker(func(actionID string) (fleetservertest.AckResponseItem, bool) {
+					acked++
+					return fleetservertest.AckResponseItem{Status: http.StatusOK, Message: http.StatusText(http.StatusOK)}, false
+				}),
+			}, fleetservertest.WithScenario(scenario))
+			defer fs.Close()
+
+			c, err := client.NewAuthWithConfig(log, "apiKey", remote.Config{Host: fs.LocalhostURL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			acker, err := fleet.NewAcker(log, testAgentInfo{}, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			retrier := New(acker, log,
+				WithInitialRetryInterval(10*time.Millisecond),
+				WithMaxRetryInterval(50*time.Millisecond),
+				WithMaxAckRetries(maxRetries),
+			)
+			go retrier.Run(ctx)
+
+			retrier.Enqueue([]fleetapi.Action{&fleetapi.ActionUnknown{ActionID: "1"}})
+
+			select {
+			case <-retrier.Done():
+			case <-ctx.Done():
+				t.Fatal("retry loop never finished")
+			}
+
+			// the truncated and invalid responses are only corrupted once
+			// fleet-server processed the ack, so each retry acks it again.
+			if diff := cmp.Diff(tc.expectedAcks, acked); diff != "" {
+				t.Fatal(diff)
+			}
+			if scenario.Injected("AgentAcks") == 0 {
+				t.Fatal("the fault was never injected")
+			}
+		})
+	}
+}
@@ -35,6 +35,8 @@ const (
 	ActionTypeCancel = "CANCEL"
 	// ActionTypeDiagnostics specifies a diagnostics action.
 	ActionTypeDiagnostics = "REQUEST_DIAGNOSTICS"
+	// ActionTypeRotateAPIKey specifies a Fleet access API key rotation action.
+	ActionTypeRotateAPIKey = "ROTATE_API_KEY"
 )
 
 // Error values that the Action interface can return
@@ -232,6 +234,7 @@ type ActionUpgrade struct {
 	SourceURI        string  `json:"source_uri,omitempty" yaml:"source_uri,omitempty" mapstructure:"-"`
 	Retry            int     `json:"retry_attempt,omitempty" yaml:"retry_attempt,omitempty" mapstructure:"-"`
 	Signed           *Signed `json:"signed,omitempty" yaml:"signed,omitempty" mapstructure:"signed,omitempty"`
+	Critical         bool    `json:"critical,omitempty" yaml:"critical,omitempty" mapstructure:"-"` // security-critical upgrades are not deferred to the maintenance windows
 	Err              error   `json:"-" yaml:"-" mapstructure:"-"`
 }
 
@@ -476,6 +479,46 @@ func (a *ActionDiagnostics) AckEvent() AckEvent {
 	return event
 }
 
+// ActionRotateAPIKey is a request to replace the API key the agent uses to
+// authenticate with fleet-server.
+type ActionRotateAPIKey struct {
+	ActionID   string `json:"action_id" yaml:"action_id"`
+	ActionType string `json:"type" yaml:"type"`
+	APIKeyID   string `json:"api_key_id" yaml:"api_key_id"`
+	APIKey     string `json:"api_key" yaml:"api_key"`
+	Err        error  `json:"-" yaml:"-"`
+}
+
+// ID returns the ID of the action.
+func (a *ActionRotateAPIKey) ID() string {
+	return a.ActionID
+}
+
+// Type returns the type of the action.
+func (a *ActionRotateAPIKey) Type() string {
+	return a.ActionType
+}
+
+// String never includes the API key, it must not be logged.
+func (a *ActionRotateAPIKey) String() string {
+	var s strings.Builder
+	s.WriteString("action_id: ")
+	s.WriteString(a.ActionID)
+	s.WriteString(", type: ")
+	s.WriteString(a.ActionType)
+	s.WriteString(", api_key_id: ")
+	s.WriteString(a.APIKeyID)
+	return s.String()
+}
+
+func (a *ActionRotateAPIKey) AckEvent() AckEvent {
+	event := newAckEvent(a.ActionID, a.ActionType)
+	if a.Err != nil {
+		event.Error = a.Err.Error()
+	}
+	return event
+}
+
 // ActionApp is the application action request.
 type ActionApp struct {
 	ActionID    string                 `json:"id" mapstructure:"id"`
@@ -625,6 +668,16 @@ func (a *Actions) UnmarshalJSON(data []byte) error {
 					"fail to decode REQUEST_DIAGNOSTICS_ACTION action",
 					errors.TypeConfig)
 			}
+		case ActionTypeRotateAPIKey:
+			action = &ActionRotateAPIKey{
+				ActionID:   response.ActionID,
+				ActionType: response.ActionType,
+			}
+			if err := json.Unmarshal(response.Data, action); err != nil {
+				return errors.New(err,
+					"fail to decode ROTATE_API_KEY action",
+					errors.TypeConfig)
+			}
 		default:
 			action = &ActionUnknown{
 				ActionID:     response.ActionID,
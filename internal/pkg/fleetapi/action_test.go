@@ -152,6 +152,19 @@ func TestActionsUnmarshalJSON(t *testing.T) {
 		assert.Equal(t, "http://example.com", action.SourceURI)
 		assert.Equal(t, 1, action.Retry)
 	})
+	t.Run("ActionRotateAPIKey", func(t *testing.T) {
+		p := []byte(`[{"id":"testid","type":"ROTATE_API_KEY","data":{"api_key_id":"key-id","api_key":"secret"}}]`)
+		a := &Actions{}
+		err := a.UnmarshalJSON(p)
+		require.Nil(t, err)
+		action, ok := (*a)[0].(*ActionRotateAPIKey)
+		require.True(t, ok, "unable to cast action to specific type")
+		assert.Equal(t, "testid", action.ActionID)
+		assert.Equal(t, ActionTypeRotateAPIKey, action.ActionType)
+		assert.Equal(t, "key-id", action.APIKeyID)
+		assert.Equal(t, "secret", action.APIKey)
+		assert.NotContains(t, action.String(), "secret")
+	})
 }
 
 func TestActionUnenrollMarshalMap(t *testing.T) {
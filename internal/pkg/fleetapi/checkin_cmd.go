@@ -28,6 +28,7 @@ type CheckinUnit struct {
 	Status  string                 `json:"status"`
 	Message string                 `json:"message"`
 	Payload map[string]interface{} `json:"payload,omitempty"`
+	Source  string                 `json:"source,omitempty"`
 }
 
 // CheckinShipperReference provides information about a component shipper connection during checkin.
@@ -36,6 +37,14 @@ type CheckinShipperReference struct {
 	UnitID      string `json:"unit_id"`
 }
 
+// CheckinResources provides the resources used by a component process during checkin.
+type CheckinResources struct {
+	PID     int     `json:"pid"`
+	RSS     uint64  `json:"rss"`
+	CPU     float64 `json:"cpu_pct"`
+	OpenFDs int     `json:"open_fds"`
+}
+
 // CheckinComponent provides information about a component during checkin.
 type CheckinComponent struct {
 	ID      string                   `json:"id"`
@@ -44,6 +53,12 @@ type CheckinComponent struct {
 	Message string                   `json:"message"`
 	Units   []CheckinUnit            `json:"units,omitempty"`
 	Shipper *CheckinShipperReference `json:"shipper,omitempty"`
+	// Resources used by the component process, as last sampled.
+	Resources *CheckinResources `json:"resources,omitempty"`
+
+	// RemovedUnits lists the IDs of the units removed since the base state of a
+	// components delta.
+	RemovedUnits []string `json:"removed_units,omitempty"`
 }
 
 // CheckinRequest consists of multiple events reported to fleet ui.
@@ -54,6 +69,19 @@ type CheckinRequest struct {
 	Message        string             `json:"message"`    // V2 Agent message
 	Components     []CheckinComponent `json:"components"` // V2 Agent components
 	UpgradeDetails *details.Details   `json:"upgrade_details,omitempty"`
+
+	// PollTimeout overrides the time fleet-server holds the checkin when there are no
+	// actions for the agent, parsable by time.ParseDuration.
+	PollTimeout string `json:"poll_timeout,omitempty"`
+
+	// ComponentsHash is the hash of the full components state of the agent.
+	ComponentsHash string `json:"components_hash,omitempty"`
+	// ComponentsBaseHash is set when Components only holds the changes since the
+	// components state with this hash, previously accepted by fleet-server.
+	ComponentsBaseHash string `json:"components_base_hash,omitempty"`
+	// RemovedComponents lists the IDs of the components removed since the base
+	// state of a components delta.
+	RemovedComponents []string `json:"removed_components,omitempty"`
 }
 
 // SerializableEvent is a representation of the event to be send to the Fleet Server API via the checkin
@@ -82,6 +110,19 @@ type CheckinResponse struct {
 	AckToken     string  `json:"ack_token"`
 	Actions      Actions `json:"actions"`
 	FleetWarning string  `json:"-"`
+
+	// ComponentsHash is the hash of the components state stored by fleet-server,
+	// the next checkin can send a delta against it. It's empty when fleet-server
+	// doesn't support components deltas.
+	ComponentsHash string `json:"components_hash,omitempty"`
+	// RequestFullState is set when fleet-server needs the full components state
+	// on the next checkin.
+	RequestFullState bool `json:"request_full_state,omitempty"`
+
+	// CheckinInterval is the minimum time fleet-server asks the agent to wait
+	// before its next checkin, parsable by time.ParseDuration. It's empty when
+	// fleet-server doesn't pace the checkins.
+	CheckinInterval string `json:"checkin_interval,omitempty"`
 }
 
 // Validate validates the response send from the server.
@@ -134,7 +175,7 @@ func (e *CheckinCmd) Execute(ctx context.Context, r *CheckinRequest) (*CheckinRe
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, sendDuration, client.ExtractError(resp.Body)
+		return nil, sendDuration, client.ExtractResponseError(resp)
 	}
 
 	rs, err := ioutil.ReadAll(resp.Body)
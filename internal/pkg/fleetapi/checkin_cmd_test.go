@@ -269,4 +269,46 @@ func TestCheckin(t *testing.T) {
 			require.Equal(t, 0, len(r.Actions))
 		},
 	))
+
+	t.Run("Checkin receives a checkin interval", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			raw := `{"actions": [], "checkin_interval": "5m"}`
+			mux := http.NewServeMux()
+			path := fmt.Sprintf("/api/fleet/agents/%s/checkin", agentInfo.AgentID())
+			mux.HandleFunc(path, authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, raw)
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			cmd := NewCheckinCmd(agentInfo, client)
+
+			r, _, err := cmd.Execute(ctx, &CheckinRequest{})
+			require.NoError(t, err)
+			require.Equal(t, "5m", r.CheckinInterval)
+		},
+	))
+
+	t.Run("Checkin returns the Retry-After of fleet-server", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			path := fmt.Sprintf("/api/fleet/agents/%s/checkin", agentInfo.AgentID())
+			mux.HandleFunc(path, authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "42")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"statusCode":429,"error":"Too Many Requests"}`)
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, c client.Sender) {
+			cmd := NewCheckinCmd(agentInfo, c)
+
+			_, _, err := cmd.Execute(ctx, &CheckinRequest{})
+			require.Error(t, err)
+			retryAfter, ok := client.RetryAfter(err)
+			require.True(t, ok)
+			require.Equal(t, 42*time.Second, retryAfter)
+		},
+	))
 }
@@ -13,6 +13,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
@@ -70,9 +72,15 @@ func init() {
 // - Send the API Key on every HTTP request.
 // - Ensure a minimun version of fleet-server is required.
 // - Send the Fleet User Agent on every HTTP request.
+// - Compress the request bodies when configured.
 func NewAuthWithConfig(log *logger.Logger, apiKey string, cfg remote.Config) (*remote.Client, error) {
 	return remote.NewWithConfig(log, cfg, func(rt http.RoundTripper) (http.RoundTripper, error) {
-		rt, err := baseRoundTrippers(rt)
+		rt, err := NewCompressionRoundTripper(rt, cfg.Compression)
+		if err != nil {
+			return nil, err
+		}
+
+		rt, err = baseRoundTrippers(rt)
 		if err != nil {
 			return nil, err
 		}
@@ -88,7 +96,14 @@ func NewAuthWithConfig(log *logger.Logger, apiKey string, cfg remote.Config) (*r
 
 // NewWithConfig takes a fleet-server configuration and create a remote.client with the appropriate tripper.
 func NewWithConfig(log *logger.Logger, cfg remote.Config) (*remote.Client, error) {
-	return remote.NewWithConfig(log, cfg, baseRoundTrippers)
+	return remote.NewWithConfig(log, cfg, func(rt http.RoundTripper) (http.RoundTripper, error) {
+		rt, err := NewCompressionRoundTripper(rt, cfg.Compression)
+		if err != nil {
+			return nil, err
+		}
+
+		return baseRoundTrippers(rt)
+	})
 }
 
 // ExtractError extracts error from a fleet-server response
@@ -122,3 +137,71 @@ func ExtractError(resp io.Reader) error {
 
 	return fmt.Errorf("could not decode the response, raw response: %s", string(data))
 }
+
+// RetryAfterError is returned when fleet-server answers with a 429 or 503 status
+// code and asks to retry the request later with a Retry-After header.
+type RetryAfterError struct {
+	StatusCode int
+	// RetryAfter is the time to wait before sending the request again.
+	RetryAfter time.Duration
+
+	err error
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", e.err, e.RetryAfter)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.err
+}
+
+// ExtractResponseError extracts the error from a fleet-server response, see
+// ExtractError. The error is a *RetryAfterError if the response has a 429 or 503
+// status code and a valid Retry-After header.
+func ExtractResponseError(resp *http.Response) error {
+	err := ExtractError(resp.Body)
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return err
+	}
+
+	retryAfter, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return err
+	}
+	return &RetryAfterError{StatusCode: resp.StatusCode, RetryAfter: retryAfter, err: err}
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, either a number of
+// seconds or a HTTP date, relatively to now. A date in the past is a zero duration.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := date.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// RetryAfter returns the time fleet-server asked to wait before retrying if err
+// is, or wraps, a *RetryAfterError.
+func RetryAfter(err error) (time.Duration, bool) {
+	var raErr *RetryAfterError
+	if errors.As(err, &raErr) {
+		return raErr.RetryAfter, true
+	}
+	return 0, false
+}
@@ -5,8 +5,10 @@
 package client
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -156,6 +158,68 @@ func TestExtract(t *testing.T) {
 	})
 }
 
+func TestExtractResponseError(t *testing.T) {
+	newResponse := func(status int, retryAfter string) *http.Response {
+		resp := &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"statusCode":%d,"error":"%s"}`, status, http.StatusText(status)))),
+		}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+
+	t.Run("429 with Retry-After", func(t *testing.T) {
+		err := ExtractResponseError(newResponse(http.StatusTooManyRequests, "30"))
+		retryAfter, ok := RetryAfter(err)
+		require.True(t, ok)
+		assert.Equal(t, 30*time.Second, retryAfter)
+		assert.Contains(t, err.Error(), "Too Many Requests")
+	})
+
+	t.Run("503 with Retry-After date", func(t *testing.T) {
+		date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+		retryAfter, ok := RetryAfter(ExtractResponseError(newResponse(http.StatusServiceUnavailable, date)))
+		require.True(t, ok)
+		assert.InDelta(t, time.Minute, retryAfter, float64(2*time.Second))
+	})
+
+	t.Run("Retry-After is ignored on other status codes", func(t *testing.T) {
+		_, ok := RetryAfter(ExtractResponseError(newResponse(http.StatusInternalServerError, "30")))
+		assert.False(t, ok)
+	})
+
+	t.Run("no Retry-After", func(t *testing.T) {
+		_, ok := RetryAfter(ExtractResponseError(newResponse(http.StatusTooManyRequests, "")))
+		assert.False(t, ok)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+	testcases := map[string]struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		"seconds":        {value: "120", expected: 2 * time.Minute, ok: true},
+		"date":           {value: "Wed, 01 Nov 2023 10:00:30 GMT", expected: 30 * time.Second, ok: true},
+		"date in past":   {value: "Wed, 01 Nov 2023 09:00:00 GMT", expected: 0, ok: true},
+		"empty":          {value: ""},
+		"negative":       {value: "-1"},
+		"invalid format": {value: "tomorrow"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			d, ok := ParseRetryAfter(tc.value, now)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, d)
+		})
+	}
+}
+
 func TestElasticApiVersion(t *testing.T) {
 	t.Run("verify that Elastic-Api-Version header is present", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -246,3 +310,135 @@ func TestElasticApiVersion(t *testing.T) {
 		assert.NotEmptyf(t, logs, "warning was not logged")
 	})
 }
+
+func TestCompressionRoundTripper(t *testing.T) {
+	ctx := context.Background()
+	const body = `{"status":"online"}`
+
+	newClient := func(t *testing.T, host string) *remote.Client {
+		cfg := config.MustNewConfigFrom(map[string]interface{}{
+			"host":        host,
+			"compression": CompressionGzip,
+		})
+		unpacked := remote.Config{}
+		require.NoError(t, cfg.Unpack(&unpacked))
+
+		log, _ := logger.NewTesting("fleet_client")
+		client, err := NewWithConfig(log, unpacked)
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("compress the body of fleet-server routes", withServer(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/agent-id/checkin", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, CompressionGzip, r.Header.Get("Content-Encoding"))
+				zr, err := gzip.NewReader(r.Body)
+				require.NoError(t, err)
+				got, err := io.ReadAll(zr)
+				require.NoError(t, err)
+				assert.Equal(t, body, string(got))
+				w.WriteHeader(http.StatusOK)
+			})
+			mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+				assert.Empty(t, r.Header.Get("Content-Encoding"))
+				w.WriteHeader(http.StatusOK)
+			})
+			return mux
+		}, func(t *testing.T, host string) {
+			client := newClient(t, host)
+
+			resp, err := client.Send(ctx, http.MethodPost, "/api/fleet/agents/agent-id/checkin", nil, nil, strings.NewReader(body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			resp, err = client.Send(ctx, http.MethodGet, "/api/status", nil, nil, strings.NewReader(body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		},
+	))
+
+	t.Run("fallback to uncompressed bodies when rejected", withServer(
+		func(t *testing.T) *http.ServeMux {
+			compressed := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/agent-id/acks", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Encoding") != "" {
+					compressed++
+					require.Equal(t, 1, compressed, "compression must be disabled after a rejection")
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				got, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, body, string(got))
+				w.WriteHeader(http.StatusOK)
+			})
+			return mux
+		}, func(t *testing.T, host string) {
+			client := newClient(t, host)
+
+			for i := 0; i < 2; i++ {
+				resp, err := client.Send(ctx, http.MethodPost, "/api/fleet/agents/agent-id/acks", nil, nil, strings.NewReader(body))
+				require.NoError(t, err)
+				resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+		},
+	))
+
+	t.Run("keep compression on validation errors", withServer(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/agent-id/acks", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, CompressionGzip, r.Header.Get("Content-Encoding"))
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"statusCode":400,"error":"BadRequest","message":"invalid action"}`))
+			})
+			return mux
+		}, func(t *testing.T, host string) {
+			client := newClient(t, host)
+
+			for i := 0; i < 2; i++ {
+				resp, err := client.Send(ctx, http.MethodPost, "/api/fleet/agents/agent-id/acks", nil, nil, strings.NewReader(body))
+				require.NoError(t, err)
+				got, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				assert.Contains(t, string(got), "invalid action")
+			}
+		},
+	))
+
+	t.Run("fallback on bad requests naming the encoding", withServer(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/agent-id/acks", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Encoding") != "" {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"statusCode":400,"error":"BadRequest","message":"unsupported Content-Encoding gzip"}`))
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			return mux
+		}, func(t *testing.T, host string) {
+			client := newClient(t, host)
+
+			resp, err := client.Send(ctx, http.MethodPost, "/api/fleet/agents/agent-id/acks", nil, nil, strings.NewReader(body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		},
+	))
+
+	t.Run("unsupported compression", func(t *testing.T) {
+		log, _ := logger.NewTesting("fleet_client")
+		_, err := NewWithConfig(log, remote.Config{Host: "localhost", Compression: "br"})
+		assert.Error(t, err)
+	})
+}
@@ -5,8 +5,14 @@
 package client
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"sync/atomic"
 
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
 )
@@ -88,3 +94,119 @@ func (r *ElasticApiVersionRoundTripper) RoundTrip(req *http.Request) (*http.Resp
 func NewElasticApiVersionRoundTripper(inner http.RoundTripper, elasticApiVersion string) http.RoundTripper {
 	return &ElasticApiVersionRoundTripper{elasticApiVersion: elasticApiVersion, rt: inner}
 }
+
+// Supported values for remote.Config.Compression.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// compressiblePaths are the fleet-server API routes accepting compressed request bodies:
+// checkin, acks and file upload chunks.
+var compressiblePaths = regexp.MustCompile(`/api/fleet/(agents/[^/]+/(checkin|acks)|uploads/[^/]+/[0-9]+)$`)
+
+// CompressionRoundTripper compresses the body of the requests sent to the fleet-server
+// routes accepting compressed payloads. When fleet-server rejects a compressed body the
+// request is sent again uncompressed and, if that succeeds, compression is disabled for
+// the lifetime of the round tripper.
+type CompressionRoundTripper struct {
+	rt       http.RoundTripper
+	encoding string
+	disabled atomic.Bool
+}
+
+// RoundTrip compresses the request body and falls back to an uncompressed body when
+// fleet-server doesn't support the encoding.
+func (r *CompressionRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.disabled.Load() ||
+		req.Body == nil ||
+		req.Header.Get("Content-Encoding") != "" ||
+		!compressiblePaths.MatchString(req.URL.Path) {
+		return r.rt.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to compress request body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress request body: %w", err)
+	}
+
+	compressed := req.Clone(req.Context())
+	compressed.Body = io.NopCloser(&buf)
+	compressed.ContentLength = int64(buf.Len())
+	compressed.GetBody = nil
+	compressed.Header.Set("Content-Encoding", r.encoding)
+	resp, err := r.rt.RoundTrip(compressed)
+	if err != nil || !isEncodingRejected(resp) {
+		return resp, err
+	}
+	// discard body for proper cancellation and connection reuse
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	uncompressed := req.Clone(req.Context())
+	uncompressed.Body = io.NopCloser(bytes.NewReader(body))
+	uncompressed.ContentLength = int64(len(body))
+	uncompressed.GetBody = nil
+	resp, err = r.rt.RoundTrip(uncompressed)
+	if err == nil && !isEncodingRejected(resp) {
+		r.disabled.Store(true)
+	}
+	return resp, err
+}
+
+// Disabled returns true when compression was turned off because fleet-server rejected
+// compressed requests.
+func (r *CompressionRoundTripper) Disabled() bool {
+	return r.disabled.Load()
+}
+
+// encodingErrorRegexp matches the errors of a server that could not decode a compressed body.
+var encodingErrorRegexp = regexp.MustCompile(`(?i)content[-_ ]encoding|gzip|decompress`)
+
+// maxEncodingErrorBody is the number of bytes of a bad request body looked at for an encoding error.
+const maxEncodingErrorBody = 4096
+
+// isEncodingRejected returns true for the responses of a server that could not decode a
+// compressed body: an unsupported media type, or a bad request naming the content encoding.
+// The body of a bad request is left readable for the caller.
+func isEncodingRejected(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnsupportedMediaType:
+		return true
+	case http.StatusBadRequest:
+		if resp.Body == nil {
+			return false
+		}
+		head, _ := io.ReadAll(io.LimitReader(resp.Body, maxEncodingErrorBody))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+		return encodingErrorRegexp.Match(head)
+	}
+	return false
+}
+
+// NewCompressionRoundTripper wraps an existing http.RoundTripper and compresses the request
+// bodies with the given encoding. The wrapped round tripper is returned when compression is
+// disabled.
+func NewCompressionRoundTripper(wrapped http.RoundTripper, encoding string) (http.RoundTripper, error) {
+	switch encoding {
+	case "", CompressionNone:
+		return wrapped, nil
+	case CompressionGzip:
+		return &CompressionRoundTripper{rt: wrapped, encoding: encoding}, nil
+	}
+	return nil, fmt.Errorf("unsupported compression %q, accepted values are %q and %q",
+		encoding, CompressionNone, CompressionGzip)
+}
@@ -7,6 +7,7 @@ package uploader
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -18,6 +19,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/testing/fleetservertest"
 )
 
 type mockBackoff struct {
@@ -225,3 +232,96 @@ func Test_Client_UploadDiagnostics(t *testing.T) {
 	assert.Equal(t, "e", string(chunk2))
 	sender.AssertExpectations(t)
 }
+
+func Test_Client_UploadDiagnosticsWithFaults(t *testing.T) {
+	const diagnostics = "abcdefghijkl"
+
+	tests := []struct {
+		name    string
+		fault   fleetservertest.Fault
+		wantErr bool
+	}{{
+		name: "429 with Retry-After on chunk",
+		fault: fleetservertest.Fault{Route: "UploadChunk", Kind: fleetservertest.FaultStatus,
+			StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second, Count: 2},
+	}, {
+		name: "5xx on chunk",
+		fault: fleetservertest.Fault{Route: "UploadChunk", Kind: fleetservertest.FaultStatus,
+			StatusCode: http.StatusServiceUnavailable, Count: 1},
+		// only the 429 status is retried
+		wantErr: true,
+	}, {
+		name:  "connection reset on chunk",
+		fault: fleetservertest.Fault{Route: "UploadChunk", Kind: fleetservertest.FaultReset, Count: 2},
+	}, {
+		name:  "connection reset on upload begin",
+		fault: fleetservertest.Fault{Route: "UploadBegin", Kind: fleetservertest.FaultReset, Count: 1},
+	}, {
+		name:    "truncated body on upload begin",
+		fault:   fleetservertest.Fault{Route: "UploadBegin", Kind: fleetservertest.FaultTruncate, Count: 1},
+		wantErr: true,
+	}, {
+		name:    "invalid JSON on upload begin",
+		fault:   fleetservertest.Fault{Route: "UploadBegin", Kind: fleetservertest.FaultInvalidJSON, Count: 1},
+		wantErr: true,
+	}, {
+		name:  "latency",
+		fault: fleetservertest.Fault{Route: "UploadChunk", Kind: fleetservertest.FaultLatency, Latency: 50 * time.Millisecond},
+	}, {
+		name:  "clock skew",
+		fault: fleetservertest.Fault{Route: "UploadComplete", Kind: fleetservertest.FaultClockSkew, Skew: -time.Hour},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scenario, err := fleetservertest.NewScenario(tc.fault)
+			require.NoError(t, err)
+
+			chunks := map[int32]string{}
+			completed := false
+			fs := fleetservertest.NewServer(&fleetservertest.Handlers{
+				UploadBeginFn: func(ctx context.Context, h *fleetservertest.Handlers, req fleetservertest.UploadBeginRequest) (*fleetservertest.UploadBeginResponse, *fleetservertest.HTTPError) {
+					return &fleetservertest.UploadBeginResponse{UploadId: "test-upload", ChunkSize: 5}, nil
+				},
+				UploadChunkFn: func(ctx context.Context, h *fleetservertest.Handlers, uploadID string, chunkNum int32, xChunkSHA2 string, body io.ReadCloser) *fleetservertest.HTTPError {
+					data, err := io.ReadAll(body)
+					if err != nil {
+						return &fleetservertest.HTTPError{StatusCode: http.StatusBadRequest, Message: err.Error()}
+					}
+					if fmt.Sprintf("%x", sha256.Sum256(data)) != xChunkSHA2 {
+						return &fleetservertest.HTTPError{StatusCode: http.StatusBadRequest, Message: "chunk hash mismatch"}
+					}
+					chunks[chunkNum] = string(data)
+					return nil
+				},
+				UploadCompleteFn: func(ctx context.Context, h *fleetservertest.Handlers, uploadID string, req fleetservertest.UploadCompleteRequest) *fleetservertest.HTTPError {
+					completed = true
+					return nil
+				},
+			}, fleetservertest.WithScenario(scenario))
+			defer fs.Close()
+
+			log, _ := logger.New("uploader", false)
+			sender, err := client.NewAuthWithConfig(log, "apiKey", remote.Config{Host: fs.LocalhostURL})
+			require.NoError(t, err)
+			c := New("test-agent", sender, config.Uploader{
+				MaxRetries: 3,
+				InitDur:    10 * time.Millisecond,
+				MaxDur:     50 * time.Millisecond,
+			})
+
+			id, err := c.UploadDiagnostics(context.Background(), "test-id", "2023-01-30T09-40-02Z-00",
+				int64(len(diagnostics)), bytes.NewBufferString(diagnostics))
+			assert.NotZero(t, scenario.Injected(tc.fault.Route), "the fault was never injected")
+			if tc.wantErr {
+				require.Error(t, err)
+				assert.False(t, completed)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "test-upload", id)
+			assert.Equal(t, map[int32]string{0: "abcde", 1: "fghij", 2: "kl"}, chunks)
+			assert.True(t, completed)
+		})
+	}
+}
@@ -10,8 +10,8 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +37,17 @@ type requestClient struct {
 	lastUsed   time.Time
 	lastErr    error
 	lastErrOcc time.Time
+
+	// priority is the position of the host in the configuration.
+	priority int
+	// latency is the moving average of the connection latency.
+	latency time.Duration
+
+	consecutiveFailures int
+	circuit             CircuitState
+	circuitOpenedAt     time.Time
+	// probing is set while the request probing a half-open circuit is in flight.
+	probing bool
 }
 
 func (r *requestClient) SetLastError(err error) {
@@ -58,6 +69,10 @@ type Client struct {
 	clientLock sync.Mutex
 	clients    []*requestClient
 	config     Config
+
+	selector HostSelector
+	// selected is the client that answered the last request.
+	selected *requestClient
 }
 
 // NewConfigFromURL returns a Config based on a received host.
@@ -140,8 +155,9 @@ func NewWithConfig(log *logger.Logger, cfg Config, wrapper wrapperFunc) (*Client
 		}
 
 		clients[i] = &requestClient{
-			host:   baseURL,
-			client: httpClient,
+			host:     baseURL,
+			client:   httpClient,
+			priority: i,
 		}
 	}
 
@@ -204,11 +220,38 @@ func (c *Client) Send(
 			}
 		}
 
-		resp, err = requester.client.Do(req.WithContext(ctx))
+		c.clientLock.Lock()
+		requester.startRequest()
+		c.clientLock.Unlock()
+
+		var connStart time.Time
+		var connLatency time.Duration
+		trace := &httptrace.ClientTrace{
+			GetConn: func(string) { connStart = time.Now() },
+			GotConn: func(info httptrace.GotConnInfo) {
+				if !info.Reused {
+					connLatency = time.Since(connStart)
+				}
+			},
+		}
+		resp, err = requester.client.Do(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
 
 		// Using the same lock that was used for sorting above
 		c.clientLock.Lock()
 		requester.SetLastError(err)
+		if connLatency > 0 {
+			requester.setLatency(connLatency)
+		}
+		// a cancelled request says nothing about the health of the host.
+		if ctx.Err() == nil {
+			failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+			requester.setResult(failed, time.Now().UTC(), c.config.HostSelection.CircuitBreaker)
+			if !failed {
+				c.selected = requester
+			}
+		} else {
+			requester.probing = false
+		}
 		c.clientLock.Unlock()
 
 		if err != nil {
@@ -260,75 +303,61 @@ func newClient(
 	cfg Config,
 	clients ...*requestClient,
 ) (*Client, error) {
+	selector, err := newHostSelector(cfg.HostSelection.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
 	// Shuffle so all the agents don't access the hosts in the same order
 	rand.Shuffle(len(clients), func(i, j int) {
 		clients[i], clients[j] = clients[j], clients[i]
 	})
 
 	c := &Client{
-		log:     log,
-		clients: clients,
-		config:  cfg,
+		log:      log,
+		clients:  clients,
+		config:   cfg,
+		selector: selector,
 	}
 	return c, nil
 }
 
-// sortClients sort the clients according to the following priority:
-//   - never used
-//   - without errors, last used first when more than one does not have errors
-//   - last errored.
+// sortClients sorts the clients with the configured HostSelector, the
+// default one when none is, and moves the ones with an open circuit last.
 //
-// It also removes the last error after retryOnBadConnTimeout has elapsed.
+// It also removes the last error after retryOnBadConnTimeout has elapsed and
+// moves the open circuits to half-open after their open timeout.
 func (c *Client) sortClients() []*requestClient {
 	c.clientLock.Lock()
 	defer c.clientLock.Unlock()
 
 	now := time.Now().UTC()
 
-	sort.Slice(c.clients, func(i, j int) bool {
-		// First, set them good if the timout has elapsed
-		if c.clients[i].lastErr != nil &&
-			now.Sub(c.clients[i].lastErrOcc) > retryOnBadConnTimeout {
-			c.clients[i].lastErr = nil
-			c.clients[i].lastErrOcc = time.Time{}
-		}
-		if c.clients[j].lastErr != nil &&
-			now.Sub(c.clients[j].lastErrOcc) > retryOnBadConnTimeout {
-			c.clients[j].lastErr = nil
-			c.clients[j].lastErrOcc = time.Time{}
-		}
-
-		// Pick not yet used first, but if both haven't been used yet,
-		// we return false to comply with the sort.Interface definition.
-		if c.clients[i].lastUsed.IsZero() &&
-			c.clients[j].lastUsed.IsZero() {
-			return false
-		}
-
-		// Pick not yet used first
-		if c.clients[i].lastUsed.IsZero() {
-			return true
-		}
-
-		// If none has errors, pick the last used
-		// Then, the one without errors
-		if c.clients[i].lastErr == nil &&
-			c.clients[j].lastErr == nil {
-			return c.clients[i].lastUsed.Before(c.clients[j].lastUsed)
-		}
-
-		// Then, the one without error
-		if c.clients[i].lastErr == nil {
-			return true
+	hosts := make([]HostHealth, len(c.clients))
+	for i, r := range c.clients {
+		if r.lastErr != nil && now.Sub(r.lastErrOcc) > retryOnBadConnTimeout {
+			r.lastErr = nil
+			r.lastErrOcc = time.Time{}
 		}
+		r.updateCircuit(now, c.config.HostSelection.CircuitBreaker)
+		hosts[i] = c.health(r, i)
+	}
 
-		// Lastly, the one that errored last
-		return c.clients[i].lastUsed.Before(c.clients[j].lastUsed)
+	selector := c.selector
+	if selector == nil {
+		selector = defaultSelector{}
+	}
+	selector.Sort(hosts)
+	sortByCircuit(hosts, func(h HostHealth) bool {
+		return c.clients[h.index].probing
 	})
 
 	// return a copy of the slice so we can iterate over it without the lock
 	res := make([]*requestClient, len(c.clients))
-	copy(res, c.clients)
+	for i, h := range hosts {
+		res[i] = c.clients[h.index]
+	}
+	copy(c.clients, res)
 	return res
 }
 
@@ -19,6 +19,14 @@ type Config struct {
 	Host     string   `config:"host" yaml:"host,omitempty"`
 	Hosts    []string `config:"hosts" yaml:"hosts,omitempty"`
 
+	// Compression is the content encoding used to compress request bodies when the
+	// remote API supports it, empty or "none" disables it.
+	Compression string `config:"compression" yaml:"compression,omitempty"`
+
+	// HostSelection configures the order the hosts are tried in and their
+	// circuit breakers.
+	HostSelection HostSelectionConfig `config:"host_selection" yaml:"host_selection,omitempty"`
+
 	Transport httpcommon.HTTPTransportSettings `config:",inline" yaml:",inline"`
 }
 
@@ -49,11 +57,12 @@ func DefaultClientConfig() Config {
 	transport.Timeout = 10 * time.Minute
 
 	return Config{
-		Protocol:  ProtocolHTTP,
-		Host:      "localhost:5601",
-		Path:      "",
-		SpaceID:   "",
-		Transport: transport,
+		Protocol:      ProtocolHTTP,
+		Host:          "localhost:5601",
+		Path:          "",
+		SpaceID:       "",
+		HostSelection: DefaultHostSelectionConfig(),
+		Transport:     transport,
 	}
 }
 
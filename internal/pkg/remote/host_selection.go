@@ -0,0 +1,300 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remote
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// HostSelectionDefault tries first the hosts never used, then the ones
+	// without errors, least recently used first, and lastly the ones that
+	// errored.
+	HostSelectionDefault = "default"
+	// HostSelectionPriority tries the hosts in the order they are configured:
+	// the first one, the primary, is used as long as it works and the next
+	// ones only when it fails. A failed host is tried again first once its
+	// error expired.
+	HostSelectionPriority = "priority"
+	// HostSelectionLatency tries first the host with the lowest connection
+	// latency, hosts not measured yet are tried before the measured ones.
+	HostSelectionLatency = "latency"
+
+	defaultFailureThreshold = 3
+	defaultOpenTimeout      = time.Minute
+
+	// latencyWeight is the weight of a new sample in the latency EWMA.
+	latencyWeight = 0.3
+)
+
+// CircuitState is the state of the circuit breaker of a host.
+type CircuitState string
+
+const (
+	// CircuitClosed is the state of a healthy host.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen is the state of a host that failed too many times in a row,
+	// it's only tried when all the other hosts failed.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen is the state of a host whose circuit has been open for
+	// the open timeout, the next request probes it: the circuit closes when
+	// the probe succeeds and opens again when it fails.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// HostSelectionConfig configures how the client selects the host a request is
+// sent to.
+type HostSelectionConfig struct {
+	// Strategy is the name of the HostSelector ordering the hosts, see
+	// RegisterHostSelector.
+	Strategy       string               `config:"strategy" yaml:"strategy,omitempty"`
+	CircuitBreaker CircuitBreakerConfig `config:"circuit_breaker" yaml:"circuit_breaker,omitempty"`
+}
+
+// Validate returns an error when the strategy isn't registered.
+func (c *HostSelectionConfig) Validate() error {
+	if _, err := newHostSelector(c.Strategy); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CircuitBreakerConfig configures the circuit breaker of each host.
+type CircuitBreakerConfig struct {
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// FailureThreshold is the number of consecutive failures opening the circuit.
+	FailureThreshold int `config:"failure_threshold" yaml:"failure_threshold,omitempty"`
+	// OpenTimeout is the time the circuit stays open before a request probes
+	// the host again.
+	OpenTimeout time.Duration `config:"open_timeout" yaml:"open_timeout,omitempty"`
+}
+
+// DefaultHostSelectionConfig returns the default host selection configuration.
+func DefaultHostSelectionConfig() HostSelectionConfig {
+	return HostSelectionConfig{
+		Strategy: HostSelectionDefault,
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:          true,
+			FailureThreshold: defaultFailureThreshold,
+			OpenTimeout:      defaultOpenTimeout,
+		},
+	}
+}
+
+func (c CircuitBreakerConfig) failureThreshold() int {
+	if c.FailureThreshold <= 0 {
+		return defaultFailureThreshold
+	}
+	return c.FailureThreshold
+}
+
+func (c CircuitBreakerConfig) openTimeout() time.Duration {
+	if c.OpenTimeout <= 0 {
+		return defaultOpenTimeout
+	}
+	return c.OpenTimeout
+}
+
+// HostHealth is the health of one of the hosts of a Client.
+type HostHealth struct {
+	Host string `yaml:"host" json:"host"`
+	// Priority is the position of the host in the configuration.
+	Priority int `yaml:"priority" json:"priority"`
+	// Selected is set on the host that answered the last request.
+	Selected            bool         `yaml:"selected" json:"selected"`
+	Circuit             CircuitState `yaml:"circuit" json:"circuit"`
+	ConsecutiveFailures int          `yaml:"consecutive_failures" json:"consecutive_failures"`
+	// Latency is the moving average of the time taken to establish a new
+	// connection to the host, reused connections aren't measured.
+	Latency     time.Duration `yaml:"latency" json:"latency"`
+	LastUsed    time.Time     `yaml:"last_used,omitempty" json:"last_used,omitempty"`
+	LastError   string        `yaml:"last_error,omitempty" json:"last_error,omitempty"`
+	LastErrorAt time.Time     `yaml:"last_error_at,omitempty" json:"last_error_at,omitempty"`
+
+	// index is the position of the host in Client.clients.
+	index int
+}
+
+// HostSelector orders the hosts of a Client before each request, the hosts are
+// tried in that order until one of them answers. Whatever the order, the hosts
+// with an open circuit are tried last.
+type HostSelector interface {
+	// Sort sorts hosts in place.
+	Sort(hosts []HostHealth)
+}
+
+var (
+	hostSelectorsMx sync.RWMutex
+	hostSelectors   = map[string]func() HostSelector{
+		HostSelectionDefault:  func() HostSelector { return defaultSelector{} },
+		HostSelectionPriority: func() HostSelector { return prioritySelector{} },
+		HostSelectionLatency:  func() HostSelector { return latencySelector{} },
+	}
+)
+
+// RegisterHostSelector registers a host selection strategy under name, so it
+// can be configured with host_selection.strategy.
+func RegisterHostSelector(name string, factory func() HostSelector) {
+	hostSelectorsMx.Lock()
+	defer hostSelectorsMx.Unlock()
+	hostSelectors[name] = factory
+}
+
+// newHostSelector returns the HostSelector registered under name, an empty
+// name is the default one.
+func newHostSelector(name string) (HostSelector, error) {
+	if name == "" {
+		name = HostSelectionDefault
+	}
+	hostSelectorsMx.RLock()
+	defer hostSelectorsMx.RUnlock()
+	factory, ok := hostSelectors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown host selection strategy %q", name)
+	}
+	return factory(), nil
+}
+
+// defaultSelector sorts the hosts according to the following priority:
+//   - never used
+//   - without errors, least recently used first
+//   - errored, least recently used first.
+type defaultSelector struct{}
+
+func (defaultSelector) Sort(hosts []HostHealth) {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		if hosts[i].LastUsed.IsZero() || hosts[j].LastUsed.IsZero() {
+			return hosts[i].LastUsed.IsZero() && !hosts[j].LastUsed.IsZero()
+		}
+		if erroredI, erroredJ := hosts[i].LastError != "", hosts[j].LastError != ""; erroredI != erroredJ {
+			return !erroredI
+		}
+		return hosts[i].LastUsed.Before(hosts[j].LastUsed)
+	})
+}
+
+// prioritySelector sorts the hosts without errors in the configured order,
+// followed by the errored ones in the configured order.
+type prioritySelector struct{}
+
+func (prioritySelector) Sort(hosts []HostHealth) {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		if erroredI, erroredJ := hosts[i].LastError != "", hosts[j].LastError != ""; erroredI != erroredJ {
+			return !erroredI
+		}
+		return hosts[i].Priority < hosts[j].Priority
+	})
+}
+
+// latencySelector sorts the hosts without errors by latency, the ones not
+// measured yet first, followed by the errored ones by latency.
+type latencySelector struct{}
+
+func (latencySelector) Sort(hosts []HostHealth) {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		if erroredI, erroredJ := hosts[i].LastError != "", hosts[j].LastError != ""; erroredI != erroredJ {
+			return !erroredI
+		}
+		if hosts[i].Latency != hosts[j].Latency {
+			return hosts[i].Latency < hosts[j].Latency
+		}
+		return hosts[i].Priority < hosts[j].Priority
+	})
+}
+
+// sortByCircuit moves the hosts whose circuit is open, or half-open with a
+// probe in flight, after the others keeping their order.
+func sortByCircuit(hosts []HostHealth, probing func(HostHealth) bool) {
+	unavailable := func(h HostHealth) bool {
+		return h.Circuit == CircuitOpen || (h.Circuit == CircuitHalfOpen && probing(h))
+	}
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return !unavailable(hosts[i]) && unavailable(hosts[j])
+	})
+}
+
+// updateCircuit moves an open circuit to half-open once the open timeout has
+// elapsed.
+func (r *requestClient) updateCircuit(now time.Time, cfg CircuitBreakerConfig) {
+	if r.circuit == CircuitOpen && now.Sub(r.circuitOpenedAt) >= cfg.openTimeout() {
+		r.circuit = CircuitHalfOpen
+		r.probing = false
+	}
+}
+
+// startRequest marks the request as the probe of a half-open circuit.
+func (r *requestClient) startRequest() {
+	if r.circuit == CircuitHalfOpen {
+		r.probing = true
+	}
+}
+
+// setResult records the result of a request in the circuit breaker of the host.
+func (r *requestClient) setResult(failed bool, now time.Time, cfg CircuitBreakerConfig) {
+	r.probing = false
+	if !failed {
+		r.consecutiveFailures = 0
+		r.circuit = CircuitClosed
+		return
+	}
+
+	r.consecutiveFailures++
+	if !cfg.Enabled {
+		return
+	}
+	if r.circuit == CircuitHalfOpen || r.consecutiveFailures >= cfg.failureThreshold() {
+		r.circuit = CircuitOpen
+		r.circuitOpenedAt = now
+	}
+}
+
+// setLatency adds a connection latency sample to the moving average.
+func (r *requestClient) setLatency(d time.Duration) {
+	if r.latency == 0 {
+		r.latency = d
+		return
+	}
+	r.latency = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(r.latency))
+}
+
+// health returns the health of the host, r must be the i-th client of c.
+func (c *Client) health(r *requestClient, i int) HostHealth {
+	h := HostHealth{
+		Host:                r.host,
+		Priority:            r.priority,
+		Selected:            r == c.selected,
+		Circuit:             r.circuit,
+		ConsecutiveFailures: r.consecutiveFailures,
+		Latency:             r.latency,
+		LastUsed:            r.lastUsed,
+		LastErrorAt:         r.lastErrOcc,
+		index:               i,
+	}
+	if h.Circuit == "" {
+		h.Circuit = CircuitClosed
+	}
+	if r.lastErr != nil {
+		h.LastError = r.lastErr.Error()
+	}
+	return h
+}
+
+// Hosts returns the health of the hosts of the client in the configured order.
+func (c *Client) Hosts() []HostHealth {
+	c.clientLock.Lock()
+	defer c.clientLock.Unlock()
+
+	hosts := make([]HostHealth, len(c.clients))
+	for i, r := range c.clients {
+		hosts[i] = c.health(r, i)
+	}
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return hosts[i].Priority < hosts[j].Priority
+	})
+	return hosts
+}
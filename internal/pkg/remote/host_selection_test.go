@@ -0,0 +1,190 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestHostSelectors(t *testing.T) {
+	now := time.Now().UTC()
+	hosts := func() []HostHealth {
+		return []HostHealth{
+			{Host: "errored", Priority: 0, LastUsed: now.Add(-3 * time.Minute), LastError: "fake error", Latency: time.Millisecond},
+			{Host: "slow", Priority: 1, LastUsed: now.Add(-time.Minute), Latency: 200 * time.Millisecond},
+			{Host: "fast", Priority: 2, LastUsed: now.Add(-2 * time.Minute), Latency: 10 * time.Millisecond},
+			{Host: "unused", Priority: 3},
+		}
+	}
+	names := func(hosts []HostHealth) []string {
+		res := make([]string, 0, len(hosts))
+		for _, h := range hosts {
+			res = append(res, h.Host)
+		}
+		return res
+	}
+
+	testCases := []struct {
+		strategy string
+		expected []string
+	}{
+		{HostSelectionDefault, []string{"unused", "fast", "slow", "errored"}},
+		{HostSelectionPriority, []string{"slow", "fast", "unused", "errored"}},
+		{HostSelectionLatency, []string{"unused", "fast", "slow", "errored"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			selector, err := newHostSelector(tc.strategy)
+			require.NoError(t, err)
+
+			h := hosts()
+			selector.Sort(h)
+			assert.Equal(t, tc.expected, names(h))
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := newHostSelector("unknown")
+		assert.ErrorContains(t, err, `unknown host selection strategy "unknown"`)
+
+		cfg := config.MustNewConfigFrom(map[string]interface{}{
+			"host":                    "localhost",
+			"host_selection.strategy": "unknown",
+		})
+		_, err = NewWithRawConfig(nil, cfg, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		RegisterHostSelector("reverse", func() HostSelector { return reverseSelector{} })
+
+		selector, err := newHostSelector("reverse")
+		require.NoError(t, err)
+		h := hosts()
+		selector.Sort(h)
+		assert.Equal(t, []string{"unused", "fast", "slow", "errored"}, names(h))
+	})
+}
+
+type reverseSelector struct{}
+
+func (reverseSelector) Sort(hosts []HostHealth) {
+	for i, j := 0, len(hosts)-1; i < j; i, j = i+1, j-1 {
+		hosts[i], hosts[j] = hosts[j], hosts[i]
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	cfg := Config{HostSelection: HostSelectionConfig{
+		Strategy: HostSelectionPriority,
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:          true,
+			FailureThreshold: 2,
+			OpenTimeout:      time.Minute,
+		},
+	}}
+	primary := &requestClient{host: "primary", priority: 0}
+	secondary := &requestClient{host: "secondary", priority: 1}
+	client, err := newClient(nil, cfg, primary, secondary)
+	require.NoError(t, err)
+	breaker := cfg.HostSelection.CircuitBreaker
+	now := time.Now().UTC()
+
+	primary.setResult(true, now, breaker)
+	assert.Equal(t, CircuitState(""), primary.circuit, "the circuit opens after 2 failures")
+	primary.setResult(true, now, breaker)
+	assert.Equal(t, CircuitOpen, primary.circuit)
+
+	// the primary would come first without the circuit breaker
+	assert.Equal(t, secondary, client.sortClients()[0])
+
+	// once the open timeout elapsed, a single request probes the primary
+	primary.circuitOpenedAt = now.Add(-2 * time.Minute)
+	assert.Equal(t, primary, client.sortClients()[0])
+	assert.Equal(t, CircuitHalfOpen, primary.circuit)
+	primary.startRequest()
+	assert.Equal(t, secondary, client.sortClients()[0], "the probe is in flight")
+
+	// a failed probe opens the circuit again
+	primary.setResult(true, now, breaker)
+	assert.Equal(t, CircuitOpen, primary.circuit)
+	assert.Equal(t, 3, primary.consecutiveFailures)
+
+	// a successful probe closes it
+	primary.circuitOpenedAt = now.Add(-2 * time.Minute)
+	client.sortClients()
+	primary.startRequest()
+	primary.setResult(false, now, breaker)
+	assert.Equal(t, CircuitClosed, primary.circuit)
+	assert.Zero(t, primary.consecutiveFailures)
+	assert.Equal(t, primary, client.sortClients()[0])
+
+	t.Run("disabled", func(t *testing.T) {
+		r := &requestClient{}
+		for i := 0; i < 10; i++ {
+			r.setResult(true, now, CircuitBreakerConfig{})
+		}
+		assert.NotEqual(t, CircuitOpen, r.circuit)
+		assert.Equal(t, 10, r.consecutiveFailures)
+	})
+}
+
+func TestSendFailover(t *testing.T) {
+	l, err := logger.New("", false)
+	require.NoError(t, err)
+
+	// a closed listener gives an address refusing connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "hello")
+	}))
+	defer s.Close()
+	up := s.Listener.Addr().String()
+
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"hosts":                                  []string{down, up},
+		"host_selection.strategy":                HostSelectionPriority,
+		"host_selection.circuit_breaker.enabled": true,
+		"host_selection.circuit_breaker.failure_threshold": 1,
+	})
+	client, err := NewWithRawConfig(l, cfg, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Send(context.Background(), http.MethodGet, "/", nil, nil, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	hosts := client.Hosts()
+	require.Len(t, hosts, 2)
+
+	assert.Equal(t, "http://"+down+"/", hosts[0].Host)
+	assert.False(t, hosts[0].Selected)
+	assert.Equal(t, CircuitOpen, hosts[0].Circuit)
+	assert.Equal(t, 1, hosts[0].ConsecutiveFailures)
+	assert.NotEmpty(t, hosts[0].LastError)
+
+	assert.Equal(t, "http://"+up+"/", hosts[1].Host)
+	assert.True(t, hosts[1].Selected)
+	assert.Equal(t, CircuitClosed, hosts[1].Circuit)
+	assert.Zero(t, hosts[1].ConsecutiveFailures)
+	assert.Positive(t, hosts[1].Latency)
+}
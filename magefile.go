@@ -29,6 +29,7 @@ import (
 	"github.com/elastic/elastic-agent/dev-tools/mage/manifest"
 	"github.com/elastic/elastic-agent/pkg/testing/define"
 	"github.com/elastic/elastic-agent/pkg/testing/ess"
+	"github.com/elastic/elastic-agent/pkg/testing/localstack"
 	"github.com/elastic/elastic-agent/pkg/testing/multipass"
 	"github.com/elastic/elastic-agent/pkg/testing/ogc"
 	"github.com/elastic/elastic-agent/pkg/testing/runner"
@@ -550,7 +551,7 @@ func commitID() string {
 
 // Update is an alias for executing control protocol, configs, and specs.
 func Update() {
-	mg.SerialDeps(Config, BuildPGP, BuildFleetCfg)
+	mg.SerialDeps(Config, BuildPGP, BuildFleetCfg, BuildRESTAPI)
 }
 
 // CrossBuild cross-builds the beat for all target platforms.
@@ -654,6 +655,15 @@ func UnitTest() {
 	mg.Deps(Test.All)
 }
 
+// BuildRESTAPI generates the OpenAPI document of the local REST management API.
+func BuildRESTAPI() error {
+	goF := filepath.Join("dev-tools", "cmd", "buildrestapi", "build_rest_api.go")
+	out := filepath.Join("docs", "rest-api.openapi.json")
+
+	fmt.Printf(">> BuildRESTAPI to %s\n", out)
+	return RunGo("run", goF, "--output", out)
+}
+
 // BuildFleetCfg embed the default fleet configuration as part of the binary.
 func BuildFleetCfg() error {
 	goF := filepath.Join("dev-tools", "cmd", "buildfleetcfg", "buildfleetcfg.go")
@@ -1802,10 +1812,12 @@ func createTestRunner(matrix bool, singleTest string, goTestFlags string, batche
 		stackProvisionerMode = ess.ProvisionerStateful
 	}
 	if stackProvisionerMode != ess.ProvisionerStateful &&
-		stackProvisionerMode != ess.ProvisionerServerless {
-		return nil, fmt.Errorf("STACK_PROVISIONER environment variable must be one of %q or %q, not %s",
+		stackProvisionerMode != ess.ProvisionerServerless &&
+		stackProvisionerMode != localstack.ProvisionerLocal {
+		return nil, fmt.Errorf("STACK_PROVISIONER environment variable must be one of %q, %q or %q, not %s",
 			ess.ProvisionerStateful,
 			ess.ProvisionerServerless,
+			localstack.ProvisionerLocal,
 			stackProvisionerMode)
 	}
 	fmt.Printf(">>>> Using %s stack provisioner\n", stackProvisionerMode)
@@ -1891,6 +1903,16 @@ func createTestRunner(matrix bool, singleTest string, goTestFlags string, batche
 		if err != nil {
 			return nil, err
 		}
+	} else if stackProvisionerMode == localstack.ProvisionerLocal {
+		// the local stack runs in this process, it must be reachable from the instances.
+		localHost := os.Getenv("STACK_LOCAL_HOST")
+		if localHost == "" {
+			localHost = localstack.DefaultHost()
+		}
+		stackProvisioner, err = localstack.NewProvisioner(localstack.ProvisionerConfig{Host: localHost})
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, fmt.Errorf("unknown stack provisioner: %s", stackProvisionerMode)
 	}
@@ -7,6 +7,7 @@ package component
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -38,6 +39,19 @@ const (
 	elasticsearchType   = "elasticsearch"
 )
 
+const (
+	// SourceKey is set by the agent on the policy inputs that don't come from the
+	// policy itself, it is removed before the input is sent to the component.
+	SourceKey = "_source"
+	// SourceLocal is the source of the inputs of the local overlay of a
+	// Fleet-managed agent.
+	SourceLocal = "local"
+)
+
+// isolationGroupRegexp matches the valid isolation group names, they are part
+// of the component IDs used in paths.
+var isolationGroupRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
 // ErrInputRuntimeCheckFail error is used when an input specification runtime prevention check occurs.
 type ErrInputRuntimeCheckFail struct {
 	// message is the reason defined in the check
@@ -84,6 +98,10 @@ type Unit struct {
 	// Err used when the Config cannot be marshalled from its value into a configuration that
 	// can actually be sent to a unit. All units with Err set should not be sent to the component.
 	Err error `yaml:"error,omitempty"`
+
+	// Source is where the input of the unit comes from when it isn't the policy, like
+	// SourceLocal for the local overlay of a Fleet-managed agent.
+	Source string `yaml:"source,omitempty"`
 }
 
 // Signed Strongly typed configuration for the signed data
@@ -189,6 +207,33 @@ type Component struct {
 	// ShipperRef references the component/unit that this component used as its output.
 	// (only applies to inputs targeting a shipper, not set when ShipperSpec is)
 	ShipperRef *ShipperReference `yaml:"shipper,omitempty"`
+
+	// IsolationGroup is the isolation group of the input units, they run in their own
+	// process instead of the one shared by the inputs of the same type and output.
+	IsolationGroup string `yaml:"isolation_group,omitempty"`
+
+	// FanOut is set when the input units write to several outputs, the component has then
+	// one output unit per output or is one of the duplicated components of the inputs.
+	FanOut *FanOut `yaml:"fan_out,omitempty"`
+}
+
+// FanOut links the components of the inputs writing to several outputs.
+type FanOut struct {
+	// ID identifies the inputs, the duplicated components of the inputs share it.
+	ID string `yaml:"id"`
+
+	// Outputs are the enabled outputs of the inputs, in the order of the policy.
+	Outputs []FanOutOutput `yaml:"outputs"`
+
+	// Duplicated is true when the input doesn't support several outputs, it runs then in
+	// one component per output.
+	Duplicated bool `yaml:"duplicated,omitempty"`
+}
+
+// FanOutOutput is an output of the inputs writing to several outputs.
+type FanOutOutput struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
 }
 
 func (c Component) MarshalYAML() (interface{}, error) {
@@ -198,6 +243,21 @@ func (c Component) MarshalYAML() (interface{}, error) {
 	return c, nil
 }
 
+// OutputTypes returns the types of the outputs of the component, the ones of all the outputs
+// when it fans out to several outputs.
+func (c *Component) OutputTypes() []string {
+	if c.FanOut == nil || c.FanOut.Duplicated {
+		return []string{c.OutputType}
+	}
+	types := make([]string, 0, len(c.FanOut.Outputs))
+	for _, output := range c.FanOut.Outputs {
+		if !containsStr(types, output.Type) {
+			types = append(types, output.Type)
+		}
+	}
+	return types
+}
+
 // Type returns the type of the component.
 func (c *Component) Type() string {
 	if c.InputSpec != nil {
@@ -306,6 +366,7 @@ func unitForInput(input inputI, id string) Unit {
 		LogLevel: input.logLevel,
 		Config:   cfg,
 		Err:      cfgErr,
+		Source:   input.source,
 	}
 }
 
@@ -333,17 +394,18 @@ func unitForShipperOutput(output outputI, id string, shipperType string) Unit {
 	}
 }
 
-// Collect all inputs of the given type going to the given output and return
-// the resulting Component. The returned Component may have no units if no
-// active inputs were found.
+// Collect the inputs of the given type and isolation group going to the given
+// output and return the resulting Component. The returned Component may have
+// no units if no active inputs were found.
 func (r *RuntimeSpecs) componentForInputType(
+	componentID string,
 	inputType string,
+	isolationGroup string,
+	inputs []inputI,
 	output outputI,
 	featureFlags *features.Flags,
 	componentConfig *ComponentConfig,
 ) Component {
-	componentID := fmt.Sprintf("%s-%s", inputType, output.name)
-
 	inputSpec, componentErr := r.GetInput(inputType)
 	var shipperRef *ShipperReference
 	if componentErr == nil {
@@ -375,12 +437,15 @@ func (r *RuntimeSpecs) componentForInputType(
 			}
 		}
 	}
+	if componentErr == nil && isolationGroup != "" && inputSpec.Spec.Service != nil {
+		componentErr = ErrIsolationGroupNotSupported
+	}
 	// If there's an error at this point we still proceed with assembling the
 	// policy into a component, we just attach the error to its Err field to
 	// indicate that it can't be run.
 
 	var units []Unit
-	for _, input := range output.inputs[inputType] {
+	for _, input := range inputs {
 		if input.enabled {
 			unitID := fmt.Sprintf("%s-%s", componentID, input.id)
 			units = append(units, unitForInput(input, unitID))
@@ -408,19 +473,95 @@ func (r *RuntimeSpecs) componentForInputType(
 		Features:   featureFlags.AsProto(),
 		Component:  componentConfig.AsProto(),
 		ShipperRef: shipperRef,
+
+		IsolationGroup: isolationGroup,
 	}
 }
 
-func (r *RuntimeSpecs) componentsForOutput(output outputI, featureFlags *features.Flags, componentConfig *ComponentConfig) []Component {
+// isolationGroups splits the inputs by isolation group, the inputs without
+// one or in a group not in allowed are in the "" group. A nil allowed allows
+// all the groups. The groups are returned in name order.
+func isolationGroups(inputs []inputI, allowed map[string]bool) ([]string, map[string][]inputI) {
+	grouped := make(map[string][]inputI)
+	folded := false
+	for _, input := range inputs {
+		group := input.isolationGroup
+		if group != "" && allowed != nil && !allowed[group] {
+			group = ""
+			folded = true
+		}
+		grouped[group] = append(grouped[group], input)
+	}
+	if folded {
+		// keep the policy order in the shared component
+		sort.SliceStable(grouped[""], func(i, j int) bool {
+			return grouped[""][i].idx < grouped[""][j].idx
+		})
+	}
+	groups := make([]string, 0, len(grouped))
+	for group := range grouped {
+		if group != "" {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	if _, ok := grouped[""]; ok {
+		groups = append([]string{""}, groups...)
+	}
+	return groups, grouped
+}
+
+// allowedIsolationGroups returns the isolation groups running in their own components, nil when
+// they all do. Above the limit, the groups are allowed in the order of their first input in the
+// policy, so adding a group never moves the groups already running. The limit applies to the
+// whole agent, a group counts once whatever the types and outputs of its inputs.
+func (r *RuntimeSpecs) allowedIsolationGroups(outputsMap map[string]outputI) map[string]bool {
+	if !r.limitIsolationGroups {
+		return nil
+	}
+	firstIdx := make(map[string]int)
+	collect := func(byType map[string][]inputI) {
+		for _, inputs := range byType {
+			for _, input := range inputs {
+				if input.isolationGroup == "" {
+					continue
+				}
+				if idx, ok := firstIdx[input.isolationGroup]; !ok || input.idx < idx {
+					firstIdx[input.isolationGroup] = input.idx
+				}
+			}
+		}
+	}
+	for _, output := range outputsMap {
+		if output.enabled {
+			collect(output.inputs)
+			collect(output.fanOutInputs)
+		}
+	}
+
+	groups := make([]string, 0, len(firstIdx))
+	for group := range firstIdx {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return firstIdx[groups[i]] < firstIdx[groups[j]]
+	})
+	if len(groups) > r.maxIsolationGroups {
+		groups = groups[:r.maxIsolationGroups]
+	}
+	allowed := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		allowed[group] = true
+	}
+	return allowed
+}
+
+// componentsForOutput returns the components of the inputs going to the given output, including
+// the duplicated components of the fan-out groups that can't write to several outputs.
+func (r *RuntimeSpecs) componentsForOutput(output outputI, duplicated []fanOutGroup, allowedGroups map[string]bool, featureFlags *features.Flags, componentConfig *ComponentConfig) []Component {
 	var components []Component
 	shipperTypes := make(map[string]bool)
-	for inputType := range output.inputs {
-		// No need for error checking at this stage -- we are guaranteed
-		// to get a Component back. If there is an error that prevents it
-		// from running then it will be in the Component's Err field and
-		// we will report it later. The only thing we skip is a component
-		// with no units.
-		component := r.componentForInputType(inputType, output, featureFlags, componentConfig)
+	add := func(component Component) {
 		if len(component.Units) > 0 {
 			if component.ShipperRef != nil {
 				// If this component uses a shipper, mark that shipper type as active
@@ -429,6 +570,27 @@ func (r *RuntimeSpecs) componentsForOutput(output outputI, featureFlags *feature
 			components = append(components, component)
 		}
 	}
+	for inputType, inputs := range output.inputs {
+		groups, grouped := isolationGroups(inputs, allowedGroups)
+		for _, group := range groups {
+			componentID := fmt.Sprintf("%s-%s", inputType, output.name)
+			if group != "" {
+				componentID = fmt.Sprintf("%s-%s", componentID, group)
+			}
+			// No need for error checking at this stage -- we are guaranteed
+			// to get a Component back. If there is an error that prevents it
+			// from running then it will be in the Component's Err field and
+			// we will report it later. The only thing we skip is a component
+			// with no units.
+			add(r.componentForInputType(componentID, inputType, group, grouped[group], output, featureFlags, componentConfig))
+		}
+	}
+	for _, fanOut := range duplicated {
+		component := r.componentForInputType(fmt.Sprintf("%s-%s", fanOut.id, output.name),
+			fanOut.inputType, fanOut.isolationGroup, fanOut.inputs, output, featureFlags, componentConfig)
+		component.FanOut = fanOut.reference(true)
+		add(component)
+	}
 
 	// create the shipper components to go with the inputs
 	for shipperType := range shipperTypes {
@@ -529,13 +691,39 @@ func (r *RuntimeSpecs) PolicyToComponents(
 		Limits: ComponentLimits(*limits),
 	}
 
+	// the inputs writing to several outputs run in one component when they
+	// support it, or are duplicated in one component per output
 	var components []Component
+	allowedGroups := r.allowedIsolationGroups(outputsMap)
+	duplicated := make(map[string][]fanOutGroup)
+	for _, group := range r.fanOutGroups(outputsMap, allowedGroups) {
+		if !r.fanOutSupported(group) {
+			for _, output := range group.outputs {
+				duplicated[output.name] = append(duplicated[output.name], group)
+			}
+			continue
+		}
+		if component := r.componentForFanOut(group, featureFlags, componentConfig); len(component.Units) > 0 {
+			components = append(components, component)
+		}
+	}
+
 	for _, outputName := range outputKeys {
 		output := outputsMap[outputName]
 		if output.enabled {
 			components = append(components,
-				r.componentsForOutput(output, featureFlags, componentConfig)...)
+				r.componentsForOutput(output, duplicated[outputName], allowedGroups, featureFlags, componentConfig)...)
+		}
+	}
+
+	// the ID of an isolation group component can be the one of the component
+	// of an output with a dash in its name
+	ids := make(map[string]bool, len(components))
+	for _, comp := range components {
+		if ids[comp.ID] {
+			return nil, fmt.Errorf("component ID %q is used twice, rename the isolation group or the output", comp.ID)
 		}
+		ids[comp.ID] = true
 	}
 
 	return components, nil
@@ -643,6 +831,8 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 		idKey        = "id"
 		useOutputKey = "use_output"
 		shipperKey   = "shipper"
+
+		isolationGroupKey = "isolation_group"
 	)
 
 	// intermediate structure for output to input mapping (this structure allows different input types per output)
@@ -728,6 +918,7 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 			outputType:     t,
 			config:         output,
 			inputs:         make(map[string][]inputI),
+			fanOutInputs:   make(map[string][]inputI),
 			shipperEnabled: shipperEnabled,
 		}
 	}
@@ -772,19 +963,22 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 		if hasDuplicate(outputsMap, id) {
 			return nil, fmt.Errorf("invalid 'inputs.%d.id', has a duplicate id %q. Please add a unique value for the 'id' key to each input in the agent policy", idx, id)
 		}
-		outputName := "default"
+		outputNames := []string{"default"}
 		if outputRaw, ok := input[useOutputKey]; ok {
-			outputNameVal, ok := outputRaw.(string)
-			if !ok {
-				return nil, fmt.Errorf("invalid 'inputs.%d.use_output', expected a string not a %T", idx, outputRaw)
+			names, err := useOutputNames(outputRaw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid 'inputs.%d.use_output', %w", idx, err)
 			}
-			outputName = outputNameVal
+			outputNames = names
 			delete(input, useOutputKey)
 		}
-		output, ok := outputsMap[outputName]
-		if !ok {
-			return nil, fmt.Errorf("invalid 'inputs.%d.use_output', references an unknown output '%s'", idx, outputName)
+		for _, outputName := range outputNames {
+			if _, ok := outputsMap[outputName]; !ok {
+				return nil, fmt.Errorf("invalid 'inputs.%d.use_output', references an unknown output '%s'", idx, outputName)
+			}
 		}
+		// the inputs writing to several outputs are kept by their first output
+		output := outputsMap[outputNames[0]]
 		enabled := true
 		if enabledRaw, ok := input[enabledKey]; ok {
 			enabledVal, ok := enabledRaw.(bool)
@@ -798,19 +992,41 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 		if err != nil {
 			return nil, fmt.Errorf("invalid 'inputs.%d.log_level', %w", idx, err)
 		}
+		isolationGroup := ""
+		if groupRaw, ok := input[isolationGroupKey]; ok {
+			groupVal, ok := groupRaw.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid 'inputs.%d.isolation_group', expected a string not a %T", idx, groupRaw)
+			}
+			if groupVal != "" && !isolationGroupRegexp.MatchString(groupVal) {
+				return nil, fmt.Errorf("invalid 'inputs.%d.isolation_group', %q must only contain letters, digits, '_' and '-'", idx, groupVal)
+			}
+			isolationGroup = groupVal
+			delete(input, isolationGroupKey)
+		}
+		source, _ := input[SourceKey].(string)
+		delete(input, SourceKey)
 
 		// Inject the top level fleet policy revision into each input configuration. This
 		// allows individual inputs (like endpoint) to detect policy changes more easily.
 		injectInputPolicyID(policy, input)
 
-		output.inputs[t] = append(output.inputs[t], inputI{
-			idx:       idx,
-			id:        id,
-			enabled:   enabled,
-			logLevel:  logLevel,
-			inputType: t,
-			config:    input,
-		})
+		in := inputI{
+			idx:            idx,
+			id:             id,
+			enabled:        enabled,
+			logLevel:       logLevel,
+			inputType:      t,
+			isolationGroup: isolationGroup,
+			source:         source,
+			config:         input,
+		}
+		if len(outputNames) > 1 {
+			in.outputs = outputNames
+			output.fanOutInputs[t] = append(output.fanOutInputs[t], in)
+		} else {
+			output.inputs[t] = append(output.inputs[t], in)
+		}
 	}
 	if len(outputsMap) == 0 {
 		return nil, nil
@@ -825,8 +1041,19 @@ type inputI struct {
 	logLevel  client.UnitLogLevel
 	inputType string // canonical (non-alias) type
 
+	// isolationGroup runs the input in its own component, with the other inputs
+	// of the group, when set.
+	isolationGroup string
+
+	// source is where the input comes from when it isn't the policy.
+	source string
+
+	// outputs are the names of the outputs of the input when it writes to
+	// several outputs, in the order of the policy.
+	outputs []string
+
 	// The raw configuration for this input, with small cleanups:
-	// - the "enabled", "use_output", and "log_level" keys are removed
+	// - the "enabled", "use_output", "log_level", "isolation_group" and SourceKey keys are removed
 	// - the key "policy.revision" is set to the current fleet policy revision
 	config map[string]interface{}
 }
@@ -848,6 +1075,10 @@ type outputI struct {
 	// inputs directed at this output, keyed by canonical (non-alias) type.
 	inputs map[string][]inputI
 
+	// fanOutInputs are the inputs directed at several outputs, this one being
+	// the first, keyed by canonical (non-alias) type.
+	fanOutInputs map[string][]inputI
+
 	// If true, RuntimeSpecs should use a shipper for this output when
 	// possible. Inputs that don't support a matching shipper will fall back
 	// to a legacy output.
@@ -914,10 +1145,12 @@ func validateRuntimeChecks(
 
 func hasDuplicate(outputsMap map[string]outputI, id string) bool {
 	for _, o := range outputsMap {
-		for _, i := range o.inputs {
-			for _, j := range i {
-				if j.id == id {
-					return true
+		for _, inputs := range []map[string][]inputI{o.inputs, o.fanOutInputs} {
+			for _, i := range inputs {
+				for _, j := range i {
+					if j.id == id {
+						return true
+					}
 				}
 			}
 		}
@@ -925,6 +1158,33 @@ func hasDuplicate(outputsMap map[string]outputI, id string) bool {
 	return false
 }
 
+// useOutputNames returns the names of the outputs of an input, use_output is the name of an
+// output or a list of names.
+func useOutputNames(raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("expected at least one output")
+		}
+		names := make([]string, 0, len(v))
+		for i, nameRaw := range v {
+			name, ok := nameRaw.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string not a %T at index %d", nameRaw, i)
+			}
+			if containsStr(names, name) {
+				return nil, fmt.Errorf("references the output '%s' more than once", name)
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	default:
+		return nil, fmt.Errorf("expected a string or a list of strings not a %T", raw)
+	}
+}
+
 func getLogLevel(val map[string]interface{}, ll logp.Level) (client.UnitLogLevel, error) {
 	const logLevelKey = "log_level"
 
@@ -271,7 +271,7 @@ func TestToComponents(t *testing.T) {
 					},
 				},
 			},
-			Err: "invalid 'inputs.0.use_output', expected a string not a int",
+			Err: "invalid 'inputs.0.use_output', expected a string or a list of strings not a int",
 		},
 		{
 			Name:     "Invalid: inputs entry use_output references unknown output",
@@ -2457,3 +2457,370 @@ func TestFlattenedDataStream(t *testing.T) {
 		t.Errorf("expecting DataStream.Namespace: %q, got: %q", expectedNamespace, dataStream.Namespace)
 	}
 }
+
+func TestIsolationGroups(t *testing.T) {
+	linuxAMD64Platform := PlatformDetail{
+		Platform: Platform{
+			OS:   Linux,
+			Arch: AMD64,
+			GOOS: Linux,
+		},
+	}
+	input := func(inputType, id, group string) map[string]any {
+		in := map[string]any{
+			"type":    inputType,
+			"id":      id,
+			"enabled": true,
+		}
+		if group != "" {
+			in["isolation_group"] = group
+		}
+		return in
+	}
+	policy := func(inputs ...any) map[string]any {
+		return map[string]any{
+			"outputs": map[string]any{
+				"default": map[string]any{
+					"type": "elasticsearch",
+				},
+			},
+			"inputs": inputs,
+		}
+	}
+	componentIDs := func(comps []Component) map[string][]string {
+		ids := make(map[string][]string)
+		for _, comp := range comps {
+			for _, unit := range comp.Units {
+				if unit.Type == client.UnitTypeInput {
+					ids[comp.ID] = append(ids[comp.ID], unit.ID)
+				}
+			}
+		}
+		return ids
+	}
+
+	t.Run("groups split components", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+
+		comps, err := runtime.ToComponents(policy(
+			input("filestream", "filestream-0", ""),
+			input("filestream", "filestream-1", "nginx"),
+			input("filestream", "filestream-2", "nginx"),
+			input("filestream", "filestream-3", "apache"),
+		), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"filestream-default":        {"filestream-default-filestream-0"},
+			"filestream-default-apache": {"filestream-default-apache-filestream-3"},
+			"filestream-default-nginx":  {"filestream-default-nginx-filestream-1", "filestream-default-nginx-filestream-2"},
+		}, componentIDs(comps))
+		groups := make(map[string]string)
+		for _, comp := range comps {
+			groups[comp.ID] = comp.IsolationGroup
+			for _, unit := range comp.Units {
+				if unit.Type == client.UnitTypeInput {
+					assert.NotContains(t, unit.Config.Source.AsMap(), "isolation_group")
+				}
+			}
+		}
+		assert.Equal(t, map[string]string{
+			"filestream-default":        "",
+			"filestream-default-apache": "apache",
+			"filestream-default-nginx":  "nginx",
+		}, groups)
+	})
+
+	t.Run("groups above the limit are shared", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+		runtime.LimitIsolationGroups(1)
+
+		comps, err := runtime.ToComponents(policy(
+			input("filestream", "filestream-0", "nginx"),
+			input("filestream", "filestream-1", ""),
+			input("filestream", "filestream-2", "apache"),
+		), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"filestream-default":       {"filestream-default-filestream-1", "filestream-default-filestream-2"},
+			"filestream-default-nginx": {"filestream-default-nginx-filestream-0"},
+		}, componentIDs(comps), "the groups of the first inputs of the policy run in their components")
+	})
+
+	t.Run("groups above the limit don't move the running groups", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+		runtime.LimitIsolationGroups(1)
+
+		// apache sorts before nginx, it's added after it in the policy
+		comps, err := runtime.ToComponents(policy(
+			input("filestream", "filestream-0", "nginx"),
+			input("filestream", "filestream-1", "apache"),
+		), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"filestream-default":       {"filestream-default-filestream-1"},
+			"filestream-default-nginx": {"filestream-default-nginx-filestream-0"},
+		}, componentIDs(comps))
+	})
+
+	t.Run("limit applies to the agent", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+		runtime.LimitIsolationGroups(2)
+
+		other := input("log", "log-0", "apache")
+		other["use_output"] = "other"
+		p := policy(
+			input("filestream", "filestream-0", "nginx"),
+			other,
+			input("log", "log-1", "nginx"),
+			input("filestream", "filestream-1", "mysql"),
+		)
+		p["outputs"].(map[string]any)["other"] = map[string]any{"type": "elasticsearch"}
+		comps, err := runtime.ToComponents(p, nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"filestream-default":       {"filestream-default-filestream-1"},
+			"filestream-default-nginx": {"filestream-default-nginx-filestream-0"},
+			"log-default-nginx":        {"log-default-nginx-log-1"},
+			"log-other-apache":         {"log-other-apache-log-0"},
+		}, componentIDs(comps), "a group counts once whatever the types and outputs of its inputs")
+	})
+
+	t.Run("invalid group name", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+
+		_, err = runtime.ToComponents(policy(
+			input("filestream", "filestream-0", "../nginx"),
+		), nil, logp.InfoLevel, nil)
+		assert.ErrorContains(t, err, "invalid 'inputs.0.isolation_group'")
+	})
+
+	t.Run("service inputs are not supported", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+		// the preventions depend on the host running the test
+		endpoint := runtime.inputSpecs["endpoint"]
+		endpoint.Spec.Runtime.Preventions = nil
+		runtime.inputSpecs["endpoint"] = endpoint
+
+		comps, err := runtime.ToComponents(policy(
+			input("endpoint", "endpoint-0", "isolated"),
+		), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		require.Len(t, comps, 1)
+		assert.ErrorIs(t, comps[0].Err, ErrIsolationGroupNotSupported)
+	})
+
+	t.Run("duplicate component IDs", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+
+		in := input("filestream", "filestream-1", "")
+		in["use_output"] = "default-nginx"
+		p := policy(input("filestream", "filestream-0", "nginx"), in)
+		p["outputs"].(map[string]any)["default-nginx"] = map[string]any{"type": "elasticsearch"}
+		_, err = runtime.ToComponents(p, nil, logp.InfoLevel, nil)
+		assert.ErrorContains(t, err, `component ID "filestream-default-nginx" is used twice`)
+	})
+}
+
+func TestFanOut(t *testing.T) {
+	linuxAMD64Platform := PlatformDetail{
+		Platform: Platform{
+			OS:   Linux,
+			Arch: AMD64,
+			GOOS: Linux,
+		},
+	}
+	input := func(id string, useOutput any) map[string]any {
+		in := map[string]any{
+			"type":    "filestream",
+			"id":      id,
+			"enabled": true,
+		}
+		if useOutput != nil {
+			in["use_output"] = useOutput
+		}
+		return in
+	}
+	policy := func(inputs ...any) map[string]any {
+		return map[string]any{
+			"outputs": map[string]any{
+				"default": map[string]any{
+					"type": "elasticsearch",
+				},
+				"kafka": map[string]any{
+					"type": "kafka",
+				},
+			},
+			"inputs": inputs,
+		}
+	}
+	unitIDs := func(comps []Component) map[string][]string {
+		ids := make(map[string][]string)
+		for _, comp := range comps {
+			for _, unit := range comp.Units {
+				ids[comp.ID] = append(ids[comp.ID], unit.ID)
+			}
+		}
+		return ids
+	}
+	fanOuts := func(comps []Component) map[string]*FanOut {
+		refs := make(map[string]*FanOut)
+		for _, comp := range comps {
+			refs[comp.ID] = comp.FanOut
+		}
+		return refs
+	}
+	bothOutputs := []FanOutOutput{{Name: "default", Type: "elasticsearch"}, {Name: "kafka", Type: "kafka"}}
+
+	t.Run("duplicated components", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+
+		comps, err := runtime.ToComponents(policy(
+			input("filestream-0", nil),
+			input("filestream-1", []any{"default", "kafka"}),
+			input("filestream-2", []any{"kafka"}),
+		), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"filestream-default":               {"filestream-default-filestream-0", "filestream-default"},
+			"filestream-kafka":                 {"filestream-kafka-filestream-2", "filestream-kafka"},
+			"filestream-default-kafka-default": {"filestream-default-kafka-default-filestream-1", "filestream-default-kafka-default"},
+			"filestream-default-kafka-kafka":   {"filestream-default-kafka-kafka-filestream-1", "filestream-default-kafka-kafka"},
+		}, unitIDs(comps))
+		duplicated := &FanOut{ID: "filestream-default-kafka", Outputs: bothOutputs, Duplicated: true}
+		assert.Equal(t, map[string]*FanOut{
+			"filestream-default":               nil,
+			"filestream-kafka":                 nil,
+			"filestream-default-kafka-default": duplicated,
+			"filestream-default-kafka-kafka":   duplicated,
+		}, fanOuts(comps))
+		for _, comp := range comps {
+			assert.Equal(t, []string{comp.OutputType}, comp.OutputTypes())
+			for _, unit := range comp.Units {
+				if unit.Type == client.UnitTypeInput {
+					assert.NotContains(t, unit.Config.Source.AsMap(), "use_output")
+				}
+			}
+		}
+	})
+
+	t.Run("fan-out component", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+		filestream := runtime.inputSpecs["filestream"]
+		filestream.Spec.MultipleOutputs = true
+		runtime.inputSpecs["filestream"] = filestream
+
+		comps, err := runtime.ToComponents(policy(
+			input("filestream-0", nil),
+			input("filestream-1", []any{"default", "kafka"}),
+			input("filestream-2", []any{"default", "kafka"}),
+		), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"filestream-default": {"filestream-default-filestream-0", "filestream-default"},
+			"filestream-default-kafka": {
+				"filestream-default-kafka-filestream-1",
+				"filestream-default-kafka-filestream-2",
+				"filestream-default-kafka-default",
+				"filestream-default-kafka-kafka",
+			},
+		}, unitIDs(comps))
+		assert.Equal(t, map[string]*FanOut{
+			"filestream-default":       nil,
+			"filestream-default-kafka": {ID: "filestream-default-kafka", Outputs: bothOutputs},
+		}, fanOuts(comps))
+
+		for _, comp := range comps {
+			if comp.ID != "filestream-default-kafka" {
+				continue
+			}
+			assert.Equal(t, []string{"elasticsearch", "kafka"}, comp.OutputTypes())
+			outputTypes := make(map[string]string)
+			for _, unit := range comp.Units {
+				if unit.Type == client.UnitTypeOutput {
+					outputTypes[unit.ID] = unit.Config.Type
+				}
+			}
+			assert.Equal(t, map[string]string{
+				"filestream-default-kafka-default": "elasticsearch",
+				"filestream-default-kafka-kafka":   "kafka",
+			}, outputTypes)
+		}
+	})
+
+	t.Run("disabled outputs are skipped", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+
+		p := policy(input("filestream-0", []any{"default", "kafka"}))
+		p["outputs"].(map[string]any)["kafka"].(map[string]any)["enabled"] = false
+		comps, err := runtime.ToComponents(p, nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		require.Len(t, comps, 1)
+		assert.Equal(t, "filestream-default-kafka-default", comps[0].ID)
+		assert.Equal(t, []FanOutOutput{{Name: "default", Type: "elasticsearch"}}, comps[0].FanOut.Outputs)
+	})
+
+	t.Run("invalid use_output", func(t *testing.T) {
+		runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+		require.NoError(t, err)
+
+		for useOutput, expected := range map[string]any{
+			"expected at least one output":                      []any{},
+			"expected a string not a int at index 1":            []any{"default", 1},
+			"references the output 'kafka' more than once":      []any{"kafka", "kafka"},
+			"references an unknown output 'other'":              []any{"default", "other"},
+			"expected a string or a list of strings not a bool": true,
+		} {
+			_, err := runtime.ToComponents(policy(input("filestream-0", expected)), nil, logp.InfoLevel, nil)
+			assert.ErrorContains(t, err, "invalid 'inputs.0.use_output', "+useOutput)
+		}
+	})
+}
+
+func TestInputSource(t *testing.T) {
+	policy := map[string]any{
+		"outputs": map[string]any{
+			"default": map[string]any{
+				"type": "elasticsearch",
+			},
+		},
+		"inputs": []any{
+			map[string]any{
+				"type": "filestream",
+				"id":   "filestream-fleet",
+			},
+			map[string]any{
+				"type":    "filestream",
+				"id":      "filestream-local",
+				SourceKey: SourceLocal,
+			},
+		},
+	}
+	runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), PlatformDetail{}, SkipBinaryCheck())
+	require.NoError(t, err)
+
+	comps, err := runtime.ToComponents(policy, nil, logp.InfoLevel, nil)
+	require.NoError(t, err)
+	require.Len(t, comps, 1)
+
+	sources := make(map[string]string)
+	for _, unit := range comps[0].Units {
+		if unit.Type == client.UnitTypeInput {
+			sources[unit.ID] = unit.Source
+			assert.NotContains(t, unit.Config.Source.AsMap(), SourceKey)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"filestream-default-filestream-fleet": "",
+		"filestream-default-filestream-local": SourceLocal,
+	}, sources)
+}
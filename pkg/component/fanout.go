@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package component
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/elastic-agent/pkg/features"
+)
+
+// fanOutGroup is the inputs of a type and isolation group writing to the same outputs.
+type fanOutGroup struct {
+	// id is "<input type>-<output 1>-<output 2>[-<isolation group>]", the ID of the fan-out
+	// component or the prefix of the duplicated components.
+	id             string
+	inputType      string
+	isolationGroup string
+	inputs         []inputI
+
+	// outputs are the enabled outputs of the inputs, in the order of the policy.
+	outputs []outputI
+}
+
+// reference returns the link between the components of the group.
+func (g fanOutGroup) reference(duplicated bool) *FanOut {
+	ref := &FanOut{ID: g.id, Duplicated: duplicated}
+	for _, output := range g.outputs {
+		ref.Outputs = append(ref.Outputs, FanOutOutput{Name: output.name, Type: output.outputType})
+	}
+	return ref
+}
+
+// fanOutGroups groups the inputs writing to several outputs by type, isolation group and
+// outputs. The groups without enabled outputs are skipped, the isolation groups not in
+// allowedGroups are shared, see allowedIsolationGroups.
+func (r *RuntimeSpecs) fanOutGroups(outputsMap map[string]outputI, allowedGroups map[string]bool) []fanOutGroup {
+	byOutputs := make(map[string]map[string][]inputI)
+	for _, output := range outputsMap {
+		for inputType, inputs := range output.fanOutInputs {
+			for _, input := range inputs {
+				key := strings.Join(input.outputs, "\x00")
+				if byOutputs[key] == nil {
+					byOutputs[key] = make(map[string][]inputI)
+				}
+				byOutputs[key][inputType] = append(byOutputs[key][inputType], input)
+			}
+		}
+	}
+
+	var groups []fanOutGroup
+	for _, key := range sortedKeys(byOutputs) {
+		names := strings.Split(key, "\x00")
+		var outputs []outputI
+		for _, name := range names {
+			if output := outputsMap[name]; output.enabled {
+				outputs = append(outputs, output)
+			}
+		}
+		if len(outputs) == 0 {
+			continue
+		}
+		for _, inputType := range sortedKeys(byOutputs[key]) {
+			inputs := byOutputs[key][inputType]
+			// keep the policy order
+			sort.SliceStable(inputs, func(i, j int) bool { return inputs[i].idx < inputs[j].idx })
+			isolationGroups, grouped := isolationGroups(inputs, allowedGroups)
+			for _, isolationGroup := range isolationGroups {
+				id := fmt.Sprintf("%s-%s", inputType, strings.Join(names, "-"))
+				if isolationGroup != "" {
+					id = fmt.Sprintf("%s-%s", id, isolationGroup)
+				}
+				groups = append(groups, fanOutGroup{
+					id:             id,
+					inputType:      inputType,
+					isolationGroup: isolationGroup,
+					inputs:         grouped[isolationGroup],
+					outputs:        outputs,
+				})
+			}
+		}
+	}
+	return groups
+}
+
+// fanOutSupported returns true when the input of the group runs in one component writing to
+// all the outputs of the group. It needs the support of the input spec and of all the output
+// types, and no shipper.
+func (r *RuntimeSpecs) fanOutSupported(group fanOutGroup) bool {
+	inputSpec, err := r.GetInput(group.inputType)
+	if err != nil || !inputSpec.Spec.MultipleOutputs {
+		return false
+	}
+	if group.isolationGroup != "" && inputSpec.Spec.Service != nil {
+		return false
+	}
+	for _, output := range group.outputs {
+		if output.shipperEnabled || !containsStr(inputSpec.Spec.Outputs, output.outputType) {
+			return false
+		}
+	}
+	return true
+}
+
+// componentForFanOut returns the component of the inputs of the group with one output unit
+// per output. The ID of an output unit is the ID of the component followed by the name of
+// the output, its state is the health of the output.
+func (r *RuntimeSpecs) componentForFanOut(
+	group fanOutGroup,
+	featureFlags *features.Flags,
+	componentConfig *ComponentConfig,
+) Component {
+	inputSpec, _ := r.GetInput(group.inputType) // the spec exists, checked by fanOutSupported
+
+	var units []Unit
+	for _, input := range group.inputs {
+		if input.enabled {
+			units = append(units, unitForInput(input, fmt.Sprintf("%s-%s", group.id, input.id)))
+		}
+	}
+	if len(units) > 0 {
+		for _, output := range group.outputs {
+			units = append(units, unitForOutput(output, fmt.Sprintf("%s-%s", group.id, output.name)))
+		}
+	}
+	return Component{
+		ID:         group.id,
+		InputSpec:  &inputSpec,
+		InputType:  group.inputType,
+		OutputType: group.outputs[0].outputType,
+		Units:      units,
+		Features:   featureFlags.AsProto(),
+		Component:  componentConfig.AsProto(),
+
+		IsolationGroup: group.isolationGroup,
+		FanOut:         group.reference(false),
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
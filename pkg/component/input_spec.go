@@ -21,6 +21,11 @@ type InputSpec struct {
 	Shippers       []string    `config:"shippers,omitempty" yaml:"shippers,omitempty"`
 	Runtime        RuntimeSpec `config:"runtime,omitempty" yaml:"runtime,omitempty"`
 
+	// MultipleOutputs is true when the input can write to several outputs from one component,
+	// with one output unit per output. None of the shipped specs set it, their inputs writing
+	// to several outputs are duplicated.
+	MultipleOutputs bool `config:"multiple_outputs,omitempty" yaml:"multiple_outputs,omitempty"`
+
 	Command *CommandSpec `config:"command,omitempty" yaml:"command,omitempty"`
 	Service *ServiceSpec `config:"service,omitempty" yaml:"service,omitempty"`
 }
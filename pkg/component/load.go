@@ -34,6 +34,9 @@ var (
 	ErrOutputShipperNotSupported = newError("no shipper supports this output type")
 	// ErrShipperOutputNotSupported is returned when an input supports at least one shipper, but none of them support the target output type.
 	ErrShipperOutputNotSupported = newError("the input does not support a shipper for this output type")
+	// ErrIsolationGroupNotSupported is returned when an input running as a service is put in an isolation group, only one
+	// instance of the service can run
+	ErrIsolationGroupNotSupported = newError("isolation groups are not supported by inputs running as a service")
 )
 
 // InputRuntimeSpec returns the specification for running this input on the current platform.
@@ -71,6 +74,11 @@ type RuntimeSpecs struct {
 
 	// shipperOutputs maps the supported outputs of a shipper to a shippers name
 	shipperOutputs map[string][]string
+
+	// maxIsolationGroups is the maximum number of isolation groups of the agent, when
+	// limitIsolationGroups is set.
+	maxIsolationGroups   int
+	limitIsolationGroups bool
 }
 
 type loadRuntimeOpts struct {
@@ -331,6 +339,13 @@ func (r *RuntimeSpecs) ServiceSpecs() []InputRuntimeSpec {
 	return services
 }
 
+// LimitIsolationGroups limits the number of isolation groups of the agent, the inputs of the groups above the
+// limit, in the order of the policy, run in the shared components. A negative max removes the limit.
+func (r *RuntimeSpecs) LimitIsolationGroups(max int) {
+	r.maxIsolationGroups = max
+	r.limitIsolationGroups = max >= 0
+}
+
 // LoadSpec loads the component specification.
 //
 // Will error in the case that the specification is not valid. Only valid specifications are allowed.
@@ -97,21 +97,43 @@ type commandRuntime struct {
 	// handled by (*commandRuntime).Run.
 	actionCh chan actionMode
 
+	// restartCh receives the requests to restart the process, see
+	// (*commandRuntime).Restart. A pending request holds the buffer, the
+	// following ones are merged into it.
+	restartCh chan struct{}
+
 	proc *process.Info
+	// restarting is the process stopped to be restarted, it's started again
+	// with new connection information once it exits.
+	restarting *process.Info
 
 	state          ComponentState
 	lastCheckin    time.Time
 	missedCheckins int
 	restartBucket  *rate.Limiter
+
+	// resources configures the sampling of the resources used by the
+	// process, nil disables it. lastResources is the previous sample, used to
+	// compute the CPU usage.
+	resources     *process.ResourcesConfig
+	lastResources process.Resources
+	// resourcesExceeded describes the thresholds exceeded by the last sample,
+	// the component is reported DEGRADED while it's set.
+	resourcesExceeded string
+	// reportedResources is the sample of the last observed state sent, the
+	// following samples are only sent when they differ enough from it.
+	reportedResources *ComponentResources
 }
 
 // newCommandRuntime creates a new command runtime for the provided component.
-func newCommandRuntime(comp component.Component, log *logger.Logger, monitor MonitoringManager) (*commandRuntime, error) {
+func newCommandRuntime(comp component.Component, log *logger.Logger, monitor MonitoringManager, resources *process.ResourcesConfig) (*commandRuntime, error) {
 	c := &commandRuntime{
 		current:     comp,
 		monitor:     monitor,
+		resources:   resources,
 		ch:          make(chan ComponentState),
 		actionCh:    make(chan actionMode, 1),
+		restartCh:   make(chan struct{}, 1),
 		procCh:      make(chan procState),
 		compCh:      make(chan component.Component, 1),
 		actionState: actionStop,
@@ -143,6 +165,12 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 	c.forceCompState(client.UnitStateStarting, "Starting")
 	t := time.NewTicker(checkinPeriod)
 	defer t.Stop()
+	var resourcesC <-chan time.Time
+	if c.resources != nil && c.resources.Period > 0 {
+		rt := time.NewTicker(c.resources.Period)
+		defer rt.Stop()
+		resourcesC = rt.C
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -160,11 +188,29 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 					c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
 				}
 			}
+		case <-c.restartCh:
+			// a component not expected to run reads the new connection information
+			// when it's started again, a process not running is started by the
+			// restart period or already being restarted.
+			if c.actionState == actionStart && c.proc != nil && c.restarting == nil {
+				c.restarting = c.proc
+				if err := c.stop(ctx); err != nil {
+					c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+				}
+			}
 		case ps := <-c.procCh:
 			// ignores old processes
 			if ps.proc == c.proc {
 				c.proc = nil
-				if c.handleProc(ps.state) {
+				restarted := ps.proc == c.restarting && c.actionState == actionStart
+				c.restarting = nil
+				if restarted {
+					c.state.Resources = nil
+					if err := c.start(comm); err != nil {
+						c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+					}
+					t.Reset(checkinPeriod)
+				} else if c.handleProc(ps.state) {
 					// start again after restart period
 					t.Reset(restartPeriod)
 				}
@@ -243,6 +289,15 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 					}
 				}
 			}
+		case <-resourcesC:
+			if c.proc == nil {
+				continue
+			}
+			if err := c.sampleResources(); errors.Is(err, process.ErrResourcesUnsupported) {
+				resourcesC = nil
+			} else if err != nil {
+				_, _ = c.logErr.Write([]byte(fmt.Sprintf("Failed to sample the resources used by pid '%d': %s", c.proc.PID, err)))
+			}
 		}
 	}
 }
@@ -293,6 +348,20 @@ func (c *commandRuntime) Stop() error {
 	return nil
 }
 
+// Restart stops the component and starts it again, it reads new connection information.
+// The restart only applies to a running component expected to run, the other ones read
+// the new connection information when they start.
+//
+// Non-blocking and never returns an error. A restart requested while another one is
+// pending is merged into it.
+func (c *commandRuntime) Restart() error {
+	select {
+	case c.restartCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
 // Teardown tears down the component.
 //
 // Non-blocking and never returns an error.
@@ -315,6 +384,18 @@ func (c *commandRuntime) forceCompState(state client.UnitState, msg string) {
 
 // compState updates just the component state not all the units.
 func (c *commandRuntime) compState(state client.UnitState) {
+	state, msg := c.compStateMessage(state)
+	if c.state.compState(state, msg) {
+		c.sendObserved()
+	}
+}
+
+// compStateMessage returns the component state and its message, a healthy
+// component exceeding its resource thresholds is reported DEGRADED.
+func (c *commandRuntime) compStateMessage(state client.UnitState) (client.UnitState, string) {
+	if state == client.UnitStateHealthy && c.resourcesExceeded != "" {
+		return client.UnitStateDegraded, fmt.Sprintf("Degraded: pid '%d' %s", c.proc.PID, c.resourcesExceeded)
+	}
 	msg := stateUnknownMessage
 	if state == client.UnitStateHealthy {
 		msg = fmt.Sprintf("Healthy: communicating with pid '%d'", c.proc.PID)
@@ -325,12 +406,50 @@ func (c *commandRuntime) compState(state client.UnitState) {
 			msg = fmt.Sprintf("Degraded: pid '%d' missed %d check-ins", c.proc.PID, c.missedCheckins)
 		}
 	}
-	if c.state.compState(state, msg) {
-		c.sendObserved()
+	return state, msg
+}
+
+// sampleResources samples the resources used by the process and reports them
+// with the component state, the observed state is only sent when the sample
+// changes it or differs significantly from the reported one.
+func (c *commandRuntime) sampleResources() error {
+	pid := c.proc.PID
+	r, err := process.SampleResources(pid)
+	if err != nil {
+		return err
+	}
+	res := &ComponentResources{
+		PID:       pid,
+		RSS:       r.RSS,
+		OpenFDs:   r.OpenFDs,
+		SampledAt: time.Now().UTC(),
+	}
+	if prev := c.state.Resources; prev != nil && prev.PID == pid {
+		res.CPU = r.CPUPercent(c.lastResources, res.SampledAt.Sub(prev.SampledAt))
 	}
+	c.lastResources = r
+	c.state.Resources = res
+	c.resourcesExceeded = exceededThresholds(c.resources.Thresholds, res)
+
+	prevState, prevMessage := c.state.State, c.state.Message
+	if c.state.State == client.UnitStateHealthy || c.state.State == client.UnitStateDegraded {
+		state := client.UnitStateHealthy
+		if c.missedCheckins > 0 {
+			state = client.UnitStateDegraded
+		}
+		c.state.compState(c.compStateMessage(state))
+	}
+	// the sample is sent with the next state change unless a threshold was
+	// crossed or the resources changed significantly
+	if c.state.State == prevState && c.state.Message == prevMessage && !resourcesChanged(c.reportedResources, res) {
+		return nil
+	}
+	c.sendObserved()
+	return nil
 }
 
 func (c *commandRuntime) sendObserved() {
+	c.reportedResources = c.state.Resources
 	c.ch <- c.state.Copy()
 }
 
@@ -439,6 +558,8 @@ func (c *commandRuntime) startWatcher(info *process.Info, comm Communicator) {
 }
 
 func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
+	c.state.Resources = nil
+	c.resourcesExceeded = ""
 	switch c.actionState {
 	case actionStart:
 		if c.restartBucket != nil && c.restartBucket.Allow() {
@@ -51,3 +51,13 @@ func TestAddToBucket(t *testing.T) {
 		})
 	}
 }
+
+func TestCommandRuntimeRestartMergesPendingRequests(t *testing.T) {
+	c := &commandRuntime{restartCh: make(chan struct{}, 1)}
+
+	// the run loop isn't reading, the requests must neither block nor be lost
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Restart())
+	}
+	require.Len(t, c.restartCh, 1)
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/core/process"
 )
 
 const (
@@ -101,6 +102,12 @@ type Manager struct {
 	tracer     *apm.Tracer
 	monitor    MonitoringManager
 	grpcConfig *configuration.GRPCConfig
+	// resources configures the sampling of the resources used by the
+	// component processes, nil disables it.
+	resources *process.ResourcesConfig
+	// certificates configures the lifetime of the certificates of the
+	// connections of the components.
+	certificates *configuration.CertificatesConfig
 
 	// Set when the RPC server is ready to receive requests, for use by tests.
 	serverReady *atomic.Bool
@@ -120,6 +127,11 @@ type Manager struct {
 	// Only access from the main runtime manager goroutine.
 	nextUpdate *component.Model
 
+	// Last component model update that was applied, it's applied again to
+	// push the renewed certificates of the shipper connections.
+	// Only access from the main runtime manager goroutine.
+	lastUpdate *component.Model
+
 	// Whether we're already waiting on the results of an update call.
 	// If this is true when the run loop finishes, we need to wait for the
 	// final update result before shutting down, otherwise the shutdown's
@@ -131,7 +143,9 @@ type Manager struct {
 	currentMx sync.RWMutex
 	current   map[string]*componentRuntimeState
 
-	shipperConns map[string]*shipperConn
+	// shipperConnsMx protects access to the shipperConns map and the pairs
+	shipperConnsMx sync.Mutex
+	shipperConns   map[string]*shipperConn
 
 	subMx         sync.RWMutex
 	subscriptions map[string][]*Subscription
@@ -154,11 +168,19 @@ func NewManager(
 	tracer *apm.Tracer,
 	monitor MonitoringManager,
 	grpcConfig *configuration.GRPCConfig,
+	processConfig *process.Config,
 ) (*Manager, error) {
 	ca, err := authority.NewCA()
 	if err != nil {
 		return nil, err
 	}
+	if processConfig == nil {
+		processConfig = &process.Config{}
+	}
+	certificates := configuration.DefaultCertificatesConfig()
+	if grpcConfig != nil && grpcConfig.Certificates != nil {
+		certificates = grpcConfig.Certificates
+	}
 	m := &Manager{
 		logger:         logger,
 		baseLogger:     baseLogger,
@@ -174,6 +196,8 @@ func NewManager(
 		errCh:          make(chan error),
 		monitor:        monitor,
 		grpcConfig:     grpcConfig,
+		resources:      processConfig.Resources,
+		certificates:   certificates,
 		serverReady:    atomic.NewBool(false),
 		doneChan:       make(chan struct{}),
 	}
@@ -253,6 +277,8 @@ func (m *Manager) Run(ctx context.Context) error {
 //   - Close doneChan when the loop ends, so the Coordinator knows not to send
 //     any more updates
 func (m *Manager) runLoop(ctx context.Context) {
+	certTicker := time.NewTicker(m.certificateCheckInterval())
+	defer certTicker.Stop()
 LOOP:
 	for ctx.Err() == nil {
 		select {
@@ -265,6 +291,14 @@ LOOP:
 		case <-m.updateDoneChan:
 			// An update call has finished, we can initiate another when available.
 			m.updateInProgress = false
+		case <-certTicker.C:
+			m.renewCertificates()
+			if m.nextUpdate == nil && m.lastUpdate != nil && m.shipperCertificatesExpiring() {
+				// applying the model again issues the expiring certificates of
+				// the shipper connections and pushes them to the components
+				m.logger.Info("Renewing the certificates of the shipper connections")
+				m.nextUpdate = m.lastUpdate
+			}
 		}
 
 		// After each select call, check if there's a pending update that
@@ -289,6 +323,7 @@ LOOP:
 				m.updateDoneChan <- struct{}{}
 			}(*m.nextUpdate)
 			m.updateInProgress = true
+			m.lastUpdate = m.nextUpdate
 			m.nextUpdate = nil
 		}
 	}
@@ -884,7 +919,7 @@ func (m *Manager) getCertificate(chi *tls.ClientHelloInfo) (*tls.Certificate, er
 	m.currentMx.RLock()
 	for _, runtime := range m.current {
 		if runtime.comm.name == chi.ServerName {
-			cert = runtime.comm.cert.Certificate
+			cert = runtime.comm.getServerCert().Certificate
 			break
 		}
 	}
@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// CertificateUsageCA is the certificate authority of the connections of the components to the agent.
+	CertificateUsageCA = "ca"
+	// CertificateUsageServer is the certificate of the agent for the connections of a component.
+	CertificateUsageServer = "server"
+	// CertificateUsageClient is the client certificate of a component.
+	CertificateUsageClient = "client"
+	// CertificateUsageShipper is the certificate of a unit for its connection to the shipper.
+	CertificateUsageShipper = "shipper"
+)
+
+// CertificateInfo describes a certificate of the connections of the components.
+type CertificateInfo struct {
+	// ComponentID is the component of the certificate, empty for the certificate authority.
+	ComponentID string `yaml:"component_id,omitempty"`
+	// UnitID is the unit of the certificates of the shipper connections.
+	UnitID   string    `yaml:"unit_id,omitempty"`
+	Usage    string    `yaml:"usage"`
+	NotAfter time.Time `yaml:"not_after"`
+}
+
+// Certificates returns the certificates of the connections of the components, sorted by component.
+func (m *Manager) Certificates() []CertificateInfo {
+	certs := []CertificateInfo{{Usage: CertificateUsageCA, NotAfter: m.ca.NotAfter()}}
+
+	m.currentMx.RLock()
+	for id, state := range m.current {
+		certs = append(certs,
+			CertificateInfo{ComponentID: id, Usage: CertificateUsageServer, NotAfter: state.comm.getServerCert().NotAfter},
+			CertificateInfo{ComponentID: id, Usage: CertificateUsageClient, NotAfter: state.comm.getCert().NotAfter},
+		)
+	}
+	m.currentMx.RUnlock()
+
+	m.shipperConnsMx.Lock()
+	for id, conn := range m.shipperConns {
+		certs = append(certs, CertificateInfo{ComponentID: id, Usage: CertificateUsageCA, NotAfter: conn.ca.NotAfter()})
+		for unitID, pair := range conn.pairs {
+			certs = append(certs, CertificateInfo{ComponentID: id, UnitID: unitID, Usage: CertificateUsageShipper, NotAfter: pair.NotAfter})
+		}
+	}
+	m.shipperConnsMx.Unlock()
+
+	sort.SliceStable(certs[1:], func(i, j int) bool {
+		a, b := certs[i+1], certs[j+1]
+		if a.ComponentID != b.ComponentID {
+			return a.ComponentID < b.ComponentID
+		}
+		if a.Usage != b.Usage {
+			return a.Usage < b.Usage
+		}
+		return a.UnitID < b.UnitID
+	})
+	return certs
+}
+
+// restarter is implemented by the runtimes passing the connection information to the component
+// only when it starts, they restart it to send a renewed client certificate.
+type restarter interface {
+	Restart() error
+}
+
+// certificateCheckInterval is the period of the checks of the expiry of the certificates.
+func (m *Manager) certificateCheckInterval() time.Duration {
+	interval := m.certificates.Renewal() / 2
+	if interval > time.Hour {
+		return time.Hour
+	}
+	if interval < time.Second {
+		return time.Second
+	}
+	return interval
+}
+
+// renewCertificates issues the certificates of the connections of the components again before
+// their expiry. The agent uses its renewed certificate for the new connections, the components
+// verify it with the CA. The components receive their renewed client certificate with their
+// connection information: the command components are restarted to read it, the service
+// components read it when they connect again.
+// Called from the main runtime manager goroutine.
+func (m *Manager) renewCertificates() {
+	m.currentMx.RLock()
+	defer m.currentMx.RUnlock()
+	for id, state := range m.current {
+		renewed, err := state.comm.renewServerCert(m.certificates.Lifetime, m.certificates.Renewal())
+		if err != nil {
+			m.logger.Errorf("Failed to renew the certificate of the connections of component %q: %s", id, err)
+		} else if renewed {
+			m.logger.Debugf("Renewed the certificate of the connections of component %q", id)
+		}
+
+		renewed, err = state.comm.renewCert(m.certificates.Lifetime, m.certificates.Renewal())
+		if err != nil {
+			m.logger.Errorf("Failed to renew the client certificate of component %q: %s", id, err)
+			continue
+		}
+		if !renewed {
+			continue
+		}
+		if r, ok := state.runtime.(restarter); ok {
+			m.logger.Infof("Restarting component %q with its renewed client certificate", id)
+			if err := r.Restart(); err != nil {
+				m.logger.Errorf("Failed to restart component %q with its renewed client certificate: %s", id, err)
+			}
+		} else {
+			m.logger.Debugf("Renewed the client certificate of component %q", id)
+		}
+	}
+}
+
+// shipperCertificatesExpiring returns true when certificates of the shipper connections must be
+// issued again.
+func (m *Manager) shipperCertificatesExpiring() bool {
+	m.shipperConnsMx.Lock()
+	defer m.shipperConnsMx.Unlock()
+	renewBefore := m.certificates.Renewal()
+	for _, conn := range m.shipperConns {
+		for _, pair := range conn.pairs {
+			if time.Until(pair.NotAfter) <= renewBefore {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/apmtest"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/core/authority"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
+func TestRuntimeCommRenewCerts(t *testing.T) {
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", nil, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
+	require.NoError(t, err)
+
+	comm, err := newRuntimeComm(newDebugLogger(t), "localhost:0", m.ca, nil, time.Hour)
+	require.NoError(t, err)
+	serverCert := comm.getServerCert()
+	assert.WithinDuration(t, time.Now().Add(time.Hour), serverCert.NotAfter, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), comm.getCert().NotAfter, time.Minute)
+
+	renewed, err := comm.renewServerCert(time.Hour, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, renewed)
+	assert.Same(t, serverCert, comm.getServerCert())
+
+	renewed, err = comm.renewServerCert(2*time.Hour, 2*time.Hour)
+	require.NoError(t, err)
+	assert.True(t, renewed)
+	assert.NotSame(t, serverCert, comm.getServerCert())
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), comm.getServerCert().NotAfter, time.Minute)
+
+	cert := comm.getCert()
+	renewed, err = comm.renewCert(time.Hour, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, renewed)
+	assert.Same(t, cert, comm.getCert())
+
+	renewed, err = comm.renewCert(2*time.Hour, 2*time.Hour)
+	require.NoError(t, err)
+	assert.True(t, renewed)
+	assert.NotSame(t, cert, comm.getCert())
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), comm.getCert().NotAfter, time.Minute)
+
+	var buf bytes.Buffer
+	require.NoError(t, comm.WriteConnInfo(&buf))
+	var connInfo proto.ConnInfo
+	require.NoError(t, protobuf.Unmarshal(buf.Bytes(), &connInfo))
+	assert.Equal(t, comm.getCert().Crt, connInfo.PeerCert, "the renewed client certificate should be sent with the connection information")
+}
+
+func TestManager_FakeInput_RenewsClientCertificate(t *testing.T) {
+	testPaths(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the certificates are checked every 2 seconds and renewed 4 seconds before their expiry
+	grpcConfig := configuration.DefaultGRPCConfig()
+	grpcConfig.Certificates = &configuration.CertificatesConfig{Lifetime: 6 * time.Second, RenewBefore: 4 * time.Second}
+	ai, _ := info.NewAgentInfo(ctx, true)
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), grpcConfig, process.DefaultConfig())
+	require.NoError(t, err)
+	errCh := make(chan error)
+	go func() {
+		err := m.Run(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		errCh <- err
+	}()
+	defer drainErrChan(errCh)
+	timedWaitForReady(t, m, time.Second)
+
+	comp := component.Component{
+		ID: "fake-default",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType:  "fake",
+			BinaryName: "",
+			BinaryPath: testBinary(t, "component"),
+			Spec:       fakeInputSpec,
+		},
+		Units: []component.Unit{
+			{
+				ID:   "fake-input",
+				Type: client.UnitTypeInput,
+				Config: component.MustExpectedConfig(map[string]interface{}{
+					"type":    "fake",
+					"state":   int(client.UnitStateHealthy),
+					"message": "Fake Healthy",
+				}),
+			},
+		},
+	}
+	clientCert := func() *authority.Pair {
+		m.currentMx.RLock()
+		defer m.currentMx.RUnlock()
+		return m.current[comp.ID].comm.getCert()
+	}
+
+	sub := m.Subscribe(ctx, comp.ID)
+	m.Update(component.Model{Components: []component.Component{comp}})
+	require.NoError(t, <-m.errCh)
+
+	// the component runs with its first client certificate, then it's restarted with the
+	// renewed one and communicates with the agent again
+	var healthy string
+	var cert *authority.Pair
+	timeout := time.NewTimer(30 * time.Second)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-timeout.C:
+			t.Fatalf("the component wasn't restarted with a renewed client certificate")
+		case err := <-errCh:
+			require.NoError(t, err)
+		case state := <-sub.Ch():
+			require.NotEqual(t, client.UnitStateFailed, state.State, state.Message)
+			if state.State != client.UnitStateHealthy {
+				continue
+			}
+			if healthy == "" {
+				healthy = state.Message
+				cert = clientCert()
+				continue
+			}
+			if state.Message != healthy {
+				assert.NotSame(t, cert, clientCert(), "the restarted component should receive a renewed client certificate")
+				return
+			}
+		}
+	}
+}
+
+func TestManagerRenewsShipperCertificates(t *testing.T) {
+	grpcConfig := configuration.DefaultGRPCConfig()
+	grpcConfig.Certificates = &configuration.CertificatesConfig{Lifetime: time.Hour}
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", nil, apmtest.DiscardTracer, newTestMonitoringMgr(), grpcConfig, process.DefaultConfig())
+	require.NoError(t, err)
+
+	components := func() []component.Component {
+		return []component.Component{
+			{
+				ID: "fake-default",
+				Units: []component.Unit{
+					{ID: "fake-default", Type: client.UnitTypeOutput, Config: component.MustExpectedConfig(map[string]interface{}{"type": "fake-shipper"})},
+				},
+				ShipperRef: &component.ShipperReference{ComponentID: "fake-shipper-default", UnitID: "fake-default"},
+			},
+			{
+				ID:          "fake-shipper-default",
+				ShipperSpec: &component.ShipperRuntimeSpec{ShipperType: "fake-shipper"},
+				Units: []component.Unit{
+					{ID: "fake-default", Type: client.UnitTypeInput, Config: component.MustExpectedConfig(map[string]interface{}{"type": "fake-shipper"})},
+				},
+			},
+		}
+	}
+	certificate := func(comps []component.Component) string {
+		ssl := comps[0].Units[0].Config.Source.AsMap()["ssl"].(map[string]interface{})
+		assert.Equal(t, ssl["certificate"], comps[1].Units[0].Config.Source.AsMap()["ssl"].(map[string]interface{})["certificate"])
+		return ssl["certificate"].(string)
+	}
+
+	comps := components()
+	require.NoError(t, m.connectShippers(comps))
+	cert := certificate(comps)
+	assert.False(t, m.shipperCertificatesExpiring())
+
+	// the pair is kept while it's valid
+	comps = components()
+	require.NoError(t, m.connectShippers(comps))
+	assert.Equal(t, cert, certificate(comps))
+
+	// and issued again before its expiry
+	m.certificates = &configuration.CertificatesConfig{Lifetime: time.Hour, RenewBefore: 2 * time.Hour}
+	assert.True(t, m.shipperCertificatesExpiring())
+	comps = components()
+	require.NoError(t, m.connectShippers(comps))
+	assert.NotEqual(t, cert, certificate(comps))
+
+	certs := m.Certificates()
+	require.Len(t, certs, 3)
+	assert.Equal(t, CertificateInfo{Usage: CertificateUsageCA, NotAfter: m.ca.NotAfter()}, certs[0])
+	assert.Equal(t, "fake-shipper-default", certs[1].ComponentID)
+	assert.Equal(t, CertificateUsageCA, certs[1].Usage)
+	assert.Equal(t, "fake-shipper-default", certs[2].ComponentID)
+	assert.Equal(t, "fake-default", certs[2].UnitID)
+	assert.Equal(t, CertificateUsageShipper, certs[2].Usage)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), certs[2].NotAfter, time.Minute)
+}
@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
@@ -16,6 +17,9 @@ import (
 )
 
 func (m *Manager) connectShippers(components []component.Component) error {
+	m.shipperConnsMx.Lock()
+	defer m.shipperConnsMx.Unlock()
+
 	// ensure that all shipper components have created connection information (must happen before we connect the units)
 	shippersTouched := make(map[string]bool)
 	for i, comp := range components {
@@ -41,7 +45,7 @@ func (m *Manager) connectShippers(components []component.Component) error {
 			for j, unit := range comp.Units {
 				if unit.Type == client.UnitTypeInput {
 					pairsTouched[unit.ID] = true
-					pair, err := pairGetOrCreate(conn, unit.ID)
+					pair, err := pairGetOrCreate(conn, unit.ID, m.certificates.Lifetime, m.certificates.Renewal())
 					if err != nil {
 						return fmt.Errorf("failed to get/create certificate pait for shipper %q/%q: %w", comp.ID, unit.ID, err)
 					}
@@ -97,13 +101,15 @@ func (m *Manager) connectShippers(components []component.Component) error {
 	return nil
 }
 
-func pairGetOrCreate(conn *shipperConn, pairID string) (*authority.Pair, error) {
+// pairGetOrCreate returns the certificate pair of the connection, it's issued again when it
+// expires in less than renewBefore.
+func pairGetOrCreate(conn *shipperConn, pairID string, lifetime, renewBefore time.Duration) (*authority.Pair, error) {
 	var err error
 	pair, ok := conn.pairs[pairID]
-	if ok {
+	if ok && time.Until(pair.NotAfter) > renewBefore {
 		return pair, nil
 	}
-	pair, err = conn.ca.GeneratePairWithName(pairID)
+	pair, err = conn.ca.GeneratePairWithLifetime(pairID, lifetime)
 	if err != nil {
 		return nil, err
 	}
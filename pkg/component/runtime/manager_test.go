@@ -37,6 +37,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/core/process"
 	"github.com/elastic/elastic-agent/pkg/features"
 )
 
@@ -81,6 +82,7 @@ func TestManager_SimpleComponentErr(t *testing.T) {
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
 		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig(),
 	)
 	require.NoError(t, err)
 
@@ -178,7 +180,7 @@ func TestManager_FakeInput_StartStop(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -306,7 +308,8 @@ func TestManager_FakeInput_Features(t *testing.T) {
 		agentInfo,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig())
 	require.NoError(t, err)
 
 	managerErrCh := make(chan error)
@@ -506,7 +509,8 @@ func TestManager_FakeInput_APM(t *testing.T) {
 		agentInfo,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig())
 	require.NoError(t, err)
 
 	managerErrCh := make(chan error)
@@ -837,6 +841,7 @@ func TestManager_FakeInput_Limits(t *testing.T) {
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
 		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig(),
 	)
 	require.NoError(t, err)
 
@@ -1001,6 +1006,7 @@ func TestManager_FakeShipper_Limits(t *testing.T) {
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
 		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig(),
 	)
 	require.NoError(t, err)
 
@@ -1157,7 +1163,7 @@ func TestManager_FakeInput_BadUnitToGood(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1326,7 +1332,7 @@ func TestManager_FakeInput_GoodUnitToBad(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	runResultChan := make(chan error, 1)
 	go func() {
@@ -1478,7 +1484,7 @@ func TestManager_FakeInput_NoDeadlock(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1614,7 +1620,7 @@ func TestManager_FakeInput_Configure(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1736,7 +1742,7 @@ func TestManager_FakeInput_RemoveUnit(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1891,7 +1897,7 @@ func TestManager_FakeInput_ActionState(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2016,7 +2022,7 @@ func TestManager_FakeInput_Restarts(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2152,7 +2158,7 @@ func TestManager_FakeInput_Restarts_ConfigKill(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2296,7 +2302,7 @@ func TestManager_FakeInput_KeepsRestarting(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2440,7 +2446,7 @@ func TestManager_FakeInput_RestartsOnMissedCheckins(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2559,7 +2565,7 @@ func TestManager_FakeInput_InvalidAction(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2684,7 +2690,8 @@ func TestManager_FakeInput_MultiComponent(t *testing.T) {
 		agentInfo,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig())
 	require.NoError(t, err)
 
 	errCh := make(chan error)
@@ -2898,7 +2905,8 @@ func TestManager_FakeInput_LogLevel(t *testing.T) {
 		ai,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig())
 	require.NoError(t, err)
 
 	errCh := make(chan error)
@@ -3044,7 +3052,7 @@ func TestManager_FakeShipper(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(ctx, true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), process.DefaultConfig())
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -3347,7 +3355,8 @@ func TestManager_FakeInput_OutputChange(t *testing.T) {
 		ai,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(),
+		process.DefaultConfig())
 	require.NoError(t, err, "could not crete new manager")
 
 	errCh := make(chan error)
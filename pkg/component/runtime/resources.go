@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/docker/go-units"
+
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
+// exceededThresholds returns a description of the thresholds exceeded by the
+// resources, empty when none is.
+func exceededThresholds(t process.ResourceThresholds, r *ComponentResources) string {
+	var exceeded []string
+	if t.CPU > 0 && r.CPU > t.CPU {
+		exceeded = append(exceeded, fmt.Sprintf("uses %.1f%% CPU, above the %.1f%% threshold", r.CPU, t.CPU))
+	}
+	if t.RSS > 0 && r.RSS > t.RSS {
+		exceeded = append(exceeded, fmt.Sprintf("uses %s of memory, above the %s threshold",
+			units.BytesSize(float64(r.RSS)), units.BytesSize(float64(t.RSS))))
+	}
+	if t.OpenFDs > 0 && r.OpenFDs > t.OpenFDs {
+		exceeded = append(exceeded, fmt.Sprintf("has %d open file descriptors, above the %d threshold", r.OpenFDs, t.OpenFDs))
+	}
+	return strings.Join(exceeded, ", ")
+}
+
+const (
+	// resourcesChangeRatio is the relative change of the memory or of the open
+	// file descriptors reported as a significant change.
+	resourcesChangeRatio = 0.1
+	// resourcesCPUChange is the change of the CPU usage, in percent of one core,
+	// reported as a significant change.
+	resourcesCPUChange = 10
+)
+
+// resourcesChanged returns true when the sample differs significantly from the
+// reported one, the small variations of a steady process aren't reported.
+func resourcesChanged(reported *ComponentResources, sample *ComponentResources) bool {
+	if reported == nil || reported.PID != sample.PID {
+		return true
+	}
+	return relativeChange(float64(reported.RSS), float64(sample.RSS)) >= resourcesChangeRatio ||
+		relativeChange(float64(reported.OpenFDs), float64(sample.OpenFDs)) >= resourcesChangeRatio ||
+		math.Abs(sample.CPU-reported.CPU) >= resourcesCPUChange
+}
+
+func relativeChange(from float64, to float64) float64 {
+	if from == 0 {
+		if to == 0 {
+			return 0
+		}
+		return 1
+	}
+	return math.Abs(to-from) / from
+}
@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
+func TestExceededThresholds(t *testing.T) {
+	r := &ComponentResources{CPU: 95.5, RSS: 600 * 1024 * 1024, OpenFDs: 2000}
+
+	assert.Empty(t, exceededThresholds(process.ResourceThresholds{}, r), "no threshold")
+	assert.Empty(t, exceededThresholds(process.ResourceThresholds{CPU: 100, RSS: 1024 * 1024 * 1024, OpenFDs: 4096}, r))
+	assert.Equal(t, "uses 95.5% CPU, above the 80.0% threshold, uses 600MiB of memory, above the 500MiB threshold, has 2000 open file descriptors, above the 1024 threshold",
+		exceededThresholds(process.ResourceThresholds{CPU: 80, RSS: 500 * 1024 * 1024, OpenFDs: 1024}, r))
+}
+
+func TestCommandRuntimeSampleResources(t *testing.T) {
+	if goruntime.GOOS != "linux" {
+		t.Skip("the resources are only sampled on Linux")
+	}
+
+	pid := os.Getpid()
+	c := &commandRuntime{
+		ch:        make(chan ComponentState, 2),
+		proc:      &process.Info{PID: pid},
+		resources: &process.ResourcesConfig{Thresholds: process.ResourceThresholds{OpenFDs: 1}},
+		state: ComponentState{
+			State:   client.UnitStateHealthy,
+			Message: "Healthy",
+		},
+	}
+
+	require.NoError(t, c.sampleResources())
+	state := <-c.ch
+	require.NotNil(t, state.Resources)
+	assert.Equal(t, pid, state.Resources.PID)
+	assert.NotZero(t, state.Resources.RSS)
+	assert.Equal(t, client.UnitStateDegraded, state.State, "more than 1 open file descriptor")
+	assert.Contains(t, state.Message, "open file descriptors, above the 1 threshold")
+
+	c.resources.Thresholds.OpenFDs = 0
+	require.NoError(t, c.sampleResources())
+	state = <-c.ch
+	assert.Equal(t, client.UnitStateHealthy, state.State)
+	assert.Equal(t, fmt.Sprintf("Healthy: communicating with pid '%d'", pid), state.Message)
+
+	// a steady sample isn't sent
+	c.reportedResources = &ComponentResources{PID: pid, RSS: c.state.Resources.RSS, OpenFDs: c.state.Resources.OpenFDs, CPU: c.state.Resources.CPU}
+	c.lastResources = process.Resources{}
+	c.state.Resources = nil
+	require.NoError(t, c.sampleResources())
+	select {
+	case state := <-c.ch:
+		if !resourcesChanged(c.reportedResources, state.Resources) {
+			assert.Fail(t, "a steady sample shouldn't be sent")
+		}
+	default:
+	}
+	require.NotNil(t, c.state.Resources, "the sample is kept for the next state sent")
+}
+
+func TestResourcesChanged(t *testing.T) {
+	reported := &ComponentResources{PID: 42, RSS: 100 * 1024 * 1024, OpenFDs: 100, CPU: 20}
+
+	testcases := []struct {
+		name    string
+		sample  ComponentResources
+		changed bool
+	}{
+		{"same", ComponentResources{PID: 42, RSS: 100 * 1024 * 1024, OpenFDs: 100, CPU: 20}, false},
+		{"small variations", ComponentResources{PID: 42, RSS: 105 * 1024 * 1024, OpenFDs: 95, CPU: 25}, false},
+		{"memory", ComponentResources{PID: 42, RSS: 110 * 1024 * 1024, OpenFDs: 100, CPU: 20}, true},
+		{"open file descriptors", ComponentResources{PID: 42, RSS: 100 * 1024 * 1024, OpenFDs: 90, CPU: 20}, true},
+		{"cpu", ComponentResources{PID: 42, RSS: 100 * 1024 * 1024, OpenFDs: 100, CPU: 35}, true},
+		{"process", ComponentResources{PID: 43, RSS: 100 * 1024 * 1024, OpenFDs: 100, CPU: 20}, true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sample := tc.sample
+			assert.Equal(t, tc.changed, resourcesChanged(reported, &sample))
+		})
+	}
+	assert.True(t, resourcesChanged(nil, reported), "the first sample is sent")
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/runner"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/core/process"
 )
 
 // componentRuntime manages runtime lifecycle operations for a component and stores its state.
@@ -58,13 +59,14 @@ func newComponentRuntime(
 	comp component.Component,
 	logger *logger.Logger,
 	monitor MonitoringManager,
+	resources *process.ResourcesConfig,
 ) (componentRuntime, error) {
 	if comp.Err != nil {
 		return newFailedRuntime(comp)
 	}
 	if comp.InputSpec != nil {
 		if comp.InputSpec.Spec.Command != nil {
-			return newCommandRuntime(comp, logger, monitor)
+			return newCommandRuntime(comp, logger, monitor, resources)
 		}
 		if comp.InputSpec.Spec.Service != nil {
 			return newServiceRuntime(comp, logger)
@@ -73,7 +75,7 @@ func newComponentRuntime(
 	}
 	if comp.ShipperSpec != nil {
 		if comp.ShipperSpec.Spec.Command != nil {
-			return newCommandRuntime(comp, logger, monitor)
+			return newCommandRuntime(comp, logger, monitor, resources)
 		}
 		return nil, errors.New("components for shippers can only support command runtime")
 	}
@@ -100,11 +102,11 @@ type componentRuntimeState struct {
 }
 
 func newComponentRuntimeState(m *Manager, logger *logger.Logger, monitor MonitoringManager, comp component.Component) (*componentRuntimeState, error) {
-	comm, err := newRuntimeComm(logger, m.getListenAddr(), m.ca, m.agentInfo)
+	comm, err := newRuntimeComm(logger, m.getListenAddr(), m.ca, m.agentInfo, m.certificates.Lifetime)
 	if err != nil {
 		return nil, err
 	}
-	runtime, err := newComponentRuntime(comp, logger, monitor)
+	runtime, err := newComponentRuntime(comp, logger, monitor, m.resources)
 	if err != nil {
 		return nil, err
 	}
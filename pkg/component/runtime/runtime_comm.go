@@ -10,6 +10,7 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -47,7 +48,14 @@ type runtimeComm struct {
 
 	name  string
 	token string
-	cert  *authority.Pair
+
+	// cert is the client certificate of the component and serverCert the
+	// certificate of the agent for its connections, they are issued again
+	// before their expiry. The client certificate is sent with the connection
+	// information, read by the component when it starts.
+	certMx     sync.RWMutex
+	cert       *authority.Pair
+	serverCert *authority.Pair
 
 	checkinConn bool
 	checkinDone chan bool
@@ -67,7 +75,7 @@ type runtimeComm struct {
 	actionsResponse chan *proto.ActionResponse
 }
 
-func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.CertificateAuthority, agentInfo *info.AgentInfo) (*runtimeComm, error) {
+func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.CertificateAuthority, agentInfo *info.AgentInfo, certLifetime time.Duration) (*runtimeComm, error) {
 	token, err := uuid.NewV4()
 	if err != nil {
 		return nil, err
@@ -76,7 +84,11 @@ func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.Cert
 	if err != nil {
 		return nil, err
 	}
-	pair, err := ca.GeneratePairWithName(name)
+	pair, err := ca.GeneratePairWithLifetime(name, certLifetime)
+	if err != nil {
+		return nil, err
+	}
+	serverPair, err := ca.GeneratePairWithLifetime(name, certLifetime)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +100,7 @@ func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.Cert
 		name:            name,
 		token:           token.String(),
 		cert:            pair,
+		serverCert:      serverPair,
 		checkinConn:     true,
 		checkinExpected: make(chan *proto.CheckinExpected, 1),
 		checkinObserved: make(chan *proto.CheckinObserved),
@@ -112,13 +125,14 @@ func (c *runtimeComm) WriteConnInfo(w io.Writer, services ...client.Service) err
 	if !hasV2 {
 		srvs = append(srvs, proto.ConnInfoServices_CheckinV2)
 	}
+	cert := c.getCert()
 	connInfo := &proto.ConnInfo{
 		Addr:       c.listenAddr,
 		ServerName: c.name,
 		Token:      c.token,
 		CaCert:     c.ca.Crt(),
-		PeerCert:   c.cert.Crt,
-		PeerKey:    c.cert.Key,
+		PeerCert:   cert.Crt,
+		PeerKey:    cert.Key,
 		Services:   srvs,
 	}
 	infoBytes, err := protobuf.Marshal(connInfo)
@@ -132,6 +146,51 @@ func (c *runtimeComm) WriteConnInfo(w io.Writer, services ...client.Service) err
 	return nil
 }
 
+// getCert returns the client certificate of the component.
+func (c *runtimeComm) getCert() *authority.Pair {
+	c.certMx.RLock()
+	defer c.certMx.RUnlock()
+	return c.cert
+}
+
+// getServerCert returns the certificate of the agent for the connections of the component.
+func (c *runtimeComm) getServerCert() *authority.Pair {
+	c.certMx.RLock()
+	defer c.certMx.RUnlock()
+	return c.serverCert
+}
+
+// renewServerCert issues the certificate of the agent again when it expires in less than
+// renewBefore, it returns true when it was renewed.
+func (c *runtimeComm) renewServerCert(lifetime, renewBefore time.Duration) (bool, error) {
+	c.certMx.Lock()
+	defer c.certMx.Unlock()
+	return c.renewPair(&c.serverCert, lifetime, renewBefore)
+}
+
+// renewCert issues the client certificate of the component again when it expires in less
+// than renewBefore, it returns true when it was renewed. The component must be restarted to
+// read it from its connection information.
+func (c *runtimeComm) renewCert(lifetime, renewBefore time.Duration) (bool, error) {
+	c.certMx.Lock()
+	defer c.certMx.Unlock()
+	return c.renewPair(&c.cert, lifetime, renewBefore)
+}
+
+// renewPair replaces the pair when it expires in less than renewBefore.
+// Called with certMx locked.
+func (c *runtimeComm) renewPair(pair **authority.Pair, lifetime, renewBefore time.Duration) (bool, error) {
+	if time.Until((*pair).NotAfter) > renewBefore {
+		return false, nil
+	}
+	renewed, err := c.ca.GeneratePairWithLifetime(c.name, lifetime)
+	if err != nil {
+		return false, err
+	}
+	*pair = renewed
+	return true, nil
+}
+
 func (c *runtimeComm) CheckinExpected(
 	expected *proto.CheckinExpected,
 	observed *proto.CheckinObserved,
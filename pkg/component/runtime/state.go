@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	gproto "google.golang.org/protobuf/proto"
 
@@ -58,6 +59,17 @@ type ComponentVersionInfo struct {
 	Meta map[string]string `yaml:"meta,omitempty"`
 }
 
+// ComponentResources are the resources used by the process of a component.
+type ComponentResources struct {
+	PID int `yaml:"pid"`
+	// RSS is the resident set size in bytes.
+	RSS uint64 `yaml:"rss"`
+	// CPU is the CPU usage in percent of one core since the previous sample.
+	CPU       float64   `yaml:"cpu_pct"`
+	OpenFDs   int       `yaml:"open_fds"`
+	SampledAt time.Time `yaml:"sampled_at"`
+}
+
 // ComponentState is the overall state of the component.
 type ComponentState struct {
 	State   client.UnitState `yaml:"state"`
@@ -75,6 +87,10 @@ type ComponentState struct {
 
 	VersionInfo ComponentVersionInfo `yaml:"version_info"`
 
+	// Resources are sampled by the command runtime, nil when the component
+	// isn't running or the resources aren't sampled.
+	Resources *ComponentResources `yaml:"resources,omitempty"`
+
 	// internal
 	expectedUnits map[ComponentUnitKey]expectedUnitState
 
@@ -80,6 +80,17 @@ type ComponentVersionInfo struct {
 	Meta map[string]string `json:"meta,omitempty" yaml:"meta,omitempty"`
 }
 
+// ComponentResources are the resources used by the process of a component.
+type ComponentResources struct {
+	PID int `json:"pid" yaml:"pid"`
+	// RSS is the resident set size in bytes.
+	RSS uint64 `json:"rss" yaml:"rss"`
+	// CPU is the CPU usage in percent of one core.
+	CPU       float64   `json:"cpu_pct" yaml:"cpu_pct"`
+	OpenFDs   int       `json:"open_fds" yaml:"open_fds"`
+	SampledAt time.Time `json:"sampled_at" yaml:"sampled_at"`
+}
+
 // ComponentUnitState is a state of a unit running inside a component.
 type ComponentUnitState struct {
 	UnitID   string                 `json:"unit_id" yaml:"unit_id"`
@@ -97,6 +108,7 @@ type ComponentState struct {
 	Message     string               `json:"message" yaml:"message"`
 	Units       []ComponentUnitState `json:"units" yaml:"units"`
 	VersionInfo ComponentVersionInfo `json:"version_info" yaml:"version_info"`
+	Resources   *ComponentResources  `json:"resources,omitempty" yaml:"resources,omitempty"`
 }
 
 // AgentStateInfo is the overall information about the Elastic Agent.
@@ -117,6 +129,7 @@ type AgentState struct {
 	Components     []ComponentState       `json:"components" yaml:"components"`
 	FleetState     State                  `yaml:"fleet_state"`
 	FleetMessage   string                 `yaml:"fleet_message"`
+	FleetHosts     []*cproto.FleetHost    `json:"fleet_hosts,omitempty" yaml:"fleet_hosts,omitempty"`
 	UpgradeDetails *cproto.UpgradeDetails `json:"upgrade_details,omitempty" yaml:"upgrade_details,omitempty"`
 }
 
@@ -175,7 +188,8 @@ type Client interface {
 	// Restart triggers restarting the current running daemon.
 	Restart(ctx context.Context) error
 	// Upgrade triggers upgrade of the current running daemon.
-	Upgrade(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) (string, error)
+	// The uninstall token is required by a protected agent to skip the package verification.
+	Upgrade(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, uninstallToken string, pgpBytes ...string) (string, error)
 	// DiagnosticAgent gathers diagnostics information for the running Elastic Agent.
 	DiagnosticAgent(ctx context.Context, additionalDiags []AdditionalMetrics) ([]DiagnosticFileResult, error)
 	// DiagnosticUnits gathers diagnostics information from specific units (or all if non are provided).
@@ -304,13 +318,14 @@ func (c *client) Restart(ctx context.Context) error {
 }
 
 // Upgrade triggers upgrade of the current running daemon.
-func (c *client) Upgrade(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) (string, error) {
+func (c *client) Upgrade(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, uninstallToken string, pgpBytes ...string) (string, error) {
 	res, err := c.client.Upgrade(ctx, &cproto.UpgradeRequest{
 		Version:        version,
 		SourceURI:      sourceURI,
 		SkipVerify:     skipVerify,
 		PgpBytes:       pgpBytes,
 		SkipDefaultPgp: skipDefaultPgp,
+		UninstallToken: uninstallToken,
 	})
 	if err != nil {
 		return "", err
@@ -480,6 +495,7 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 		Message:        res.Message,
 		FleetState:     res.FleetState,
 		FleetMessage:   res.FleetMessage,
+		FleetHosts:     res.FleetHosts,
 		UpgradeDetails: res.UpgradeDetails,
 
 		Components: make([]ComponentState, 0, len(res.Components)),
@@ -516,6 +532,15 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 				Meta:    comp.VersionInfo.Meta,
 			}
 		}
+		if r := comp.Resources; r != nil {
+			cs.Resources = &ComponentResources{
+				PID:       int(r.Pid),
+				RSS:       r.Rss,
+				CPU:       r.CpuPct,
+				OpenFDs:   int(r.OpenFds),
+				SampledAt: r.SampledAt.AsTime(),
+			}
+		}
 		s.Components = append(s.Components, cs)
 	}
 	return s, nil
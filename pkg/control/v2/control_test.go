@@ -44,6 +44,25 @@ func TestServerClient_Version(t *testing.T) {
 	}, ver)
 }
 
+func TestServerClient_VersionWithAccessControl(t *testing.T) {
+	cfg := configuration.DefaultGRPCConfig()
+	cfg.Access.Enabled = true
+	srv := server.New(newErrorLogger(t), nil, nil, apmtest.DiscardTracer, nil, cfg)
+	err := srv.Start()
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	c := client.New()
+	err = c.Connect(context.Background())
+	require.NoError(t, err)
+	defer c.Disconnect()
+
+	// the test runs as the user running the agent, granted all the RPCs
+	ver, err := c.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, release.Version(), ver.Version)
+}
+
 func newErrorLogger(t *testing.T) *logger.Logger {
 	t.Helper()
 
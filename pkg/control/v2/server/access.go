@@ -0,0 +1,224 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// readOnlyMethods are the RPCs that don't change the agent nor collect its
+// diagnostics, the calls to the other ones are audited.
+var readOnlyMethods = map[string]bool{
+	"Version":    true,
+	"State":      true,
+	"StateWatch": true,
+	"Status":     true, // v1
+}
+
+// peerCreds are the credentials of the process connected to the control socket.
+type peerCreds struct {
+	credentials.CommonAuthInfo
+	UID int
+	GID int
+	PID int
+}
+
+// AuthType implements credentials.AuthInfo.
+func (peerCreds) AuthType() string {
+	return "peercred"
+}
+
+// accessControl authorizes the control protocol RPCs with the credentials of
+// the calling process.
+type accessControl struct {
+	log *logger.Logger
+	// uid and gid run the agent, they are granted all the RPCs like root.
+	uid int
+	gid int
+	// users and groups map the IDs to the granted RPCs.
+	users  map[int]map[string]bool
+	groups map[int]map[string]bool
+	// lookupGroups returns the groups of a user, replaced in tests.
+	lookupGroups func(uid int) ([]int, error)
+}
+
+func newAccessControl(log *logger.Logger, cfg *configuration.ControlAccessConfig) *accessControl {
+	a := &accessControl{
+		log:          log,
+		uid:          os.Geteuid(),
+		gid:          os.Getegid(),
+		users:        make(map[int]map[string]bool),
+		groups:       make(map[int]map[string]bool),
+		lookupGroups: lookupGroups,
+	}
+	for name, role := range cfg.Roles {
+		for _, u := range role.Users {
+			id, err := lookupID(u, func(name string) (string, error) {
+				usr, err := user.Lookup(name)
+				if err != nil {
+					return "", err
+				}
+				return usr.Uid, nil
+			})
+			if err != nil {
+				log.Warnf("Ignoring user %q of the control protocol role %q: %s", u, name, err)
+				continue
+			}
+			grant(a.users, id, role.Methods)
+		}
+		for _, g := range role.Groups {
+			id, err := lookupID(g, func(name string) (string, error) {
+				grp, err := user.LookupGroup(name)
+				if err != nil {
+					return "", err
+				}
+				return grp.Gid, nil
+			})
+			if err != nil {
+				log.Warnf("Ignoring group %q of the control protocol role %q: %s", g, name, err)
+				continue
+			}
+			grant(a.groups, id, role.Methods)
+		}
+	}
+	return a
+}
+
+// lookupID returns the ID of a user or group given by name or ID.
+func lookupID(nameOrID string, lookup func(name string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+	id, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
+func grant(ids map[int]map[string]bool, id int, methods []string) {
+	if ids[id] == nil {
+		ids[id] = make(map[string]bool)
+	}
+	for _, m := range methods {
+		ids[id][m] = true
+	}
+}
+
+func lookupGroups(uid int) ([]int, error) {
+	usr, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return nil, err
+	}
+	gids, err := usr.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(gids))
+	for _, gid := range gids {
+		if id, err := strconv.Atoi(gid); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// allowed returns true when the RPC method is granted to the process.
+func (a *accessControl) allowed(creds peerCreds, method string) bool {
+	if creds.UID == 0 || creds.UID == a.uid || creds.GID == a.gid {
+		return true
+	}
+	if a.users[creds.UID][method] || a.groups[creds.GID][method] {
+		return true
+	}
+	gids, err := a.lookupGroups(creds.UID)
+	if err != nil {
+		a.log.Debugf("Failed to look up the groups of uid %d: %s", creds.UID, err)
+		return false
+	}
+	for _, gid := range gids {
+		if gid == a.gid || a.groups[gid][method] {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize returns a PermissionDenied error when the RPC isn't granted to the
+// calling process, the calls to the RPCs that aren't read-only are audited.
+func (a *accessControl) authorize(ctx context.Context, fullMethod string) error {
+	method := path.Base(fullMethod)
+	var creds peerCreds
+	p, ok := peer.FromContext(ctx)
+	if ok {
+		creds, ok = p.AuthInfo.(peerCreds)
+	}
+	if !ok {
+		a.log.Errorw("Denied control protocol call without peer credentials", "method", method)
+		return status.Error(codes.PermissionDenied, "no peer credentials")
+	}
+
+	if !a.authorizeCreds(creds, method, "grpc") {
+		return status.Errorf(codes.PermissionDenied, "%s isn't granted to uid %d", method, creds.UID)
+	}
+	return nil
+}
+
+// authorizeCreds returns true when the method is granted to the process with the
+// credentials, the denied calls and the calls to the methods that aren't read-only
+// are audited.
+func (a *accessControl) authorizeCreds(creds peerCreds, method string, transport string) bool {
+	allowed := a.allowed(creds, method)
+	if !readOnlyMethods[method] || !allowed {
+		a.log.Infow("Control protocol call",
+			"method", method,
+			"transport", transport,
+			"allowed", allowed,
+			"user.id", creds.UID,
+			"group.id", creds.GID,
+			"process.pid", creds.PID)
+	}
+	return allowed
+}
+
+// auditToken audits a call of the REST API authenticated with its bearer token, the
+// token grants all the methods.
+func (a *accessControl) auditToken(method string, remoteAddr string) {
+	if !readOnlyMethods[method] {
+		a.log.Infow("Control protocol call",
+			"method", method,
+			"transport", "rest",
+			"allowed", true,
+			"auth", "token",
+			"source.address", remoteAddr)
+	}
+}
+
+func (a *accessControl) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *accessControl) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,118 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestAccessControlAuthorize(t *testing.T) {
+	log, obs := logger.NewTesting("control")
+	cfg := configuration.DefaultControlAccessConfig()
+	monitoring := cfg.Roles["monitoring"]
+	monitoring.Users = []string{"2001"}
+	monitoring.Groups = []string{"3001"}
+	cfg.Roles["monitoring"] = monitoring
+	cfg.Roles["diagnostics"] = configuration.ControlRole{
+		Groups:  []string{"3002"},
+		Methods: []string{"DiagnosticAgent"},
+	}
+
+	a := newAccessControl(log, cfg)
+	a.uid, a.gid = 1000, 1000
+	a.lookupGroups = func(uid int) ([]int, error) {
+		switch uid {
+		case 2002:
+			return []int{3002}, nil
+		case 2003:
+			return []int{1000}, nil
+		}
+		return nil, errors.New("unknown user")
+	}
+
+	authorize := func(uid, gid int, method string) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: peerCreds{UID: uid, GID: gid, PID: 42}})
+		return a.authorize(ctx, method)
+	}
+	testcases := []struct {
+		name    string
+		uid     int
+		gid     int
+		method  string
+		allowed bool
+	}{
+		{"root", 0, 0, cproto.ElasticAgentControl_Upgrade_FullMethodName, true},
+		{"agent user", 1000, 5000, cproto.ElasticAgentControl_Restart_FullMethodName, true},
+		{"agent group", 5000, 1000, cproto.ElasticAgentControl_Configure_FullMethodName, true},
+		{"agent supplementary group", 2003, 5000, cproto.ElasticAgentControl_Upgrade_FullMethodName, true},
+		{"monitoring user state", 2001, 5000, cproto.ElasticAgentControl_State_FullMethodName, true},
+		{"monitoring user upgrade", 2001, 5000, cproto.ElasticAgentControl_Upgrade_FullMethodName, false},
+		{"monitoring group watch", 5000, 3001, cproto.ElasticAgentControl_StateWatch_FullMethodName, true},
+		{"monitoring group v1 status", 5000, 3001, "/proto.ElasticAgentControl/Status", true},
+		{"diagnostics supplementary group", 2002, 5000, cproto.ElasticAgentControl_DiagnosticAgent_FullMethodName, true},
+		{"diagnostics supplementary group restart", 2002, 5000, cproto.ElasticAgentControl_Restart_FullMethodName, false},
+		{"other user", 5000, 5000, cproto.ElasticAgentControl_Version_FullMethodName, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := authorize(tc.uid, tc.gid, tc.method)
+			if tc.allowed {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.PermissionDenied, status.Code(err), err)
+		})
+	}
+
+	err := a.authorize(context.Background(), cproto.ElasticAgentControl_State_FullMethodName)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "no peer credentials")
+
+	// the mutating calls and the denied ones are audited
+	obs.TakeAll()
+	require.NoError(t, authorize(0, 0, cproto.ElasticAgentControl_State_FullMethodName))
+	assert.Zero(t, obs.Len())
+	require.NoError(t, authorize(0, 0, cproto.ElasticAgentControl_Restart_FullMethodName))
+	require.Error(t, authorize(2001, 5000, cproto.ElasticAgentControl_Restart_FullMethodName))
+	logs := obs.FilterMessage("Control protocol call").TakeAll()
+	require.Len(t, logs, 2)
+	assert.Equal(t, true, logs[0].ContextMap()["allowed"])
+	assert.Equal(t, map[string]interface{}{
+		"method":      "Restart",
+		"transport":   "grpc",
+		"allowed":     false,
+		"user.id":     int64(2001),
+		"group.id":    int64(5000),
+		"process.pid": int64(42),
+	}, logs[1].ContextMap())
+}
+
+func TestAccessControlIgnoresUnknownNames(t *testing.T) {
+	log, obs := logger.NewTesting("control")
+	cfg := &configuration.ControlAccessConfig{
+		Roles: map[string]configuration.ControlRole{
+			"monitoring": {
+				Users:   []string{"no-such-user-for-the-test"},
+				Groups:  []string{"no-such-group-for-the-test"},
+				Methods: []string{"State"},
+			},
+		},
+	}
+	a := newAccessControl(log, cfg)
+	assert.Empty(t, a.users)
+	assert.Empty(t, a.groups)
+	assert.Equal(t, 2, obs.FilterMessageSnippet("Ignoring").Len())
+}
@@ -19,7 +19,10 @@ import (
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
-func createListener(log *logger.Logger) (net.Listener, error) {
+// createListener creates the control socket, only accessible by the user and
+// group running the agent unless allUsers is set because the RPCs are
+// authorized with the peer credentials.
+func createListener(log *logger.Logger, allUsers bool) (net.Listener, error) {
 	path := strings.TrimPrefix(control.Address(), "unix://")
 	if _, err := os.Stat(path); !os.IsNotExist(err) {
 		cleanupListener(log)
@@ -41,6 +44,9 @@ func createListener(log *logger.Logger) (net.Listener, error) {
 		// allow group access when not running as root
 		mode = os.FileMode(0770)
 	}
+	if allUsers {
+		mode = os.FileMode(0666)
+	}
 	err = os.Chmod(path, mode)
 	if err != nil {
 		// failed to set permissions (close listener)
@@ -20,7 +20,9 @@ import (
 )
 
 // createListener creates a named pipe listener on Windows
-func createListener(log *logger.Logger) (net.Listener, error) {
+// createListener creates the control named pipe, the peer credentials aren't
+// supported so allUsers is ignored.
+func createListener(log *logger.Logger, _ bool) (net.Listener, error) {
 	sd, err := securityDescriptor(log)
 	if err != nil {
 		return nil, err
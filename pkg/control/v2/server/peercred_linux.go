@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/credentials"
+)
+
+// peerCredsSupported is true on the platforms reading the peer credentials.
+const peerCredsSupported = true
+
+// peerCredentials are transport credentials reading SO_PEERCRED from the unix
+// socket connections, without securing them.
+type peerCredentials struct{}
+
+func newPeerCredentials() credentials.TransportCredentials {
+	return peerCredentials{}
+}
+
+func (peerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	creds, err := connPeerCreds(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, creds, nil
+}
+
+// connPeerCreds reads the credentials of the process connected to a unix socket.
+func connPeerCreds(conn net.Conn) (peerCreds, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return peerCreds{}, fmt.Errorf("peer credentials need a unix socket, got %T", conn)
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return peerCreds{}, err
+	}
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err == nil {
+		err = credErr
+	}
+	if err != nil {
+		return peerCreds{}, fmt.Errorf("failed to read the peer credentials: %w", err)
+	}
+	return peerCreds{
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity},
+		UID:            int(cred.Uid),
+		GID:            int(cred.Gid),
+		PID:            int(cred.Pid),
+	}, nil
+}
+
+func (peerCredentials) ClientHandshake(_ context.Context, _ string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, nil, nil
+}
+
+func (peerCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peercred"}
+}
+
+func (c peerCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (peerCredentials) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// peerCredsSupported is true on the platforms reading the peer credentials.
+const peerCredsSupported = false
+
+func newPeerCredentials() credentials.TransportCredentials {
+	return nil
+}
+
+// connPeerCreds reads the credentials of the process connected to a unix socket.
+func connPeerCreds(net.Conn) (peerCreds, error) {
+	return peerCreds{}, errors.New("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,451 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	controlclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// RESTAPIVersion is the version of the REST API, the prefix of its paths.
+const RESTAPIVersion = "v1"
+
+// restMarshaler encodes the control protocol messages, the JSON schema of the
+// REST API is the one of control_v2.proto.
+var restMarshaler = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// RESTServer is the local REST management API of standalone agents, an
+// HTTP/JSON mapping of the control protocol served by the control server.
+type RESTServer struct {
+	logger   *logger.Logger
+	control  *Server
+	address  string
+	token    string
+	listener net.Listener
+	server   *http.Server
+
+	// access authorizes and audits the calls like the control protocol, nil
+	// when the access control is disabled.
+	access *accessControl
+}
+
+// restCredsKey is the context key of the credentials of the process connected
+// to the unix socket of the REST API.
+type restCredsKey struct{}
+
+// NewREST creates the REST API of the control server listening on address, a
+// unix:// socket or a localhost TCP address. The requests must have the token
+// as bearer token when it is set. The calls are authorized and audited like
+// the control protocol when its access control is enabled.
+func NewREST(log *logger.Logger, control *Server, address string, token string) *RESTServer {
+	r := &RESTServer{
+		logger:  log,
+		control: control,
+		address: address,
+		token:   token,
+	}
+	if control.grpcConfig != nil && control.grpcConfig.Access != nil && control.grpcConfig.Access.Enabled && peerCredsSupported {
+		r.access = newAccessControl(log, control.grpcConfig.Access)
+	}
+	return r
+}
+
+// Start starts the REST API server.
+func (r *RESTServer) Start() error {
+	if r.server != nil {
+		// already started
+		return nil
+	}
+
+	lis, err := createRESTListener(r.address)
+	if err != nil {
+		r.logger.Errorf("unable to create REST API listener: %s", err)
+		return err
+	}
+	r.listener = lis
+	r.server = &http.Server{
+		Handler:           r.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			if _, ok := c.(*net.UnixConn); !ok || r.access == nil {
+				return ctx
+			}
+			creds, err := connPeerCreds(c)
+			if err != nil {
+				r.logger.Errorf("REST API: %s", err)
+				return ctx
+			}
+			return context.WithValue(ctx, restCredsKey{}, creds)
+		},
+	}
+	go func() {
+		if err := r.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.logger.Errorf("REST API server failed: %s", err)
+		}
+	}()
+	r.logger.Infof("REST API listening on %s", r.address)
+	return nil
+}
+
+// Stop stops the REST API server.
+func (r *RESTServer) Stop() {
+	if r.server != nil {
+		_ = r.server.Close()
+		r.server = nil
+		r.listener = nil
+		if path, ok := strings.CutPrefix(r.address, configuration.RESTUnixPrefix); ok {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// Handler returns the handler of the REST API routes.
+func (r *RESTServer) Handler() http.Handler {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/" + RESTAPIVersion).Subrouter()
+	api.Use(r.authenticate)
+	for _, route := range restRoutes {
+		api.Handle(route.path, r.authorize(route.methods, route.handler(r))).Methods(route.httpMethod)
+	}
+	return router
+}
+
+func (r *RESTServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.token != "" {
+			token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeRESTError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// authorize serves the request when the methods are granted to the caller, the
+// process connected to the unix socket or the holder of the token over TCP. The
+// calls are audited like the ones of the control protocol.
+func (r *RESTServer) authorize(methods []string, next http.HandlerFunc) http.HandlerFunc {
+	if r.access == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		creds, ok := req.Context().Value(restCredsKey{}).(peerCreds)
+		for _, method := range methods {
+			switch {
+			case ok:
+				if !r.access.authorizeCreds(creds, method, "rest") {
+					writeRESTError(w, http.StatusForbidden, fmt.Errorf("%s isn't granted to uid %d", method, creds.UID))
+					return
+				}
+			case r.token != "":
+				// the token was verified by authenticate
+				r.access.auditToken(method, req.RemoteAddr)
+			default:
+				r.logger.Errorw("Denied REST API call without peer credentials nor token", "method", method)
+				writeRESTError(w, http.StatusForbidden, errors.New("no peer credentials nor token"))
+				return
+			}
+		}
+		next(w, req)
+	}
+}
+
+func (r *RESTServer) version(w http.ResponseWriter, req *http.Request) {
+	resp, err := r.control.Version(req.Context(), &cproto.Empty{})
+	writeRESTMessage(w, resp, err)
+}
+
+func (r *RESTServer) state(w http.ResponseWriter, req *http.Request) {
+	resp, err := r.control.State(req.Context(), &cproto.Empty{})
+	writeRESTMessage(w, resp, err)
+}
+
+// stateWatch streams the state as server-sent events, like the StateWatch RPC.
+func (r *RESTServer) stateWatch(w http.ResponseWriter, req *http.Request) {
+	events, ok := newEventStream(w)
+	if !ok {
+		writeRESTError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	ctx := req.Context()
+	subChan := r.control.coord.StateSubscribe(ctx, 32)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case state := <-subChan:
+			resp, err := stateToProto(&state, r.control.agentInfo)
+			if err != nil {
+				r.logger.Errorf("REST API state watch: %s", err)
+				return
+			}
+			if err := events.send("state", resp); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// upgrade runs the Upgrade RPC with the UpgradeRequest of the body. With
+// "Accept: text/event-stream" the upgrade details are streamed as "details"
+// events until the UpgradeResponse "result" event.
+func (r *RESTServer) upgrade(w http.ResponseWriter, req *http.Request) {
+	upgradeReq := &cproto.UpgradeRequest{}
+	if err := readRESTMessage(req, upgradeReq); err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		resp, err := r.control.Upgrade(req.Context(), upgradeReq)
+		writeRESTMessage(w, resp, err)
+		return
+	}
+
+	events, ok := newEventStream(w)
+	if !ok {
+		writeRESTError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	subChan := r.control.coord.StateSubscribe(ctx, 32)
+	done := make(chan *cproto.UpgradeResponse, 1)
+	go func() {
+		resp, err := r.control.Upgrade(ctx, upgradeReq)
+		if err != nil {
+			resp = &cproto.UpgradeResponse{Status: cproto.ActionStatus_FAILURE, Error: err.Error()}
+		}
+		done <- resp
+	}()
+
+	var last *cproto.UpgradeDetails
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case resp := <-done:
+			_ = events.send("result", resp)
+			return
+		case state := <-subChan:
+			resp, err := stateToProto(&state, r.control.agentInfo)
+			if err != nil || resp.UpgradeDetails == nil || protobuf.Equal(resp.UpgradeDetails, last) {
+				continue
+			}
+			last = resp.UpgradeDetails
+			if err := events.send("details", resp.UpgradeDetails); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// diagnostics returns the diagnostics archive of the agent, the components and
+// the units, like the diagnostics command.
+func (r *RESTServer) diagnostics(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	agentResp, err := r.control.DiagnosticAgent(ctx, &cproto.DiagnosticAgentRequest{})
+	if err != nil {
+		writeRESTError(w, http.StatusInternalServerError, err)
+		return
+	}
+	agentDiag := make([]controlclient.DiagnosticFileResult, 0, len(agentResp.Results))
+	for _, res := range agentResp.Results {
+		agentDiag = append(agentDiag, controlclient.DiagnosticFileResult{
+			Name:        res.Name,
+			Filename:    res.Filename,
+			Description: res.Description,
+			ContentType: res.ContentType,
+			Content:     res.Content,
+			Generated:   res.Generated.AsTime(),
+		})
+	}
+
+	unitDiags := make([]controlclient.DiagnosticUnitResult, 0)
+	for _, d := range r.control.coord.PerformDiagnostics(ctx) {
+		unitDiags = append(unitDiags, controlclient.DiagnosticUnitResult{
+			ComponentID: d.Component.ID,
+			UnitID:      d.Unit.ID,
+			UnitType:    controlclient.UnitType(d.Unit.Type),
+			Err:         d.Err,
+			Results:     diagFileResults(d.Results),
+		})
+	}
+
+	compDiags := make([]controlclient.DiagnosticComponentResult, 0)
+	comps, err := r.control.coord.PerformComponentDiagnostics(ctx, nil)
+	if err != nil {
+		writeRESTError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, d := range comps {
+		compDiags = append(compDiags, controlclient.DiagnosticComponentResult{
+			ComponentID: d.Component.ID,
+			Err:         d.Err,
+			Results:     diagFileResults(d.Results),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="elastic-agent-diagnostics-%s.zip"`, time.Now().UTC().Format("2006-01-02T15-04-05Z07-00")))
+	if err := diagnostics.ZipArchive(io.Discard, w, nil, agentDiag, unitDiags, compDiags); err != nil {
+		r.logger.Errorf("REST API diagnostics: %s", err)
+	}
+}
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// logLevel changes the log level of the running agent, the level of the
+// configuration is used again after a restart.
+func (r *RESTServer) logLevel(w http.ResponseWriter, req *http.Request) {
+	var body logLevelRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeRESTError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	var lvl logp.Level
+	if err := lvl.Unpack(body.Level); err != nil {
+		writeRESTError(w, http.StatusBadRequest, fmt.Errorf("invalid log level %q: %w", body.Level, err))
+		return
+	}
+	if err := r.control.coord.SetLogLevel(req.Context(), lvl); err != nil {
+		writeRESTError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func diagFileResults(results []*proto.ActionDiagnosticUnitResult) []controlclient.DiagnosticFileResult {
+	files := make([]controlclient.DiagnosticFileResult, 0, len(results))
+	for _, res := range results {
+		files = append(files, controlclient.DiagnosticFileResult{
+			Name:        res.Name,
+			Filename:    res.Filename,
+			Description: res.Description,
+			ContentType: res.ContentType,
+			Content:     res.Content,
+			Generated:   res.Generated.AsTime(),
+		})
+	}
+	return files
+}
+
+func readRESTMessage(req *http.Request, msg protobuf.Message) error {
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1024*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if err := protojson.Unmarshal(body, msg); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+	return nil
+}
+
+func writeRESTMessage(w http.ResponseWriter, msg protobuf.Message, err error) {
+	if err != nil {
+		writeRESTError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data, err := restMarshaler.Marshal(msg)
+	if err != nil {
+		writeRESTError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func writeRESTError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// eventStream writes server-sent events.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &eventStream{w: w, flusher: flusher}, true
+}
+
+func (e *eventStream) send(event string, msg protobuf.Message) error {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+// createRESTListener listens on a unix socket, only accessible by the user and
+// group running the agent, or on a TCP address.
+func createRESTListener(address string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, configuration.RESTUnixPrefix)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	mode := os.FileMode(0700)
+	if root, _ := utils.HasRoot(); !root {
+		// allow group access when not running as root
+		mode = os.FileMode(0770)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
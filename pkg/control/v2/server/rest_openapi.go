@@ -0,0 +1,289 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+// restSetLogLevel is the method granting PUT /log-level in the roles of the
+// access control, the control protocol has no RPC to change the log level.
+const restSetLogLevel = "SetLogLevel"
+
+// restRoute is a route of the REST API, the routes are served by Handler and
+// documented by RESTOpenAPI.
+type restRoute struct {
+	httpMethod string
+	path       string
+	summary    string
+	// methods are the control protocol methods the caller must be granted.
+	methods []string
+	// request and response are the JSON bodies, nil without a JSON body.
+	request  protobuf.Message
+	response protobuf.Message
+	// contentType of the response when it isn't JSON.
+	contentType string
+	handler     func(r *RESTServer) http.HandlerFunc
+}
+
+var restRoutes = []restRoute{
+	{
+		httpMethod: http.MethodGet,
+		path:       "/version",
+		summary:    "Returns the version of the agent.",
+		methods:    []string{"Version"},
+		response:   &cproto.VersionResponse{},
+		handler:    func(r *RESTServer) http.HandlerFunc { return r.version },
+	},
+	{
+		httpMethod: http.MethodGet,
+		path:       "/state",
+		summary:    "Returns the state of the agent and of its components.",
+		methods:    []string{"State"},
+		response:   &cproto.StateResponse{},
+		handler:    func(r *RESTServer) http.HandlerFunc { return r.state },
+	},
+	{
+		httpMethod:  http.MethodGet,
+		path:        "/state/watch",
+		summary:     "Streams the state of the agent as state server-sent events of StateResponse.",
+		methods:     []string{"StateWatch"},
+		response:    &cproto.StateResponse{},
+		contentType: "text/event-stream",
+		handler:     func(r *RESTServer) http.HandlerFunc { return r.stateWatch },
+	},
+	{
+		httpMethod: http.MethodPost,
+		path:       "/upgrade",
+		summary:    "Upgrades the agent. With Accept: text/event-stream the UpgradeDetails are streamed as details events, then the UpgradeResponse as a result event.",
+		methods:    []string{"Upgrade"},
+		request:    &cproto.UpgradeRequest{},
+		response:   &cproto.UpgradeResponse{},
+		handler:    func(r *RESTServer) http.HandlerFunc { return r.upgrade },
+	},
+	{
+		httpMethod:  http.MethodGet,
+		path:        "/diagnostics",
+		summary:     "Returns the diagnostics archive of the agent, without the CPU profile.",
+		methods:     []string{"DiagnosticAgent", "DiagnosticUnits", "DiagnosticComponents"},
+		contentType: "application/zip",
+		handler:     func(r *RESTServer) http.HandlerFunc { return r.diagnostics },
+	},
+	{
+		httpMethod: http.MethodPut,
+		path:       "/log-level",
+		summary:    "Changes the log level of the running agent, the level of the configuration is used again after a restart.",
+		methods:    []string{restSetLogLevel},
+		handler:    func(r *RESTServer) http.HandlerFunc { return r.logLevel },
+	},
+}
+
+// RESTOpenAPI returns the OpenAPI document of the REST API, its schemas are
+// generated from the messages of control_v2.proto.
+func RESTOpenAPI() ([]byte, error) {
+	schemas := map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"error"},
+			"properties": map[string]interface{}{
+				"error": map[string]interface{}{"type": "string"},
+			},
+		},
+		"LogLevelRequest": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"level"},
+			"properties": map[string]interface{}{
+				"level": map[string]interface{}{
+					"type": "string",
+					"enum": []string{"critical", "error", "warning", "info", "debug"},
+				},
+			},
+		},
+	}
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content":     jsonContent(schemaRef("Error")),
+	}
+
+	paths := make(map[string]interface{})
+	for _, route := range restRoutes {
+		op := map[string]interface{}{
+			"summary":     route.summary,
+			"operationId": route.methods[0],
+			"x-methods":   route.methods,
+		}
+		switch {
+		case route.request != nil:
+			desc := route.request.ProtoReflect().Descriptor()
+			addMessageSchema(schemas, desc)
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(schemaRef(string(desc.Name()))),
+			}
+		case route.path == "/log-level":
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(schemaRef("LogLevelRequest")),
+			}
+		}
+
+		responses := map[string]interface{}{"default": errorResponse}
+		switch {
+		case route.response != nil:
+			desc := route.response.ProtoReflect().Descriptor()
+			addMessageSchema(schemas, desc)
+			contentType := route.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			responses["200"] = map[string]interface{}{
+				"description": string(desc.Name()),
+				"content": map[string]interface{}{
+					contentType: map[string]interface{}{"schema": schemaRef(string(desc.Name()))},
+				},
+			}
+		case route.contentType != "":
+			responses["200"] = map[string]interface{}{
+				"description": "OK",
+				"content": map[string]interface{}{
+					route.contentType: map[string]interface{}{
+						"schema": map[string]interface{}{"type": "string", "format": "binary"},
+					},
+				},
+			}
+		default:
+			responses["204"] = map[string]interface{}{"description": "No Content"}
+		}
+		op["responses"] = responses
+
+		item, ok := paths["/api/"+RESTAPIVersion+route.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths["/api/"+RESTAPIVersion+route.path] = item
+		}
+		item[strings.ToLower(route.httpMethod)] = op
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Elastic Agent local REST management API",
+			"description": "HTTP/JSON mapping of the control protocol of standalone agents. x-methods are the control protocol methods the caller must be granted by the access control.",
+			"version":     RESTAPIVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"token": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"token": []string{}},
+		},
+	}
+	// json.Marshal sorts the keys, the document is stable
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// addMessageSchema adds the schema of the JSON mapping of the message, with
+// the field names of the proto file, and the ones of the messages it uses.
+func addMessageSchema(schemas map[string]interface{}, desc protoreflect.MessageDescriptor) {
+	name := string(desc.Name())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	if desc.FullName() == "google.protobuf.Timestamp" {
+		schemas[name] = map[string]interface{}{"type": "string", "format": "date-time"}
+		return
+	}
+	properties := make(map[string]interface{})
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	// added before the fields for the recursive messages
+	schemas[name] = schema
+
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		var fieldSchema interface{}
+		switch {
+		case field.IsMap():
+			fieldSchema = map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": kindSchema(schemas, field.MapValue()),
+			}
+		case field.IsList():
+			fieldSchema = map[string]interface{}{
+				"type":  "array",
+				"items": kindSchema(schemas, field),
+			}
+		default:
+			fieldSchema = kindSchema(schemas, field)
+		}
+		properties[string(field.Name())] = fieldSchema
+	}
+}
+
+// kindSchema returns the schema of a single value of the field.
+func kindSchema(schemas map[string]interface{}, field protoreflect.FieldDescriptor) interface{} {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// the JSON mapping of the 64-bit integers is a string
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return map[string]interface{}{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		sort.Strings(names)
+		return map[string]interface{}{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		addMessageSchema(schemas, field.Message())
+		return schemaRef(string(field.Message().Name()))
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,210 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestRESTAuthentication(t *testing.T) {
+	rest := NewREST(logger.NewWithoutConfig("testing"), New(logger.NewWithoutConfig("testing"), nil, nil, nil, nil, configuration.DefaultGRPCConfig()), "", "secret")
+	handler := rest.Handler()
+
+	for name, header := range map[string]string{
+		"no token":     "",
+		"wrong token":  "Bearer wrong",
+		"basic scheme": "Basic secret",
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var version map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &version))
+	assert.Equal(t, release.Version(), version["version"])
+	assert.Equal(t, release.Commit(), version["commit"])
+}
+
+func TestRESTLogLevelValidation(t *testing.T) {
+	rest := NewREST(logger.NewWithoutConfig("testing"), New(logger.NewWithoutConfig("testing"), nil, nil, nil, nil, configuration.DefaultGRPCConfig()), "", "")
+	handler := rest.Handler()
+
+	for name, body := range map[string]string{
+		"invalid json":  `{"level": `,
+		"invalid level": `{"level": "loud"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/log-level", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"error"`)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/log-level", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestRESTAccessControl(t *testing.T) {
+	log, obs := logger.NewTesting("control")
+	cfg := configuration.DefaultGRPCConfig()
+	cfg.Access.Enabled = true
+	monitoring := cfg.Access.Roles["monitoring"]
+	monitoring.Users = []string{"2001"}
+	cfg.Access.Roles["monitoring"] = monitoring
+	cfg.Access.Roles["logging"] = configuration.ControlRole{
+		Users:   []string{"2002"},
+		Methods: []string{"SetLogLevel"},
+	}
+	rest := NewREST(log, New(log, nil, nil, nil, nil, cfg), "", "secret")
+	if !peerCredsSupported {
+		require.Nil(t, rest.access, "the access control is only supported with peer credentials")
+		return
+	}
+	require.NotNil(t, rest.access)
+	rest.access.uid, rest.access.gid = 1000, 1000
+	rest.access.lookupGroups = func(uid int) ([]int, error) {
+		return nil, nil
+	}
+	handler := rest.Handler()
+
+	serve := func(method, path, body string, creds *peerCreds) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if creds != nil {
+			req = req.WithContext(context.WithValue(req.Context(), restCredsKey{}, *creds))
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	testcases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		creds  *peerCreds
+		status int
+	}{
+		{"granted version", http.MethodGet, "/api/v1/version", "", &peerCreds{UID: 2001, GID: 5000}, http.StatusOK},
+		{"denied version", http.MethodGet, "/api/v1/version", "", &peerCreds{UID: 5000, GID: 5000}, http.StatusForbidden},
+		{"denied upgrade", http.MethodPost, "/api/v1/upgrade", `{}`, &peerCreds{UID: 2001, GID: 5000}, http.StatusForbidden},
+		{"denied diagnostics", http.MethodGet, "/api/v1/diagnostics", "", &peerCreds{UID: 2001, GID: 5000}, http.StatusForbidden},
+		{"denied log level", http.MethodPut, "/api/v1/log-level", `{"level": "debug"}`, &peerCreds{UID: 2001, GID: 5000}, http.StatusForbidden},
+		// authorized, the invalid level is rejected by the handler
+		{"granted log level", http.MethodPut, "/api/v1/log-level", `{"level": "loud"}`, &peerCreds{UID: 2002, GID: 5000}, http.StatusBadRequest},
+		{"token", http.MethodPut, "/api/v1/log-level", `{"level": "loud"}`, nil, http.StatusBadRequest},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tc.method, tc.path, tc.body, tc.creds)
+			assert.Equal(t, tc.status, rec.Code, rec.Body.String())
+		})
+	}
+
+	// the calls are audited like the control protocol ones
+	obs.TakeAll()
+	serve(http.MethodGet, "/api/v1/version", "", &peerCreds{UID: 2001, GID: 5000, PID: 42})
+	assert.Zero(t, obs.FilterMessage("Control protocol call").Len())
+	serve(http.MethodPut, "/api/v1/log-level", `{"level": "loud"}`, &peerCreds{UID: 2001, GID: 5000, PID: 42})
+	serve(http.MethodPut, "/api/v1/log-level", `{"level": "loud"}`, nil)
+	logs := obs.FilterMessage("Control protocol call").TakeAll()
+	require.Len(t, logs, 2)
+	assert.Equal(t, map[string]interface{}{
+		"method":      "SetLogLevel",
+		"transport":   "rest",
+		"allowed":     false,
+		"user.id":     int64(2001),
+		"group.id":    int64(5000),
+		"process.pid": int64(42),
+	}, logs[0].ContextMap())
+	assert.Equal(t, "token", logs[1].ContextMap()["auth"])
+	assert.Equal(t, "rest", logs[1].ContextMap()["transport"])
+
+	// no peer credentials nor token
+	rest = NewREST(log, New(log, nil, nil, nil, nil, cfg), "", "")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+	rec := httptest.NewRecorder()
+	rest.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestRESTOpenAPIUpToDate(t *testing.T) {
+	doc, err := RESTOpenAPI()
+	require.NoError(t, err)
+
+	committed, err := os.ReadFile(filepath.Join("..", "..", "..", "..", "docs", "rest-api.openapi.json"))
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(doc), "docs/rest-api.openapi.json is outdated, run mage update")
+
+	var parsed map[string]interface{}
+	require.NoError(t, json.Unmarshal(doc, &parsed))
+	paths, ok := parsed["paths"].(map[string]interface{})
+	require.True(t, ok)
+	for _, route := range restRoutes {
+		assert.Contains(t, paths, "/api/"+RESTAPIVersion+route.path)
+	}
+}
+
+func TestRESTUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not tested on windows")
+	}
+	// the socket path must be short
+	path := filepath.Join(t.TempDir(), "rest.sock")
+	// the user running the agent is granted all the methods with its peer credentials
+	cfg := configuration.DefaultGRPCConfig()
+	cfg.Access.Enabled = true
+	rest := NewREST(logger.NewWithoutConfig("testing"), New(logger.NewWithoutConfig("testing"), nil, nil, nil, nil, cfg), configuration.RESTUnixPrefix+path, "")
+	require.NoError(t, rest.Start())
+	defer rest.Stop()
+
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/api/v1/version")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var version map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&version))
+	assert.Equal(t, release.Commit(), version["commit"])
+}
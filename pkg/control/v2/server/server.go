@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/elastic/elastic-agent/pkg/control"
@@ -26,7 +27,9 @@ import (
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/component"
@@ -80,18 +83,34 @@ func (s *Server) Start() error {
 		return nil
 	}
 
-	lis, err := createListener(s.logger)
+	access := s.grpcConfig.Access
+	accessControlled := access != nil && access.Enabled
+	if accessControlled && !peerCredsSupported {
+		s.logger.Warn("The access control of the control protocol is only supported on Linux, it's disabled")
+		accessControlled = false
+	}
+
+	lis, err := createListener(s.logger, accessControlled)
 	if err != nil {
 		s.logger.Errorf("unable to create listener: %s", err)
 		return err
 	}
 	s.listener = lis
+
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize)}
+	var unary []grpc.UnaryServerInterceptor
 	if s.tracer != nil {
-		apmInterceptor := apmgrpc.NewUnaryServerInterceptor(apmgrpc.WithRecovery(), apmgrpc.WithTracer(s.tracer))
-		s.server = grpc.NewServer(grpc.UnaryInterceptor(apmInterceptor), grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize))
-	} else {
-		s.server = grpc.NewServer(grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize))
+		unary = append(unary, apmgrpc.NewUnaryServerInterceptor(apmgrpc.WithRecovery(), apmgrpc.WithTracer(s.tracer)))
+	}
+	if accessControlled {
+		ac := newAccessControl(s.logger, access)
+		unary = append(unary, ac.unaryInterceptor)
+		opts = append(opts, grpc.Creds(newPeerCredentials()), grpc.StreamInterceptor(ac.streamInterceptor))
+	}
+	if len(unary) > 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(unary...))
 	}
+	s.server = grpc.NewServer(opts...)
 	cproto.RegisterElasticAgentControlServer(s.server, s)
 
 	v1Wrapper := v1server.New(s.logger, s, s.tracer)
@@ -169,6 +188,16 @@ func (s *Server) Restart(_ context.Context, _ *cproto.Empty) (*cproto.RestartRes
 
 // Upgrade performs the upgrade operation.
 func (s *Server) Upgrade(ctx context.Context, request *cproto.UpgradeRequest) (*cproto.UpgradeResponse, error) {
+	// changing how the package is verified is protected by the uninstall token
+	if reason := protectedUpgrade(request); reason != "" {
+		if err := s.coord.VerifyProtectedOperation(protection.OperationUpgrade, request.UninstallToken); err != nil {
+			return &cproto.UpgradeResponse{
+				Status: cproto.ActionStatus_FAILURE,
+				Error:  fmt.Sprintf("%s refused: %s", reason, err),
+			}, nil
+		}
+	}
+
 	err := s.coord.Upgrade(ctx, request.Version, request.SourceURI, nil, request.SkipVerify, request.SkipDefaultPgp, request.PgpBytes...)
 	if err != nil {
 		//nolint:nilerr // ignore the error, return a failure upgrade response
@@ -183,6 +212,22 @@ func (s *Server) Upgrade(ctx context.Context, request *cproto.UpgradeRequest) (*
 	}, nil
 }
 
+// protectedUpgrade returns the kind of upgrade the request is when it needs the uninstall token
+// of a protected agent: an upgrade skipping the package verification, verifying the package
+// with custom PGP keys, or downloading it from a source other than the default one. It
+// returns an empty string for the other upgrades.
+func protectedUpgrade(request *cproto.UpgradeRequest) string {
+	switch {
+	case request.SkipVerify || request.SkipDefaultPgp:
+		return "upgrade without package verification"
+	case len(request.PgpBytes) > 0:
+		return "upgrade verified with custom PGP keys"
+	case request.SourceURI != "" && strings.TrimSuffix(request.SourceURI, "/") != strings.TrimSuffix(artifact.DefaultSourceURI, "/"):
+		return "upgrade from a custom source URI"
+	}
+	return ""
+}
+
 // DiagnosticAgent returns diagnostic information for this running Elastic Agent.
 func (s *Server) DiagnosticAgent(ctx context.Context, req *cproto.DiagnosticAgentRequest) (*cproto.DiagnosticAgentResponse, error) {
 	res := make([]*cproto.DiagnosticFileResult, 0, len(s.diagHooks))
@@ -349,6 +394,16 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 				Payload:  string(payload),
 			})
 		}
+		var resources *cproto.ComponentResources
+		if r := comp.State.Resources; r != nil {
+			resources = &cproto.ComponentResources{
+				Pid:       int64(r.PID),
+				Rss:       r.RSS,
+				CpuPct:    r.CPU,
+				OpenFds:   int64(r.OpenFDs),
+				SampledAt: timestamppb.New(r.SampledAt),
+			}
+		}
 		components = append(components, &cproto.ComponentState{
 			Id:      comp.Component.ID,
 			Name:    comp.Component.Type(),
@@ -360,6 +415,7 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 				Version: comp.State.VersionInfo.Version,
 				Meta:    comp.State.VersionInfo.Meta,
 			},
+			Resources: resources,
 		})
 	}
 
@@ -390,6 +446,26 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 		}
 	}
 
+	fleetHosts := make([]*cproto.FleetHost, 0, len(state.FleetHosts))
+	for _, h := range state.FleetHosts {
+		host := &cproto.FleetHost{
+			Host:                h.Host,
+			Priority:            int32(h.Priority),
+			Selected:            h.Selected,
+			Circuit:             string(h.Circuit),
+			ConsecutiveFailures: int32(h.ConsecutiveFailures),
+			Latency:             int64(h.Latency),
+			LastError:           h.LastError,
+		}
+		if !h.LastUsed.IsZero() {
+			host.LastUsed = h.LastUsed.Format(control.TimeFormat())
+		}
+		if !h.LastErrorAt.IsZero() {
+			host.LastErrorAt = h.LastErrorAt.Format(control.TimeFormat())
+		}
+		fleetHosts = append(fleetHosts, host)
+	}
+
 	return &cproto.StateResponse{
 		Info: &cproto.StateAgentInfo{
 			Id:        agentInfo.AgentID(),
@@ -403,6 +479,7 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 		Message:        state.Message,
 		FleetState:     state.FleetState,
 		FleetMessage:   state.FleetMessage,
+		FleetHosts:     fleetHosts,
 		Components:     components,
 		UpgradeDetails: upgradeDetails,
 	}, nil
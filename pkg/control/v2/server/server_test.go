@@ -16,6 +16,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/control"
@@ -31,6 +32,7 @@ func TestStateMapping(t *testing.T) {
 		fleetState     cproto.State
 		fleetMessage   string
 		upgradeDetails *details.Details
+		fleetHosts     []remote.HostHealth
 	}{
 		{
 			name:         "waiting first checkin response",
@@ -73,6 +75,31 @@ func TestStateMapping(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:         "with fleet hosts",
+			agentState:   cproto.State_HEALTHY,
+			agentMessage: "Healthy",
+			fleetState:   cproto.State_HEALTHY,
+			fleetMessage: "Connected",
+			fleetHosts: []remote.HostHealth{
+				{
+					Host:                "https://fleet-1:8220/",
+					Circuit:             remote.CircuitOpen,
+					ConsecutiveFailures: 3,
+					LastUsed:            now,
+					LastError:           "connection refused",
+					LastErrorAt:         now,
+				},
+				{
+					Host:     "https://fleet-2:8220/",
+					Priority: 1,
+					Selected: true,
+					Circuit:  remote.CircuitClosed,
+					Latency:  25 * time.Millisecond,
+					LastUsed: now,
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -82,6 +109,7 @@ func TestStateMapping(t *testing.T) {
 				Message:      tc.agentMessage,
 				FleetState:   tc.fleetState,
 				FleetMessage: tc.fleetMessage,
+				FleetHosts:   tc.fleetHosts,
 				LogLevel:     logp.ErrorLevel,
 				Components: []runtime.ComponentComponentState{
 					{
@@ -143,6 +171,24 @@ func TestStateMapping(t *testing.T) {
 			assert.Equal(t, stateResponse.Message, tc.agentMessage)
 			assert.Equal(t, stateResponse.FleetState, tc.fleetState)
 			assert.Equal(t, stateResponse.FleetMessage, tc.fleetMessage)
+			if assert.Len(t, stateResponse.FleetHosts, len(tc.fleetHosts)) && len(tc.fleetHosts) > 0 {
+				assert.Equal(t, &cproto.FleetHost{
+					Host:                "https://fleet-1:8220/",
+					Circuit:             "open",
+					ConsecutiveFailures: 3,
+					LastUsed:            now.Format(control.TimeFormat()),
+					LastError:           "connection refused",
+					LastErrorAt:         now.Format(control.TimeFormat()),
+				}, stateResponse.FleetHosts[0])
+				assert.Equal(t, &cproto.FleetHost{
+					Host:     "https://fleet-2:8220/",
+					Priority: 1,
+					Selected: true,
+					Circuit:  "closed",
+					Latency:  int64(25 * time.Millisecond),
+					LastUsed: now.Format(control.TimeFormat()),
+				}, stateResponse.FleetHosts[1])
+			}
 			if assert.Len(t, stateResponse.Components, 1) {
 				expectedCompState := &cproto.ComponentState{
 					Id:      "some-component",
@@ -193,3 +239,24 @@ func TestStateMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestProtectedUpgrade(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  *cproto.UpgradeRequest
+		expected string
+	}{
+		{"default upgrade", &cproto.UpgradeRequest{Version: "8.13.0"}, ""},
+		{"default source URI", &cproto.UpgradeRequest{Version: "8.13.0", SourceURI: "https://artifacts.elastic.co/downloads"}, ""},
+		{"skip verification", &cproto.UpgradeRequest{SkipVerify: true}, "upgrade without package verification"},
+		{"skip default PGP", &cproto.UpgradeRequest{SkipDefaultPgp: true}, "upgrade without package verification"},
+		{"custom PGP key", &cproto.UpgradeRequest{PgpBytes: []string{"pgp_raw:key"}}, "upgrade verified with custom PGP keys"},
+		{"custom PGP URI", &cproto.UpgradeRequest{PgpBytes: []string{"pgp_uri:https://example.com/key"}}, "upgrade verified with custom PGP keys"},
+		{"custom source URI", &cproto.UpgradeRequest{SourceURI: "https://example.com/downloads/"}, "upgrade from a custom source URI"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, protectedUpgrade(tc.request))
+		})
+	}
+}
@@ -8,18 +8,48 @@ import "time"
 
 // Config for fine tuning new process
 type Config struct {
-	SpawnTimeout   time.Duration `yaml:"spawn_timeout" config:"spawn_timeout"`
-	StopTimeout    time.Duration `yaml:"stop_timeout" config:"stop_timeout"`
-	FailureTimeout time.Duration `yaml:"failure_timeout" config:"failure_timeout"`
+	SpawnTimeout   time.Duration    `yaml:"spawn_timeout" config:"spawn_timeout"`
+	StopTimeout    time.Duration    `yaml:"stop_timeout" config:"stop_timeout"`
+	FailureTimeout time.Duration    `yaml:"failure_timeout" config:"failure_timeout"`
+	Resources      *ResourcesConfig `yaml:"resources" config:"resources"`
 
 	// TODO: cgroups and namespaces
 }
 
+// ResourcesConfig configures the sampling of the resources used by the
+// component processes.
+type ResourcesConfig struct {
+	// Period between samples, 0 disables the sampling.
+	Period time.Duration `yaml:"period" config:"period"`
+	// Thresholds above which a component is reported DEGRADED.
+	Thresholds ResourceThresholds `yaml:"thresholds" config:"thresholds"`
+}
+
+// ResourceThresholds are the resource limits of a component process, 0
+// disables a threshold.
+type ResourceThresholds struct {
+	// CPU is the CPU usage in percent of one core.
+	CPU float64 `yaml:"cpu_pct" config:"cpu_pct"`
+	// RSS is the resident set size in bytes.
+	RSS uint64 `yaml:"rss" config:"rss"`
+	// OpenFDs is the number of open file descriptors.
+	OpenFDs int `yaml:"open_fds" config:"open_fds"`
+}
+
+// DefaultResourcesConfig samples the resources every 30 seconds without
+// thresholds.
+func DefaultResourcesConfig() *ResourcesConfig {
+	return &ResourcesConfig{
+		Period: 30 * time.Second,
+	}
+}
+
 // DefaultConfig creates a config with pre-set default values.
 func DefaultConfig() *Config {
 	return &Config{
 		SpawnTimeout:   30 * time.Second,
 		StopTimeout:    30 * time.Second,
 		FailureTimeout: 10 * time.Second,
+		Resources:      DefaultResourcesConfig(),
 	}
 }
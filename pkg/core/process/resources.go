@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package process
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrResourcesUnsupported is returned by SampleResources on the platforms it
+// doesn't support.
+var ErrResourcesUnsupported = errors.New("sampling process resources is not supported on this platform")
+
+// Resources are the resources used by a process.
+type Resources struct {
+	// RSS is the resident set size in bytes.
+	RSS uint64
+	// CPUTime is the CPU time the process spent in user and kernel mode.
+	CPUTime time.Duration
+	// OpenFDs is the number of open file descriptors.
+	OpenFDs int
+}
+
+// CPUPercent returns the CPU usage in percent of one core between the prev
+// sample and r, taken elapsed apart.
+func (r Resources) CPUPercent(prev Resources, elapsed time.Duration) float64 {
+	if elapsed <= 0 || r.CPUTime < prev.CPUTime {
+		return 0
+	}
+	return float64(r.CPUTime-prev.CPUTime) / float64(elapsed) * 100
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// userHZ is the unit of the CPU times in /proc/<pid>/stat, it's fixed to 100
+// on all the architectures the agent supports.
+const userHZ = 100
+
+// procRoot is the procfs mount point, replaced in tests.
+var procRoot = "/proc"
+
+// SampleResources reads the resources used by the process pid from /proc.
+func SampleResources(pid int) (Resources, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return Resources{}, fmt.Errorf("failed to read the stat of pid %d: %w", pid, err)
+	}
+	r, err := parseStat(stat)
+	if err != nil {
+		return Resources{}, fmt.Errorf("failed to parse the stat of pid %d: %w", pid, err)
+	}
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return Resources{}, fmt.Errorf("failed to list the file descriptors of pid %d: %w", pid, err)
+	}
+	r.OpenFDs = len(fds)
+	return r, nil
+}
+
+// parseStat parses the content of /proc/<pid>/stat, see proc(5).
+func parseStat(stat []byte) (Resources, error) {
+	// the command name is in parentheses and can contain spaces and
+	// parentheses, the other fields follow the last one.
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return Resources{}, fmt.Errorf("no command name")
+	}
+	// fields starts with field 3 (state)
+	fields := bytes.Fields(stat[end+1:])
+	const (
+		utime = 14 - 3
+		stime = 15 - 3
+		rss   = 24 - 3
+	)
+	if len(fields) <= rss {
+		return Resources{}, fmt.Errorf("expected at least %d fields, got %d", rss+3, len(fields)+2)
+	}
+	var values [3]uint64
+	for i, idx := range []int{utime, stime, rss} {
+		v, err := strconv.ParseUint(string(fields[idx]), 10, 64)
+		if err != nil {
+			return Resources{}, fmt.Errorf("invalid field %d: %w", idx+3, err)
+		}
+		values[i] = v
+	}
+	return Resources{
+		RSS:     values[2] * uint64(os.Getpagesize()),
+		CPUTime: time.Duration(values[0]+values[1]) * time.Second / userHZ,
+	}, nil
+}
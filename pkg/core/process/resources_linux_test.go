@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleResources(t *testing.T) {
+	procRoot = t.TempDir()
+	t.Cleanup(func() { procRoot = "/proc" })
+
+	dir := filepath.Join(procRoot, "42")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+	for _, fd := range []string{"0", "1", "2"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "fd", fd), nil, 0644))
+	}
+	stat := "42 (file beat) (x) S 1 42 42 0 -1 4194560 5000 0 0 0 250 50 0 0 20 0 12 0 1000 2000000000 1024 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+
+	r, err := SampleResources(42)
+	require.NoError(t, err)
+	assert.Equal(t, Resources{
+		RSS:     1024 * uint64(os.Getpagesize()),
+		CPUTime: 3 * time.Second,
+		OpenFDs: 3,
+	}, r)
+
+	_, err = SampleResources(43)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSampleResourcesSelf(t *testing.T) {
+	r, err := SampleResources(os.Getpid())
+	require.NoError(t, err)
+	assert.NotZero(t, r.RSS)
+	assert.NotZero(t, r.OpenFDs)
+}
+
+func TestResourcesCPUPercent(t *testing.T) {
+	prev := Resources{CPUTime: time.Second}
+	r := Resources{CPUTime: 4 * time.Second}
+	assert.Equal(t, 30.0, r.CPUPercent(prev, 10*time.Second))
+	assert.Zero(t, prev.CPUPercent(r, 10*time.Second), "the CPU time of another process")
+	assert.Zero(t, r.CPUPercent(prev, 0))
+}
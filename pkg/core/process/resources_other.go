@@ -0,0 +1,13 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package process
+
+// SampleResources returns ErrResourcesUnsupported, the resources are only
+// sampled on Linux.
+func SampleResources(pid int) (Resources, error) {
+	return Resources{}, ErrResourcesUnsupported
+}
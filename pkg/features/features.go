@@ -37,6 +37,9 @@ type Flags struct {
 	fqdnCallbacks map[string]BoolValueOnChangeCallback
 
 	tamperProtection bool
+
+	cloudMetadata          bool
+	cloudMetadataCallbacks map[string]BoolValueOnChangeCallback
 }
 
 type cfg struct {
@@ -48,6 +51,9 @@ type cfg struct {
 			TamperProtection *struct {
 				Enabled bool `json:"enabled" yaml:"enabled" config:"enabled"`
 			} `json:"tamper_protection,omitempty" yaml:"tamper_protection,omitempty" config:"tamper_protection,omitempty"`
+			CloudMetadata struct {
+				Enabled bool `json:"enabled" yaml:"enabled" config:"enabled"`
+			} `json:"cloud_metadata" yaml:"cloud_metadata" config:"cloud_metadata"`
 		} `json:"features" yaml:"features" config:"features"`
 	} `json:"agent" yaml:"agent" config:"agent"`
 }
@@ -66,6 +72,13 @@ func (f *Flags) TamperProtection() bool {
 	return f.tamperProtection
 }
 
+func (f *Flags) CloudMetadata() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.cloudMetadata
+}
+
 func (f *Flags) AsProto() *proto.Features {
 	return &proto.Features{
 		Fqdn: &proto.FQDNFeature{
@@ -121,6 +134,43 @@ func (f *Flags) setTamperProtection(newValue bool) {
 	f.tamperProtection = newValue
 }
 
+// AddCloudMetadataOnChangeCallback takes a callback function that will be called with the new
+// and old values of `flags.cloudMetadata` whenever it changes. It also takes a string ID to
+// de-register the callback with `RemoveCloudMetadataOnChangeCallback`.
+func AddCloudMetadataOnChangeCallback(cb BoolValueOnChangeCallback, id string) error {
+	current.mu.Lock()
+	defer current.mu.Unlock()
+
+	// Initialize callbacks map if necessary.
+	if current.cloudMetadataCallbacks == nil {
+		current.cloudMetadataCallbacks = map[string]BoolValueOnChangeCallback{}
+	}
+
+	current.cloudMetadataCallbacks[id] = cb
+	return nil
+}
+
+// RemoveCloudMetadataOnChangeCallback removes the callback function associated with the given ID
+// (originally passed to `AddCloudMetadataOnChangeCallback`).
+func RemoveCloudMetadataOnChangeCallback(id string) {
+	current.mu.Lock()
+	defer current.mu.Unlock()
+
+	delete(current.cloudMetadataCallbacks, id)
+}
+
+// setCloudMetadata sets the value of the CloudMetadata flag in Flags.
+func (f *Flags) setCloudMetadata(newValue bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	oldValue := f.cloudMetadata
+	f.cloudMetadata = newValue
+	for _, cb := range f.cloudMetadataCallbacks {
+		cb(newValue, oldValue)
+	}
+}
+
 // setSource sets the source from he given cfg.
 func (f *Flags) setSource(c cfg) error {
 	// Use JSON marshalling-unmarshalling to convert cfg to mapstr
@@ -186,6 +236,8 @@ func Parse(policy any) (*Flags, error) {
 		flags.setTamperProtection(defaultTamperProtection)
 	}
 
+	flags.setCloudMetadata(parsedFlags.Agent.Features.CloudMetadata.Enabled)
+
 	if err := flags.setSource(parsedFlags); err != nil {
 		return nil, fmt.Errorf("error creating feature flags source: %w", err)
 	}
@@ -208,6 +260,7 @@ func Apply(c *config.Config) error {
 
 	current.setFQDN(parsed.FQDN())
 	current.setTamperProtection(parsed.TamperProtection())
+	current.setCloudMetadata(parsed.CloudMetadata())
 	return err
 }
 
@@ -220,3 +273,8 @@ func FQDN() bool {
 func TamperProtection() bool {
 	return current.TamperProtection()
 }
+
+// CloudMetadata reports if the cloud instance metadata should be added to the agent local metadata.
+func CloudMetadata() bool {
+	return current.CloudMetadata()
+}
@@ -82,6 +82,59 @@ agent:
 	}
 }
 
+func TestCloudMetadata(t *testing.T) {
+	tcs := []struct {
+		name string
+		yaml string
+		want bool
+	}{
+		{
+			name: "cloud metadata enabled",
+			yaml: `
+agent:
+  features:
+    cloud_metadata:
+      enabled: true`,
+			want: true,
+		},
+		{
+			name: "cloud metadata disabled",
+			yaml: `
+agent:
+  features:
+    cloud_metadata:
+      enabled: false`,
+			want: false,
+		},
+		{
+			name: "cloud metadata absent",
+			yaml: `
+agent:
+  features:`,
+			want: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := config.NewConfigFrom(tc.yaml)
+			if err != nil {
+				t.Fatalf("could not parse config YAML: %v", err)
+			}
+
+			err = Apply(c)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+
+			got := CloudMetadata()
+			if got != tc.want {
+				t.Errorf("want: %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
 func TestFQDNCallbacks(t *testing.T) {
 	cb1Called, cb2Called := false, false
 
@@ -116,3 +169,21 @@ func TestFQDNCallbacks(t *testing.T) {
 	RemoveFQDNOnChangeCallback("cb2")
 	require.Len(t, current.fqdnCallbacks, 0)
 }
+
+func TestCloudMetadataCallbacks(t *testing.T) {
+	var got []bool
+	err := AddCloudMetadataOnChangeCallback(func(new, old bool) {
+		got = append(got, new, old)
+	}, "cb")
+	require.NoError(t, err)
+	defer RemoveCloudMetadataOnChangeCallback("cb")
+
+	current.setCloudMetadata(false)
+	current.setCloudMetadata(true)
+	require.Equal(t, []bool{false, false, true, false}, got)
+
+	RemoveCloudMetadataOnChangeCallback("cb")
+	require.Len(t, current.cloudMetadataCallbacks, 0)
+	current.setCloudMetadata(false)
+	require.Len(t, got, 4)
+}
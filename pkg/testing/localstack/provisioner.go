@@ -0,0 +1,269 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package localstack provisions hermetic stacks running in the process of the
+// integration test runner: an Elasticsearch stand-in recording the documents
+// it receives, a Fleet stand-in enrolling agents and delivering their policies
+// and actions, and the subset of the Kibana Fleet API the tests tools use.
+//
+// Tests only needing Fleet interactions and checking the data shipped by the
+// agent with estools can run against it fully offline.
+package localstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/elastic/elastic-agent/pkg/testing/runner"
+	"github.com/elastic/elastic-agent/testing/elasticsearchtest"
+	"github.com/elastic/elastic-agent/testing/fleetservertest"
+)
+
+// ProvisionerLocal is the name of the local stack provisioner.
+const ProvisionerLocal = "local"
+
+const username = "elastic"
+
+// ProvisionerConfig is the configuration for the local stack provisioner.
+type ProvisionerConfig struct {
+	// Host is the host the stack services are advertised on, it must be
+	// reachable from the instances running the tests.
+	Host string
+}
+
+// Validate returns an error if the information is invalid.
+func (c *ProvisionerConfig) Validate() error {
+	if c.Host == "" {
+		return errors.New("field Host must be set")
+	}
+	return nil
+}
+
+// DefaultHost returns the first non-loopback IPv4 address of the host, which
+// is usually reachable from local virtual machines, or localhost if there is
+// none.
+func DefaultHost() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "localhost"
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return "localhost"
+}
+
+// stack is a stack running in this process.
+type stack struct {
+	es     *elasticsearchtest.Server
+	fleet  *fleetservertest.Fleet
+	fs     *fleetservertest.Server
+	kibana *httptest.Server
+}
+
+func (s *stack) close() {
+	s.kibana.Close()
+	s.fs.Close()
+	s.es.Close()
+}
+
+type provisioner struct {
+	logger runner.Logger
+	cfg    ProvisionerConfig
+
+	mx     sync.Mutex
+	stacks map[string]*stack
+}
+
+// NewProvisioner creates the local stack provisioner.
+func NewProvisioner(cfg ProvisionerConfig) (runner.StackProvisioner, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &provisioner{
+		cfg:    cfg,
+		stacks: map[string]*stack{},
+	}, nil
+}
+
+func (p *provisioner) Name() string {
+	return ProvisionerLocal
+}
+
+func (p *provisioner) SetLogger(l runner.Logger) {
+	p.logger = l
+}
+
+// Create starts a stack.
+func (p *provisioner) Create(ctx context.Context, request runner.StackRequest) (runner.Stack, error) {
+	s := runner.Stack{
+		ID:          request.ID,
+		Provisioner: p.Name(),
+		Version:     request.Version,
+		Username:    username,
+		Password:    uuid.New().String(),
+	}
+	p.logf("Starting local stack %s [stack_id: %s]", request.Version, request.ID)
+	return p.start(s)
+}
+
+// WaitForReady waits for the stack services to answer. A stack only lives as
+// long as the process which created it, so it's never reported as ready: the
+// runner always calls WaitForReady, which starts again a stack created by a
+// previous run.
+func (p *provisioner) WaitForReady(ctx context.Context, s runner.Stack) (runner.Stack, error) {
+	p.mx.Lock()
+	_, running := p.stacks[s.ID]
+	p.mx.Unlock()
+	if !running {
+		p.logf("Local stack %s [stack_id: %s] is not running, starting it", s.Version, s.ID)
+		started, err := p.start(s)
+		if err != nil {
+			return s, err
+		}
+		s = started
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	for _, u := range []string{s.Elasticsearch, s.Kibana + "/api/fleet/fleet_server_hosts"} {
+		if err := waitForURL(ctx, u, s.Username, s.Password); err != nil {
+			return s, fmt.Errorf("local stack %s [stack_id: %s] never became ready: %w", s.Version, s.ID, err)
+		}
+	}
+	return s, nil
+}
+
+// Delete stops the stack.
+func (p *provisioner) Delete(ctx context.Context, s runner.Stack) error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	running, ok := p.stacks[s.ID]
+	if !ok {
+		return nil
+	}
+	p.logf("Stopping local stack %s [stack_id: %s]", s.Version, s.ID)
+	running.close()
+	delete(p.stacks, s.ID)
+	return nil
+}
+
+// start starts the services of the stack and returns it with their URLs.
+func (p *provisioner) start(s runner.Stack) (runner.Stack, error) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	es := elasticsearchtest.NewServer("",
+		elasticsearchtest.WithCredentials(s.Username, s.Password),
+		elasticsearchtest.WithVersion(s.Version))
+	esURL, err := p.advertisedURL(es.URL)
+	if err != nil {
+		es.Close()
+		return s, err
+	}
+
+	fleet := fleetservertest.NewFleet(fleetservertest.APIKey{
+		ID:  uuid.New().String(),
+		Key: uuid.New().String(),
+	})
+	fleet.SetOutput(map[string]interface{}{
+		"type":     "elasticsearch",
+		"hosts":    []string{esURL},
+		"username": s.Username,
+		"password": s.Password,
+	})
+	fs := fleetservertest.NewFleetServer(fleet)
+	fleetURL, err := p.advertisedURL(fs.URL)
+	if err != nil {
+		fs.Close()
+		es.Close()
+		return s, err
+	}
+	fleet.SetFleetHosts(fleetURL)
+
+	l, err := net.Listen("tcp", ":0") //nolint:gosec // it's a test stack
+	if err != nil {
+		fs.Close()
+		es.Close()
+		return s, fmt.Errorf("failed to create a net.Listener for the Kibana stand-in of local stack %s [stack_id: %s]: %w", s.Version, s.ID, err)
+	}
+	kibana := &httptest.Server{
+		Listener: l,
+		Config:   &http.Server{Handler: fleet.KibanaHandler()}, //nolint:gosec // it's a test stack
+	}
+	kibana.Start()
+	running := &stack{es: es, fleet: fleet, fs: fs, kibana: kibana}
+	kibanaURL, err := p.advertisedURL(kibana.URL)
+	if err != nil {
+		running.close()
+		return s, err
+	}
+
+	p.stacks[s.ID] = running
+
+	s.Elasticsearch = esURL
+	s.Kibana = kibanaURL
+	s.Ready = false
+	s.Internal = map[string]interface{}{
+		"fleet_url": fleetURL,
+	}
+	return s, nil
+}
+
+// advertisedURL replaces the host of a server URL by the configured host.
+func (p *provisioner) advertisedURL(serverURL string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse server URL %q: %w", serverURL, err)
+	}
+	u.Host = net.JoinHostPort(p.cfg.Host, u.Port())
+	return u.String(), nil
+}
+
+func (p *provisioner) logf(format string, args ...any) {
+	if p.logger != nil {
+		p.logger.Logf(format, args...)
+	}
+}
+
+// waitForURL waits for a GET on u to succeed.
+func waitForURL(ctx context.Context, u string, username string, password string) error {
+	var lastErr error
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(username, password)
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("GET %s: unexpected status code %d", u, resp.StatusCode)
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ctx.Err(), lastErr)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package localstack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/kibana"
+	"github.com/elastic/elastic-agent/pkg/testing/runner"
+	"github.com/elastic/elastic-agent/pkg/testing/tools/estools"
+	"github.com/elastic/elastic-agent/pkg/testing/tools/fleettools"
+)
+
+func TestProvisioner(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewProvisioner(ProvisionerConfig{Host: "127.0.0.1"})
+	require.NoError(t, err)
+
+	stack, err := p.Create(ctx, runner.StackRequest{ID: "8120", Version: "8.12.0"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = p.Delete(ctx, stack) })
+
+	stack, err = p.WaitForReady(ctx, stack)
+	require.NoError(t, err)
+	assert.False(t, stack.Ready, "a local stack must never be reported as ready")
+	checkStack(t, stack)
+
+	// a stack from a previous run is started again
+	require.NoError(t, p.Delete(ctx, stack))
+	restarted, err := p.WaitForReady(ctx, stack)
+	require.NoError(t, err)
+	assert.NotEqual(t, stack.Elasticsearch, restarted.Elasticsearch)
+	checkStack(t, restarted)
+	stack = restarted
+}
+
+func TestProvisionerConfigValidate(t *testing.T) {
+	_, err := NewProvisioner(ProvisionerConfig{})
+	assert.Error(t, err)
+}
+
+func TestProvisionerAdvertisedURL(t *testing.T) {
+	p := &provisioner{cfg: ProvisionerConfig{Host: "10.0.0.1"}}
+	advertised, err := p.advertisedURL("http://127.0.0.1:9200")
+	require.NoError(t, err)
+	assert.Equal(t, "http://10.0.0.1:9200", advertised)
+
+	_, err = p.advertisedURL("http://[::1")
+	assert.Error(t, err, "an invalid server URL must be returned as an error")
+}
+
+// checkStack checks the stack can be used through the clients the tests use.
+func checkStack(t *testing.T, stack runner.Stack) {
+	es, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{stack.Elasticsearch},
+		Username:  stack.Username,
+		Password:  stack.Password,
+	})
+	require.NoError(t, err)
+	ping, err := estools.GetPing(context.Background(), es)
+	require.NoError(t, err)
+	assert.Equal(t, stack.Version, ping.Version.Number)
+
+	kib, err := kibana.NewClientWithConfig(&kibana.ClientConfig{
+		Host:          stack.Kibana,
+		Username:      stack.Username,
+		Password:      stack.Password,
+		IgnoreVersion: true,
+	}, "localstack", "", "", "")
+	require.NoError(t, err)
+	fleetURL, err := fleettools.DefaultURL(kib)
+	require.NoError(t, err)
+	assert.Equal(t, stack.Internal["fleet_url"], fleetURL)
+}
@@ -0,0 +1,337 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package elasticsearchtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// match returns true if the document matches the query. The supported queries
+// are:
+//   - match_all,
+//   - bool, with must, filter, should and must_not,
+//   - match, which requires all the terms of the query to be in the field,
+//   - match_phrase, which requires the terms to be consecutive in the field,
+//   - term and terms, comparing the values as is,
+//   - exists.
+//
+// The text is analysed by splitting it on anything but letters, digits and
+// '_', and lower-casing it. This is close enough to the standard analyzer for
+// the checks done by the tests.
+func match(query map[string]interface{}, doc map[string]interface{}) (bool, error) {
+	if len(query) == 0 {
+		return true, nil
+	}
+	if len(query) != 1 {
+		return false, fmt.Errorf("a query must have a single root, got %d", len(query))
+	}
+
+	for kind, body := range query {
+		switch kind {
+		case "match_all":
+			return true, nil
+		case "bool":
+			clauses, ok := body.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("[bool] query malformed")
+			}
+			return matchBool(clauses, doc)
+		case "match", "match_phrase", "term", "terms":
+			field, value, err := fieldQuery(kind, body)
+			if err != nil {
+				return false, err
+			}
+			return matchField(kind, lookup(doc, field), value), nil
+		case "exists":
+			params, ok := body.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("[exists] query malformed")
+			}
+			field, _ := params["field"].(string)
+			return lookup(doc, field) != nil, nil
+		default:
+			return false, fmt.Errorf("unsupported query [%s]", kind)
+		}
+	}
+	return false, nil
+}
+
+func matchBool(clauses map[string]interface{}, doc map[string]interface{}) (bool, error) {
+	hasRequired := false
+	shouldMatches := 0
+	shouldCount := 0
+	for occur, value := range clauses {
+		var queries []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			queries = v
+		case map[string]interface{}:
+			queries = []interface{}{v}
+		default:
+			if occur == "minimum_should_match" {
+				continue
+			}
+			return false, fmt.Errorf("[bool] malformed [%s] clause", occur)
+		}
+
+		for _, q := range queries {
+			query, ok := q.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("[bool] malformed [%s] clause", occur)
+			}
+			matched, err := match(query, doc)
+			if err != nil {
+				return false, err
+			}
+
+			switch occur {
+			case "must", "filter":
+				hasRequired = true
+				if !matched {
+					return false, nil
+				}
+			case "must_not":
+				if matched {
+					return false, nil
+				}
+			case "should":
+				shouldCount++
+				if matched {
+					shouldMatches++
+				}
+			default:
+				return false, fmt.Errorf("[bool] unsupported clause [%s]", occur)
+			}
+		}
+	}
+
+	// as Elasticsearch, should clauses are required only when there is
+	// no must nor filter clauses.
+	if shouldCount > 0 && !hasRequired {
+		return shouldMatches > 0, nil
+	}
+	return true, nil
+}
+
+// fieldQuery returns the field and the value of a query on a single field, in
+// its short form {"field": value} or full form {"field": {"query": value}}.
+func fieldQuery(kind string, body interface{}) (string, interface{}, error) {
+	params, ok := body.(map[string]interface{})
+	if !ok || len(params) != 1 {
+		return "", nil, fmt.Errorf("[%s] query must be on a single field", kind)
+	}
+	for field, value := range params {
+		if full, ok := value.(map[string]interface{}); ok {
+			for _, key := range []string{"query", "value"} {
+				if v, ok := full[key]; ok {
+					return field, v, nil
+				}
+			}
+			return "", nil, fmt.Errorf("[%s] query malformed, no query nor value on field [%s]", kind, field)
+		}
+		return field, value, nil
+	}
+	return "", nil, nil
+}
+
+// matchField returns true if a value of the field matches the query value.
+func matchField(kind string, field interface{}, value interface{}) bool {
+	values, ok := field.([]interface{})
+	if !ok {
+		values = []interface{}{field}
+	}
+
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		switch kind {
+		case "term":
+			if fmt.Sprint(v) == fmt.Sprint(value) {
+				return true
+			}
+		case "terms":
+			terms, _ := value.([]interface{})
+			for _, t := range terms {
+				if fmt.Sprint(v) == fmt.Sprint(t) {
+					return true
+				}
+			}
+		case "match":
+			if containsAll(analyze(fmt.Sprint(v)), analyze(fmt.Sprint(value))) {
+				return true
+			}
+		case "match_phrase":
+			field := " " + strings.Join(analyze(fmt.Sprint(v)), " ") + " "
+			phrase := " " + strings.Join(analyze(fmt.Sprint(value)), " ") + " "
+			if strings.Contains(field, phrase) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func analyze(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+func containsAll(tokens []string, terms []string) bool {
+	if len(terms) == 0 {
+		return false
+	}
+	set := make(map[string]struct{}, len(tokens))
+	for _, t := range tokens {
+		set[t] = struct{}{}
+	}
+	for _, t := range terms {
+		if _, ok := set[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value of the field in the document, the path can go
+// through nested objects or dotted keys, e.g. "data_stream.namespace" matches
+// both {"data_stream": {"namespace": "x"}} and {"data_stream.namespace": "x"}.
+func lookup(doc map[string]interface{}, path string) interface{} {
+	if v, ok := doc[path]; ok {
+		return v
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		if nested, ok := doc[path[:i]].(map[string]interface{}); ok {
+			if v := lookup(nested, path[i+1:]); v != nil {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// sortDocuments sorts the documents according to the sort of a search
+// request, either {"field": "order"}, {"field": {"order": "order"}} or a list
+// of those.
+func sortDocuments(docs []Document, sortParam interface{}) error {
+	if sortParam == nil {
+		return nil
+	}
+
+	var fields []interface{}
+	switch s := sortParam.(type) {
+	case []interface{}:
+		fields = s
+	default:
+		fields = []interface{}{s}
+	}
+
+	type sortField struct {
+		name string
+		desc bool
+	}
+	var sortFields []sortField
+	for _, f := range fields {
+		switch field := f.(type) {
+		case string:
+			sortFields = append(sortFields, sortField{name: field})
+		case map[string]interface{}:
+			for name, order := range field {
+				if params, ok := order.(map[string]interface{}); ok {
+					order = params["order"]
+				}
+				sortFields = append(sortFields, sortField{name: name, desc: order == "desc"})
+			}
+		default:
+			return fmt.Errorf("malformed sort %v", sortParam)
+		}
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, f := range sortFields {
+			c := compare(lookup(docs[i].Source, f.name), lookup(docs[j].Source, f.name))
+			if c == 0 {
+				continue
+			}
+			if f.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
+
+// compare compares two field values, missing values are sorted last.
+func compare(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	if fa, ok := a.(float64); ok {
+		if fb, ok := b.(float64); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// filterSource returns the source to include in a search hit according to the
+// _source parameter: a boolean, a field or a list of fields.
+func filterSource(source map[string]interface{}, param interface{}) (map[string]interface{}, bool) {
+	var fields []string
+	switch p := param.(type) {
+	case nil:
+		return source, true
+	case bool:
+		return source, p
+	case string:
+		fields = []string{p}
+	case []interface{}:
+		for _, f := range p {
+			fields = append(fields, fmt.Sprint(f))
+		}
+	default:
+		return source, true
+	}
+
+	filtered := map[string]interface{}{}
+	for _, field := range fields {
+		v := lookup(source, field)
+		if v == nil {
+			continue
+		}
+		keys := strings.Split(field, ".")
+		m := filtered
+		for _, k := range keys[:len(keys)-1] {
+			nested, ok := m[k].(map[string]interface{})
+			if !ok {
+				nested = map[string]interface{}{}
+				m[k] = nested
+			}
+			m = nested
+		}
+		m[keys[len(keys)-1]] = v
+	}
+	return filtered, true
+}
@@ -0,0 +1,532 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package elasticsearchtest is a minimal Elasticsearch stand-in for tests. It
+// records the documents indexed through the bulk API and answers the searches
+// the estools package sends, so tests checking the data shipped by the agent
+// can run without an Elasticsearch cluster.
+package elasticsearchtest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultVersion is the Elasticsearch version reported by default.
+const DefaultVersion = "8.12.0"
+
+// dataStreamNameRegexp matches the names following the data stream naming
+// scheme, documents created into them are stored in a backing index.
+var dataStreamNameRegexp = regexp.MustCompile(`^(logs|metrics|traces|synthetics)-[^-]+-[^-]+$`)
+
+// Document is a document stored by the Server.
+type Document struct {
+	// Index is the index holding the document, for data streams it is the
+	// backing index.
+	Index string
+	// DataStream is the data stream the document was created into, if any.
+	DataStream string
+	// ID is the document ID.
+	ID string
+	// Source is the document.
+	Source map[string]interface{}
+}
+
+// Server is an Elasticsearch stand-in. It supports:
+//   - GET / returning the cluster information,
+//   - the bulk API, with the create and index operations,
+//   - the search API, with a subset of the query DSL, see match,
+//   - the cat indices API.
+//
+// Any other API answers with http.StatusNotImplemented.
+type Server struct {
+	*httptest.Server
+
+	// LocalhostURL is the server URL as "http://localhost:PORT".
+	LocalhostURL string
+
+	username string
+	password string
+	version  string
+	logFn    func(format string, a ...any)
+
+	mu      sync.Mutex
+	indices map[string][]Document
+	// dataStreams maps the data streams to their backing index.
+	dataStreams map[string]string
+}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithCredentials makes the server require basic authentication with the
+// given username and password.
+func WithCredentials(username, password string) Option {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithVersion sets the Elasticsearch version the server reports.
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
+}
+
+// WithRequestLog sets the server to log every request using logFn.
+func WithRequestLog(logFn func(format string, a ...any)) Option {
+	return func(s *Server) {
+		s.logFn = func(format string, a ...any) {
+			logFn("[elasticsearch] "+format, a...)
+		}
+	}
+}
+
+// NewServer returns a new started Elasticsearch stand-in listening on address,
+// all network interfaces and a random port if address is empty.
+func NewServer(address string, opts ...Option) *Server {
+	s := &Server{
+		version:     DefaultVersion,
+		logFn:       func(format string, a ...any) {},
+		indices:     map[string][]Document{},
+		dataStreams: map[string]string{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	if address == "" {
+		address = ":0"
+	}
+	l, err := net.Listen("tcp", address) //nolint:gosec // it's a test
+	if err != nil {
+		panic(fmt.Sprintf("NewServer failed to create a net.Listener: %v", err))
+	}
+
+	s.Server = &httptest.Server{
+		Listener: l,
+		Config:   &http.Server{Handler: s}, //nolint:gosec // it's a test
+	}
+	s.Start()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		panic(fmt.Sprintf("could parse elasticsearch URL: %v", err))
+	}
+	s.LocalhostURL = "http://localhost:" + u.Port()
+
+	return s
+}
+
+// Documents returns the documents stored in the indices or data streams
+// matching the pattern, a comma separated list of names accepting wildcards.
+func (s *Server) Documents(pattern string) []Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var docs []Document
+	for _, index := range s.matchIndices(pattern) {
+		docs = append(docs, s.indices[index]...)
+	}
+	return docs
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.logFn("%s %s", r.Method, r.URL)
+
+	// clients check the server is Elasticsearch with this header.
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+
+	if s.username != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != s.username || password != s.password {
+			respondError(w, http.StatusUnauthorized, "security_exception", "unable to authenticate user")
+			return
+		}
+	}
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "parse_exception", fmt.Sprintf("could not decompress request body: %v", err))
+			return
+		}
+		r.Body = zr
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		s.info(w)
+	case parts[len(parts)-1] == "_bulk" && len(parts) <= 2 &&
+		(r.Method == http.MethodPost || r.Method == http.MethodPut):
+		index := ""
+		if len(parts) == 2 {
+			index = parts[0]
+		}
+		s.bulk(w, r, index)
+	case parts[len(parts)-1] == "_search" && len(parts) <= 2 &&
+		(r.Method == http.MethodPost || r.Method == http.MethodGet):
+		pattern := "*"
+		if len(parts) == 2 {
+			pattern = parts[0]
+		}
+		s.search(w, r, pattern)
+	case len(parts) >= 2 && parts[0] == "_cat" && parts[1] == "indices" && r.Method == http.MethodGet:
+		pattern := "*"
+		if len(parts) == 3 {
+			pattern = parts[2]
+		}
+		s.catIndices(w, pattern)
+	default:
+		respondError(w, http.StatusNotImplemented, "not_implemented",
+			fmt.Sprintf("%s %s is not implemented by the Elasticsearch stand-in", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) info(w http.ResponseWriter) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"name":         "elasticsearchtest",
+		"cluster_name": "elasticsearchtest",
+		"cluster_uuid": "elasticsearchtest",
+		"version": map[string]interface{}{
+			"number":       s.version,
+			"build_flavor": "default",
+		},
+		"tagline": "You Know, for Search",
+	})
+}
+
+// bulk stores the documents of a bulk request, defaultIndex is the index
+// from the request path.
+func (s *Server) bulk(w http.ResponseWriter, r *http.Request, defaultIndex string) {
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), 100*1024*1024)
+
+	var items []map[string]interface{}
+	hasErrors := false
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var meta map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if err := json.Unmarshal(line, &meta); err != nil || len(meta) != 1 {
+			respondError(w, http.StatusBadRequest, "illegal_argument_exception", fmt.Sprintf("malformed action/metadata line: %s", line))
+			return
+		}
+		var op string
+		for k := range meta {
+			op = k
+		}
+		index := meta[op].Index
+		if index == "" {
+			index = defaultIndex
+		}
+
+		if op != "create" && op != "index" {
+			if op != "delete" && !scanner.Scan() {
+				break
+			}
+			hasErrors = true
+			items = append(items, bulkItem(op, index, meta[op].ID, http.StatusBadRequest,
+				fmt.Sprintf("operation %q is not supported by the Elasticsearch stand-in", op)))
+			continue
+		}
+
+		if !scanner.Scan() {
+			respondError(w, http.StatusBadRequest, "illegal_argument_exception", "the bulk request must be terminated by a newline")
+			return
+		}
+		var source map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &source); err != nil {
+			hasErrors = true
+			items = append(items, bulkItem(op, index, meta[op].ID, http.StatusBadRequest,
+				fmt.Sprintf("failed to parse the document: %v", err)))
+			continue
+		}
+		if index == "" {
+			hasErrors = true
+			items = append(items, bulkItem(op, index, meta[op].ID, http.StatusBadRequest, "index is missing"))
+			continue
+		}
+
+		doc := s.store(op, index, meta[op].ID, source)
+		items = append(items, bulkItem(op, doc.Index, doc.ID, http.StatusCreated, ""))
+	}
+	if err := scanner.Err(); err != nil {
+		respondError(w, http.StatusBadRequest, "parse_exception", fmt.Sprintf("could not read the bulk request: %v", err))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"took":   0,
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
+
+// store stores a document, documents created into a name following the
+// data stream naming scheme go to the data stream backing index.
+func (s *Server) store(op string, index string, id string, source map[string]interface{}) Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == "" {
+		id = uuid.New().String()
+	}
+	doc := Document{Index: index, ID: id, Source: source}
+
+	if backing, ok := s.dataStreams[index]; ok {
+		doc.Index = backing
+		doc.DataStream = index
+	} else if op == "create" && dataStreamNameRegexp.MatchString(index) {
+		backing = fmt.Sprintf(".ds-%s-%s-000001", index, time.Now().UTC().Format("2006.01.02"))
+		s.dataStreams[index] = backing
+		doc.Index = backing
+		doc.DataStream = index
+	}
+
+	s.indices[doc.Index] = append(s.indices[doc.Index], doc)
+	return doc
+}
+
+func bulkItem(op string, index string, id string, status int, errMsg string) map[string]interface{} {
+	item := map[string]interface{}{
+		"_index": index,
+		"_id":    id,
+		"status": status,
+	}
+	if errMsg != "" {
+		item["error"] = map[string]interface{}{
+			"type":   "illegal_argument_exception",
+			"reason": errMsg,
+		}
+	} else {
+		item["result"] = "created"
+	}
+	return map[string]interface{}{op: item}
+}
+
+// searchRequest is the part of the search request body the stand-in supports.
+type searchRequest struct {
+	Query  map[string]interface{} `json:"query"`
+	Source interface{}            `json:"_source"`
+	Sort   interface{}            `json:"sort"`
+	Size   *int                   `json:"size"`
+	From   int                    `json:"from"`
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request, pattern string) {
+	req := searchRequest{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "parse_exception", fmt.Sprintf("could not read the search request: %v", err))
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			respondError(w, http.StatusBadRequest, "parsing_exception", fmt.Sprintf("malformed search request: %v", err))
+			return
+		}
+	}
+
+	params := r.URL.Query()
+	if q := params.Get("q"); q != "" {
+		field, value, ok := strings.Cut(q, ":")
+		if !ok {
+			respondError(w, http.StatusBadRequest, "parsing_exception", fmt.Sprintf("unsupported query string %q, only field:value is supported", q))
+			return
+		}
+		termQuery := map[string]interface{}{"term": map[string]interface{}{field: value}}
+		if req.Query == nil {
+			req.Query = termQuery
+		} else {
+			req.Query = map[string]interface{}{"bool": map[string]interface{}{
+				"must": []interface{}{req.Query, termQuery},
+			}}
+		}
+	}
+	if size := params.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "illegal_argument_exception", fmt.Sprintf("invalid size %q", size))
+			return
+		}
+		req.Size = &n
+	}
+	size := 10
+	if req.Size != nil {
+		size = *req.Size
+	}
+
+	var hits []Document
+	for _, doc := range s.Documents(pattern) {
+		ok, err := match(req.Query, doc.Source)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "parsing_exception", err.Error())
+			return
+		}
+		if ok {
+			hits = append(hits, doc)
+		}
+	}
+
+	if err := sortDocuments(hits, req.Sort); err != nil {
+		respondError(w, http.StatusBadRequest, "parsing_exception", err.Error())
+		return
+	}
+
+	total := len(hits)
+	if req.From > len(hits) {
+		hits = nil
+	} else {
+		hits = hits[req.From:]
+	}
+	if size < len(hits) {
+		hits = hits[:size]
+	}
+
+	items := make([]map[string]interface{}, 0, len(hits))
+	for _, doc := range hits {
+		item := map[string]interface{}{
+			"_index": doc.Index,
+			"_id":    doc.ID,
+			"_score": 1.0,
+		}
+		if source, ok := filterSource(doc.Source, req.Source); ok {
+			item["_source"] = source
+		}
+		items = append(items, item)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"took":      0,
+		"timed_out": false,
+		"_shards":   map[string]interface{}{"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+		"hits": map[string]interface{}{
+			"total":     map[string]interface{}{"value": total, "relation": "eq"},
+			"max_score": 1.0,
+			"hits":      items,
+		},
+	})
+}
+
+func (s *Server) catIndices(w http.ResponseWriter, pattern string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	indices := []map[string]interface{}{}
+	for _, index := range s.matchIndices(pattern) {
+		size := 0
+		for _, doc := range s.indices[index] {
+			raw, _ := json.Marshal(doc.Source)
+			size += len(raw)
+		}
+		indices = append(indices, map[string]interface{}{
+			"health":         "green",
+			"status":         "open",
+			"index":          index,
+			"uuid":           index,
+			"pri":            "1",
+			"rep":            "0",
+			"docs.count":     strconv.Itoa(len(s.indices[index])),
+			"docs.deleted":   "0",
+			"store.size":     strconv.Itoa(size),
+			"pri.store.size": strconv.Itoa(size),
+		})
+	}
+	respondJSON(w, http.StatusOK, indices)
+}
+
+// matchIndices returns the indices matching the comma separated list of
+// patterns, either directly or through their data stream. It must be called
+// with s.mu held.
+func (s *Server) matchIndices(patterns string) []string {
+	var names []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern == "_all" {
+			pattern = "*"
+		}
+		for index := range s.indices {
+			if wildcardMatch(pattern, index) {
+				names = append(names, index)
+			}
+		}
+		for ds, backing := range s.dataStreams {
+			if wildcardMatch(pattern, ds) {
+				names = append(names, backing)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	unique := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// wildcardMatch returns true if value matches pattern, where '*' matches any
+// sequence of characters.
+func wildcardMatch(pattern string, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
+
+func respondJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func respondError(w http.ResponseWriter, status int, errType string, reason string) {
+	respondJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"type":   errType,
+			"reason": reason,
+		},
+		"status": status,
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package elasticsearchtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/testing/tools/estools"
+)
+
+const bulkBody = `{"create":{"_index":"logs-elastic_agent-default"}}
+{"@timestamp":"2023-11-01T10:00:00Z","message":"Unit state changed to HEALTHY","log":{"level":"info"},"data_stream":{"type":"logs","dataset":"elastic_agent","namespace":"default"}}
+{"create":{"_index":"logs-elastic_agent-default"}}
+{"@timestamp":"2023-11-01T10:00:01Z","message":"failed to connect to the output","log.level":"error","data_stream":{"type":"logs","dataset":"elastic_agent","namespace":"default"}}
+{"create":{"_index":"logs-elastic_agent.filebeat-other"}}
+{"@timestamp":"2023-11-01T10:00:02Z","message":"Harvester started","log":{"level":"info"},"data_stream":{"type":"logs","dataset":"elastic_agent.filebeat","namespace":"other"}}
+{"index":{"_index":"my-index","_id":"1"}}
+{"@timestamp":"2023-11-01T10:00:03Z","message":"plain index"}
+`
+
+func newClient(t *testing.T, s *Server) *elasticsearch.Client {
+	c, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{s.URL},
+		Username:  "elastic",
+		Password:  "changeme",
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func newServerWithDocuments(t *testing.T) *Server {
+	s := NewServer("", WithCredentials("elastic", "changeme"))
+	t.Cleanup(s.Close)
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/_bulk", strings.NewReader(bulkBody))
+	require.NoError(t, err)
+	req.SetBasicAuth("elastic", "changeme")
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	return s
+}
+
+func TestServerBulk(t *testing.T) {
+	s := newServerWithDocuments(t)
+
+	docs := s.Documents("logs-elastic_agent-default")
+	require.Len(t, docs, 2)
+	assert.Equal(t, "logs-elastic_agent-default", docs[0].DataStream)
+	assert.True(t, strings.HasPrefix(docs[0].Index, ".ds-logs-elastic_agent-default-"), docs[0].Index)
+	assert.Equal(t, "Unit state changed to HEALTHY", docs[0].Source["message"])
+
+	assert.Len(t, s.Documents("logs-*"), 3)
+	assert.Len(t, s.Documents(".ds-logs*"), 3)
+
+	docs = s.Documents("my-index")
+	require.Len(t, docs, 1)
+	assert.Equal(t, "1", docs[0].ID)
+	assert.Empty(t, docs[0].DataStream)
+}
+
+func TestServerBulkCompressed(t *testing.T) {
+	s := NewServer("")
+	t.Cleanup(s.Close)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(bulkBody))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/_bulk", &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Len(t, s.Documents("*"), 4)
+}
+
+func TestServerAuthentication(t *testing.T) {
+	s := NewServer("", WithCredentials("elastic", "changeme"))
+	t.Cleanup(s.Close)
+
+	resp, err := http.Get(s.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestServerWithEstools(t *testing.T) {
+	s := newServerWithDocuments(t)
+	client := newClient(t, s)
+	ctx := context.Background()
+
+	t.Run("ping", func(t *testing.T) {
+		ping, err := estools.GetPing(ctx, client)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultVersion, ping.Version.Number)
+	})
+
+	t.Run("indices", func(t *testing.T) {
+		indices, err := estools.GetAllindicies(client)
+		require.NoError(t, err)
+		require.Len(t, indices, 3)
+		assert.Equal(t, "my-index", indices[2].Index)
+		assert.EqualValues(t, 1, indices[2].DocsCount)
+	})
+
+	t.Run("errors in logs", func(t *testing.T) {
+		docs, err := estools.CheckForErrorsInLogs(client, "default", nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, docs.Hits.Total.Value)
+		assert.Equal(t, "failed to connect to the output", docs.Hits.Hits[0].Source["message"])
+
+		docs, err = estools.CheckForErrorsInLogs(client, "default", []string{"connect to the output"})
+		require.NoError(t, err)
+		assert.Zero(t, docs.Hits.Total.Value)
+	})
+
+	t.Run("matching log lines", func(t *testing.T) {
+		docs, err := estools.FindMatchingLogLines(client, "default", "changed to HEALTHY")
+		require.NoError(t, err)
+		assert.Equal(t, 1, docs.Hits.Total.Value)
+
+		docs, err = estools.FindMatchingLogLines(client, "default", "HEALTHY changed")
+		require.NoError(t, err)
+		assert.Zero(t, docs.Hits.Total.Value)
+	})
+
+	t.Run("latest document", func(t *testing.T) {
+		docs, err := estools.GetLatestDocumentMatchingQuery(ctx, client, map[string]interface{}{
+			"match": map[string]interface{}{"data_stream.dataset": "elastic_agent"},
+		}, "logs-*")
+		require.NoError(t, err)
+		assert.Equal(t, 3, docs.Hits.Total.Value)
+		require.Len(t, docs.Hits.Hits, 1)
+		assert.Equal(t, "2023-11-01T10:00:02Z", docs.Hits.Hits[0].Source["@timestamp"])
+	})
+
+	t.Run("datastream", func(t *testing.T) {
+		docs, err := estools.GetLogsForDatastream(ctx, client, "logs", "elastic_agent.filebeat", "other")
+		require.NoError(t, err)
+		require.Equal(t, 1, docs.Hits.Total.Value)
+		assert.Equal(t, map[string]interface{}{"message": "Harvester started"}, docs.Hits.Hits[0].Source)
+	})
+}
+
+func TestServerUnsupportedQuery(t *testing.T) {
+	s := newServerWithDocuments(t)
+	client := newClient(t, s)
+
+	_, err := estools.GetLatestDocumentMatchingQuery(context.Background(), client, map[string]interface{}{
+		"fuzzy": map[string]interface{}{"message": "HEALTHI"},
+	}, "logs-*")
+	assert.ErrorContains(t, err, "unsupported query [fuzzy]")
+}
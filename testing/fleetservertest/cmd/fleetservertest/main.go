@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// fleetservertest runs a stateful fleet-server stand-in, optionally injecting
+// the faults of a scenario file, to manually test an Elastic Agent against it:
+//
+//	go run ./testing/fleetservertest/cmd/fleetservertest -scenario faults.yml
+//
+// It creates a policy and logs the enrollment token to enroll the agent with.
+// The scenario file is reloaded on SIGHUP.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/elastic/elastic-agent/testing/fleetservertest"
+)
+
+func main() {
+	addr := flag.String("addr", ":8220", "address to listen on")
+	scenarioPath := flag.String("scenario", "", "path of the scenario file defining the faults to inject")
+	esHost := flag.String("es-host", "http://localhost:9200", "Elasticsearch host of the output sent in the policy")
+	apiKeyID := flag.String("api-key-id", "apiKeyID", "ID of the API key given to the enrolled agents")
+	apiKey := flag.String("api-key", "apiKey", "API key given to the enrolled agents")
+	verbose := flag.Bool("v", false, "log every request")
+	flag.Parse()
+
+	scenario, err := fleetservertest.NewScenario()
+	if err != nil {
+		log.Fatalf("could not create scenario: %v", err)
+	}
+	if *scenarioPath != "" {
+		if err := loadScenario(scenario, *scenarioPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fleet := fleetservertest.NewFleet(fleetservertest.APIKey{ID: *apiKeyID, Key: *apiKey})
+	fleet.SetOutput(map[string]interface{}{
+		"type":  "elasticsearch",
+		"hosts": []string{*esHost},
+	})
+	policy := fleet.PutPolicy(fleetservertest.FleetPolicy{Name: "fleetservertest", Namespace: "default"})
+	token, err := fleet.EnrollmentToken(policy.ID)
+	if err != nil {
+		log.Fatalf("could not create enrollment token: %v", err)
+	}
+
+	opts := []fleetservertest.Option{
+		fleetservertest.WithAddress(*addr),
+		fleetservertest.WithScenario(scenario),
+	}
+	if *verbose {
+		opts = append(opts, fleetservertest.WithRequestLog(log.Printf))
+	}
+	fs := fleetservertest.NewFleetServer(fleet, opts...)
+	defer fs.Close()
+
+	log.Printf("fleet-server listening on %s", fs.LocalhostURL)
+	log.Printf("enroll with: elastic-agent enroll --url %s --enrollment-token %s --insecure", fs.LocalhostURL, token)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			return
+		}
+		if *scenarioPath == "" {
+			continue
+		}
+		if err := loadScenario(scenario, *scenarioPath); err != nil {
+			log.Printf("keeping the current scenario: %v", err)
+		}
+	}
+}
+
+// loadScenario replaces the faults of scenario by the ones defined in path.
+func loadScenario(scenario *fleetservertest.Scenario, path string) error {
+	loaded, err := fleetservertest.LoadScenario(path)
+	if err != nil {
+		return err
+	}
+	scenario.Reset()
+	if err := scenario.Add(loaded.Faults()...); err != nil {
+		return err
+	}
+	log.Printf("loaded scenario %s", path)
+	return nil
+}
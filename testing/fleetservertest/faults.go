@@ -0,0 +1,337 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetservertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FaultKind is the kind of fault a Scenario injects.
+type FaultKind string
+
+const (
+	// FaultLatency delays the request by Fault.Latency before handling it.
+	FaultLatency FaultKind = "latency"
+	// FaultStatus answers with Fault.StatusCode without calling the handler.
+	// If Fault.RetryAfter is set, the Retry-After header is sent with it,
+	// rounded up to the second.
+	FaultStatus FaultKind = "status"
+	// FaultReset resets the connection without sending any response.
+	FaultReset FaultKind = "reset"
+	// FaultTruncate sends the headers of the handler response, including
+	// its full Content-Length, but only half of its body before closing the
+	// connection.
+	FaultTruncate FaultKind = "truncate"
+	// FaultInvalidJSON sends a well-formed HTTP response with only the first
+	// half of the handler response body, which isn't valid JSON any more.
+	FaultInvalidJSON FaultKind = "invalid_json"
+	// FaultClockSkew shifts by Fault.Skew the Date header and the
+	// "expiration" and "start_time" of the actions in the handler response,
+	// as a fleet-server with a skewed clock would.
+	FaultClockSkew FaultKind = "clock_skew"
+)
+
+// Fault is a fault injected by a Scenario into the requests of a route.
+type Fault struct {
+	Kind FaultKind `yaml:"kind" json:"kind"`
+	// Route is the name of the route, as defined by Handlers.Routes, the
+	// fault applies to. An empty Route applies to all routes.
+	Route string `yaml:"route" json:"route"`
+	// Count is the number of requests the fault is injected into, 0 means
+	// all requests. Several requests in a row answered with a 5xx status are
+	// a FaultStatus with a Count.
+	Count int `yaml:"count" json:"count"`
+
+	Latency    time.Duration `yaml:"latency" json:"latency"`
+	StatusCode int           `yaml:"status_code" json:"status_code"`
+	RetryAfter time.Duration `yaml:"retry_after" json:"retry_after"`
+	Skew       time.Duration `yaml:"skew" json:"skew"`
+}
+
+// Validate returns an error if the fault is invalid.
+func (f Fault) Validate() error {
+	switch f.Kind {
+	case FaultLatency:
+		if f.Latency <= 0 {
+			return fmt.Errorf("fault %q requires a positive latency", f.Kind)
+		}
+	case FaultStatus:
+		if f.StatusCode < 100 || f.StatusCode > 599 {
+			return fmt.Errorf("fault %q has an invalid status_code %d", f.Kind, f.StatusCode)
+		}
+	case FaultClockSkew:
+		if f.Skew == 0 {
+			return fmt.Errorf("fault %q requires a skew", f.Kind)
+		}
+	case FaultReset, FaultTruncate, FaultInvalidJSON:
+	default:
+		return fmt.Errorf("unknown fault kind %q", f.Kind)
+	}
+	if f.Count < 0 {
+		return fmt.Errorf("fault %q has a negative count", f.Kind)
+	}
+	return nil
+}
+
+// Scenario injects faults into the requests handled by the server, see
+// WithScenario. For every request, the first fault matching its route and
+// not exhausted yet is injected. Faults can be added while the server runs.
+type Scenario struct {
+	mu       sync.Mutex
+	faults   []Fault
+	used     []int
+	injected map[string]int
+}
+
+// scenarioFile is the format of the files read by LoadScenario.
+type scenarioFile struct {
+	Faults []Fault `yaml:"faults" json:"faults"`
+}
+
+// NewScenario returns a Scenario injecting faults.
+func NewScenario(faults ...Fault) (*Scenario, error) {
+	s := &Scenario{injected: map[string]int{}}
+	if err := s.Add(faults...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParseScenario parses a YAML, or JSON, scenario:
+//
+//	faults:
+//	  - route: AgentCheckin
+//	    kind: status
+//	    status_code: 429
+//	    retry_after: 30s
+//	    count: 2
+//	  - route: AgentAcks
+//	    kind: reset
+func ParseScenario(data []byte) (*Scenario, error) {
+	f := scenarioFile{}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("could not parse scenario: %w", err)
+	}
+	return NewScenario(f.Faults...)
+}
+
+// LoadScenario reads a scenario from path, see ParseScenario.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read scenario: %w", err)
+	}
+	return ParseScenario(data)
+}
+
+// Add adds faults at the end of the scenario.
+func (s *Scenario) Add(faults ...Fault) error {
+	for i, f := range faults {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("invalid fault %d: %w", i, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+	s.used = append(s.used, make([]int, len(faults))...)
+	return nil
+}
+
+// Reset removes all the faults of the scenario and zeroes the counts
+// returned by Injected.
+func (s *Scenario) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+	s.used = nil
+	s.injected = map[string]int{}
+}
+
+// Faults returns the faults of the scenario.
+func (s *Scenario) Faults() []Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Fault(nil), s.faults...)
+}
+
+// Injected returns the number of faults injected into the requests of route.
+func (s *Scenario) Injected(route string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.injected[route]
+}
+
+// next returns the fault to inject into a request to route, if any.
+func (s *Scenario) next(route string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if f.Route != "" && f.Route != route {
+			continue
+		}
+		if f.Count > 0 && s.used[i] >= f.Count {
+			continue
+		}
+		s.used[i]++
+		s.injected[route]++
+		return f, true
+	}
+	return Fault{}, false
+}
+
+// wrap returns a handler injecting the faults of the scenario into the
+// requests to the route before, or after, calling next. It's a no-op on a
+// nil Scenario.
+func (s *Scenario) wrap(route string, logFn func(format string, a ...any), next http.Handler) http.Handler {
+	if s == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := s.next(route)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logFn("injecting fault %q into %s %s\n", f.Kind, r.Method, r.URL)
+
+		switch f.Kind {
+		case FaultLatency:
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(f.Latency):
+			}
+			next.ServeHTTP(w, r)
+		case FaultStatus:
+			if f.RetryAfter > 0 {
+				w.Header().Set("Retry-After",
+					strconv.Itoa(int(math.Ceil(f.RetryAfter.Seconds()))))
+			}
+			respondAsJSON(f.StatusCode, HTTPError{
+				StatusCode: f.StatusCode,
+				Message:    fmt.Sprintf("injected fault: %s", http.StatusText(f.StatusCode)),
+			}, w)
+		case FaultReset:
+			resetConnection(w)
+		case FaultTruncate, FaultInvalidJSON, FaultClockSkew:
+			rec := &recordingResponseWriter{header: http.Header{}, statusCode: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			writeFaultyResponse(w, f, rec)
+		}
+	})
+}
+
+// resetConnection closes the connection of w, discarding any unsent data, so
+// the client gets a connection reset.
+func resetConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic("fleetservertest: the response writer does not support hijacking the connection")
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(fmt.Sprintf("fleetservertest: could not hijack the connection: %v", err))
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+	_ = conn.Close()
+}
+
+// writeFaultyResponse writes the response recorded in rec altered by f.
+func writeFaultyResponse(w http.ResponseWriter, f Fault, rec *recordingResponseWriter) {
+	for k, v := range rec.header {
+		w.Header()[k] = v
+	}
+
+	body := rec.body.Bytes()
+	switch f.Kind {
+	case FaultTruncate:
+		// the server closes the connection once the handler returns without
+		// having written the announced Content-Length.
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		body = body[:len(body)/2]
+	case FaultInvalidJSON:
+		body = body[:len(body)/2]
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	case FaultClockSkew:
+		body = skewActions(body, f.Skew)
+		w.Header().Set("Date", timeNow().Add(f.Skew).UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	w.WriteHeader(rec.statusCode)
+	_, _ = w.Write(body)
+}
+
+// skewActions shifts by skew the "expiration" and "start_time" of the actions
+// of a checkin response. Any other body is returned unchanged.
+func skewActions(body []byte, skew time.Duration) []byte {
+	resp := map[string]interface{}{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return body
+	}
+	actions, ok := resp["actions"].([]interface{})
+	if !ok {
+		return body
+	}
+
+	for _, a := range actions {
+		action, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, field := range []string{"expiration", "start_time"} {
+			v, ok := action[field].(string)
+			if !ok {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				continue
+			}
+			action[field] = t.Add(skew).Format(time.RFC3339)
+		}
+	}
+
+	skewed, err := json.Marshal(resp)
+	if err != nil {
+		return body
+	}
+	return skewed
+}
+
+// recordingResponseWriter records a response to alter it before sending it.
+type recordingResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *recordingResponseWriter) Write(bs []byte) (int, error) {
+	return rw.body.Write(bs)
+}
+
+func (rw *recordingResponseWriter) WriteHeader(statusCode int) {
+	rw.statusCode = statusCode
+}
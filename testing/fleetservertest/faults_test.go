@@ -0,0 +1,197 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetservertest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const faultyAction = `{
+  "agent_id": "agentID",
+  "id": "actionID",
+  "type": "UPGRADE",
+  "start_time": "2023-11-01T10:00:00Z",
+  "expiration": "2023-11-01T12:00:00Z",
+  "data": {"version": "8.12.0"}
+}`
+
+func newFaultyServer(t *testing.T, faults ...Fault) (*Server, *Scenario) {
+	scenario, err := NewScenario(faults...)
+	require.NoError(t, err)
+
+	s := NewServer(&Handlers{
+		AgentID: "agentID",
+		CheckinFn: NewHandlerCheckin(func() (CheckinAction, *HTTPError) {
+			return CheckinAction{Actions: []string{faultyAction}}, nil
+		}),
+		StatusFn: NewHandlerStatusHealthy(),
+	}, WithScenario(scenario))
+	t.Cleanup(s.Close)
+	return s, scenario
+}
+
+func checkin(t *testing.T, s *Server) (*http.Response, error) {
+	resp, err := http.Post(s.LocalhostURL+NewPathCheckin("agentID"), "application/json", strings.NewReader(`{"status":"online"}`))
+	if err == nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestScenarioStatus(t *testing.T) {
+	s, scenario := newFaultyServer(t, Fault{
+		Route:      "AgentCheckin",
+		Kind:       FaultStatus,
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: 1500 * time.Millisecond,
+		Count:      2,
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := checkin(t, s)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	}
+
+	resp, err := checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the fault must be exhausted")
+	assert.Equal(t, 2, scenario.Injected("AgentCheckin"))
+
+	// faults only apply to their route
+	resp, err = http.Get(s.LocalhostURL + PathStatus)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Zero(t, scenario.Injected("Status"))
+}
+
+func TestScenarioLatency(t *testing.T) {
+	s, _ := newFaultyServer(t, Fault{Kind: FaultLatency, Latency: 200 * time.Millisecond})
+
+	start := time.Now()
+	resp, err := checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestScenarioReset(t *testing.T) {
+	s, _ := newFaultyServer(t, Fault{Kind: FaultReset, Count: 1})
+
+	_, err := checkin(t, s)
+	require.Error(t, err)
+
+	resp, err := checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestScenarioResetFaults(t *testing.T) {
+	s, scenario := newFaultyServer(t, Fault{
+		Route:      "AgentCheckin",
+		Kind:       FaultStatus,
+		StatusCode: http.StatusServiceUnavailable,
+	})
+
+	resp, err := checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, scenario.Injected("AgentCheckin"))
+
+	scenario.Reset()
+	assert.Empty(t, scenario.Faults())
+	assert.Zero(t, scenario.Injected("AgentCheckin"), "Reset must zero the injected counts")
+
+	resp, err = checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Zero(t, scenario.Injected("AgentCheckin"))
+}
+
+func TestScenarioTruncate(t *testing.T) {
+	s, _ := newFaultyServer(t, Fault{Kind: FaultTruncate})
+
+	resp, err := checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = io.ReadAll(resp.Body)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestScenarioInvalidJSON(t *testing.T) {
+	s, _ := newFaultyServer(t, Fault{Kind: FaultInvalidJSON})
+
+	resp, err := checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, body)
+	assert.False(t, json.Valid(body), "body must be invalid JSON: %s", body)
+}
+
+func TestScenarioClockSkew(t *testing.T) {
+	s, _ := newFaultyServer(t, Fault{Kind: FaultClockSkew, Skew: -time.Hour})
+
+	resp, err := checkin(t, s)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), date, time.Minute)
+
+	body := struct {
+		Actions []struct {
+			StartTime  string `json:"start_time"`
+			Expiration string `json:"expiration"`
+		} `json:"actions"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Actions, 1)
+	assert.Equal(t, "2023-11-01T09:00:00Z", body.Actions[0].StartTime)
+	assert.Equal(t, "2023-11-01T11:00:00Z", body.Actions[0].Expiration)
+}
+
+func TestParseScenario(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`
+faults:
+  - route: AgentCheckin
+    kind: status
+    status_code: 503
+    count: 3
+  - route: AgentAcks
+    kind: latency
+    latency: 2s
+  - kind: clock_skew
+    skew: -1h
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []Fault{
+		{Route: "AgentCheckin", Kind: FaultStatus, StatusCode: 503, Count: 3},
+		{Route: "AgentAcks", Kind: FaultLatency, Latency: 2 * time.Second},
+		{Kind: FaultClockSkew, Skew: -time.Hour},
+	}, scenario.Faults())
+
+	scenario, err = ParseScenario([]byte(`{"faults": [{"kind": "reset", "count": 1}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Fault{{Kind: FaultReset, Count: 1}}, scenario.Faults())
+
+	_, err = ParseScenario([]byte(`{"faults": [{"kind": "status"}]}`))
+	assert.ErrorContains(t, err, "invalid status_code")
+
+	_, err = ParseScenario([]byte(`{"faults": [{"kind": "unknown"}]}`))
+	assert.ErrorContains(t, err, "unknown fault kind")
+}
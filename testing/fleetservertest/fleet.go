@@ -0,0 +1,513 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetservertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FleetPolicy is an agent policy managed by Fleet.
+type FleetPolicy struct {
+	ID        string
+	Name      string
+	Namespace string
+	// Revision is set by Fleet, it's incremented on every update.
+	Revision int
+	// MonitoringLogs and MonitoringMetrics enable the agent monitoring.
+	MonitoringLogs    bool
+	MonitoringMetrics bool
+	// Inputs are the policy inputs as sent to the agent.
+	Inputs    []map[string]interface{}
+	UpdatedAt time.Time
+}
+
+// FleetAgent is an agent enrolled into Fleet.
+type FleetAgent struct {
+	ID     string
+	Active bool
+	// Status is the agent status as computed by Fleet: enrolling before
+	// the first checkin, then online, degraded or error.
+	Status string
+	// Message is the message sent with the last checkin.
+	Message        string
+	PolicyID       string
+	PolicyRevision int
+	Tags           []string
+	LocalMetadata  json.RawMessage
+	EnrolledAt     time.Time
+	LastCheckin    time.Time
+}
+
+// fleetAction is an action queued for an agent.
+type fleetAction struct {
+	action    Action
+	delivered bool
+	acked     bool
+	// policyRevision is the policy revision sent by a POLICY_CHANGE action.
+	policyRevision int
+}
+
+type fleetAgent struct {
+	FleetAgent
+	actions []*fleetAction
+	// checkins counts the checkins with actions, it's used as ack token.
+	checkins int
+}
+
+// Fleet is a stateful stand-in of Fleet: it holds the agent policies, the
+// enrollment tokens and the enrolled agents. It enrolls any number of agents
+// with a valid enrollment token, sends a POLICY_CHANGE to the agents when
+// their policy is assigned or updated and delivers the actions queued for
+// the agents on checkin.
+//
+// Use Fleet.Handlers to serve it as a fleet-server, see NewFleetServer, and
+// Fleet.KibanaHandler to serve the subset of the Kibana Fleet API used by the
+// integration tests tools.
+type Fleet struct {
+	mu sync.Mutex
+
+	apiKey     APIKey
+	fleetHosts []string
+	output     map[string]interface{}
+
+	policies map[string]*FleetPolicy
+	// tokens maps the enrollment tokens to their policy.
+	tokens map[string]string
+	agents map[string]*fleetAgent
+}
+
+// NewFleet returns a Fleet without any policy nor agent. The enrolled agents
+// receive apiKey as access API key.
+func NewFleet(apiKey APIKey) *Fleet {
+	return &Fleet{
+		apiKey:   apiKey,
+		output:   map[string]interface{}{"type": "elasticsearch"},
+		policies: map[string]*FleetPolicy{},
+		tokens:   map[string]string{},
+		agents:   map[string]*fleetAgent{},
+	}
+}
+
+// SetFleetHosts sets the fleet-server hosts sent to the agents in the policies.
+func (f *Fleet) SetFleetHosts(hosts ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fleetHosts = hosts
+}
+
+// SetOutput sets the default output sent to the agents in the policies.
+func (f *Fleet) SetOutput(output map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.output = output
+}
+
+// PutPolicy creates or updates a policy, a policy without ID gets a new one.
+// The agents assigned to the policy receive the new revision on their next
+// checkin. It returns the stored policy.
+func (f *Fleet) PutPolicy(policy FleetPolicy) FleetPolicy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if policy.ID == "" {
+		policy.ID = uuid.New().String()
+	}
+	policy.Revision = 1
+	if existing, ok := f.policies[policy.ID]; ok {
+		policy.Revision = existing.Revision + 1
+	}
+	policy.UpdatedAt = timeNow()
+	f.policies[policy.ID] = &policy
+
+	for _, a := range f.agents {
+		if a.Active && a.PolicyID == policy.ID {
+			f.queuePolicyChange(a)
+		}
+	}
+	return policy
+}
+
+// Policy returns the policy with the given ID.
+func (f *Fleet) Policy(id string) (FleetPolicy, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.policies[id]
+	if !ok {
+		return FleetPolicy{}, false
+	}
+	return *p, true
+}
+
+// EnrollmentToken returns a new enrollment token for the policy.
+func (f *Fleet) EnrollmentToken(policyID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.policies[policyID]; !ok {
+		return "", fmt.Errorf("policy %q not found", policyID)
+	}
+	token := uuid.New().String()
+	f.tokens[token] = policyID
+	return token, nil
+}
+
+// AssignPolicy assigns a policy to an agent, the agent receives it on its
+// next checkin.
+func (f *Fleet) AssignPolicy(agentID string, policyID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.activeAgent(agentID)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.policies[policyID]; !ok {
+		return fmt.Errorf("policy %q not found", policyID)
+	}
+	a.PolicyID = policyID
+	f.queuePolicyChange(a)
+	return nil
+}
+
+// QueueAction queues an action for the agent, it's delivered on the next
+// checkin. It returns the action ID.
+func (f *Fleet) QueueAction(agentID string, actionType string, data interface{}) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.activeAgent(agentID)
+	if err != nil {
+		return "", err
+	}
+	return f.queueAction(a, actionType, data).action.Id, nil
+}
+
+// Unenroll queues an UNENROLL action for the agent. The agent becomes
+// inactive once it acks the action or immediately if revoke is true, as its
+// API key would be revoked.
+func (f *Fleet) Unenroll(agentID string, revoke bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.activeAgent(agentID)
+	if err != nil {
+		return err
+	}
+	f.queueAction(a, "UNENROLL", map[string]interface{}{})
+	if revoke {
+		a.Active = false
+	}
+	return nil
+}
+
+// Acked returns true if the agent acked the action.
+func (f *Fleet) Acked(agentID string, actionID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, ok := f.agents[agentID]
+	if !ok {
+		return false
+	}
+	for _, action := range a.actions {
+		if action.action.Id == actionID {
+			return action.acked
+		}
+	}
+	return false
+}
+
+// Agent returns the agent with the given ID.
+func (f *Fleet) Agent(id string) (FleetAgent, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, ok := f.agents[id]
+	if !ok {
+		return FleetAgent{}, false
+	}
+	return a.FleetAgent, true
+}
+
+// Agents returns all the agents ever enrolled, including the inactive ones.
+func (f *Fleet) Agents() []FleetAgent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	agents := make([]FleetAgent, 0, len(f.agents))
+	for _, a := range f.agents {
+		agents = append(agents, a.FleetAgent)
+	}
+	return agents
+}
+
+// Handlers returns the fleet-server Handlers serving this Fleet: enroll,
+// checkin, ack and status.
+func (f *Fleet) Handlers() *Handlers {
+	return &Handlers{
+		APIKey:   f.apiKey.Key,
+		EnrollFn: f.enroll,
+		CheckinFn: func(ctx context.Context, h *Handlers, id string, userAgent string, acceptEncoding string, checkinRequest CheckinRequest) (*CheckinResponse, *HTTPError) {
+			return f.checkin(id, checkinRequest)
+		},
+		AckFn: func(ctx context.Context, h *Handlers, agentID string, ackRequest AckRequest) (*AckResponse, *HTTPError) {
+			return f.ack(agentID, ackRequest)
+		},
+		StatusFn: NewHandlerStatusHealthy(),
+	}
+}
+
+// NewFleetServer returns a new started fleet-server serving fleet. The fleet
+// hosts of fleet are set to the server URL if none was set.
+func NewFleetServer(fleet *Fleet, opts ...Option) *Server {
+	s := NewServer(fleet.Handlers(), opts...)
+
+	fleet.mu.Lock()
+	defer fleet.mu.Unlock()
+	if len(fleet.fleetHosts) == 0 {
+		fleet.fleetHosts = []string{s.LocalhostURL}
+	}
+	return s
+}
+
+func (f *Fleet) enroll(
+	ctx context.Context,
+	h *Handlers,
+	userAgent string,
+	enrollmentToken string,
+	enrollRequest EnrollRequest) (*EnrollResponse, *HTTPError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token := ""
+	if len(enrollmentToken) > len(APIKeyPrefix) {
+		token = enrollmentToken[len(APIKeyPrefix):]
+	}
+	policyID, ok := f.tokens[token]
+	if !ok {
+		return nil, &HTTPError{
+			StatusCode: http.StatusUnauthorized,
+			Message:    "invalid enrollment token",
+		}
+	}
+
+	agentID := uuid.New().String()
+	localMetadata, err := updateLocalMetaAgentID(enrollRequest.Metadata.Local, agentID)
+	if err != nil {
+		return nil, &HTTPError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("could not update local metadata: %v", err),
+		}
+	}
+
+	a := &fleetAgent{FleetAgent: FleetAgent{
+		ID:            agentID,
+		Active:        true,
+		Status:        "enrolling",
+		PolicyID:      policyID,
+		Tags:          enrollRequest.Metadata.Tags,
+		LocalMetadata: localMetadata,
+		EnrolledAt:    timeNow(),
+	}}
+	f.agents[agentID] = a
+	f.queuePolicyChange(a)
+
+	return &EnrollResponse{
+		Action: "created",
+		Item: EnrollResponseItem{
+			AgentID:        agentID,
+			Active:         true,
+			PolicyID:       policyID,
+			Type:           "PERMANENT",
+			EnrolledAt:     a.EnrolledAt.Format(time.RFC3339),
+			LocalMetadata:  localMetadata,
+			AccessApiKeyID: f.apiKey.ID,
+			AccessApiKey:   f.apiKey.Key,
+			Status:         "online",
+			Tags:           enrollRequest.Metadata.Tags,
+		},
+	}, nil
+}
+
+func (f *Fleet) checkin(agentID string, req CheckinRequest) (*CheckinResponse, *HTTPError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, ok := f.agents[agentID]
+	if !ok {
+		return nil, &HTTPError{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("agent %q not found", agentID),
+		}
+	}
+	if !a.Active {
+		return nil, &HTTPError{
+			StatusCode: http.StatusUnauthorized,
+			Message:    fmt.Sprintf("agent %q is not active", agentID),
+		}
+	}
+
+	a.LastCheckin = timeNow()
+	a.Message = req.Message
+	switch req.Status {
+	case "error", "degraded":
+		a.Status = req.Status
+	default:
+		a.Status = "online"
+	}
+	if len(req.LocalMetadata) > 0 {
+		a.LocalMetadata = req.LocalMetadata
+	}
+
+	resp := &CheckinResponse{Action: "checkin"}
+	for _, action := range a.actions {
+		if action.delivered {
+			continue
+		}
+		action.delivered = true
+		resp.Actions = append(resp.Actions, action.action)
+	}
+	if len(resp.Actions) > 0 {
+		a.checkins++
+		resp.AckToken = strconv.Itoa(a.checkins)
+	}
+	return resp, nil
+}
+
+func (f *Fleet) ack(agentID string, req AckRequest) (*AckResponse, *HTTPError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, ok := f.agents[agentID]
+	if !ok {
+		return nil, &HTTPError{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("agent %q not found", agentID),
+		}
+	}
+
+	resp := &AckResponse{Action: "acks"}
+	for _, e := range req.Events {
+		item := AckResponseItem{
+			Status:  http.StatusNotFound,
+			Message: fmt.Sprintf("action %q not found", e.ActionId),
+		}
+		for _, action := range a.actions {
+			if action.action.Id != e.ActionId || !action.delivered {
+				continue
+			}
+			action.acked = true
+			switch action.action.Type {
+			case "POLICY_CHANGE":
+				if action.policyRevision > a.PolicyRevision {
+					a.PolicyRevision = action.policyRevision
+				}
+			case "UNENROLL":
+				a.Active = false
+			}
+			item = AckResponseItem{
+				Status:  http.StatusOK,
+				Message: http.StatusText(http.StatusOK),
+			}
+			break
+		}
+		resp.Errors = resp.Errors || item.Status != http.StatusOK
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, nil
+}
+
+// activeAgent returns the agent if it's enrolled and active. It must be
+// called with f.mu held.
+func (f *Fleet) activeAgent(agentID string) (*fleetAgent, error) {
+	a, ok := f.agents[agentID]
+	if !ok {
+		return nil, fmt.Errorf("agent %q not found", agentID)
+	}
+	if !a.Active {
+		return nil, fmt.Errorf("agent %q is not active", agentID)
+	}
+	return a, nil
+}
+
+// queueAction queues an action for the agent. It must be called with f.mu
+// held.
+func (f *Fleet) queueAction(a *fleetAgent, actionType string, data interface{}) *fleetAction {
+	action := &fleetAction{action: Action{
+		AgentId:   a.ID,
+		CreatedAt: timeNow().Format(time.RFC3339),
+		Data:      &data,
+		Id:        uuid.New().String(),
+		Type:      actionType,
+	}}
+	a.actions = append(a.actions, action)
+	return action
+}
+
+// queuePolicyChange queues a POLICY_CHANGE with the current revision of the
+// agent policy, replacing any POLICY_CHANGE not yet delivered. It must be
+// called with f.mu held.
+func (f *Fleet) queuePolicyChange(a *fleetAgent) {
+	p := f.policies[a.PolicyID]
+
+	actions := a.actions[:0]
+	for _, action := range a.actions {
+		if action.action.Type != "POLICY_CHANGE" || action.delivered {
+			actions = append(actions, action)
+		}
+	}
+	a.actions = actions
+
+	action := f.queueAction(a, "POLICY_CHANGE", map[string]interface{}{
+		"policy": f.agentPolicy(p),
+	})
+	action.policyRevision = p.Revision
+}
+
+// agentPolicy returns the policy as sent to the agents. It must be called
+// with f.mu held.
+func (f *Fleet) agentPolicy(p *FleetPolicy) map[string]interface{} {
+	inputs := p.Inputs
+	if inputs == nil {
+		inputs = []map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"id":       p.ID,
+		"revision": p.Revision,
+		"agent": map[string]interface{}{
+			"monitoring": map[string]interface{}{
+				"enabled":    p.MonitoringLogs || p.MonitoringMetrics,
+				"logs":       p.MonitoringLogs,
+				"metrics":    p.MonitoringMetrics,
+				"namespace":  p.Namespace,
+				"use_output": "default",
+			},
+			"protection": map[string]interface{}{
+				"enabled": false,
+			},
+		},
+		"fleet": map[string]interface{}{
+			"hosts": f.fleetHosts,
+		},
+		"outputs": map[string]interface{}{
+			"default": f.output,
+		},
+		"output_permissions": map[string]interface{}{
+			"default": map[string]interface{}{},
+		},
+		"inputs": inputs,
+	}
+}
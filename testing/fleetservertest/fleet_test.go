@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetservertest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/kibana"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestFleet(t *testing.T) {
+	ctx := context.Background()
+	apiKey := APIKey{ID: "apiKeyID", Key: "apiKeyKey"}
+
+	fleet := NewFleet(apiKey)
+	fleet.SetOutput(map[string]interface{}{"type": "elasticsearch", "hosts": []string{"http://localhost:9200"}})
+	fs := NewFleetServer(fleet)
+	defer fs.Close()
+	kbn := httptest.NewServer(fleet.KibanaHandler())
+	defer kbn.Close()
+
+	kibClient, err := kibana.NewClientWithConfig(&kibana.ClientConfig{
+		Host:          kbn.URL,
+		IgnoreVersion: true,
+	}, "fleetservertest", "", "", "")
+	require.NoError(t, err)
+
+	// create a policy and an enrollment token as the integration tests tools do
+	policy, err := kibClient.CreatePolicy(ctx, kibana.AgentPolicy{
+		Name:              "test-policy",
+		Namespace:         "default",
+		MonitoringEnabled: []kibana.MonitoringEnabledOption{kibana.MonitoringEnabledLogs},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, policy.Revision)
+
+	token, err := kibClient.CreateEnrollmentAPIKey(ctx, kibana.CreateEnrollmentAPIKeyRequest{PolicyID: policy.ID})
+	require.NoError(t, err)
+
+	hosts, err := kibClient.ListFleetServerHosts(ctx, kibana.ListFleetServerHostsRequest{})
+	require.NoError(t, err)
+	require.Len(t, hosts.Items, 1)
+	assert.Equal(t, []string{fs.LocalhostURL}, hosts.Items[0].HostURLs)
+
+	log, _ := logger.NewTesting("fleet_client")
+
+	// an invalid enrollment token is rejected
+	unauthed, err := client.NewWithConfig(log, remote.Config{Host: fs.LocalhostURL})
+	require.NoError(t, err)
+	_, err = fleetapi.NewEnrollCmd(unauthed).Execute(ctx, &fleetapi.EnrollRequest{
+		EnrollAPIKey: "invalid",
+		Type:         fleetapi.PermanentEnroll,
+	})
+	require.Error(t, err)
+
+	enrollResp, err := fleetapi.NewEnrollCmd(unauthed).Execute(ctx, &fleetapi.EnrollRequest{
+		EnrollAPIKey: token.APIKey,
+		Type:         fleetapi.PermanentEnroll,
+		Metadata: fleetapi.Metadata{
+			Local: &info.ECSMeta{
+				Elastic: &info.ElasticECSMeta{Agent: &info.AgentECSMeta{Version: "8.12.0"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	agentID := enrollResp.Item.ID
+	assert.Equal(t, policy.ID, enrollResp.Item.PolicyID)
+	assert.Equal(t, apiKey.Key, enrollResp.Item.AccessAPIKey)
+
+	c, err := client.NewAuthWithConfig(log, apiKey.Key, remote.Config{Host: fs.LocalhostURL})
+	require.NoError(t, err)
+	checkin := func() *fleetapi.CheckinResponse {
+		resp, _, err := fleetapi.NewCheckinCmd(agentInfo(agentID), c).
+			Execute(ctx, &fleetapi.CheckinRequest{Status: "online"})
+		require.NoError(t, err)
+		return resp
+	}
+	ack := func(actionID string) *fleetapi.AckResponse {
+		resp, err := fleetapi.NewAckCmd(agentInfo(agentID), c).
+			Execute(ctx, &fleetapi.AckRequest{Events: []fleetapi.AckEvent{{
+				EventType: "ACTION_RESULT",
+				SubType:   "ACKNOWLEDGED",
+				ActionID:  actionID,
+				AgentID:   agentID,
+			}}})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// the policy is sent on the first checkin
+	resp := checkin()
+	require.Len(t, resp.Actions, 1)
+	policyChange, ok := resp.Actions[0].(*fleetapi.ActionPolicyChange)
+	require.True(t, ok, "expected a POLICY_CHANGE, got %T", resp.Actions[0])
+	assert.Equal(t, policy.ID, policyChange.Policy["id"])
+	assert.Equal(t, []interface{}{fs.LocalhostURL}, policyChange.Policy["fleet"].(map[string]interface{})["hosts"])
+
+	agent, err := kibClient.GetAgent(ctx, kibana.GetAgentRequest{ID: agentID})
+	require.NoError(t, err)
+	assert.Equal(t, "online", agent.Status)
+	assert.Equal(t, "8.12.0", agent.Agent.Version)
+	assert.Zero(t, agent.PolicyRevision, "the policy revision is set once the policy is acked")
+
+	ackResp := ack(policyChange.ActionID)
+	assert.False(t, ackResp.Errors)
+	agent, err = kibClient.GetAgent(ctx, kibana.GetAgentRequest{ID: agentID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, agent.PolicyRevision)
+
+	// nothing new to deliver
+	assert.Empty(t, checkin().Actions)
+
+	// updating the policy sends the new revision
+	_, err = kibClient.UpdatePolicy(ctx, policy.ID, kibana.AgentPolicyUpdateRequest{Name: "test-policy", Namespace: "other"})
+	require.NoError(t, err)
+	resp = checkin()
+	require.Len(t, resp.Actions, 1)
+	policyChange = resp.Actions[0].(*fleetapi.ActionPolicyChange)
+	assert.EqualValues(t, 2, policyChange.Policy["revision"])
+
+	// queued actions are delivered once
+	actionID, err := fleet.QueueAction(agentID, "SETTINGS", map[string]interface{}{"log_level": "debug"})
+	require.NoError(t, err)
+	resp = checkin()
+	require.Len(t, resp.Actions, 1)
+	assert.Equal(t, actionID, resp.Actions[0].ID())
+	assert.False(t, fleet.Acked(agentID, actionID))
+	assert.False(t, ack(actionID).Errors)
+	assert.True(t, fleet.Acked(agentID, actionID))
+	assert.True(t, ack("unknown").Errors)
+
+	// unenroll
+	list, err := kibClient.ListAgents(ctx, kibana.ListAgentsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+
+	_, err = kibClient.UnEnrollAgent(ctx, kibana.UnEnrollAgentRequest{ID: agentID})
+	require.NoError(t, err)
+	resp = checkin()
+	require.Len(t, resp.Actions, 1)
+	assert.Equal(t, fleetapi.ActionTypeUnenroll, resp.Actions[0].Type())
+	ack(resp.Actions[0].ID())
+
+	list, err = kibClient.ListAgents(ctx, kibana.ListAgentsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Items)
+	_, _, err = fleetapi.NewCheckinCmd(agentInfo(agentID), c).
+		Execute(ctx, &fleetapi.CheckinRequest{Status: "online"})
+	assert.Error(t, err, "an unenrolled agent must not be able to checkin")
+}
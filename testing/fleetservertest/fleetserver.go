@@ -28,9 +28,11 @@ var timeNow = time.Now
 type Option func(o *options)
 
 type options struct {
-	address string
-	logFn   func(format string, a ...any)
-	agentID string
+	address           string
+	logFn             func(format string, a ...any)
+	agentID           string
+	rejectCompression bool
+	scenario          *Scenario
 }
 
 // NewServerWithHandlers returns a Fleet Server ready for use to Agent's
@@ -85,6 +87,12 @@ func NewServer(h *Handlers, opts ...Option) *Server {
 	if optns.agentID != "" {
 		h.AgentID = optns.agentID
 	}
+	if optns.rejectCompression {
+		h.RejectCompression = true
+	}
+	if optns.scenario != nil {
+		h.Scenario = optns.scenario
+	}
 
 	mux := NewRouter(h)
 
@@ -140,3 +148,19 @@ func WithAgentID(id string) Option {
 		o.agentID = id
 	}
 }
+
+// WithRejectCompression sets the server to reject compressed request bodies,
+// as a fleet-server without compression support would.
+func WithRejectCompression() Option {
+	return func(o *options) {
+		o.rejectCompression = true
+	}
+}
+
+// WithScenario sets the server to inject the faults of scenario into the
+// requests it receives.
+func WithScenario(scenario *Scenario) Option {
+	return func(o *options) {
+		o.scenario = scenario
+	}
+}
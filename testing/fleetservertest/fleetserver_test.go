@@ -11,12 +11,19 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
 // TestRunFleetServer shows how to configure and run a fleet-server capable of
@@ -113,6 +120,72 @@ func TestRunFleetServer(t *testing.T) {
 	wg.Wait()
 }
 
+func TestCompressedRequests(t *testing.T) {
+	agentID := "agentID"
+	apiKey := "apiKey"
+
+	newHandlers := func() *Handlers {
+		return &Handlers{
+			APIKey: apiKey,
+			CheckinFn: NewHandlerCheckin(func() (CheckinAction, *HTTPError) {
+				return CheckinAction{AckToken: "ackToken"}, nil
+			}),
+			AckFn: NewHandlerAck(),
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		opts             []Option
+		expectedRequests int
+	}{
+		"compressed bodies are accepted": {
+			expectedRequests: 2,
+		},
+		"compressed bodies are rejected": {
+			opts: []Option{WithRejectCompression()},
+			// the first checkin is sent again without compression
+			expectedRequests: 3,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			opts := append([]Option{
+				WithAgentID(agentID),
+				WithRequestLog(func(format string, a ...any) {
+					if strings.Contains(format, "STARTING") {
+						mu.Lock()
+						requests++
+						mu.Unlock()
+					}
+				}),
+			}, tc.opts...)
+			ts := NewServer(newHandlers(), opts...)
+			defer ts.Close()
+
+			log, _ := logger.NewTesting("fleet_client")
+			c, err := client.NewAuthWithConfig(log, apiKey, remote.Config{
+				Host:        ts.LocalhostURL,
+				Compression: client.CompressionGzip,
+			})
+			require.NoError(t, err)
+
+			resp, _, err := fleetapi.NewCheckinCmd(agentInfo(agentID), c).
+				Execute(context.Background(), &fleetapi.CheckinRequest{Status: "online"})
+			require.NoError(t, err)
+			assert.Equal(t, "ackToken", resp.AckToken)
+
+			_, err = fleetapi.NewAckCmd(agentInfo(agentID), c).
+				Execute(context.Background(), &fleetapi.AckRequest{})
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tc.expectedRequests, requests)
+		})
+	}
+}
+
 func ExampleNewServer_status() {
 	apiKey := "aAPIKey"
 	ts := NewServer(&Handlers{
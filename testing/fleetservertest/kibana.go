@@ -0,0 +1,248 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetservertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/elastic/elastic-agent-libs/kibana"
+)
+
+// KibanaHandler returns a http.Handler serving the subset of the Kibana Fleet
+// API the integration tests tools use, backed by this Fleet:
+//   - create, get and update agent policies,
+//   - create enrollment API keys,
+//   - list, get and unenroll agents,
+//   - list the fleet-server hosts.
+//
+// Any other route answers with http.StatusNotFound. There is no authentication.
+func (f *Fleet) KibanaHandler() http.Handler {
+	router := mux.NewRouter().StrictSlash(true)
+	router.Methods(http.MethodPost).Path("/api/fleet/agent_policies").HandlerFunc(f.kibanaCreatePolicy)
+	router.Methods(http.MethodGet).Path("/api/fleet/agent_policies/{id}").HandlerFunc(f.kibanaGetPolicy)
+	router.Methods(http.MethodPut).Path("/api/fleet/agent_policies/{id}").HandlerFunc(f.kibanaUpdatePolicy)
+	router.Methods(http.MethodPost).Path("/api/fleet/enrollment_api_keys").HandlerFunc(f.kibanaCreateEnrollmentAPIKey)
+	router.Methods(http.MethodGet).Path("/api/fleet/agents").HandlerFunc(f.kibanaListAgents)
+	router.Methods(http.MethodGet).Path("/api/fleet/agents/{id}").HandlerFunc(f.kibanaGetAgent)
+	router.Methods(http.MethodPost).Path("/api/fleet/agents/{id}/unenroll").HandlerFunc(f.kibanaUnenrollAgent)
+	router.Methods(http.MethodGet).Path("/api/fleet/fleet_server_hosts").HandlerFunc(f.kibanaListFleetServerHosts)
+	return router
+}
+
+func (f *Fleet) kibanaCreatePolicy(w http.ResponseWriter, r *http.Request) {
+	req := kibana.AgentPolicy{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondKibanaError(w, http.StatusBadRequest, fmt.Sprintf("could not decode policy: %v", err))
+		return
+	}
+
+	p := f.PutPolicy(FleetPolicy{
+		ID:                req.ID,
+		Name:              req.Name,
+		Namespace:         req.Namespace,
+		MonitoringLogs:    hasMonitoring(req.MonitoringEnabled, kibana.MonitoringEnabledLogs),
+		MonitoringMetrics: hasMonitoring(req.MonitoringEnabled, kibana.MonitoringEnabledMetrics),
+	})
+	respondAsJSON(http.StatusOK, map[string]interface{}{"item": policyResponse(p)}, w)
+}
+
+func (f *Fleet) kibanaGetPolicy(w http.ResponseWriter, r *http.Request) {
+	p, ok := f.Policy(mux.Vars(r)["id"])
+	if !ok {
+		respondKibanaError(w, http.StatusNotFound, "agent policy not found")
+		return
+	}
+	respondAsJSON(http.StatusOK, map[string]interface{}{"item": policyResponse(p)}, w)
+}
+
+func (f *Fleet) kibanaUpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	existing, ok := f.Policy(id)
+	if !ok {
+		respondKibanaError(w, http.StatusNotFound, "agent policy not found")
+		return
+	}
+
+	req := kibana.AgentPolicyUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondKibanaError(w, http.StatusBadRequest, fmt.Sprintf("could not decode policy: %v", err))
+		return
+	}
+
+	existing.Name = req.Name
+	existing.Namespace = req.Namespace
+	existing.MonitoringLogs = hasMonitoring(req.MonitoringEnabled, kibana.MonitoringEnabledLogs)
+	existing.MonitoringMetrics = hasMonitoring(req.MonitoringEnabled, kibana.MonitoringEnabledMetrics)
+	p := f.PutPolicy(existing)
+	respondAsJSON(http.StatusOK, map[string]interface{}{"item": policyResponse(p)}, w)
+}
+
+func (f *Fleet) kibanaCreateEnrollmentAPIKey(w http.ResponseWriter, r *http.Request) {
+	req := kibana.CreateEnrollmentAPIKeyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondKibanaError(w, http.StatusBadRequest, fmt.Sprintf("could not decode enrollment API key request: %v", err))
+		return
+	}
+
+	token, err := f.EnrollmentToken(req.PolicyID)
+	if err != nil {
+		respondKibanaError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondAsJSON(http.StatusOK, map[string]interface{}{
+		"item": kibana.CreateEnrollmentAPIKeyResponse{
+			Active:   true,
+			APIKey:   token,
+			APIKeyID: token,
+			ID:       token,
+			Name:     req.Name,
+			PolicyID: req.PolicyID,
+		},
+	}, w)
+}
+
+func (f *Fleet) kibanaListAgents(w http.ResponseWriter, r *http.Request) {
+	agents := f.Agents()
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].EnrolledAt.Before(agents[j].EnrolledAt)
+	})
+
+	// as Kibana, only the active agents are listed.
+	items := []map[string]interface{}{}
+	for _, a := range agents {
+		if a.Active {
+			items = append(items, agentResponse(a))
+		}
+	}
+	respondAsJSON(http.StatusOK, map[string]interface{}{
+		"items":   items,
+		"total":   len(items),
+		"page":    1,
+		"perPage": len(items),
+	}, w)
+}
+
+func (f *Fleet) kibanaGetAgent(w http.ResponseWriter, r *http.Request) {
+	a, ok := f.Agent(mux.Vars(r)["id"])
+	if !ok {
+		respondKibanaError(w, http.StatusNotFound, "agent not found")
+		return
+	}
+	respondAsJSON(http.StatusOK, map[string]interface{}{"item": agentResponse(a)}, w)
+}
+
+func (f *Fleet) kibanaUnenrollAgent(w http.ResponseWriter, r *http.Request) {
+	req := kibana.UnEnrollAgentRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondKibanaError(w, http.StatusBadRequest, fmt.Sprintf("could not decode unenroll request: %v", err))
+		return
+	}
+
+	if err := f.Unenroll(mux.Vars(r)["id"], req.Revoke); err != nil {
+		respondKibanaError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondAsJSON(http.StatusOK, map[string]interface{}{}, w)
+}
+
+func (f *Fleet) kibanaListFleetServerHosts(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	hosts := f.fleetHosts
+	f.mu.Unlock()
+
+	respondAsJSON(http.StatusOK, kibana.ListFleetServerHostsResponse{
+		Items: []kibana.FleetServerHost{{
+			ID:        "fleet-default-fleet-server-host",
+			Name:      "Default",
+			IsDefault: true,
+			HostURLs:  hosts,
+		}},
+	}, w)
+}
+
+func hasMonitoring(enabled []kibana.MonitoringEnabledOption, option kibana.MonitoringEnabledOption) bool {
+	for _, e := range enabled {
+		if e == option {
+			return true
+		}
+	}
+	return false
+}
+
+func policyResponse(p FleetPolicy) kibana.PolicyResponse {
+	var monitoring []kibana.MonitoringEnabledOption
+	if p.MonitoringLogs {
+		monitoring = append(monitoring, kibana.MonitoringEnabledLogs)
+	}
+	if p.MonitoringMetrics {
+		monitoring = append(monitoring, kibana.MonitoringEnabledMetrics)
+	}
+	return kibana.PolicyResponse{
+		AgentPolicy: kibana.AgentPolicy{
+			ID:                p.ID,
+			Name:              p.Name,
+			Namespace:         p.Namespace,
+			MonitoringEnabled: monitoring,
+		},
+		UpdatedOn:       p.UpdatedAt,
+		UpdatedBy:       "fleetservertest",
+		Revision:        p.Revision,
+		PackagePolicies: []map[string]interface{}{},
+	}
+}
+
+// agentResponse returns the agent as the Kibana Fleet API does.
+func agentResponse(a FleetAgent) map[string]interface{} {
+	var localMetadata struct {
+		Elastic struct {
+			Agent struct {
+				Version string `json:"version"`
+			} `json:"agent"`
+		} `json:"elastic"`
+	}
+	var rawLocalMetadata map[string]interface{}
+	if len(a.LocalMetadata) > 0 {
+		_ = json.Unmarshal(a.LocalMetadata, &localMetadata)
+		_ = json.Unmarshal(a.LocalMetadata, &rawLocalMetadata)
+	}
+
+	status := a.Status
+	if !a.Active {
+		status = "unenrolled"
+	}
+	agent := map[string]interface{}{
+		"id":     a.ID,
+		"active": a.Active,
+		"status": status,
+		"agent": map[string]interface{}{
+			"id":      a.ID,
+			"version": localMetadata.Elastic.Agent.Version,
+		},
+		"local_metadata":  rawLocalMetadata,
+		"policy_id":       a.PolicyID,
+		"policy_revision": a.PolicyRevision,
+		"tags":            a.Tags,
+		"enrolled_at":     a.EnrolledAt.Format(time.RFC3339),
+	}
+	if !a.LastCheckin.IsZero() {
+		agent["last_checkin"] = a.LastCheckin.Format(time.RFC3339)
+		agent["last_checkin_message"] = a.Message
+	}
+	return agent
+}
+
+func respondKibanaError(w http.ResponseWriter, status int, message string) {
+	respondAsJSON(status, map[string]interface{}{
+		"statusCode": status,
+		"error":      http.StatusText(status),
+		"message":    message,
+	}, w)
+}
@@ -117,6 +117,15 @@ type CheckinRequest struct {
 
 	// An optional timeout value that informs fleet-server of when a client will time out on it's checkin request. If not specified fleet-server will use the timeout values specified in the config (defaults to 5m polling and a 10m write timeout). The value, if specified is expected to be a string that is parsable by [time.ParseDuration](https://pkg.go.dev/time#ParseDuration). If specified fleet-server will set its poll timeout to `max(1m, poll_timeout-2m)` and its write timeout to `max(2m, poll_timout-1m)`.
 	PollTimeout string `json:"poll_timeout,omitempty"`
+
+	// The hash of the full components state of the agent.
+	ComponentsHash string `json:"components_hash,omitempty"`
+
+	// Set when components only holds the changes since the components state with this hash, previously accepted by fleet-server.
+	ComponentsBaseHash string `json:"components_base_hash,omitempty"`
+
+	// The IDs of the components removed since the base state of a components delta.
+	RemovedComponents []string `json:"removed_components,omitempty"`
 }
 type CheckinResponse struct {
 
@@ -128,6 +137,15 @@ type CheckinResponse struct {
 
 	// A list of actions that the agent must execute.
 	Actions []Action `json:"actions,omitempty"`
+
+	// The hash of the components state stored by fleet-server. When set, the agent can send a components delta against it on the next checkin.
+	ComponentsHash string `json:"components_hash,omitempty"`
+
+	// Set when fleet-server needs the full components state on the next checkin.
+	RequestFullState bool `json:"request_full_state,omitempty"`
+
+	// The minimum time the agent must wait before its next checkin, parsable by time.ParseDuration.
+	CheckinInterval string `json:"checkin_interval,omitempty"`
 }
 
 // Action - An action for an elastic-agent. The actions are defined in generic terms on the fleet-server. The elastic-agent will have additional details for what is expected when a specific action-type is received. Many attributes in this schema also contain yaml tags so the elastic-agent may serialize them. The structure of the `data` attribute will vary between action types.  An additional consideration is Scheduled Actions. Scheduled actions are currently defined as actions that have non-empty values for both the `start_time` and `expiration` attributes.
@@ -5,6 +5,7 @@
 package fleetservertest
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -38,6 +39,15 @@ type Handlers struct {
 	// logFn if set will be used to log every request.
 	logFn func(format string, a ...any)
 
+	// RejectCompression makes the server answer with
+	// http.StatusUnsupportedMediaType to any request with a compressed body,
+	// like a fleet-server without compression support. By default, gzip
+	// encoded bodies are decompressed before reaching the handlers.
+	RejectCompression bool
+
+	// Scenario, if set, injects faults into the requests, see Scenario.
+	Scenario *Scenario
+
 	// =============================== Handlers ===============================
 	AckFn func(
 		ctx context.Context,
@@ -114,7 +124,7 @@ func NewRouter(handlers *Handlers) *mux.Router {
 			Methods(route.Method).
 			Path(route.Pattern).
 			Name(route.Name).
-			Handler(
+			Handler(handlers.Scenario.wrap(route.Name, handlers.logFn,
 				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					mu.Lock()
 					defer mu.Unlock()
@@ -124,16 +134,49 @@ func NewRouter(handlers *Handlers) *mux.Router {
 					requestID := uuid.New().String()
 					handlers.logFn("[%s] STARTING - %s %s %s %s\n",
 						requestID, r.Method, r.URL, r.Proto, r.RemoteAddr)
-					route.Handler.
-						ServeHTTP(ww, r)
+					if herr := handlers.decompressBody(r); herr != nil {
+						respondAsJSON(herr.StatusCode, herr, ww)
+					} else {
+						route.Handler.
+							ServeHTTP(ww, r)
+					}
 					handlers.logFn("[%s] DONE %d - %s %s %s %s\n",
 						requestID, ww.statusCode, r.Method, r.URL, r.Proto, r.RemoteAddr)
-				}))
+				})))
 	}
 
 	return router
 }
 
+// decompressBody replaces the body of a request sent with a Content-Encoding
+// by its decompressed content.
+func (h *Handlers) decompressBody(r *http.Request) *HTTPError {
+	encoding := r.Header.Get("Content-Encoding")
+	if encoding == "" {
+		return nil
+	}
+
+	if h.RejectCompression || encoding != "gzip" {
+		return &HTTPError{
+			StatusCode: http.StatusUnsupportedMediaType,
+			Message:    fmt.Sprintf("unsupported content encoding %q", encoding),
+		}
+	}
+
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return &HTTPError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("could not decompress request body: %v", err),
+		}
+	}
+
+	r.Body = zr
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	return nil
+}
+
 // Routes returns all the api routes for the Handlers
 func (h *Handlers) Routes() []Route {
 	return []Route{